@@ -34,8 +34,11 @@ type TaskHandler struct {
 	cancel    context.CancelFunc
 	wg        sync.WaitGroup
 	id        string
+	group     ActorGroup
+	uniqueID  interface{}
 	actors    map[string]IActor
 	state     ActorState
+	logger    *log.Logger
 }
 
 // todo SetHandler
@@ -53,13 +56,21 @@ func InitTaskHandler(ActorGroup ActorGroup, uniqueID interface{}, a IActor) *Tas
 			ctx:       ctx,
 			cancel:    cancel,
 			id:        id,
+			group:     ActorGroup,
+			uniqueID:  uniqueID,
 			actors:    make(map[string]IActor),
+			logger:    log.With("actor", id),
 		}
 		h.actors[actorName] = a
 		return h
 	}
 }
 
+// Logger 绑定了Actor id的日志
+func (h *TaskHandler) Logger() *log.Logger {
+	return h.logger
+}
+
 // 获取泛型T对应的collection名称
 func getActorNameByType[T any]() string {
 	var t T
@@ -113,6 +124,27 @@ func (b *TaskHandler) SendTask(f func() *Response) *Response {
 	}
 }
 
+// PostTask 投递任务，不等待执行结果，同一个goroutine投递的任务按顺序执行
+func (b *TaskHandler) PostTask(f func()) error {
+	if b.ctx.Err() != nil {
+		return b.ctx.Err()
+	}
+
+	task := &TaskQueue{
+		f: func() *Response {
+			f()
+			return nil
+		},
+		response: make(chan *Response, 1),
+	}
+	select {
+	case b.taskQueue <- task:
+		return nil
+	case <-b.ctx.Done():
+		return b.ctx.Err()
+	}
+}
+
 // 添加从 TaskHandler 中移除特定 Actor 的方法
 func (b *TaskHandler) RemoveActor(actorName string) {
 	delete(b.actors, actorName)
@@ -131,6 +163,7 @@ func (b *TaskHandler) Start() {
 	// 注册到Actor管理器
 	if b.id != "" {
 		Register(b.id, b)
+		announce(b, true)
 	}
 
 	b.wg.Add(1)
@@ -147,6 +180,7 @@ func (b *TaskHandler) Stop() {
 	// 从Actor管理器注销
 	if b.id != "" {
 		Unregister(b.id)
+		announce(b, false)
 	}
 }
 
@@ -25,6 +25,7 @@ var (
 // Init 初始化全局Actor管理器实例
 func Init(milliseconds int) {
 	globalActorManager = NewActorManager()
+	initRemote()
 }
 
 func NewActorManager() *ActorManager {
@@ -0,0 +1,457 @@
+package actor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gameserver/core/chanrpc"
+	"gameserver/core/cluster"
+	"gameserver/core/log"
+)
+
+// 跨节点寻址
+//
+// Actor按 ActorGroup_uniqueID 在一致性哈希环上分配到节点，环上的节点是注册表中提供"actor/<ActorGroup>"服务的节点，
+// 注册表中没有该服务时Actor都在本节点。跨节点的消息按名字注册处理函数，参数和返回值经集群序列化，
+// 处理函数在目标Actor的TaskHandler中执行，和SendTask一样串行
+//
+// 节点排空时通知其他节点把它移出哈希环，并把本节点上可迁移的Actor导出到新的节点；
+// 排空期间收到的消息转发到新的节点
+//
+// 只有用RegisterGroup注册了Export、Import的组按哈希环放置和迁移。Player和Room注册为Pinned：
+// 玩家绑定在本节点的客户端连接上，房间的广播频道订阅的是本节点的连接，换到其他节点后无法送达，
+// 它们留在创建的节点上，排空时也不转发。Pinned的Actor启动和停止时通知其他节点，
+// 其他节点按目录把消息发到它所在的节点，目录中没有时才按哈希环
+
+var (
+	ErrActorNotFound = errors.New("actor not found")
+	ErrNoHandler     = errors.New("actor message handler not found")
+)
+
+const (
+	requestService = "actor"      // Request、Import、Drain，每个调用单独的goroutine
+	postService    = "actor_post" // Post，按收到的顺序投递
+)
+
+// MessageHandler 跨节点消息的处理函数，在Actor的TaskHandler中执行
+type MessageHandler func(uniqueID interface{}, args []interface{}) (interface{}, error)
+
+// GroupOptions 可以跨节点寻址的Actor组
+type GroupOptions struct {
+	Activate func(uniqueID interface{}) error               // 收到消息而Actor不在本节点时创建，为nil时返回ErrActorNotFound
+	Export   func(uniqueID interface{}) ([]byte, error)     // 迁出时在Actor的TaskHandler中导出状态，为nil时不迁移
+	Import   func(uniqueID interface{}, state []byte) error // 在新的节点上用导出的状态创建Actor
+	Pinned   bool                                           // 留在创建的节点上，位置登记到其他节点的目录
+}
+
+type placementRing struct {
+	members string
+	ring    *hashRing
+}
+
+var (
+	remoteOnce sync.Once
+	remoteMu   sync.RWMutex
+	groups     = make(map[ActorGroup]*GroupOptions)
+	handlers   = make(map[string]MessageHandler)
+	draining   = make(map[string]bool)
+	rings      = make(map[ActorGroup]*placementRing)
+	directory  = make(map[string]string) // 其他节点上Pinned的Actor id -> 节点名
+)
+
+// initRemote 注册集群服务，其他节点通过它把消息发给本节点上的Actor
+func initRemote() {
+	remoteOnce.Do(func() {
+		requests := chanrpc.NewServer(1000)
+		requests.Register("Request", rpcRequest)
+		requests.Register("Import", rpcImport)
+		requests.Register("Drain", rpcDrain)
+		go func() {
+			for ci := range requests.ChanCall {
+				go requests.Exec(ci)
+			}
+		}()
+
+		posts := chanrpc.NewServer(1000)
+		posts.Register("Post", rpcPost)
+		posts.Register("Pin", rpcPin)
+		posts.Register("Pins", rpcPins)
+		go func() {
+			for ci := range posts.ChanCall {
+				posts.Exec(ci)
+			}
+		}()
+
+		cluster.Register(requestService, requests)
+		cluster.Register(postService, posts)
+		cluster.OnLink(onLink)
+	})
+}
+
+// RegisterGroup 注册可以迁移的Actor组
+func RegisterGroup(group ActorGroup, opts GroupOptions) {
+	remoteMu.Lock()
+	defer remoteMu.Unlock()
+	groups[group] = &opts
+}
+
+// HandleMessage 注册跨节点消息的处理函数
+func HandleMessage(group ActorGroup, name string, f MessageHandler) {
+	remoteMu.Lock()
+	defer remoteMu.Unlock()
+	key := string(group) + "/" + name
+	if _, ok := handlers[key]; ok {
+		panic(fmt.Sprintf("actor message %v already registered", key))
+	}
+	handlers[key] = f
+}
+
+func getGroup(group ActorGroup) *GroupOptions {
+	remoteMu.RLock()
+	defer remoteMu.RUnlock()
+	return groups[group]
+}
+
+func getMessageHandler(group ActorGroup, name string) MessageHandler {
+	remoteMu.RLock()
+	defer remoteMu.RUnlock()
+	return handlers[string(group)+"/"+name]
+}
+
+// PlacementService Actor组在注册表中的服务名
+func PlacementService(group ActorGroup) string {
+	return "actor/" + string(group)
+}
+
+// Locate Actor所在的节点，本节点上已有该Actor时返回本节点，排空时只有不能迁移的Actor返回本节点
+// 其他节点登记了的Pinned的Actor返回登记的节点，其余按哈希环
+// 可迁移的Actor应在Locate返回的节点上创建，否则其他节点发来的消息找不到它
+func Locate(group ActorGroup, uniqueID interface{}) string {
+	self := cluster.Self()
+	id := getUniqueId(group, uniqueID)
+	if _, ok := GetHandler(id); ok && (!isDraining(self) || !migratable(group)) {
+		return self
+	}
+	remoteMu.RLock()
+	node, ok := directory[id]
+	remoteMu.RUnlock()
+	if ok {
+		return node
+	}
+	return owner(group, uniqueID)
+}
+
+// pinned 组注册为Pinned
+func pinned(group ActorGroup) bool {
+	opts := getGroup(group)
+	return opts != nil && opts.Pinned
+}
+
+// announce Pinned的Actor启动或停止时通知已连接的节点，按Post的顺序投递，同一个Actor的通知不会乱序
+func announce(h *TaskHandler, on bool) {
+	if !pinned(h.group) {
+		return
+	}
+	self := cluster.Self()
+	for _, name := range cluster.Links() {
+		if err := cluster.GoNode(name, postService, "Pin", string(h.group), h.uniqueID, self, on); err != nil {
+			log.Error("announce actor %v to %v: %v", h.id, name, err)
+		}
+	}
+}
+
+// migratable 组注册了Export，排空时会迁出
+func migratable(group ActorGroup) bool {
+	opts := getGroup(group)
+	return opts != nil && opts.Export != nil
+}
+
+// owner 按哈希环计算的节点，没有可用节点时返回本节点
+func owner(group ActorGroup, uniqueID interface{}) string {
+	remoteMu.RLock()
+	var members []string
+	for _, name := range cluster.Providers(PlacementService(group)) {
+		if !draining[name] {
+			members = append(members, name)
+		}
+	}
+	key := strings.Join(members, ",")
+	cached := rings[group]
+	remoteMu.RUnlock()
+
+	if len(members) == 0 {
+		return cluster.Self()
+	}
+	if cached == nil || cached.members != key {
+		cached = &placementRing{members: key, ring: newHashRing(members)}
+		remoteMu.Lock()
+		rings[group] = cached
+		remoteMu.Unlock()
+	}
+	return cached.ring.get(getUniqueId(group, uniqueID))
+}
+
+func isDraining(node string) bool {
+	remoteMu.RLock()
+	defer remoteMu.RUnlock()
+	return draining[node]
+}
+
+func setDraining(node string, on bool) {
+	remoteMu.Lock()
+	defer remoteMu.Unlock()
+	if on {
+		draining[node] = true
+	} else {
+		delete(draining, node)
+	}
+}
+
+// Request 发消息给Actor并等待处理结果，Actor在其他节点时转发
+func Request(group ActorGroup, uniqueID interface{}, name string, args ...interface{}) (interface{}, error) {
+	node := Locate(group, uniqueID)
+	if node == cluster.Self() {
+		return deliver(group, uniqueID, name, args)
+	}
+	rets, err := cluster.CallNode(node, requestService, "Request", remoteArgs(group, uniqueID, name, args)...)
+	if err != nil {
+		return nil, err
+	}
+	return rets[0], remoteError(rets[1])
+}
+
+// Post 发消息给Actor，不等待处理结果，同一个goroutine发给同一个Actor的消息按顺序处理
+func Post(group ActorGroup, uniqueID interface{}, name string, args ...interface{}) error {
+	node := Locate(group, uniqueID)
+	if node == cluster.Self() {
+		return post(group, uniqueID, name, args)
+	}
+	return cluster.GoNode(node, postService, "Post", remoteArgs(group, uniqueID, name, args)...)
+}
+
+func remoteArgs(group ActorGroup, uniqueID interface{}, name string, args []interface{}) []interface{} {
+	return append([]interface{}{string(group), uniqueID, name}, args...)
+}
+
+// remoteError 还原远端返回的错误，ErrActorNotFound和ErrNoHandler保持原值方便判断
+func remoteError(v interface{}) error {
+	msg, _ := v.(string)
+	switch msg {
+	case "":
+		return nil
+	case ErrActorNotFound.Error():
+		return ErrActorNotFound
+	case ErrNoHandler.Error():
+		return ErrNoHandler
+	}
+	return errors.New(msg)
+}
+
+// localHandler 本节点上的Actor，不存在时按组的Activate创建
+func localHandler(group ActorGroup, uniqueID interface{}) (*TaskHandler, error) {
+	id := getUniqueId(group, uniqueID)
+	if h, ok := GetHandler(id); ok {
+		return h, nil
+	}
+	opts := getGroup(group)
+	if opts == nil || opts.Activate == nil {
+		return nil, ErrActorNotFound
+	}
+	if err := opts.Activate(uniqueID); err != nil {
+		return nil, err
+	}
+	if h, ok := GetHandler(id); ok {
+		return h, nil
+	}
+	return nil, ErrActorNotFound
+}
+
+func deliver(group ActorGroup, uniqueID interface{}, name string, args []interface{}) (interface{}, error) {
+	f := getMessageHandler(group, name)
+	if f == nil {
+		return nil, ErrNoHandler
+	}
+	h, err := localHandler(group, uniqueID)
+	if err != nil {
+		return nil, err
+	}
+	response := h.SendTask(func() *Response {
+		ret, err := f(uniqueID, args)
+		return &Response{Result: []interface{}{ret}, Error: err}
+	})
+	if response.Error != nil || len(response.Result) == 0 {
+		return nil, response.Error
+	}
+	return response.Result[0], nil
+}
+
+func post(group ActorGroup, uniqueID interface{}, name string, args []interface{}) error {
+	f := getMessageHandler(group, name)
+	if f == nil {
+		return ErrNoHandler
+	}
+	h, err := localHandler(group, uniqueID)
+	if err != nil {
+		return err
+	}
+	return h.PostTask(func() {
+		if _, err := f(uniqueID, args); err != nil {
+			log.Error("actor %v message %v: %v", h.id, name, err)
+		}
+	})
+}
+
+// rpcRequest 其他节点发来的Request，本节点排空时转发到新的节点
+func rpcRequest(args []interface{}) []interface{} {
+	group, uniqueID, name := ActorGroup(args[0].(string)), args[1], args[2].(string)
+	var ret interface{}
+	var err error
+	if isDraining(cluster.Self()) {
+		ret, err = Request(group, uniqueID, name, args[3:]...)
+	} else {
+		ret, err = deliver(group, uniqueID, name, args[3:])
+	}
+	if err != nil {
+		return []interface{}{ret, err.Error()}
+	}
+	return []interface{}{ret, ""}
+}
+
+// rpcPost 其他节点发来的Post
+func rpcPost(args []interface{}) {
+	group, uniqueID, name := ActorGroup(args[0].(string)), args[1], args[2].(string)
+	var err error
+	if isDraining(cluster.Self()) {
+		err = Post(group, uniqueID, name, args[3:]...)
+	} else {
+		err = post(group, uniqueID, name, args[3:])
+	}
+	if err != nil {
+		log.Error("actor %v message %v: %v", getUniqueId(group, uniqueID), name, err)
+	}
+}
+
+// rpcPin 其他节点上Pinned的Actor启动或停止
+func rpcPin(args []interface{}) {
+	id, node, on := getUniqueId(ActorGroup(args[0].(string)), args[1]), args[2].(string), args[3].(bool)
+	remoteMu.Lock()
+	defer remoteMu.Unlock()
+	if on {
+		directory[id] = node
+	} else if directory[id] == node {
+		delete(directory, id)
+	}
+}
+
+// rpcPins 其他节点连接时发来的全部Pinned的Actor，替换该节点之前登记的，断线期间错过的停止通知一并清除
+// 参数为节点名和交替的组、uniqueID
+func rpcPins(args []interface{}) {
+	node := args[0].(string)
+	remoteMu.Lock()
+	defer remoteMu.Unlock()
+	for id, n := range directory {
+		if n == node {
+			delete(directory, id)
+		}
+	}
+	for i := 1; i+1 < len(args); i += 2 {
+		directory[getUniqueId(ActorGroup(args[i].(string)), args[i+1])] = node
+	}
+}
+
+// rpcImport 其他节点排空时迁入的Actor，已经被消息激活的Actor先停止，以迁入的状态为准
+func rpcImport(args []interface{}) []interface{} {
+	group, uniqueID := ActorGroup(args[0].(string)), args[1]
+	state, _ := args[2].([]byte)
+	opts := getGroup(group)
+	if opts == nil || opts.Import == nil {
+		return []interface{}{ErrNoHandler.Error()}
+	}
+	if h, ok := GetHandler(getUniqueId(group, uniqueID)); ok {
+		h.Stop()
+	}
+	if err := opts.Import(uniqueID, state); err != nil {
+		return []interface{}{err.Error()}
+	}
+	return []interface{}{""}
+}
+
+// rpcDrain 其他节点开始或取消排空
+func rpcDrain(args []interface{}) {
+	node, on := args[0].(string), args[1].(bool)
+	setDraining(node, on)
+	log.Release("cluster node %v draining: %v", node, on)
+}
+
+// onLink 新连接的节点可能错过了排空通知和Pinned的Actor的启停通知，把本节点的状态发给它
+func onLink(name string) {
+	self := cluster.Self()
+	if isDraining(self) {
+		cluster.GoNode(name, requestService, "Drain", self, true)
+	}
+	pins := []interface{}{self}
+	for _, h := range GetAllTaskHandlers() {
+		if pinned(h.group) {
+			pins = append(pins, string(h.group), h.uniqueID)
+		}
+	}
+	cluster.GoNode(name, postService, "Pins", pins...)
+}
+
+// Drain 排空本节点：其他节点把本节点移出哈希环，可迁移的Actor导出到新的节点，返回迁移的Actor数
+// 没有其他节点可以接收的Actor留在本节点
+func Drain() int {
+	self := cluster.Self()
+	setDraining(self, true)
+	for _, name := range cluster.Links() {
+		if err := cluster.GoNode(name, requestService, "Drain", self, true); err != nil {
+			log.Error("notify drain to %v: %v", name, err)
+		}
+	}
+
+	migrated := 0
+	for _, h := range GetAllTaskHandlers() {
+		if !migratable(h.group) {
+			continue
+		}
+		opts := getGroup(h.group)
+		target := owner(h.group, h.uniqueID)
+		if target == self {
+			continue
+		}
+		if err := migrate(h, opts, target); err != nil {
+			log.Error("migrate actor %v to %v: %v", h.id, target, err)
+			continue
+		}
+		migrated++
+	}
+	log.Release("actor drain: %d actors migrated", migrated)
+	return migrated
+}
+
+// migrate 在Actor中导出状态并由目标节点导入，导入成功后才停止本地的Actor
+// 导入在Actor的TaskHandler中完成，期间不会处理其他任务，导出的状态不会过期；
+// 目标节点不可用或导入失败时Actor留在本节点继续运行
+func migrate(h *TaskHandler, opts *GroupOptions, target string) error {
+	response := h.SendTask(func() *Response {
+		state, err := opts.Export(h.uniqueID)
+		if err != nil {
+			return &Response{Error: err}
+		}
+		if state == nil {
+			state = []byte{}
+		}
+		rets, err := cluster.CallNode(target, requestService, "Import", string(h.group), h.uniqueID, state)
+		if err != nil {
+			return &Response{Error: err}
+		}
+		return &Response{Error: remoteError(rets[0])}
+	})
+	if response.Error != nil {
+		return response.Error
+	}
+	h.Stop()
+	return nil
+}
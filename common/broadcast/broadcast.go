@@ -0,0 +1,249 @@
+package broadcast
+
+import (
+	"fmt"
+	"gameserver/common/msg"
+	"gameserver/common/msg/message"
+	"gameserver/core/gate"
+	"gameserver/core/log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// World 全服频道，所有登录的连接都会加入
+const World = "world"
+
+// Server 区服频道
+func Server(serverId int32) string {
+	return fmt.Sprintf("server:%d", serverId)
+}
+
+// Room 房间频道
+func Room(roomId int64) string {
+	return fmt.Sprintf("room:%d", roomId)
+}
+
+// Team 队伍频道
+func Team(teamId int64) string {
+	return fmt.Sprintf("team:%d", teamId)
+}
+
+// Guild 公会频道
+func Guild(guildId int64) string {
+	return fmt.Sprintf("guild:%d", guildId)
+}
+
+// Service 频道订阅与消息扇出
+// 同一条消息只序列化一次，再写给频道内的所有连接
+type Service struct {
+	mutex         sync.RWMutex
+	channels      map[string]map[gate.Agent]struct{} // 频道 -> 订阅的连接
+	agentChannels map[gate.Agent]map[string]struct{} // 连接 -> 已订阅的频道，用于断线时清理
+
+	published int64 // 发布次数（即序列化次数）
+	delivered int64 // 写出的连接次数
+}
+
+var (
+	service     *Service
+	serviceOnce sync.Once
+)
+
+func GetService() *Service {
+	serviceOnce.Do(func() {
+		service = NewService()
+	})
+	return service
+}
+
+func NewService() *Service {
+	return &Service{
+		channels:      make(map[string]map[gate.Agent]struct{}),
+		agentChannels: make(map[gate.Agent]map[string]struct{}),
+	}
+}
+
+// Subscribe 连接加入频道
+func (s *Service) Subscribe(a gate.Agent, channels ...string) {
+	if a == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	joined, ok := s.agentChannels[a]
+	if !ok {
+		joined = make(map[string]struct{})
+		s.agentChannels[a] = joined
+	}
+	for _, ch := range channels {
+		agents, ok := s.channels[ch]
+		if !ok {
+			agents = make(map[gate.Agent]struct{})
+			s.channels[ch] = agents
+		}
+		agents[a] = struct{}{}
+		joined[ch] = struct{}{}
+	}
+}
+
+// Unsubscribe 连接离开频道
+func (s *Service) Unsubscribe(a gate.Agent, channels ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, ch := range channels {
+		s.remove(a, ch)
+	}
+}
+
+// UnsubscribeAll 连接离开所有频道，断线时调用
+func (s *Service) UnsubscribeAll(a gate.Agent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for ch := range s.agentChannels[a] {
+		s.remove(a, ch)
+	}
+	delete(s.agentChannels, a)
+}
+
+// CloseChannel 解散频道，如房间结束
+func (s *Service) CloseChannel(ch string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for a := range s.channels[ch] {
+		s.remove(a, ch)
+	}
+}
+
+func (s *Service) remove(a gate.Agent, ch string) {
+	if agents, ok := s.channels[ch]; ok {
+		delete(agents, a)
+		if len(agents) == 0 {
+			delete(s.channels, ch)
+		}
+	}
+	if joined, ok := s.agentChannels[a]; ok {
+		delete(joined, ch)
+		if len(joined) == 0 {
+			delete(s.agentChannels, a)
+		}
+	}
+}
+
+// Members 频道当前的连接数
+func (s *Service) Members(ch string) int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.channels[ch])
+}
+
+// Publish 向频道发送消息，except中的连接不会收到，返回写出的连接数
+func (s *Service) Publish(ch string, m proto.Message, except ...gate.Agent) int {
+	return s.PublishMulti([]string{ch}, m, except...)
+}
+
+// PublishMulti 向多个频道发送消息，同时在多个频道中的连接只收到一次
+func (s *Service) PublishMulti(channels []string, m proto.Message, except ...gate.Agent) int {
+	s.mutex.RLock()
+	var targets []gate.Agent
+	seen := make(map[gate.Agent]struct{})
+	for _, a := range except {
+		seen[a] = struct{}{}
+	}
+	for _, ch := range channels {
+		for a := range s.channels[ch] {
+			if _, ok := seen[a]; ok {
+				continue
+			}
+			seen[a] = struct{}{}
+			targets = append(targets, a)
+		}
+	}
+	s.mutex.RUnlock()
+
+	if len(targets) == 0 {
+		return 0
+	}
+
+	// 锁外写，避免慢连接阻塞订阅操作
+	data, err := msg.Processor.Marshal(m)
+	if err != nil {
+		log.Error("broadcast marshal message %T error: %v", m, err)
+		return 0
+	}
+	for _, a := range targets {
+		a.WriteData(data...)
+	}
+
+	atomic.AddInt64(&s.published, 1)
+	atomic.AddInt64(&s.delivered, int64(len(targets)))
+	return len(targets)
+}
+
+// Announce 全服公告
+func (s *Service) Announce(content string) int {
+	return s.Publish(World, &message.S2C_Announcement{
+		Content: content,
+		Time:    time.Now().Unix(),
+	})
+}
+
+// Report 频道与发送统计，供console命令查看
+func (s *Service) Report() string {
+	s.mutex.RLock()
+	names := make([]string, 0, len(s.channels))
+	counts := make(map[string]int, len(s.channels))
+	for ch, agents := range s.channels {
+		names = append(names, ch)
+		counts[ch] = len(agents)
+	}
+	agentNum := len(s.agentChannels)
+	s.mutex.RUnlock()
+
+	sort.Strings(names)
+	var b strings.Builder
+	fmt.Fprintf(&b, "agents: %d, channels: %d, published: %d, delivered: %d",
+		agentNum, len(names), atomic.LoadInt64(&s.published), atomic.LoadInt64(&s.delivered))
+	for _, ch := range names {
+		fmt.Fprintf(&b, "\r\n%-24s %d", ch, counts[ch])
+	}
+	return b.String()
+}
+
+// 以下为默认Service的快捷方法
+
+func Subscribe(a gate.Agent, channels ...string) {
+	GetService().Subscribe(a, channels...)
+}
+
+func Unsubscribe(a gate.Agent, channels ...string) {
+	GetService().Unsubscribe(a, channels...)
+}
+
+func UnsubscribeAll(a gate.Agent) {
+	GetService().UnsubscribeAll(a)
+}
+
+func CloseChannel(ch string) {
+	GetService().CloseChannel(ch)
+}
+
+func Publish(ch string, m proto.Message, except ...gate.Agent) int {
+	return GetService().Publish(ch, m, except...)
+}
+
+func PublishMulti(channels []string, m proto.Message, except ...gate.Agent) int {
+	return GetService().PublishMulti(channels, m, except...)
+}
+
+func Announce(content string) int {
+	return GetService().Announce(content)
+}
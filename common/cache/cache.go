@@ -0,0 +1,537 @@
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gameserver/common/monitor"
+	"gameserver/core/log"
+)
+
+// Loader 未命中时加载数据，ok为false表示数据不存在，不会缓存
+type Loader[K comparable, V any] func(key K) (value V, ok bool, err error)
+
+// Writer 批量写回脏数据
+type Writer[K comparable, V any] func(items map[K]V) error
+
+// EvictReason 条目被移出缓存的原因
+type EvictReason int
+
+const (
+	Expired     EvictReason = iota // 超过TTL未访问
+	Capacity                       // 超出容量，淘汰最久未访问的
+	Invalidated                    // 调用Invalidate或Clear
+	Removed                        // 调用Remove，脏数据已写回
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Capacity:
+		return "capacity"
+	case Invalidated:
+		return "invalidated"
+	case Removed:
+		return "removed"
+	}
+	return fmt.Sprintf("EvictReason(%d)", int(r))
+}
+
+// 默认的写回延迟
+const defaultFlushDelay = time.Second
+
+// Options 缓存配置
+type Options[K comparable, V any] struct {
+	Name       string        // 名称，用于统计和日志，不为空时注册到全局，见Report和CloseAll
+	MaxSize    int           // 最多缓存的条目数，超出时淘汰最久未访问的，0不限制
+	TTL        time.Duration // 条目在最后一次访问后的存活时间，0不过期
+	Loader     Loader[K, V]  // 读穿透加载，nil时Load只读缓存
+	Writer     Writer[K, V]  // 写回，nil时Store等同于Set
+	FlushDelay time.Duration // 脏数据最长延迟多久写回，默认1秒
+	// OnEvict 条目被移出缓存时回调，在锁外调用
+	OnEvict func(key K, value V, reason EvictReason)
+}
+
+type entry[K comparable, V any] struct {
+	key      K
+	value    V
+	expireAt time.Time
+	dirty    bool
+	flushing bool // 正在写回，写回结束前和脏数据一样不会被淘汰
+}
+
+// Cache 读穿透、写回的本地缓存，并发安全
+// 脏数据和正在写回的数据不会因为容量或TTL被淘汰，写回成功后才会参与淘汰
+type Cache[K comparable, V any] struct {
+	opts  Options[K, V]
+	stats monitor.ChannelStats
+
+	mu      sync.Mutex
+	items   map[K]*list.Element
+	lru     *list.List // 前面是最近访问的
+	dirty   int
+	timer   *time.Timer // 等待写回的定时器，没有脏数据时为nil
+	closed  bool
+	flushMu sync.Mutex // 保证写回按顺序执行
+
+	flushes     int64
+	flushErrors int64
+}
+
+// New 创建缓存
+func New[K comparable, V any](opts Options[K, V]) *Cache[K, V] {
+	if opts.FlushDelay <= 0 {
+		opts.FlushDelay = defaultFlushDelay
+	}
+	c := &Cache[K, V]{
+		opts:  opts,
+		items: make(map[K]*list.Element),
+		lru:   list.New(),
+	}
+	if opts.Name != "" {
+		register(opts.Name, c)
+	}
+	return c
+}
+
+// Name 缓存名称
+func (c *Cache[K, V]) Name() string {
+	return c.opts.Name
+}
+
+// Get 只从缓存读取
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	value, ok, evicted := c.get(key)
+	c.mu.Unlock()
+	c.notify(evicted)
+	if ok {
+		c.stats.IncrementCacheHits()
+	} else {
+		c.stats.IncrementCacheMisses()
+	}
+	return value, ok
+}
+
+// Load 读穿透，未命中时通过Loader加载并缓存
+func (c *Cache[K, V]) Load(key K) (V, bool, error) {
+	if value, ok := c.Get(key); ok || c.opts.Loader == nil {
+		return value, ok, nil
+	}
+
+	// 加载时不持有锁，同一个key并发加载时以先写入缓存的为准
+	value, ok, err := c.opts.Loader(key)
+	if err != nil || !ok {
+		return value, false, err
+	}
+	c.mu.Lock()
+	if elem, exists := c.items[key]; exists {
+		value = elem.Value.(*entry[K, V]).value
+	} else {
+		c.set(key, value, false)
+	}
+	evicted := c.evict()
+	c.mu.Unlock()
+	c.notify(evicted)
+	return value, true, nil
+}
+
+// Set 写入缓存，不标记为脏数据，用于已经落地或不需要落地的数据
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	c.set(key, value, false)
+	evicted := c.evict()
+	c.mu.Unlock()
+	c.notify(evicted)
+}
+
+// Store 写入缓存并标记为脏数据，最迟FlushDelay后通过Writer写回
+func (c *Cache[K, V]) Store(key K, value V) {
+	c.mu.Lock()
+	dirty := c.opts.Writer != nil
+	c.set(key, value, dirty)
+	if dirty && c.timer == nil && !c.closed {
+		c.timer = time.AfterFunc(c.opts.FlushDelay, c.flushLater)
+	}
+	evicted := c.evict()
+	c.mu.Unlock()
+	c.notify(evicted)
+}
+
+// Remove 移出缓存，脏数据先写回，写回失败时保留在缓存中
+func (c *Cache[K, V]) Remove(key K) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	for {
+		elem, exists := c.items[key]
+		if !exists {
+			c.mu.Unlock()
+			return nil
+		}
+		e := elem.Value.(*entry[K, V])
+		if !e.dirty {
+			c.remove(elem)
+			c.mu.Unlock()
+			c.notify([]evicted[K, V]{entryEvicted(e, Removed)})
+			return nil
+		}
+		// 写回期间可能又有修改，重新检查
+		items := map[K]V{key: e.value}
+		e.dirty = false
+		e.flushing = true
+		c.dirty--
+		c.mu.Unlock()
+		err := c.write(items)
+		c.mu.Lock()
+		c.flushed(items, err)
+		if err != nil {
+			c.mu.Unlock()
+			return err
+		}
+	}
+}
+
+// Invalidate 丢弃缓存的条目，未写回的修改也一起丢弃
+// 用于数据在缓存之外被修改后，让下次Load重新加载
+func (c *Cache[K, V]) Invalidate(key K) {
+	c.mu.Lock()
+	elem, exists := c.items[key]
+	if !exists {
+		c.mu.Unlock()
+		return
+	}
+	e := elem.Value.(*entry[K, V])
+	c.remove(elem)
+	c.mu.Unlock()
+	c.notify([]evicted[K, V]{entryEvicted(e, Invalidated)})
+}
+
+// Clear 写回脏数据后清空缓存，写回失败时不清空
+func (c *Cache[K, V]) Clear() error {
+	if err := c.Flush(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	evicted := make([]evicted[K, V], 0, len(c.items))
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		e := elem.Value.(*entry[K, V])
+		evicted = append(evicted, entryEvicted(e, Invalidated))
+	}
+	c.items = make(map[K]*list.Element)
+	c.lru.Init()
+	c.dirty = 0
+	c.mu.Unlock()
+	c.notify(evicted)
+	return nil
+}
+
+// Flush 立即写回所有脏数据
+func (c *Cache[K, V]) Flush() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.dirty == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	items := make(map[K]V, c.dirty)
+	for _, elem := range c.items {
+		if e := elem.Value.(*entry[K, V]); e.dirty {
+			items[e.key] = e.value
+			e.dirty = false
+			e.flushing = true
+		}
+	}
+	c.dirty = 0
+	c.mu.Unlock()
+
+	err := c.write(items)
+	c.mu.Lock()
+	c.flushed(items, err)
+	// 写回期间又有新的脏数据
+	if c.dirty > 0 && c.timer == nil && !c.closed {
+		c.timer = time.AfterFunc(c.opts.FlushDelay, c.flushLater)
+	}
+	evicted := c.evict()
+	c.mu.Unlock()
+	c.notify(evicted)
+	return err
+}
+
+// Close 写回脏数据并停止定时写回，之后Store不再触发写回
+func (c *Cache[K, V]) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	if c.opts.Name != "" {
+		unregister(c.opts.Name, c)
+	}
+	return c.Flush()
+}
+
+// Range 遍历缓存，fn返回false时停止，遍历期间不能调用缓存的其他方法
+func (c *Cache[K, V]) Range(fn func(key K, value V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		e := elem.Value.(*entry[K, V])
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Len 缓存的条目数
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Stats 命中率统计
+func (c *Cache[K, V]) Stats() map[string]interface{} {
+	perf := c.stats.GetPerformanceStats()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return map[string]interface{}{
+		"size":           len(c.items),
+		"dirty":          c.dirty,
+		"cache_hits":     perf["cache_hits"],
+		"cache_misses":   perf["cache_misses"],
+		"cache_hit_rate": perf["cache_hit_rate"],
+		"flushes":        c.flushes,
+		"flush_errors":   c.flushErrors,
+	}
+}
+
+// Report 统计的文本形式
+func (c *Cache[K, V]) Report() string {
+	s := c.Stats()
+	return fmt.Sprintf("%s: size %d, dirty %d, hits %d, misses %d, hit rate %s, flushes %d, flush errors %d",
+		c.opts.Name, s["size"], s["dirty"], s["cache_hits"], s["cache_misses"], s["cache_hit_rate"],
+		s["flushes"], s["flush_errors"])
+}
+
+// flushLater 定时写回，失败时保留脏数据等待下次写回
+func (c *Cache[K, V]) flushLater() {
+	if err := c.Flush(); err != nil {
+		log.Error("cache %s flush failed: %v", c.opts.Name, err)
+	}
+}
+
+func (c *Cache[K, V]) write(items map[K]V) error {
+	err := c.opts.Writer(items)
+	c.mu.Lock()
+	c.flushes++
+	if err != nil {
+		c.flushErrors++
+	}
+	c.mu.Unlock()
+	return err
+}
+
+// flushed 写回结束，条目重新参与淘汰
+// 写回失败时，还在缓存中且没有更新的修改的条目重新标记为脏数据
+func (c *Cache[K, V]) flushed(items map[K]V, err error) {
+	for key, value := range items {
+		elem, exists := c.items[key]
+		if !exists {
+			// 写回期间被Invalidate，修改按约定丢弃
+			continue
+		}
+		e := elem.Value.(*entry[K, V])
+		e.flushing = false
+		if err != nil && !e.dirty {
+			e.value = value
+			e.dirty = true
+			c.dirty++
+		}
+	}
+}
+
+func (c *Cache[K, V]) get(key K) (V, bool, []evicted[K, V]) {
+	var zero V
+	elem, exists := c.items[key]
+	if !exists {
+		return zero, false, nil
+	}
+	e := elem.Value.(*entry[K, V])
+	if c.opts.TTL > 0 {
+		now := time.Now()
+		if !e.dirty && !e.flushing && now.After(e.expireAt) {
+			c.remove(elem)
+			return zero, false, []evicted[K, V]{entryEvicted(e, Expired)}
+		}
+		e.expireAt = now.Add(c.opts.TTL)
+	}
+	c.lru.MoveToFront(elem)
+	return e.value, true, nil
+}
+
+func (c *Cache[K, V]) set(key K, value V, dirty bool) {
+	var expireAt time.Time
+	if c.opts.TTL > 0 {
+		expireAt = time.Now().Add(c.opts.TTL)
+	}
+	if elem, exists := c.items[key]; exists {
+		e := elem.Value.(*entry[K, V])
+		e.value = value
+		e.expireAt = expireAt
+		if dirty && !e.dirty {
+			e.dirty = true
+			c.dirty++
+		}
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.lru.PushFront(&entry[K, V]{key: key, value: value, expireAt: expireAt, dirty: dirty})
+	if dirty {
+		c.dirty++
+	}
+}
+
+func (c *Cache[K, V]) remove(elem *list.Element) {
+	e := elem.Value.(*entry[K, V])
+	if e.dirty {
+		c.dirty--
+	}
+	c.lru.Remove(elem)
+	delete(c.items, e.key)
+}
+
+// evict 从最久未访问的开始淘汰过期和超出容量的条目，跳过脏数据和正在写回的条目
+// 按最后访问时间计算TTL，所以LRU的顺序也是过期的顺序
+func (c *Cache[K, V]) evict() []evicted[K, V] {
+	if c.opts.TTL <= 0 && c.opts.MaxSize <= 0 {
+		return nil
+	}
+	var out []evicted[K, V]
+	now := time.Now()
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		e := elem.Value.(*entry[K, V])
+		overSize := c.opts.MaxSize > 0 && len(c.items) > c.opts.MaxSize
+		expired := c.opts.TTL > 0 && now.After(e.expireAt)
+		if !overSize && !expired {
+			break
+		}
+		if !e.dirty && !e.flushing {
+			c.remove(elem)
+			reason := Capacity
+			if expired {
+				reason = Expired
+			}
+			out = append(out, entryEvicted(e, reason))
+		}
+		elem = prev
+	}
+	return out
+}
+
+type evicted[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+func entryEvicted[K comparable, V any](e *entry[K, V], reason EvictReason) evicted[K, V] {
+	return evicted[K, V]{e.key, e.value, reason}
+}
+
+func (c *Cache[K, V]) notify(items []evicted[K, V]) {
+	if c.opts.OnEvict == nil {
+		return
+	}
+	for _, item := range items {
+		c.opts.OnEvict(item.key, item.value, item.reason)
+	}
+}
+
+// 全局注册的缓存，用于控制台统计和停服时写回
+type namedCache interface {
+	Name() string
+	Report() string
+	Flush() error
+	Close() error
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]namedCache{}
+)
+
+func register(name string, c namedCache) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[name]; exists {
+		log.Error("cache %s registered twice, the old one is replaced", name)
+	}
+	registry[name] = c
+}
+
+func unregister(name string, c namedCache) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if registry[name] == c {
+		delete(registry, name)
+	}
+}
+
+func registered() []namedCache {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	caches := make([]namedCache, 0, len(registry))
+	for _, c := range registry {
+		caches = append(caches, c)
+	}
+	sort.Slice(caches, func(i, j int) bool { return caches[i].Name() < caches[j].Name() })
+	return caches
+}
+
+// Report 所有注册的缓存的统计
+func Report() string {
+	caches := registered()
+	if len(caches) == 0 {
+		return "no caches"
+	}
+	lines := make([]string, 0, len(caches))
+	for _, c := range caches {
+		lines = append(lines, c.Report())
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// FlushAll 立即写回所有注册的缓存
+func FlushAll() error {
+	var errs []error
+	for _, c := range registered() {
+		if err := c.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("cache %s: %w", c.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CloseAll 写回并关闭所有注册的缓存，停服时调用
+func CloseAll() error {
+	var errs []error
+	for _, c := range registered() {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("cache %s: %w", c.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
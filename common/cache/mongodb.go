@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"context"
+
+	"gameserver/common/db/mongodb"
+)
+
+// ById 按_id从数据库读取的Loader
+func ById[K comparable, T mongodb.PersistData](repo *mongodb.Repository[T]) Loader[K, *T] {
+	return func(key K) (*T, bool, error) {
+		doc, err := repo.FindById(context.Background(), key)
+		if err != nil || doc == nil {
+			return nil, false, err
+		}
+		return doc, true, nil
+	}
+}
+
+// SaveAll 按_id批量保存到数据库的Writer
+func SaveAll[K comparable, V mongodb.PersistData]() Writer[K, V] {
+	return func(items map[K]V) error {
+		docs := make([]mongodb.PersistData, 0, len(items))
+		for _, value := range items {
+			docs = append(docs, value)
+		}
+		_, err := mongodb.BulkSave(docs)
+		return err
+	}
+}
@@ -1,35 +1,135 @@
 package config
 
 import (
+	"crypto/sha1"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gameserver/core/log"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// 保留的历史版本数，用于回滚
+const maxHistory = 5
+
+// Subscriber 配置版本切换后的回调，old为切换前的版本
+type Subscriber func(old, new *Snapshot)
+
 // ConfigManager 配置管理器
+// 读取总是访问当前版本的Snapshot，加载、重载和回滚都是构造完整的新版本后原子替换
 type ConfigManager struct {
-	configs map[string]map[string]interface{} // 文件名 -> {ID -> 配置数据}
-	mu      sync.RWMutex
+	current atomic.Pointer[Snapshot]
+	mu      sync.Mutex  // 串行化加载、重载与回滚
+	history []*Snapshot // 被替换下来的旧版本，最近的在最后
+	version uint64
 	baseDir string
+
+	subscribers []Subscriber
+	subMu       sync.Mutex
 }
 
 // NewConfigManager 创建新的配置管理器
 func NewConfigManager(baseDir string) *ConfigManager {
-	return &ConfigManager{
-		configs: make(map[string]map[string]interface{}),
-		baseDir: baseDir,
+	cm := &ConfigManager{baseDir: baseDir}
+	cm.current.Store(newSnapshot())
+	return cm
+}
+
+// Current 当前版本的配置，同一次业务处理中应使用同一个Snapshot
+func (cm *ConfigManager) Current() *Snapshot {
+	return cm.current.Load()
+}
+
+// Version 当前配置版本号，每次成功加载加一，未加载时为0
+func (cm *ConfigManager) Version() uint64 {
+	return cm.Current().Version
+}
+
+// History 可回滚的历史版本号，最近的在最后
+func (cm *ConfigManager) History() []uint64 {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	versions := make([]uint64, len(cm.history))
+	for i, snap := range cm.history {
+		versions[i] = snap.Version
+	}
+	return versions
+}
+
+// Subscribe 订阅版本切换，回调在切换完成后同步执行，需要修改业务状态时应投递到对应的Actor
+func (cm *ConfigManager) Subscribe(subscriber Subscriber) {
+	cm.subMu.Lock()
+	defer cm.subMu.Unlock()
+	cm.subscribers = append(cm.subscribers, subscriber)
+}
+
+func (cm *ConfigManager) notify(old, new *Snapshot) {
+	cm.subMu.Lock()
+	subscribers := append([]Subscriber(nil), cm.subscribers...)
+	cm.subMu.Unlock()
+
+	for _, subscriber := range subscribers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Error("配置订阅回调异常: %v", r)
+				}
+			}()
+			subscriber(old, new)
+		}()
+	}
+}
+
+// swap 替换为新版本，调用方持有cm.mu
+func (cm *ConfigManager) swap(next *Snapshot) *Snapshot {
+	cm.version++
+	next.Version = cm.version
+	next.LoadedAt = time.Now()
+
+	old := cm.current.Load()
+	if old.Version > 0 {
+		cm.history = append(cm.history, old)
+		if len(cm.history) > maxHistory {
+			cm.history = cm.history[len(cm.history)-maxHistory:]
+		}
 	}
+	cm.current.Store(next)
+	return old
 }
 
-// LoadConfig 加载指定JSON配置文件
+// LoadConfig 加载指定JSON配置文件，生成包含该文件新内容的新版本，校验失败时当前版本不变
 func (cm *ConfigManager) LoadConfig(filename string) error {
 	cm.mu.Lock()
-	defer cm.mu.Unlock()
+	next := cm.Current().clone()
+	var errs []*FieldError
+	if err := cm.readFile(next, filename); err != nil {
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			cm.mu.Unlock()
+			return err
+		}
+		errs = verr.Errors
+	}
+	if errs = append(errs, checkFileReferences(next, filename)...); len(errs) > 0 {
+		cm.mu.Unlock()
+		return &ValidationError{Errors: errs}
+	}
+	old := cm.swap(next)
+	cm.mu.Unlock()
 
+	cm.notify(old, next)
+	return nil
+}
+
+// readFile 读取并解码单个配置文件到snapshot，有schema的表同时校验字段
+// 字段校验失败时仍写入已解码的部分，便于调用方继续收集外键错误，失败的snapshot不会被使用
+func (cm *ConfigManager) readFile(snap *Snapshot, filename string) error {
 	filePath := filepath.Join(cm.baseDir, filename)
 
 	// 读取文件
@@ -55,30 +155,74 @@ func (cm *ConfigManager) LoadConfig(filename string) error {
 		}
 	}
 
-	cm.configs[filename] = configMap
-	return nil
+	// 有schema的表解码为结构体并校验
+	if loader, ok := tableLoaders[filename]; ok {
+		reader := newTableReader(filename, configArray)
+		snap.tables[filename] = loader(reader)
+		snap.refs[filename] = reader.refs
+		if len(reader.errs) > 0 {
+			err = &ValidationError{Errors: reader.errs}
+		}
+	}
+
+	snap.configs[filename] = configMap
+	snap.sums[filename] = sha1.Sum(data)
+	return err
 }
 
-// GetConfig 根据文件名和ID获取配置
-func (cm *ConfigManager) GetConfig(filename, id string) (interface{}, bool) {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
+// checkFileReferences 校验指定文件的外键，以及其他文件对它的引用
+func checkFileReferences(snap *Snapshot, filename string) []*FieldError {
+	keys := snapshotKeys(snap)
+	errs := checkReferences(snap.refs[filename], keys)
+	var incoming []reference
+	for file, fileRefs := range snap.refs {
+		if file == filename {
+			continue
+		}
+		for _, ref := range fileRefs {
+			if ref.target == filename {
+				incoming = append(incoming, ref)
+			}
+		}
+	}
+	return append(errs, checkReferences(incoming, keys)...)
+}
 
-	configMap, exists := cm.configs[filename]
-	if !exists {
-		return nil, false
+// checkAllReferences 校验所有文件的外键
+func checkAllReferences(snap *Snapshot) []*FieldError {
+	var refs []reference
+	for _, filename := range snap.Files() {
+		refs = append(refs, snap.refs[filename]...)
 	}
+	return checkReferences(refs, snapshotKeys(snap))
+}
+
+func snapshotKeys(snap *Snapshot) map[string]map[string]struct{} {
+	keys := make(map[string]map[string]struct{}, len(snap.configs))
+	for file, configs := range snap.configs {
+		ids := make(map[string]struct{}, len(configs))
+		for id := range configs {
+			ids[id] = struct{}{}
+		}
+		keys[file] = ids
+	}
+	return keys
+}
 
-	config, exists := configMap[id]
-	return config, exists
+// GetTable 获取生成代码解码后的表
+func (cm *ConfigManager) GetTable(filename string) (interface{}, bool) {
+	return cm.Current().GetTable(filename)
+}
+
+// GetConfig 根据文件名和ID获取配置
+func (cm *ConfigManager) GetConfig(filename, id string) (interface{}, bool) {
+	return cm.Current().GetConfig(filename, id)
 }
 
 // GetConfigByID 根据ID获取配置（自动查找所有已加载的文件）
 func (cm *ConfigManager) GetConfigByID(id string) (string, interface{}, bool) {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
-	for filename, configMap := range cm.configs {
+	snap := cm.Current()
+	for filename, configMap := range snap.configs {
 		if config, exists := configMap[id]; exists {
 			return filename, config, true
 		}
@@ -89,11 +233,7 @@ func (cm *ConfigManager) GetConfigByID(id string) (string, interface{}, bool) {
 
 // GetAllConfigs 获取指定文件的所有配置
 func (cm *ConfigManager) GetAllConfigs(filename string) (map[string]interface{}, bool) {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
-	configMap, exists := cm.configs[filename]
-	return configMap, exists
+	return cm.Current().GetAllConfigs(filename)
 }
 
 // ReloadConfig 重新加载指定配置文件
@@ -101,76 +241,41 @@ func (cm *ConfigManager) ReloadConfig(filename string) error {
 	return cm.LoadConfig(filename)
 }
 
-// ReloadAll 重新加载所有配置文件
+// ReloadAll 从目录重新加载所有配置文件，全部校验通过后才切换版本
 func (cm *ConfigManager) ReloadAll() error {
-	// 先重新加载原始配置文件
-	cm.mu.Lock()
-	filenames := make([]string, 0, len(cm.configs))
-	for filename := range cm.configs {
-		filenames = append(filenames, filename)
-	}
-	cm.mu.Unlock()
-
-	for _, filename := range filenames {
-		if err := cm.ReloadConfig(filename); err != nil {
-			return err
-		}
-	}
-
-	// 然后调用每个生成文件的 reload 方法
-	if err := reloadAllGeneratedConfigs(); err != nil {
-		return fmt.Errorf("重新加载生成配置失败: %v", err)
-	}
-
-	return nil
+	return cm.LoadAllConfigs()
 }
 
-// reloadAllGeneratedConfigs 重新加载所有生成的配置文件
-func reloadAllGeneratedConfigs() error {
-	// 由于生成的配置文件在不同的包中，我们需要通过其他方式来调用它们的 reload 方法
-	// 这里我们提供一个通用的机制，让外部代码可以注册自己的 reload 函数
-
-	// 调用所有注册的 reload 函数
-	for _, reloadFunc := range registeredReloadFuncs {
-		if err := reloadFunc(); err != nil {
-			return fmt.Errorf("调用注册的 reload 函数失败: %v", err)
-		}
+// Rollback 回滚到上一个版本
+func (cm *ConfigManager) Rollback() error {
+	cm.mu.Lock()
+	if len(cm.history) == 0 {
+		cm.mu.Unlock()
+		return errors.New("没有可回滚的版本")
 	}
+	prev := cm.history[len(cm.history)-1]
+	cm.history = cm.history[:len(cm.history)-1]
+	old := cm.current.Load()
+	cm.current.Store(prev)
+	cm.mu.Unlock()
 
+	log.Release("配置从版本 %d 回滚到版本 %d", old.Version, prev.Version)
+	cm.notify(old, prev)
 	return nil
 }
 
-// ReloadFunc 重新加载配置的函数类型
-type ReloadFunc func() error
-
-// registeredReloadFuncs 存储所有注册的 reload 函数
-var registeredReloadFuncs []ReloadFunc
-
-// RegisterReloadFunc 注册一个重新加载配置的函数
-func RegisterReloadFunc(reloadFunc ReloadFunc) {
-	registeredReloadFuncs = append(registeredReloadFuncs, reloadFunc)
-}
-
-// UnregisterAllReloadFuncs 清空所有注册的 reload 函数
-func UnregisterAllReloadFuncs() {
-	registeredReloadFuncs = nil
-}
-
 // ListLoadedFiles 列出已加载的配置文件
 func (cm *ConfigManager) ListLoadedFiles() []string {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
-	files := make([]string, 0, len(cm.configs))
-	for filename := range cm.configs {
-		files = append(files, filename)
-	}
-	return files
+	return cm.Current().Files()
 }
 
-// LoadAllConfigs 加载指定目录下的所有JSON文件
+// LoadAllConfigs 加载指定目录下的所有JSON文件，生成新版本
+// 所有文件的错误会一并返回，任何错误都不会切换版本
 func (cm *ConfigManager) LoadAllConfigs() error {
-	return filepath.Walk(cm.baseDir, func(path string, info os.FileInfo, err error) error {
+	cm.mu.Lock()
+	next := newSnapshot()
+	var errs []*FieldError
+	err := filepath.Walk(cm.baseDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -181,11 +286,31 @@ func (cm *ConfigManager) LoadAllConfigs() error {
 				return err
 			}
 
-			if err := cm.LoadConfig(relPath); err != nil {
-				return fmt.Errorf("加载配置文件失败 %s: %v", relPath, err)
+			if err := cm.readFile(next, relPath); err != nil {
+				var verr *ValidationError
+				if errors.As(err, &verr) {
+					errs = append(errs, verr.Errors...)
+				} else {
+					errs = append(errs, &FieldError{File: relPath, Msg: err.Error()})
+				}
 			}
 		}
 
 		return nil
 	})
+	if err != nil {
+		cm.mu.Unlock()
+		return err
+	}
+	errs = append(errs, checkAllReferences(next)...)
+	if len(errs) > 0 {
+		cm.mu.Unlock()
+		return &ValidationError{Errors: errs}
+	}
+	old := cm.swap(next)
+	cm.mu.Unlock()
+
+	log.Release("配置加载完成，版本 %d，变化的文件: %v", next.Version, next.ChangedFiles(old))
+	cm.notify(old, next)
+	return nil
 }
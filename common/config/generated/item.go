@@ -1,154 +1,126 @@
+// Code generated by config_generator. DO NOT EDIT.
+
 package config
 
 import (
 	"fmt"
 	"gameserver/common/config"
-	"reflect"
-	"sync"
 )
 
-// Item items.json配置结构体
-type Item struct {
-	Id string `json:"id"` // id
-	Name string `json:"name"` // name
-	Type string `json:"type"` // type
-	Attack float64 `json:"attack"` // attack
-	Price float64 `json:"price"` // price
-	Durability float64 `json:"durability"` // durability
-	Description string `json:"description"` // description
-}
+// ItemType 物品类型
+type ItemType string
 
-// ItemCache items.json配置缓存
-type ItemCache struct {
-	cache map[string]*Item
-	mu    sync.RWMutex
-}
+const (
+	ItemTypeWeapon     ItemType = "weapon"
+	ItemTypeArmor      ItemType = "armor"
+	ItemTypeConsumable ItemType = "consumable"
+	ItemTypePotion     ItemType = "potion"
+)
 
-var ItemCacheInstance = &ItemCache{
-	cache: make(map[string]*Item),
+// Item 物品配置
+type Item struct {
+	Id          string   `json:"id"`          // 物品ID
+	Name        string   `json:"name"`        // 名称
+	Type        ItemType `json:"type"`        // 物品类型
+	Attack      int      `json:"attack"`      // 攻击力
+	Magic       int      `json:"magic"`       // 魔力
+	Heal        int      `json:"heal"`        // 恢复生命值
+	Mana        int      `json:"mana"`        // 恢复魔法值
+	Durability  int      `json:"durability"`  // 耐久度
+	Price       int      `json:"price"`       // 价格
+	Description string   `json:"description"` // 描述
 }
 
-// getItemFromCache 从缓存获取配置
-func (c *ItemCache) getItemFromCache(id string) (*Item, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	
-	if item, exists := c.cache[id]; exists {
-		return item, true
+// decodeItem 解码Item，字段错误记录在行上
+func decodeItem(r *config.Row) *Item {
+	if r == nil {
+		return nil
+	}
+	return &Item{
+		Id:          r.String("id", config.Rule{Required: true}),
+		Name:        r.String("name", config.Rule{Required: true}),
+		Type:        ItemType(r.String("type", config.Rule{Required: true, Values: []string{"weapon", "armor", "consumable", "potion"}})),
+		Attack:      r.Int("attack", config.Rule{Min: config.Limit(0)}),
+		Magic:       r.Int("magic", config.Rule{Min: config.Limit(0)}),
+		Heal:        r.Int("heal", config.Rule{Min: config.Limit(0)}),
+		Mana:        r.Int("mana", config.Rule{Min: config.Limit(0)}),
+		Durability:  r.Int("durability", config.Rule{Min: config.Limit(0)}),
+		Price:       r.Int("price", config.Rule{Min: config.Limit(0)}),
+		Description: r.String("description", config.Rule{}),
 	}
-	return nil, false
 }
 
-// setItemToCache 设置配置到缓存
-func (c *ItemCache) setItemToCache(id string, item *Item) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	c.cache[id] = item
+// itemTable items.json解码后的数据
+type itemTable struct {
+	rows   map[string]*Item
+	list   []*Item // 文件中的顺序
+	byType map[ItemType][]*Item
 }
 
-// clearItemCache 清空缓存
-func (c *ItemCache) clearItemCache() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	c.cache = make(map[string]*Item)
+// loadItemTable 加载items.json时解码、校验并建立索引，之后的查询不再转换
+func loadItemTable(t *config.TableReader) interface{} {
+	table := &itemTable{
+		rows:   make(map[string]*Item),
+		byType: make(map[ItemType][]*Item),
+	}
+	for _, r := range t.Rows() {
+		item := decodeItem(r)
+		table.rows[r.Id()] = item
+		table.list = append(table.list, item)
+	}
+	for _, item := range table.list {
+		key := item.Type
+		table.byType[key] = append(table.byType[key], item)
+	}
+	return table
 }
 
-// convertToItem 将原始配置转换为Item结构体
-func convertToItem(config interface{}) (*Item, bool) {
-	if configMap, ok := config.(map[string]interface{}); ok {
-		result := &Item{}
-		
-		// 使用反射设置字段值
-		configValue := reflect.ValueOf(result).Elem()
-		configType := configValue.Type()
-		
-		for i := 0; i < configValue.NumField(); i++ {
-			field := configValue.Field(i)
-			fieldType := configType.Field(i)
-			jsonTag := fieldType.Tag.Get("json")
-			
-			if value, exists := configMap[jsonTag]; exists {
-				// 根据字段类型进行类型转换
-				switch field.Kind() {
-				case reflect.String:
-					if str, ok := value.(string); ok {
-						field.SetString(str)
-					}
-				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-					if num, ok := value.(float64); ok {
-						field.SetInt(int64(num))
-					}
-				case reflect.Float32, reflect.Float64:
-					if num, ok := value.(float64); ok {
-						field.SetFloat(num)
-					}
-				case reflect.Bool:
-					if b, ok := value.(bool); ok {
-						field.SetBool(b)
-					}
-				case reflect.Slice:
-					if slice, ok := value.([]interface{}); ok {
-						// 处理字符串切片
-						if field.Type().Elem().Kind() == reflect.String {
-							strSlice := make([]string, len(slice))
-							for j, item := range slice {
-								if str, ok := item.(string); ok {
-									strSlice[j] = str
-								}
-							}
-							field.Set(reflect.ValueOf(strSlice))
-						}
-					}
-				}
-			}
-		}
-		
-		return result, true
+func getItemTable() *itemTable {
+	if table, ok := config.GetTable("items.json"); ok {
+		return table.(*itemTable)
 	}
-
-	return nil, false
+	return nil
 }
 
-// GetItemConfig 获取items.json配置（带缓存）
+// GetItemConfig 获取items.json配置
 func GetItemConfig(id string) (*Item, bool) {
-	// 先从缓存获取
-	if item, exists := ItemCacheInstance.getItemFromCache(id); exists {
-		return item, true
-	}
-	
-	// 缓存未命中，从原始配置获取
-	config, exists := config.GetConfig("items.json", id)
-	if !exists {
+	table := getItemTable()
+	if table == nil {
 		return nil, false
 	}
-
-	// 转换为结构体
-	if item, ok := convertToItem(config); ok {
-		// 设置到缓存
-		ItemCacheInstance.setItemToCache(id, item)
-		return item, true
-	}
-
-	return nil, false
+	item, exists := table.rows[id]
+	return item, exists
 }
 
-// GetAllItemConfigs 获取所有items.json配置（带缓存）
+// GetAllItemConfigs 获取所有items.json配置
 func GetAllItemConfigs() (map[string]*Item, bool) {
-	configs, exists := config.GetAllConfigs("items.json")
-	if !exists {
+	table := getItemTable()
+	if table == nil {
 		return nil, false
 	}
+	result := make(map[string]*Item, len(table.rows))
+	for id, item := range table.rows {
+		result[id] = item
+	}
+	return result, true
+}
 
-	result := make(map[string]*Item)
-	for id := range configs {
-		if item, ok := GetItemConfig(id); ok {
-			result[id] = item
-		}
+// ListItemConfigs 获取所有items.json配置，按文件中的顺序
+func ListItemConfigs() []*Item {
+	table := getItemTable()
+	if table == nil {
+		return nil
 	}
+	return append([]*Item(nil), table.list...)
+}
 
-	return result, true
+// ListItemByType 获取type相同的items.json配置，按文件中的顺序
+func ListItemByType(typ ItemType) []*Item {
+	table := getItemTable()
+	if table == nil {
+		return nil
+	}
+	return append([]*Item(nil), table.byType[typ]...)
 }
 
 // GetItemName 获取items.json名称
@@ -159,12 +131,8 @@ func GetItemName(id string) (string, bool) {
 	return "", false
 }
 
-// ReloadItemConfig 重新加载items.json配置并清空缓存
+// ReloadItemConfig 重新加载items.json配置
 func ReloadItemConfig() error {
-	// 清空缓存
-	ItemCacheInstance.clearItemCache()
-	
-	// 重新加载配置
 	return config.ReloadConfig("items.json")
 }
 
@@ -175,8 +143,3 @@ func ValidateItemConfig(id string) error {
 	}
 	return nil
 }
-
-// ClearItemCache 手动清空items.json配置缓存
-func ClearItemCache() {
-	ItemCacheInstance.clearItemCache()
-}
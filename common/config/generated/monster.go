@@ -1,156 +1,156 @@
+// Code generated by config_generator. DO NOT EDIT.
+
 package config
 
 import (
 	"fmt"
 	"gameserver/common/config"
-	"reflect"
-	"sync"
+	"sort"
+)
+
+// MonsterType 怪物类型
+type MonsterType string
+
+const (
+	MonsterTypeNormal MonsterType = "normal"
+	MonsterTypeElite  MonsterType = "elite"
+	MonsterTypeBoss   MonsterType = "boss"
 )
 
-// Monster monsters.json配置结构体
+// Monster 怪物配置
 type Monster struct {
-	Id string `json:"id"` // id
-	Name string `json:"name"` // name
-	Type string `json:"type"` // type
-	Level float64 `json:"level"` // level
-	Hp float64 `json:"hp"` // hp
-	Attack float64 `json:"attack"` // attack
-	Defense float64 `json:"defense"` // defense
-	Exp float64 `json:"exp"` // exp
-	Drops []string `json:"drops"` // drops
+	Id      string      `json:"id"`      // 怪物ID
+	Name    string      `json:"name"`    // 名称
+	Type    MonsterType `json:"type"`    // 怪物类型
+	Level   int         `json:"level"`   // 等级
+	Hp      int         `json:"hp"`      // 生命值
+	Attack  int         `json:"attack"`  // 攻击力
+	Defense int         `json:"defense"` // 防御力
+	Exp     int         `json:"exp"`     // 击杀经验
+	Drops   []string    `json:"drops"`   // 掉落物品ID
 }
 
-// MonsterCache monsters.json配置缓存
-type MonsterCache struct {
-	cache map[string]*Monster
-	mu    sync.RWMutex
+// decodeMonster 解码Monster，字段错误记录在行上
+func decodeMonster(r *config.Row) *Monster {
+	if r == nil {
+		return nil
+	}
+	return &Monster{
+		Id:      r.String("id", config.Rule{Required: true}),
+		Name:    r.String("name", config.Rule{Required: true}),
+		Type:    MonsterType(r.String("type", config.Rule{Required: true, Values: []string{"normal", "elite", "boss"}})),
+		Level:   r.Int("level", config.Rule{Min: config.Limit(1), Max: config.Limit(100)}),
+		Hp:      r.Int("hp", config.Rule{Min: config.Limit(1)}),
+		Attack:  r.Int("attack", config.Rule{Min: config.Limit(0)}),
+		Defense: r.Int("defense", config.Rule{Min: config.Limit(0)}),
+		Exp:     r.Int("exp", config.Rule{Min: config.Limit(0)}),
+		Drops:   r.Strings("drops", config.Rule{Ref: "items.json"}),
+	}
 }
 
-var MonsterCacheInstance = &MonsterCache{
-	cache: make(map[string]*Monster),
+// monsterTypeAndLevelKey type+level联合索引的key
+type monsterTypeAndLevelKey struct {
+	Type  MonsterType
+	Level int
 }
 
-// getMonsterFromCache 从缓存获取配置
-func (c *MonsterCache) getMonsterFromCache(id string) (*Monster, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	
-	if item, exists := c.cache[id]; exists {
-		return item, true
-	}
-	return nil, false
+// monsterTable monsters.json解码后的数据
+type monsterTable struct {
+	rows           map[string]*Monster
+	list           []*Monster // level升序
+	byType         map[MonsterType][]*Monster
+	byTypeAndLevel map[monsterTypeAndLevelKey][]*Monster
 }
 
-// setMonsterToCache 设置配置到缓存
-func (c *MonsterCache) setMonsterToCache(id string, item *Monster) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	c.cache[id] = item
+// lessMonster 默认排序：level升序
+func lessMonster(a, b *Monster) bool {
+	if a.Level != b.Level {
+		return a.Level < b.Level
+	}
+	return false
 }
 
-// clearMonsterCache 清空缓存
-func (c *MonsterCache) clearMonsterCache() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	c.cache = make(map[string]*Monster)
+// loadMonsterTable 加载monsters.json时解码、校验并建立索引，之后的查询不再转换
+func loadMonsterTable(t *config.TableReader) interface{} {
+	table := &monsterTable{
+		rows:           make(map[string]*Monster),
+		byType:         make(map[MonsterType][]*Monster),
+		byTypeAndLevel: make(map[monsterTypeAndLevelKey][]*Monster),
+	}
+	for _, r := range t.Rows() {
+		item := decodeMonster(r)
+		table.rows[r.Id()] = item
+		table.list = append(table.list, item)
+	}
+	sort.SliceStable(table.list, func(i, j int) bool {
+		return lessMonster(table.list[i], table.list[j])
+	})
+	for _, item := range table.list {
+		key := item.Type
+		table.byType[key] = append(table.byType[key], item)
+	}
+	for _, item := range table.list {
+		key := monsterTypeAndLevelKey{item.Type, item.Level}
+		table.byTypeAndLevel[key] = append(table.byTypeAndLevel[key], item)
+	}
+	return table
 }
 
-// convertToMonster 将原始配置转换为Monster结构体
-func convertToMonster(config interface{}) (*Monster, bool) {
-	if configMap, ok := config.(map[string]interface{}); ok {
-		result := &Monster{}
-		
-		// 使用反射设置字段值
-		configValue := reflect.ValueOf(result).Elem()
-		configType := configValue.Type()
-		
-		for i := 0; i < configValue.NumField(); i++ {
-			field := configValue.Field(i)
-			fieldType := configType.Field(i)
-			jsonTag := fieldType.Tag.Get("json")
-			
-			if value, exists := configMap[jsonTag]; exists {
-				// 根据字段类型进行类型转换
-				switch field.Kind() {
-				case reflect.String:
-					if str, ok := value.(string); ok {
-						field.SetString(str)
-					}
-				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-					if num, ok := value.(float64); ok {
-						field.SetInt(int64(num))
-					}
-				case reflect.Float32, reflect.Float64:
-					if num, ok := value.(float64); ok {
-						field.SetFloat(num)
-					}
-				case reflect.Bool:
-					if b, ok := value.(bool); ok {
-						field.SetBool(b)
-					}
-				case reflect.Slice:
-					if slice, ok := value.([]interface{}); ok {
-						// 处理字符串切片
-						if field.Type().Elem().Kind() == reflect.String {
-							strSlice := make([]string, len(slice))
-							for j, item := range slice {
-								if str, ok := item.(string); ok {
-									strSlice[j] = str
-								}
-							}
-							field.Set(reflect.ValueOf(strSlice))
-						}
-					}
-				}
-			}
-		}
-		
-		return result, true
-	}
-
-	return nil, false
+func getMonsterTable() *monsterTable {
+	if table, ok := config.GetTable("monsters.json"); ok {
+		return table.(*monsterTable)
+	}
+	return nil
 }
 
-// GetMonsterConfig 获取monsters.json配置（带缓存）
+// GetMonsterConfig 获取monsters.json配置
 func GetMonsterConfig(id string) (*Monster, bool) {
-	// 先从缓存获取
-	if item, exists := MonsterCacheInstance.getMonsterFromCache(id); exists {
-		return item, true
-	}
-	
-	// 缓存未命中，从原始配置获取
-	config, exists := config.GetConfig("monsters.json", id)
-	if !exists {
+	table := getMonsterTable()
+	if table == nil {
 		return nil, false
 	}
-
-	// 转换为结构体
-	if item, ok := convertToMonster(config); ok {
-		// 设置到缓存
-		MonsterCacheInstance.setMonsterToCache(id, item)
-		return item, true
-	}
-
-	return nil, false
+	item, exists := table.rows[id]
+	return item, exists
 }
 
-// GetAllMonsterConfigs 获取所有monsters.json配置（带缓存）
+// GetAllMonsterConfigs 获取所有monsters.json配置
 func GetAllMonsterConfigs() (map[string]*Monster, bool) {
-	configs, exists := config.GetAllConfigs("monsters.json")
-	if !exists {
+	table := getMonsterTable()
+	if table == nil {
 		return nil, false
 	}
+	result := make(map[string]*Monster, len(table.rows))
+	for id, item := range table.rows {
+		result[id] = item
+	}
+	return result, true
+}
+
+// ListMonsterConfigs 获取所有monsters.json配置，按level升序
+func ListMonsterConfigs() []*Monster {
+	table := getMonsterTable()
+	if table == nil {
+		return nil
+	}
+	return append([]*Monster(nil), table.list...)
+}
 
-	result := make(map[string]*Monster)
-	for id := range configs {
-		if item, ok := GetMonsterConfig(id); ok {
-			result[id] = item
-		}
+// ListMonsterByType 获取type相同的monsters.json配置，按level升序
+func ListMonsterByType(typ MonsterType) []*Monster {
+	table := getMonsterTable()
+	if table == nil {
+		return nil
 	}
+	return append([]*Monster(nil), table.byType[typ]...)
+}
 
-	return result, true
+// ListMonsterByTypeAndLevel 获取type+level相同的monsters.json配置，按level升序
+func ListMonsterByTypeAndLevel(typ MonsterType, level int) []*Monster {
+	table := getMonsterTable()
+	if table == nil {
+		return nil
+	}
+	return append([]*Monster(nil), table.byTypeAndLevel[monsterTypeAndLevelKey{typ, level}]...)
 }
 
 // GetMonsterName 获取monsters.json名称
@@ -161,12 +161,8 @@ func GetMonsterName(id string) (string, bool) {
 	return "", false
 }
 
-// ReloadMonsterConfig 重新加载monsters.json配置并清空缓存
+// ReloadMonsterConfig 重新加载monsters.json配置
 func ReloadMonsterConfig() error {
-	// 清空缓存
-	MonsterCacheInstance.clearMonsterCache()
-	
-	// 重新加载配置
 	return config.ReloadConfig("monsters.json")
 }
 
@@ -177,8 +173,3 @@ func ValidateMonsterConfig(id string) error {
 	}
 	return nil
 }
-
-// ClearMonsterCache 手动清空monsters.json配置缓存
-func ClearMonsterCache() {
-	MonsterCacheInstance.clearMonsterCache()
-}
@@ -1,155 +1,131 @@
+// Code generated by config_generator. DO NOT EDIT.
+
 package config
 
 import (
 	"fmt"
 	"gameserver/common/config"
-	"reflect"
-	"sync"
+	"sort"
 )
 
-// Recharge recharge.json配置结构体
-type Recharge struct {
-	Id string `json:"id"` // id
-	Name string `json:"name"` // name
-	Description string `json:"description"` // description
-	Amount int64 `json:"amount"` // amount
-	Bonus int64 `json:"bonus"` // bonus
-	Currency string `json:"currency"` // currency
-	Is bool `json:"is_active"` // is_active
-	Sort int64 `json:"sort_order"` // sort_order
-}
+// RechargeCurrency 币种
+type RechargeCurrency string
 
-// RechargeCache recharge.json配置缓存
-type RechargeCache struct {
-	cache map[string]*Recharge
-	mu    sync.RWMutex
-}
+const (
+	RechargeCurrencyCNY RechargeCurrency = "CNY"
+)
 
-var RechargeCacheInstance = &RechargeCache{
-	cache: make(map[string]*Recharge),
+// Recharge 充值档位配置
+type Recharge struct {
+	Id          string           `json:"id"`          // 档位ID
+	Name        string           `json:"name"`        // 名称
+	Amount      int64            `json:"amount"`      // 金额，单位分
+	Bonus       int64            `json:"bonus"`       // 赠送金额，单位分
+	Currency    RechargeCurrency `json:"currency"`    // 币种
+	Description string           `json:"description"` // 描述
+	IsActive    bool             `json:"is_active"`   // 是否上架
+	SortOrder   int              `json:"sort_order"`  // 排序，越小越靠前
 }
 
-// getRechargeFromCache 从缓存获取配置
-func (c *RechargeCache) getRechargeFromCache(id string) (*Recharge, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	
-	if item, exists := c.cache[id]; exists {
-		return item, true
+// decodeRecharge 解码Recharge，字段错误记录在行上
+func decodeRecharge(r *config.Row) *Recharge {
+	if r == nil {
+		return nil
+	}
+	return &Recharge{
+		Id:          r.String("id", config.Rule{Required: true}),
+		Name:        r.String("name", config.Rule{Required: true}),
+		Amount:      r.Int64("amount", config.Rule{Required: true, Min: config.Limit(1)}),
+		Bonus:       r.Int64("bonus", config.Rule{Min: config.Limit(0)}),
+		Currency:    RechargeCurrency(r.String("currency", config.Rule{Required: true, Values: []string{"CNY"}})),
+		Description: r.String("description", config.Rule{}),
+		IsActive:    r.Bool("is_active", config.Rule{}),
+		SortOrder:   r.Int("sort_order", config.Rule{}),
 	}
-	return nil, false
 }
 
-// setRechargeToCache 设置配置到缓存
-func (c *RechargeCache) setRechargeToCache(id string, item *Recharge) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	c.cache[id] = item
+// rechargeTable recharge.json解码后的数据
+type rechargeTable struct {
+	rows       map[string]*Recharge
+	list       []*Recharge // sort_order升序
+	byIsActive map[bool][]*Recharge
 }
 
-// clearRechargeCache 清空缓存
-func (c *RechargeCache) clearRechargeCache() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	c.cache = make(map[string]*Recharge)
+// lessRecharge 默认排序：sort_order升序
+func lessRecharge(a, b *Recharge) bool {
+	if a.SortOrder != b.SortOrder {
+		return a.SortOrder < b.SortOrder
+	}
+	return false
 }
 
-// convertToRecharge 将原始配置转换为Recharge结构体
-func convertToRecharge(config interface{}) (*Recharge, bool) {
-	if configMap, ok := config.(map[string]interface{}); ok {
-		result := &Recharge{}
-		
-		// 使用反射设置字段值
-		configValue := reflect.ValueOf(result).Elem()
-		configType := configValue.Type()
-		
-		for i := 0; i < configValue.NumField(); i++ {
-			field := configValue.Field(i)
-			fieldType := configType.Field(i)
-			jsonTag := fieldType.Tag.Get("json")
-			
-			if value, exists := configMap[jsonTag]; exists {
-				// 根据字段类型进行类型转换
-				switch field.Kind() {
-				case reflect.String:
-					if str, ok := value.(string); ok {
-						field.SetString(str)
-					}
-				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-					if num, ok := value.(float64); ok {
-						field.SetInt(int64(num))
-					}
-				case reflect.Float32, reflect.Float64:
-					if num, ok := value.(float64); ok {
-						field.SetFloat(num)
-					}
-				case reflect.Bool:
-					if b, ok := value.(bool); ok {
-						field.SetBool(b)
-					}
-				case reflect.Slice:
-					if slice, ok := value.([]interface{}); ok {
-						// 处理字符串切片
-						if field.Type().Elem().Kind() == reflect.String {
-							strSlice := make([]string, len(slice))
-							for j, item := range slice {
-								if str, ok := item.(string); ok {
-									strSlice[j] = str
-								}
-							}
-							field.Set(reflect.ValueOf(strSlice))
-						}
-					}
-				}
-			}
-		}
-		
-		return result, true
+// loadRechargeTable 加载recharge.json时解码、校验并建立索引，之后的查询不再转换
+func loadRechargeTable(t *config.TableReader) interface{} {
+	table := &rechargeTable{
+		rows:       make(map[string]*Recharge),
+		byIsActive: make(map[bool][]*Recharge),
+	}
+	for _, r := range t.Rows() {
+		item := decodeRecharge(r)
+		table.rows[r.Id()] = item
+		table.list = append(table.list, item)
+	}
+	sort.SliceStable(table.list, func(i, j int) bool {
+		return lessRecharge(table.list[i], table.list[j])
+	})
+	for _, item := range table.list {
+		key := item.IsActive
+		table.byIsActive[key] = append(table.byIsActive[key], item)
 	}
+	return table
+}
 
-	return nil, false
+func getRechargeTable() *rechargeTable {
+	if table, ok := config.GetTable("recharge.json"); ok {
+		return table.(*rechargeTable)
+	}
+	return nil
 }
 
-// GetRechargeConfig 获取recharge.json配置（带缓存）
+// GetRechargeConfig 获取recharge.json配置
 func GetRechargeConfig(id string) (*Recharge, bool) {
-	// 先从缓存获取
-	if item, exists := RechargeCacheInstance.getRechargeFromCache(id); exists {
-		return item, true
-	}
-	
-	// 缓存未命中，从原始配置获取
-	config, exists := config.GetConfig("recharge.json", id)
-	if !exists {
+	table := getRechargeTable()
+	if table == nil {
 		return nil, false
 	}
-
-	// 转换为结构体
-	if item, ok := convertToRecharge(config); ok {
-		// 设置到缓存
-		RechargeCacheInstance.setRechargeToCache(id, item)
-		return item, true
-	}
-
-	return nil, false
+	item, exists := table.rows[id]
+	return item, exists
 }
 
-// GetAllRechargeConfigs 获取所有recharge.json配置（带缓存）
+// GetAllRechargeConfigs 获取所有recharge.json配置
 func GetAllRechargeConfigs() (map[string]*Recharge, bool) {
-	configs, exists := config.GetAllConfigs("recharge.json")
-	if !exists {
+	table := getRechargeTable()
+	if table == nil {
 		return nil, false
 	}
+	result := make(map[string]*Recharge, len(table.rows))
+	for id, item := range table.rows {
+		result[id] = item
+	}
+	return result, true
+}
 
-	result := make(map[string]*Recharge)
-	for id := range configs {
-		if item, ok := GetRechargeConfig(id); ok {
-			result[id] = item
-		}
+// ListRechargeConfigs 获取所有recharge.json配置，按sort_order升序
+func ListRechargeConfigs() []*Recharge {
+	table := getRechargeTable()
+	if table == nil {
+		return nil
 	}
+	return append([]*Recharge(nil), table.list...)
+}
 
-	return result, true
+// ListRechargeByIsActive 获取is_active相同的recharge.json配置，按sort_order升序
+func ListRechargeByIsActive(isActive bool) []*Recharge {
+	table := getRechargeTable()
+	if table == nil {
+		return nil
+	}
+	return append([]*Recharge(nil), table.byIsActive[isActive]...)
 }
 
 // GetRechargeName 获取recharge.json名称
@@ -160,12 +136,8 @@ func GetRechargeName(id string) (string, bool) {
 	return "", false
 }
 
-// ReloadRechargeConfig 重新加载recharge.json配置并清空缓存
+// ReloadRechargeConfig 重新加载recharge.json配置
 func ReloadRechargeConfig() error {
-	// 清空缓存
-	RechargeCacheInstance.clearRechargeCache()
-	
-	// 重新加载配置
 	return config.ReloadConfig("recharge.json")
 }
 
@@ -176,8 +148,3 @@ func ValidateRechargeConfig(id string) error {
 	}
 	return nil
 }
-
-// ClearRechargeCache 手动清空recharge.json配置缓存
-func ClearRechargeCache() {
-	RechargeCacheInstance.clearRechargeCache()
-}
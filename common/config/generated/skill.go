@@ -1,158 +1,151 @@
+// Code generated by config_generator. DO NOT EDIT.
+
 package config
 
 import (
 	"fmt"
 	"gameserver/common/config"
-	"reflect"
-	"sync"
+	"sort"
 )
 
-// Skill skills.json配置结构体
+// Skill 技能配置
 type Skill struct {
-	Id string `json:"id"` // id
-	Name string `json:"name"` // name
-	Type string `json:"type"` // type
-	Level float64 `json:"level"` // level
-	Description string `json:"description"` // description
-	Cooldown float64 `json:"cooldown"` // cooldown
-	Range float64 `json:"range"` // range
-	Unlock float64 `json:"unlock_level"` // unlock_level
-	Damage float64 `json:"damage"` // damage
-	Mana float64 `json:"mana_cost"` // mana_cost
-	Effects []string `json:"effects"` // effects
-}
-
-// SkillCache skills.json配置缓存
-type SkillCache struct {
-	cache map[string]*Skill
-	mu    sync.RWMutex
+	Id          string   `json:"id"`           // 技能ID
+	Name        string   `json:"name"`         // 名称
+	Type        string   `json:"type"`         // 技能类型
+	Level       int      `json:"level"`        // 等级
+	Damage      int      `json:"damage"`       // 伤害
+	HealAmount  int      `json:"heal_amount"`  // 治疗量
+	ManaCost    int      `json:"mana_cost"`    // 魔法消耗
+	StaminaCost int      `json:"stamina_cost"` // 体力消耗
+	Cooldown    float64  `json:"cooldown"`     // 冷却时间，秒
+	Range       float64  `json:"range"`        // 施法范围
+	Description string   `json:"description"`  // 描述
+	Effects     []string `json:"effects"`      // 附带效果
+	UnlockLevel int      `json:"unlock_level"` // 解锁等级
 }
 
-var SkillCacheInstance = &SkillCache{
-	cache: make(map[string]*Skill),
-}
-
-// getSkillFromCache 从缓存获取配置
-func (c *SkillCache) getSkillFromCache(id string) (*Skill, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	
-	if item, exists := c.cache[id]; exists {
-		return item, true
+// decodeSkill 解码Skill，字段错误记录在行上
+func decodeSkill(r *config.Row) *Skill {
+	if r == nil {
+		return nil
+	}
+	return &Skill{
+		Id:          r.String("id", config.Rule{Required: true}),
+		Name:        r.String("name", config.Rule{Required: true}),
+		Type:        r.String("type", config.Rule{Required: true}),
+		Level:       r.Int("level", config.Rule{Min: config.Limit(1)}),
+		Damage:      r.Int("damage", config.Rule{Min: config.Limit(0)}),
+		HealAmount:  r.Int("heal_amount", config.Rule{Min: config.Limit(0)}),
+		ManaCost:    r.Int("mana_cost", config.Rule{Min: config.Limit(0)}),
+		StaminaCost: r.Int("stamina_cost", config.Rule{Min: config.Limit(0)}),
+		Cooldown:    r.Float("cooldown", config.Rule{Min: config.Limit(0)}),
+		Range:       r.Float("range", config.Rule{Min: config.Limit(0)}),
+		Description: r.String("description", config.Rule{}),
+		Effects:     r.Strings("effects", config.Rule{}),
+		UnlockLevel: r.Int("unlock_level", config.Rule{Min: config.Limit(1)}),
 	}
-	return nil, false
 }
 
-// setSkillToCache 设置配置到缓存
-func (c *SkillCache) setSkillToCache(id string, item *Skill) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	c.cache[id] = item
+// skillTable skills.json解码后的数据
+type skillTable struct {
+	rows   map[string]*Skill
+	list   []*Skill // unlock_level升序
+	byName map[string]*Skill
+	byType map[string][]*Skill
 }
 
-// clearSkillCache 清空缓存
-func (c *SkillCache) clearSkillCache() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	c.cache = make(map[string]*Skill)
+// lessSkill 默认排序：unlock_level升序
+func lessSkill(a, b *Skill) bool {
+	if a.UnlockLevel != b.UnlockLevel {
+		return a.UnlockLevel < b.UnlockLevel
+	}
+	return false
 }
 
-// convertToSkill 将原始配置转换为Skill结构体
-func convertToSkill(config interface{}) (*Skill, bool) {
-	if configMap, ok := config.(map[string]interface{}); ok {
-		result := &Skill{}
-		
-		// 使用反射设置字段值
-		configValue := reflect.ValueOf(result).Elem()
-		configType := configValue.Type()
-		
-		for i := 0; i < configValue.NumField(); i++ {
-			field := configValue.Field(i)
-			fieldType := configType.Field(i)
-			jsonTag := fieldType.Tag.Get("json")
-			
-			if value, exists := configMap[jsonTag]; exists {
-				// 根据字段类型进行类型转换
-				switch field.Kind() {
-				case reflect.String:
-					if str, ok := value.(string); ok {
-						field.SetString(str)
-					}
-				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-					if num, ok := value.(float64); ok {
-						field.SetInt(int64(num))
-					}
-				case reflect.Float32, reflect.Float64:
-					if num, ok := value.(float64); ok {
-						field.SetFloat(num)
-					}
-				case reflect.Bool:
-					if b, ok := value.(bool); ok {
-						field.SetBool(b)
-					}
-				case reflect.Slice:
-					if slice, ok := value.([]interface{}); ok {
-						// 处理字符串切片
-						if field.Type().Elem().Kind() == reflect.String {
-							strSlice := make([]string, len(slice))
-							for j, item := range slice {
-								if str, ok := item.(string); ok {
-									strSlice[j] = str
-								}
-							}
-							field.Set(reflect.ValueOf(strSlice))
-						}
-					}
-				}
-			}
+// loadSkillTable 加载skills.json时解码、校验并建立索引，之后的查询不再转换
+func loadSkillTable(t *config.TableReader) interface{} {
+	table := &skillTable{
+		rows:   make(map[string]*Skill),
+		byName: make(map[string]*Skill),
+		byType: make(map[string][]*Skill),
+	}
+	for _, r := range t.Rows() {
+		item := decodeSkill(r)
+		if prev, exists := table.byName[item.Name]; exists {
+			r.Errorf("name", "唯一索引name重复，与id=%s相同", prev.Id)
+		} else {
+			table.byName[item.Name] = item
 		}
-		
-		return result, true
+		table.rows[r.Id()] = item
+		table.list = append(table.list, item)
+	}
+	sort.SliceStable(table.list, func(i, j int) bool {
+		return lessSkill(table.list[i], table.list[j])
+	})
+	for _, item := range table.list {
+		key := item.Type
+		table.byType[key] = append(table.byType[key], item)
 	}
+	return table
+}
 
-	return nil, false
+func getSkillTable() *skillTable {
+	if table, ok := config.GetTable("skills.json"); ok {
+		return table.(*skillTable)
+	}
+	return nil
 }
 
-// GetSkillConfig 获取skills.json配置（带缓存）
+// GetSkillConfig 获取skills.json配置
 func GetSkillConfig(id string) (*Skill, bool) {
-	// 先从缓存获取
-	if item, exists := SkillCacheInstance.getSkillFromCache(id); exists {
-		return item, true
-	}
-	
-	// 缓存未命中，从原始配置获取
-	config, exists := config.GetConfig("skills.json", id)
-	if !exists {
+	table := getSkillTable()
+	if table == nil {
 		return nil, false
 	}
+	item, exists := table.rows[id]
+	return item, exists
+}
 
-	// 转换为结构体
-	if item, ok := convertToSkill(config); ok {
-		// 设置到缓存
-		SkillCacheInstance.setSkillToCache(id, item)
-		return item, true
+// GetAllSkillConfigs 获取所有skills.json配置
+func GetAllSkillConfigs() (map[string]*Skill, bool) {
+	table := getSkillTable()
+	if table == nil {
+		return nil, false
 	}
+	result := make(map[string]*Skill, len(table.rows))
+	for id, item := range table.rows {
+		result[id] = item
+	}
+	return result, true
+}
 
-	return nil, false
+// ListSkillConfigs 获取所有skills.json配置，按unlock_level升序
+func ListSkillConfigs() []*Skill {
+	table := getSkillTable()
+	if table == nil {
+		return nil
+	}
+	return append([]*Skill(nil), table.list...)
 }
 
-// GetAllSkillConfigs 获取所有skills.json配置（带缓存）
-func GetAllSkillConfigs() (map[string]*Skill, bool) {
-	configs, exists := config.GetAllConfigs("skills.json")
-	if !exists {
+// GetSkillByName 按name获取skills.json配置
+func GetSkillByName(name string) (*Skill, bool) {
+	table := getSkillTable()
+	if table == nil {
 		return nil, false
 	}
+	item, exists := table.byName[name]
+	return item, exists
+}
 
-	result := make(map[string]*Skill)
-	for id := range configs {
-		if item, ok := GetSkillConfig(id); ok {
-			result[id] = item
-		}
+// ListSkillByType 获取type相同的skills.json配置，按unlock_level升序
+func ListSkillByType(typ string) []*Skill {
+	table := getSkillTable()
+	if table == nil {
+		return nil
 	}
-
-	return result, true
+	return append([]*Skill(nil), table.byType[typ]...)
 }
 
 // GetSkillName 获取skills.json名称
@@ -163,12 +156,8 @@ func GetSkillName(id string) (string, bool) {
 	return "", false
 }
 
-// ReloadSkillConfig 重新加载skills.json配置并清空缓存
+// ReloadSkillConfig 重新加载skills.json配置
 func ReloadSkillConfig() error {
-	// 清空缓存
-	SkillCacheInstance.clearSkillCache()
-	
-	// 重新加载配置
 	return config.ReloadConfig("skills.json")
 }
 
@@ -179,8 +168,3 @@ func ValidateSkillConfig(id string) error {
 	}
 	return nil
 }
-
-// ClearSkillCache 手动清空skills.json配置缓存
-func ClearSkillCache() {
-	SkillCacheInstance.clearSkillCache()
-}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"sync"
+	"time"
 )
 
 var (
@@ -9,12 +10,21 @@ var (
 	once          sync.Once
 )
 
-// InitGlobalConfig 初始化全局配置管理器
-func InitGlobalConfig(baseDir string) {
+// InitGlobalConfig 初始化全局配置管理器，配置校验失败时返回错误
+func InitGlobalConfig(baseDir string) error {
+	var err error
 	once.Do(func() {
 		globalManager = NewConfigManager(baseDir)
-		globalManager.LoadAllConfigs()
+		err = globalManager.LoadAllConfigs()
 	})
+	return err
+}
+
+// SetGlobalManager 替换全局配置管理器并返回原来的，用于测试或工具中使用其他目录的配置
+func SetGlobalManager(cm *ConfigManager) *ConfigManager {
+	prev := globalManager
+	globalManager = cm
+	return prev
 }
 
 // LoadConfig 全局加载配置文件
@@ -27,6 +37,11 @@ func GetConfig(filename, id string) (interface{}, bool) {
 	return globalManager.GetConfig(filename, id)
 }
 
+// GetTable 全局获取解码后的表
+func GetTable(filename string) (interface{}, bool) {
+	return globalManager.GetTable(filename)
+}
+
 // GetConfigByID 全局根据ID获取配置
 func GetConfigByID(id string) (string, interface{}, bool) {
 	return globalManager.GetConfigByID(id)
@@ -56,3 +71,33 @@ func ListLoadedFiles() []string {
 func LoadAllConfigs() error {
 	return globalManager.LoadAllConfigs()
 }
+
+// Current 全局当前版本的配置
+func Current() *Snapshot {
+	return globalManager.Current()
+}
+
+// Version 全局当前配置版本号
+func Version() uint64 {
+	return globalManager.Version()
+}
+
+// History 全局可回滚的历史版本号
+func History() []uint64 {
+	return globalManager.History()
+}
+
+// Rollback 全局回滚到上一个版本
+func Rollback() error {
+	return globalManager.Rollback()
+}
+
+// Subscribe 全局订阅配置版本切换
+func Subscribe(subscriber Subscriber) {
+	globalManager.Subscribe(subscriber)
+}
+
+// Watch 全局监听配置目录变化并自动重新加载
+func Watch(interval time.Duration) (stop func()) {
+	return globalManager.Watch(interval)
+}
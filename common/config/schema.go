@@ -0,0 +1,397 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Rule 字段校验规则，由配置生成器根据schema生成
+type Rule struct {
+	Required bool     // 必填，列表要求非空
+	Min      *float64 // 数值下限（含），列表时作用于每个元素
+	Max      *float64 // 数值上限（含）
+	Values   []string // 枚举可选值
+	Ref      string   // 外键，引用的配置文件，如 "items.json"
+}
+
+// Limit 生成代码中构造Rule.Min/Max
+func Limit(v float64) *float64 {
+	return &v
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	File  string
+	Row   int // 从1开始，对应配置数组中的第几条
+	Id    string
+	Field string
+	Msg   string
+}
+
+func (e *FieldError) Error() string {
+	if e.Row == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	if e.Field == "" {
+		return fmt.Sprintf("%s 第%d行(id=%s): %s", e.File, e.Row, e.Id, e.Msg)
+	}
+	return fmt.Sprintf("%s 第%d行(id=%s) 字段%s: %s", e.File, e.Row, e.Id, e.Field, e.Msg)
+}
+
+// ValidationError 一次加载中的所有校验错误
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Errors)+1)
+	lines = append(lines, fmt.Sprintf("配置校验失败，共%d处错误:", len(e.Errors)))
+	for _, err := range e.Errors {
+		lines = append(lines, "  "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reference 待校验的外键引用，所有表解码完成后统一检查
+type reference struct {
+	file   string
+	row    int
+	id     string
+	field  string
+	value  string
+	target string
+}
+
+// TableLoader 生成代码注册的解码函数，返回解码后的表数据
+type TableLoader func(t *TableReader) interface{}
+
+var tableLoaders = make(map[string]TableLoader)
+
+// RegisterTable 注册配置文件的解码函数，加载该文件时解码一次并校验
+func RegisterTable(filename string, loader TableLoader) {
+	tableLoaders[filename] = loader
+}
+
+// TableReader 逐行读取配置并记录错误
+type TableReader struct {
+	file   string
+	rows   []map[string]interface{}
+	ids    map[string]int
+	errs   []*FieldError
+	refs   []reference
+	keySet map[string]struct{}
+}
+
+func newTableReader(file string, rows []map[string]interface{}) *TableReader {
+	return &TableReader{
+		file:   file,
+		rows:   rows,
+		ids:    make(map[string]int),
+		keySet: make(map[string]struct{}),
+	}
+}
+
+// Rows 按文件中的顺序返回所有行，缺少id或id重复的行会记录错误并跳过
+func (t *TableReader) Rows() []*Row {
+	rows := make([]*Row, 0, len(t.rows))
+	for i, data := range t.rows {
+		rowNum := i + 1
+		value, ok := data["id"]
+		if !ok {
+			t.errs = append(t.errs, &FieldError{File: t.file, Row: rowNum, Field: "id", Msg: "缺少id"})
+			continue
+		}
+		id := fmt.Sprintf("%v", value)
+		if prev, ok := t.ids[id]; ok {
+			t.errs = append(t.errs, &FieldError{File: t.file, Row: rowNum, Id: id, Field: "id",
+				Msg: fmt.Sprintf("id与第%d行重复", prev)})
+			continue
+		}
+		t.ids[id] = rowNum
+		t.keySet[id] = struct{}{}
+		rows = append(rows, &Row{table: t, row: rowNum, id: id, data: data})
+	}
+	return rows
+}
+
+// Row 配置中的一行或行内的嵌套结构
+type Row struct {
+	table *TableReader
+	row   int
+	id    string
+	path  string // 嵌套结构的字段前缀，如 "reward." 或 "rewards[1]."
+	data  map[string]interface{}
+}
+
+// Id 行的id
+func (r *Row) Id() string {
+	return r.id
+}
+
+// Errorf 记录行上的错误，生成代码用于校验唯一索引等跨行约束
+func (r *Row) Errorf(field string, format string, args ...interface{}) {
+	r.fail(field, format, args...)
+}
+
+func (r *Row) fail(field string, format string, args ...interface{}) {
+	r.table.errs = append(r.table.errs, &FieldError{
+		File:  r.table.file,
+		Row:   r.row,
+		Id:    r.id,
+		Field: r.path + field,
+		Msg:   fmt.Sprintf(format, args...),
+	})
+}
+
+// value 取字段原始值，必填字段缺失时记录错误
+func (r *Row) value(name string, rule Rule) (interface{}, bool) {
+	v, ok := r.data[name]
+	if !ok || v == nil {
+		if rule.Required {
+			r.fail(name, "必填字段缺失")
+		}
+		return nil, false
+	}
+	return v, true
+}
+
+func (r *Row) checkRange(field string, v float64, rule Rule) {
+	if rule.Min != nil && v < *rule.Min {
+		r.fail(field, "值%v小于下限%v", v, *rule.Min)
+	}
+	if rule.Max != nil && v > *rule.Max {
+		r.fail(field, "值%v大于上限%v", v, *rule.Max)
+	}
+}
+
+func (r *Row) checkRef(field string, value string, rule Rule) {
+	if rule.Ref == "" {
+		return
+	}
+	r.table.refs = append(r.table.refs, reference{
+		file:   r.table.file,
+		row:    r.row,
+		id:     r.id,
+		field:  r.path + field,
+		value:  value,
+		target: rule.Ref,
+	})
+}
+
+func (r *Row) toString(field string, v interface{}, rule Rule) string {
+	var s string
+	switch val := v.(type) {
+	case string:
+		s = val
+	case float64:
+		// 外键列在数据里可能写成数字
+		if rule.Ref == "" {
+			r.fail(field, "应为字符串，实际为%v", v)
+			return ""
+		}
+		s = fmt.Sprintf("%v", val)
+	default:
+		r.fail(field, "应为字符串，实际为%v", v)
+		return ""
+	}
+	if len(rule.Values) > 0 && !containsString(rule.Values, s) {
+		r.fail(field, "枚举值%q不合法，可选值%v", s, rule.Values)
+	}
+	r.checkRef(field, s, rule)
+	return s
+}
+
+func (r *Row) toNumber(field string, v interface{}, rule Rule, integer bool) float64 {
+	num, ok := v.(float64)
+	if !ok {
+		r.fail(field, "应为数值，实际为%v", v)
+		return 0
+	}
+	if integer && num != math.Trunc(num) {
+		r.fail(field, "应为整数，实际为%v", num)
+		return 0
+	}
+	r.checkRange(field, num, rule)
+	r.checkRef(field, fmt.Sprintf("%v", num), rule)
+	return num
+}
+
+func (r *Row) toBool(field string, v interface{}) bool {
+	b, ok := v.(bool)
+	if !ok {
+		r.fail(field, "应为布尔值，实际为%v", v)
+	}
+	return b
+}
+
+// list 取列表字段，必填时要求非空
+func (r *Row) list(name string, rule Rule) []interface{} {
+	v, ok := r.value(name, rule)
+	if !ok {
+		return nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		r.fail(name, "应为列表，实际为%v", v)
+		return nil
+	}
+	if rule.Required && len(list) == 0 {
+		r.fail(name, "必填列表为空")
+	}
+	return list
+}
+
+func (r *Row) String(name string, rule Rule) string {
+	if v, ok := r.value(name, rule); ok {
+		return r.toString(name, v, rule)
+	}
+	return ""
+}
+
+func (r *Row) Int(name string, rule Rule) int {
+	if v, ok := r.value(name, rule); ok {
+		return int(r.toNumber(name, v, rule, true))
+	}
+	return 0
+}
+
+func (r *Row) Int64(name string, rule Rule) int64 {
+	if v, ok := r.value(name, rule); ok {
+		return int64(r.toNumber(name, v, rule, true))
+	}
+	return 0
+}
+
+func (r *Row) Float(name string, rule Rule) float64 {
+	if v, ok := r.value(name, rule); ok {
+		return r.toNumber(name, v, rule, false)
+	}
+	return 0
+}
+
+func (r *Row) Bool(name string, rule Rule) bool {
+	if v, ok := r.value(name, rule); ok {
+		return r.toBool(name, v)
+	}
+	return false
+}
+
+func (r *Row) Strings(name string, rule Rule) []string {
+	list := r.list(name, rule)
+	if list == nil {
+		return nil
+	}
+	result := make([]string, len(list))
+	for i, v := range list {
+		result[i] = r.toString(fmt.Sprintf("%s[%d]", name, i), v, rule)
+	}
+	return result
+}
+
+func (r *Row) Ints(name string, rule Rule) []int {
+	list := r.list(name, rule)
+	if list == nil {
+		return nil
+	}
+	result := make([]int, len(list))
+	for i, v := range list {
+		result[i] = int(r.toNumber(fmt.Sprintf("%s[%d]", name, i), v, rule, true))
+	}
+	return result
+}
+
+func (r *Row) Int64s(name string, rule Rule) []int64 {
+	list := r.list(name, rule)
+	if list == nil {
+		return nil
+	}
+	result := make([]int64, len(list))
+	for i, v := range list {
+		result[i] = int64(r.toNumber(fmt.Sprintf("%s[%d]", name, i), v, rule, true))
+	}
+	return result
+}
+
+func (r *Row) Floats(name string, rule Rule) []float64 {
+	list := r.list(name, rule)
+	if list == nil {
+		return nil
+	}
+	result := make([]float64, len(list))
+	for i, v := range list {
+		result[i] = r.toNumber(fmt.Sprintf("%s[%d]", name, i), v, rule, false)
+	}
+	return result
+}
+
+// Struct 取嵌套结构，字段缺失时返回nil
+func (r *Row) Struct(name string, rule Rule) *Row {
+	v, ok := r.value(name, rule)
+	if !ok {
+		return nil
+	}
+	return r.child(name, v)
+}
+
+// Structs 取结构列表
+func (r *Row) Structs(name string, rule Rule) []*Row {
+	list := r.list(name, rule)
+	if list == nil {
+		return nil
+	}
+	result := make([]*Row, 0, len(list))
+	for i, v := range list {
+		if child := r.child(fmt.Sprintf("%s[%d]", name, i), v); child != nil {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+func (r *Row) child(field string, v interface{}) *Row {
+	data, ok := v.(map[string]interface{})
+	if !ok {
+		r.fail(field, "应为对象，实际为%v", v)
+		return nil
+	}
+	return &Row{table: r.table, row: r.row, id: r.id, path: r.path + field + ".", data: data}
+}
+
+// checkReferences 校验外键，keys为各配置文件当前的id集合
+func checkReferences(refs []reference, keys map[string]map[string]struct{}) []*FieldError {
+	var errs []*FieldError
+	for _, ref := range refs {
+		targetKeys, ok := keys[ref.target]
+		if !ok {
+			// 被引用的表尚未加载，加载该表时再校验
+			continue
+		}
+		if _, ok := targetKeys[ref.value]; !ok {
+			errs = append(errs, &FieldError{
+				File:  ref.file,
+				Row:   ref.row,
+				Id:    ref.id,
+				Field: ref.field,
+				Msg:   fmt.Sprintf("引用的%s中不存在id %s", ref.target, ref.value),
+			})
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].File != errs[j].File {
+			return errs[i].File < errs[j].File
+		}
+		return errs[i].Row < errs[j].Row
+	})
+	return errs
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"crypto/sha1"
+	"sort"
+	"time"
+)
+
+// Snapshot 某一版本的全部配置，创建后只读
+// 重新加载时构造新的Snapshot，校验通过后整体替换，读者不会看到新旧混合的配置
+type Snapshot struct {
+	Version  uint64
+	LoadedAt time.Time
+
+	configs map[string]map[string]interface{} // 文件名 -> {ID -> 配置数据}
+	tables  map[string]interface{}            // 文件名 -> 生成代码解码后的表
+	refs    map[string][]reference            // 文件名 -> 该文件中的外键引用
+	sums    map[string][sha1.Size]byte        // 文件名 -> 内容摘要，用于判断文件是否变化
+}
+
+func newSnapshot() *Snapshot {
+	return &Snapshot{
+		configs: make(map[string]map[string]interface{}),
+		tables:  make(map[string]interface{}),
+		refs:    make(map[string][]reference),
+		sums:    make(map[string][sha1.Size]byte),
+	}
+}
+
+// clone 浅拷贝，各文件的数据本身只读，可在新旧版本间共享
+func (s *Snapshot) clone() *Snapshot {
+	next := newSnapshot()
+	for k, v := range s.configs {
+		next.configs[k] = v
+	}
+	for k, v := range s.tables {
+		next.tables[k] = v
+	}
+	for k, v := range s.refs {
+		next.refs[k] = v
+	}
+	for k, v := range s.sums {
+		next.sums[k] = v
+	}
+	return next
+}
+
+// GetConfig 根据文件名和ID获取配置
+func (s *Snapshot) GetConfig(filename, id string) (interface{}, bool) {
+	configMap, exists := s.configs[filename]
+	if !exists {
+		return nil, false
+	}
+	config, exists := configMap[id]
+	return config, exists
+}
+
+// GetAllConfigs 获取指定文件的所有配置
+func (s *Snapshot) GetAllConfigs(filename string) (map[string]interface{}, bool) {
+	configMap, exists := s.configs[filename]
+	return configMap, exists
+}
+
+// GetTable 获取生成代码解码后的表
+func (s *Snapshot) GetTable(filename string) (interface{}, bool) {
+	table, exists := s.tables[filename]
+	return table, exists
+}
+
+// Files 已加载的配置文件，按文件名排序
+func (s *Snapshot) Files() []string {
+	files := make([]string, 0, len(s.configs))
+	for filename := range s.configs {
+		files = append(files, filename)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Changed 与旧版本相比指定文件是否变化（新增、删除或内容不同）
+func (s *Snapshot) Changed(old *Snapshot, filename string) bool {
+	if old == nil {
+		_, exists := s.sums[filename]
+		return exists
+	}
+	sum, exists := s.sums[filename]
+	oldSum, oldExists := old.sums[filename]
+	return exists != oldExists || sum != oldSum
+}
+
+// ChangedFiles 与旧版本相比变化的文件
+func (s *Snapshot) ChangedFiles(old *Snapshot) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, snap := range []*Snapshot{s, old} {
+		if snap == nil {
+			continue
+		}
+		for filename := range snap.sums {
+			if !seen[filename] && s.Changed(old, filename) {
+				files = append(files, filename)
+			}
+			seen[filename] = true
+		}
+	}
+	sort.Strings(files)
+	return files
+}
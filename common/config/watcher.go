@@ -0,0 +1,81 @@
+package config
+
+import (
+	"gameserver/core/log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileStamp 文件的修改时间与大小
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// scanStamps 扫描目录下所有JSON文件
+func (cm *ConfigManager) scanStamps() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	filepath.Walk(cm.baseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".json" {
+			stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return stamps
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, stamp := range a {
+		if other, ok := b[path]; !ok || !other.modTime.Equal(stamp.modTime) || other.size != stamp.size {
+			return false
+		}
+	}
+	return true
+}
+
+// Watch 轮询配置目录，文件变化后在连续两次扫描结果一致时（避免读到写了一半的文件）重新加载全部配置
+// 加载失败只记录日志，当前版本保持不变，返回停止函数
+func (cm *ConfigManager) Watch(interval time.Duration) (stop func()) {
+	closeSig := make(chan struct{})
+	applied := cm.scanStamps()
+	var pending map[string]fileStamp
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closeSig:
+				return
+			case <-ticker.C:
+			}
+
+			stamps := cm.scanStamps()
+			if sameStamps(stamps, applied) {
+				pending = nil
+				continue
+			}
+			if pending == nil || !sameStamps(stamps, pending) {
+				pending = stamps
+				continue
+			}
+
+			// 无论成功与否都记为已处理，修复文件后会再次触发
+			applied, pending = stamps, nil
+			if err := cm.ReloadAll(); err != nil {
+				log.Error("配置文件变化，重新加载失败，继续使用版本 %d: %v", cm.Version(), err)
+			}
+		}
+	}()
+
+	return func() {
+		close(closeSig)
+	}
+}
@@ -0,0 +1,84 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Backend 持久化后端，MongoDB或内存
+// 仓储、迁移、索引和事务都通过当前后端访问数据，业务代码不直接依赖具体实现
+type Backend interface {
+	// Collection 获取集合
+	Collection(name string) Collection
+	// WithTransaction 在事务中执行fn，见WithTransaction
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// Close 关闭后端
+	Close(ctx context.Context) error
+}
+
+// Collection 集合操作，文档以bson.Raw交换
+// filter支持MongoDB的查询语法，内存后端支持的操作符见memory.go
+type Collection interface {
+	// Find 按查询条件遍历文档，fn返回错误时停止
+	Find(ctx context.Context, q *Query, fn func(doc bson.Raw) error) error
+	// Count 统计匹配filter的文档数
+	Count(ctx context.Context, filter interface{}) (int64, error)
+	// Insert 插入文档，_id已存在时返回重复键错误
+	Insert(ctx context.Context, doc interface{}) error
+	// Replace 整体替换第一个匹配filter的文档，upsert为true且没有匹配时插入
+	Replace(ctx context.Context, filter, doc interface{}, upsert bool) (WriteResult, error)
+	// BulkReplace 按_id批量整体替换，不存在时插入
+	BulkReplace(ctx context.Context, docs []Replacement) (WriteResult, error)
+	// Update 部分更新匹配filter的文档，many为false时只更新第一个
+	Update(ctx context.Context, filter interface{}, update bson.D, many bool) (WriteResult, error)
+	// Delete 删除第一个匹配filter的文档，返回删除的数量
+	Delete(ctx context.Context, filter interface{}) (int64, error)
+	// Indexes 索引管理
+	Indexes() IndexView
+}
+
+// IndexView 集合的索引管理
+type IndexView interface {
+	// List 当前的索引，集合不存在时返回空
+	List(ctx context.Context) ([]IndexSpec, error)
+	// Create 创建索引
+	Create(ctx context.Context, idx Index) error
+	// Drop 删除索引
+	Drop(ctx context.Context, name string) error
+}
+
+// IndexSpec 后端中已存在的索引定义，与listIndexes返回的字段一致
+type IndexSpec struct {
+	Name               string `bson:"name"`
+	Key                bson.D `bson:"key"`
+	Unique             bool   `bson:"unique"`
+	Sparse             bool   `bson:"sparse"`
+	ExpireAfterSeconds *int64 `bson:"expireAfterSeconds"`
+	Partial            bson.D `bson:"partialFilterExpression"`
+}
+
+// Replacement 批量替换中的一个文档
+type Replacement struct {
+	Id  interface{}
+	Doc interface{}
+}
+
+// WriteResult 写入结果
+type WriteResult struct {
+	Matched  int64 // 匹配的文档数
+	Modified int64 // 内容发生变化的文档数
+	Upserted int64 // upsert插入的文档数
+}
+
+var current Backend
+
+// Use 切换持久化后端，通常在启动时调用一次
+func Use(b Backend) {
+	current = b
+}
+
+// Current 当前的持久化后端，未初始化时为nil
+func Current() Backend {
+	return current
+}
@@ -0,0 +1,446 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gameserver/core/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Indexed 持久化类型通过Indexes声明集合需要的索引，_id索引不需要声明
+type Indexed interface {
+	PersistData
+	Indexes() []Index
+}
+
+// Index 索引声明
+//
+//	mongodb.Index{Keys: mongodb.Keys("player_id", "-create_time")}
+//	mongodb.Index{Keys: mongodb.Keys("expire_at"), TTL: time.Hour}
+type Index struct {
+	Name    string        // 为空时按字段生成，与MongoDB默认命名一致，如 player_id_1_create_time_-1
+	Keys    bson.D        // 索引字段，用Keys生成
+	Unique  bool          // 唯一索引
+	Sparse  bool          // 稀疏索引，不包含没有该字段的文档
+	TTL     time.Duration // 大于0时为TTL索引，只支持单个时间类型字段，文档在字段时间之后TTL过期
+	Partial bson.D        // 部分索引，只包含匹配该条件的文档
+}
+
+// Keys 索引字段，"-"前缀表示降序
+func Keys(fields ...string) bson.D {
+	keys := make(bson.D, 0, len(fields))
+	for _, field := range fields {
+		if strings.HasPrefix(field, "-") {
+			keys = append(keys, bson.E{Key: field[1:], Value: -1})
+		} else {
+			keys = append(keys, bson.E{Key: field, Value: 1})
+		}
+	}
+	return keys
+}
+
+// IndexDiff 一个集合中声明的索引与数据库的差异
+type IndexDiff struct {
+	Collection string
+	Missing    []Index  // 声明了但数据库中没有
+	Changed    []Index  // 同名但定义不同，需要删除后重建
+	Extra      []string // 数据库中有但没有声明
+}
+
+// Empty 没有差异
+func (d *IndexDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Changed) == 0 && len(d.Extra) == 0
+}
+
+func (d *IndexDiff) String() string {
+	if d.Empty() {
+		return d.Collection + ": up to date"
+	}
+	var sb strings.Builder
+	sb.WriteString(d.Collection + ":")
+	for _, idx := range d.Missing {
+		fmt.Fprintf(&sb, "\r\n  + %s", idx)
+	}
+	for _, idx := range d.Changed {
+		fmt.Fprintf(&sb, "\r\n  ~ %s", idx)
+	}
+	for _, name := range d.Extra {
+		fmt.Fprintf(&sb, "\r\n  - %s", name)
+	}
+	return sb.String()
+}
+
+func (idx Index) String() string {
+	var opts []string
+	if idx.Unique {
+		opts = append(opts, "unique")
+	}
+	if idx.Sparse {
+		opts = append(opts, "sparse")
+	}
+	if idx.TTL > 0 {
+		opts = append(opts, "ttl="+idx.TTL.String())
+	}
+	if len(idx.Partial) > 0 {
+		opts = append(opts, "partial="+extJSON(idx.Partial))
+	}
+	if len(opts) == 0 {
+		return idx.Name
+	}
+	return idx.Name + " (" + strings.Join(opts, ", ") + ")"
+}
+
+var (
+	indexes   = make(map[string][]Index)
+	indexesMu sync.RWMutex
+)
+
+// RegisterIndexes 注册T声明的索引，集合名与Repository一致，通常在init中调用
+// 索引字段必须是T中存在的bson字段，声明有误时panic
+func RegisterIndexes[T Indexed]() {
+	var t T
+	name := getCollectionName(t)
+	typ := reflect.TypeOf(t)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	declared := t.Indexes()
+	names := make(map[string]bool, len(declared))
+	for i := range declared {
+		idx := &declared[i]
+		if len(idx.Keys) == 0 {
+			panic(fmt.Sprintf("mongodb: 集合%s的第%d个索引没有字段", name, i+1))
+		}
+		for _, key := range idx.Keys {
+			if _, ok := lookupFieldType(typ, key.Key); !ok {
+				panic(fmt.Sprintf("mongodb: 集合%s的索引字段%s不存在", name, key.Key))
+			}
+		}
+		if idx.TTL > 0 {
+			ft, _ := lookupFieldType(typ, idx.Keys[0].Key)
+			if len(idx.Keys) != 1 || !isTimeType(ft) {
+				panic(fmt.Sprintf("mongodb: 集合%s的TTL索引只支持单个时间类型字段", name))
+			}
+		}
+		if idx.Name == "" {
+			idx.Name = indexName(idx.Keys)
+		}
+		if idx.Name == "_id_" || names[idx.Name] {
+			panic(fmt.Sprintf("mongodb: 集合%s的索引名%s重复", name, idx.Name))
+		}
+		names[idx.Name] = true
+	}
+
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+	indexes[name] = declared
+}
+
+// IndexedCollections 声明了索引的集合
+func IndexedCollections() []string {
+	indexesMu.RLock()
+	defer indexesMu.RUnlock()
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// indexName MongoDB的默认索引名
+func indexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// lookupFieldType 按bson字段路径查找字段类型，map和interface下的路径不做检查
+func lookupFieldType(typ reflect.Type, path string) (reflect.Type, bool) {
+	for _, part := range strings.Split(path, ".") {
+		for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+			typ = typ.Elem()
+		}
+		switch typ.Kind() {
+		case reflect.Map, reflect.Interface:
+			return typ, true
+		case reflect.Struct:
+		default:
+			return nil, false
+		}
+		field, ok := structField(typ, part)
+		if !ok {
+			return nil, false
+		}
+		typ = field
+	}
+	return typ, true
+}
+
+func structField(typ reflect.Type, name string) (reflect.Type, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldName, inline, skip := bsonFieldName(field)
+		if skip {
+			continue
+		}
+		if inline {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if t, ok := structField(ft, name); ok {
+					return t, true
+				}
+			}
+			continue
+		}
+		if fieldName == name {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+func isTimeType(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ == reflect.TypeOf(time.Time{}) || typ == reflect.TypeOf(primitive.DateTime(0))
+}
+
+// matches 数据库中的索引与声明一致
+func (l *IndexSpec) matches(idx Index) bool {
+	if len(l.Key) != len(idx.Keys) || l.Unique != idx.Unique || l.Sparse != idx.Sparse {
+		return false
+	}
+	for i, key := range idx.Keys {
+		if l.Key[i].Key != key.Key || normalizeNumber(l.Key[i].Value) != normalizeNumber(key.Value) {
+			return false
+		}
+	}
+	var ttl int64
+	if l.ExpireAfterSeconds != nil {
+		ttl = *l.ExpireAfterSeconds
+	}
+	if ttl != int64(idx.TTL/time.Second) {
+		return false
+	}
+	return extJSON(l.Partial) == extJSON(idx.Partial)
+}
+
+func normalizeNumber(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int:
+		return int64(n)
+	case float64:
+		if n == float64(int64(n)) {
+			return int64(n)
+		}
+	}
+	return v
+}
+
+func extJSON(d bson.D) string {
+	if len(d) == 0 {
+		return ""
+	}
+	data, err := bson.MarshalExtJSON(d, false, false)
+	if err != nil {
+		return fmt.Sprint(d)
+	}
+	return string(data)
+}
+
+// DiffIndexes 对比声明的索引与数据库，collections为空时对比所有声明了索引的集合
+func DiffIndexes(ctx context.Context, collections ...string) ([]*IndexDiff, error) {
+	if current == nil {
+		return nil, ErrNotInitialized
+	}
+	if len(collections) == 0 {
+		collections = IndexedCollections()
+	}
+
+	diffs := make([]*IndexDiff, 0, len(collections))
+	for _, collection := range collections {
+		indexesMu.RLock()
+		declared, ok := indexes[collection]
+		indexesMu.RUnlock()
+		if !ok {
+			return diffs, fmt.Errorf("mongodb: 集合%s没有声明索引", collection)
+		}
+		diff, err := diffIndexes(ctx, collection, declared)
+		if err != nil {
+			return diffs, err
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+func diffIndexes(ctx context.Context, collection string, declared []Index) (*IndexDiff, error) {
+	coll, err := getCollection(collection)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	live, err := coll.Indexes().List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("mongodb: 获取集合%s的索引失败: %w", collection, err)
+	}
+
+	diff := &IndexDiff{Collection: collection}
+	liveByName := make(map[string]*IndexSpec, len(live))
+	for i := range live {
+		liveByName[live[i].Name] = &live[i]
+	}
+	declaredNames := make(map[string]bool, len(declared))
+	for _, idx := range declared {
+		declaredNames[idx.Name] = true
+		l, ok := liveByName[idx.Name]
+		switch {
+		case !ok:
+			diff.Missing = append(diff.Missing, idx)
+		case !l.matches(idx):
+			diff.Changed = append(diff.Changed, idx)
+		}
+	}
+	for _, l := range live {
+		if l.Name != "_id_" && !declaredNames[l.Name] {
+			diff.Extra = append(diff.Extra, l.Name)
+		}
+	}
+	return diff, nil
+}
+
+// ApplyIndexes 按声明修改数据库：创建缺少的索引，删除后重建定义不同的索引，dropExtra为true时删除没有声明的索引
+// 重建期间该索引不可用，唯一索引在重建前后之间不生效，大集合建议在维护时段执行
+func ApplyIndexes(ctx context.Context, dropExtra bool, collections ...string) ([]*IndexDiff, error) {
+	diffs, err := DiffIndexes(ctx, collections...)
+	if err != nil {
+		return diffs, err
+	}
+	for _, diff := range diffs {
+		if err := applyIndexDiff(ctx, diff, true, dropExtra); err != nil {
+			return diffs, err
+		}
+	}
+	return diffs, nil
+}
+
+// EnsureIndexes 启动时调用，只创建缺少的索引，定义不同和多余的索引记录到日志，由index命令处理
+// collections为空时处理所有声明了索引的集合，某个集合失败不影响其他集合
+func EnsureIndexes(ctx context.Context, collections ...string) error {
+	if current == nil {
+		return ErrNotInitialized
+	}
+	if len(collections) == 0 {
+		collections = IndexedCollections()
+	}
+
+	var errs []error
+	for _, collection := range collections {
+		diffs, err := DiffIndexes(ctx, collection)
+		if err == nil {
+			diff := diffs[0]
+			if len(diff.Changed) > 0 || len(diff.Extra) > 0 {
+				log.Error("EnsureIndexes: 集合 %s 的索引与声明不一致，使用 'index apply' 命令处理\r\n%s", collection, diff)
+			}
+			err = applyIndexDiff(ctx, diff, false, false)
+		}
+		if err != nil {
+			log.Error("EnsureIndexes: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func applyIndexDiff(ctx context.Context, diff *IndexDiff, rebuild, dropExtra bool) error {
+	coll, err := getCollection(diff.Collection)
+	if err != nil {
+		return err
+	}
+	view := coll.Indexes()
+	drop := func(name string) error {
+		opCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		if err := view.Drop(opCtx, name); err != nil {
+			return fmt.Errorf("mongodb: 删除集合%s的索引%s失败: %w", diff.Collection, name, err)
+		}
+		log.Release("删除集合 %s 的索引 %s", diff.Collection, name)
+		return nil
+	}
+	create := func(idx Index) error {
+		opCtx, cancel := withTimeout(ctx)
+		defer cancel()
+		if err := view.Create(opCtx, idx); err != nil {
+			return fmt.Errorf("mongodb: 创建集合%s的索引%s失败: %w", diff.Collection, idx.Name, err)
+		}
+		log.Release("创建集合 %s 的索引 %s", diff.Collection, idx)
+		return nil
+	}
+
+	for _, idx := range diff.Missing {
+		if err := create(idx); err != nil {
+			return err
+		}
+	}
+	if rebuild {
+		for _, idx := range diff.Changed {
+			if err := drop(idx.Name); err != nil {
+				return err
+			}
+			if err := create(idx); err != nil {
+				return err
+			}
+		}
+	}
+	if dropExtra {
+		for _, name := range diff.Extra {
+			if err := drop(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func indexModel(idx Index) mongo.IndexModel {
+	opts := options.Index().SetName(idx.Name)
+	if idx.Unique {
+		opts.SetUnique(true)
+	}
+	if idx.Sparse {
+		opts.SetSparse(true)
+	}
+	if idx.TTL > 0 {
+		opts.SetExpireAfterSeconds(int32(idx.TTL / time.Second))
+	}
+	if len(idx.Partial) > 0 {
+		opts.SetPartialFilterExpression(idx.Partial)
+	}
+	return mongo.IndexModel{Keys: idx.Keys, Options: opts}
+}
@@ -0,0 +1,886 @@
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongo "go.mongodb.org/mongo-driver/mongo"
+)
+
+// Memory 内存后端，数据只保存在进程内，停服后丢失，用于开发和测试
+//
+// 支持仓储、迁移和存盘用到的操作：
+//   - 查询：字段相等、a.b嵌套路径、数组包含，$eq $ne $gt $gte $lt $lte $in $nin $exists，$and $or $nor
+//   - 排序、包含字段的投影、skip和limit
+//   - 更新：$set $inc $unset
+//   - _id唯一，重复插入返回与MongoDB相同的重复键错误
+//
+// 索引只记录定义供DiffIndexes对比，不检查唯一约束；事务串行执行，fn返回错误时恢复到事务开始时的数据
+type Memory struct {
+	mu          sync.Mutex
+	collections map[string]*memCollection
+	txMu        sync.Mutex // 事务串行执行
+}
+
+// NewMemory 创建内存后端
+func NewMemory() *Memory {
+	return &Memory{collections: make(map[string]*memCollection)}
+}
+
+// UseMemory 创建内存后端并切换为当前后端
+func UseMemory() *Memory {
+	m := NewMemory()
+	Use(m)
+	return m
+}
+
+// Collection 获取集合，不存在时创建
+func (m *Memory) Collection(name string) Collection {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.collections[name]
+	if !ok {
+		c = &memCollection{name: name, docs: make(map[string]*memDoc)}
+		m.collections[name] = c
+	}
+	return c
+}
+
+// WithTransaction 串行执行fn，fn返回错误时恢复所有集合
+// 事务期间其他协程不经过事务的写入在回滚时也会被覆盖
+func (m *Memory) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	snapshot := m.snapshot()
+	if err := fn(ctx); err != nil {
+		m.restore(snapshot)
+		return err
+	}
+	return nil
+}
+
+// Close 内存后端不需要关闭
+func (m *Memory) Close(ctx context.Context) error {
+	return nil
+}
+
+// snapshot 复制所有集合，文档保存后不再修改，只需要复制map
+func (m *Memory) snapshot() map[string]*memCollection {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]*memCollection, len(m.collections))
+	for name, c := range m.collections {
+		c.mu.RLock()
+		docs := make(map[string]*memDoc, len(c.docs))
+		for key, doc := range c.docs {
+			docs[key] = doc
+		}
+		snapshot[name] = &memCollection{docs: docs, seq: c.seq, indexes: append([]IndexSpec(nil), c.indexes...)}
+		c.mu.RUnlock()
+	}
+	return snapshot
+}
+
+// restore 恢复到快照，集合对象保持不变，事务中新建的集合清空
+func (m *Memory) restore(snapshot map[string]*memCollection) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, c := range m.collections {
+		c.mu.Lock()
+		if snap, ok := snapshot[name]; ok {
+			c.docs, c.seq, c.indexes = snap.docs, snap.seq, snap.indexes
+		} else {
+			c.docs, c.indexes = make(map[string]*memDoc), nil
+		}
+		c.mu.Unlock()
+	}
+}
+
+// memCollection 内存集合，文档按插入顺序保存
+type memCollection struct {
+	name    string
+	mu      sync.RWMutex
+	docs    map[string]*memDoc // _id -> 文档
+	seq     int64
+	indexes []IndexSpec
+}
+
+// memDoc 保存后不再修改，更新时整体替换
+type memDoc struct {
+	seq int64
+	raw bson.Raw
+	doc bson.D
+}
+
+func (c *memCollection) Find(ctx context.Context, q *Query, fn func(doc bson.Raw) error) error {
+	filter, err := toDocument(q.filter)
+	if err != nil {
+		return err
+	}
+	c.mu.RLock()
+	matched, err := c.match(filter)
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if len(q.sort) > 0 {
+		sort.SliceStable(matched, func(i, j int) bool {
+			for _, key := range q.sort {
+				a, _ := firstValue(matched[i].doc, key.Key)
+				b, _ := firstValue(matched[j].doc, key.Key)
+				if r := compareForSort(a, b); r != 0 {
+					if n, _ := toInt64(key.Value); n < 0 {
+						return r > 0
+					}
+					return r < 0
+				}
+			}
+			return false
+		})
+	}
+	if q.skip > 0 {
+		if q.skip >= int64(len(matched)) {
+			matched = nil
+		} else {
+			matched = matched[q.skip:]
+		}
+	}
+	if q.limit > 0 && q.limit < int64(len(matched)) {
+		matched = matched[:q.limit]
+	}
+
+	for _, doc := range matched {
+		raw := doc.raw
+		if len(q.projection) > 0 {
+			if raw, err = bson.Marshal(project(doc.doc, q.projection)); err != nil {
+				return err
+			}
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *memCollection) Count(ctx context.Context, filter interface{}) (int64, error) {
+	f, err := toDocument(filter)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	matched, err := c.match(f)
+	return int64(len(matched)), err
+}
+
+func (c *memCollection) Insert(ctx context.Context, doc interface{}) error {
+	d, err := toDocument(doc)
+	if err != nil {
+		return err
+	}
+	if _, ok := lookupKey(d, "_id"); !ok {
+		d = append(bson.D{{Key: "_id", Value: primitive.NewObjectID()}}, d...)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.insert(d)
+}
+
+func (c *memCollection) Replace(ctx context.Context, filter, doc interface{}, upsert bool) (WriteResult, error) {
+	f, err := toDocument(filter)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	d, err := toDocument(doc)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.replace(f, d, upsert)
+}
+
+func (c *memCollection) BulkReplace(ctx context.Context, docs []Replacement) (WriteResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var total WriteResult
+	for _, r := range docs {
+		d, err := toDocument(r.Doc)
+		if err != nil {
+			return total, err
+		}
+		result, err := c.replace(bson.D{{Key: "_id", Value: r.Id}}, d, true)
+		if err != nil {
+			return total, err
+		}
+		total.Matched += result.Matched
+		total.Modified += result.Modified
+		total.Upserted += result.Upserted
+	}
+	return total, nil
+}
+
+func (c *memCollection) Update(ctx context.Context, filter interface{}, update bson.D, many bool) (WriteResult, error) {
+	f, err := toDocument(filter)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	u, err := toDocument(update)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	matched, err := c.match(f)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	if !many && len(matched) > 1 {
+		matched = matched[:1]
+	}
+
+	var result WriteResult
+	for _, old := range matched {
+		d, err := applyUpdate(old.doc, u)
+		if err != nil {
+			return result, err
+		}
+		result.Matched++
+		modified, err := c.store(old, d)
+		if err != nil {
+			return result, err
+		}
+		if modified {
+			result.Modified++
+		}
+	}
+	return result, nil
+}
+
+func (c *memCollection) Delete(ctx context.Context, filter interface{}) (int64, error) {
+	f, err := toDocument(filter)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	matched, err := c.match(f)
+	if err != nil || len(matched) == 0 {
+		return 0, err
+	}
+	id, _ := lookupKey(matched[0].doc, "_id")
+	delete(c.docs, idKey(id))
+	return 1, nil
+}
+
+func (c *memCollection) Indexes() IndexView {
+	return memIndexView{c: c}
+}
+
+// match 按插入顺序返回匹配filter的文档，调用方持有锁
+func (c *memCollection) match(filter bson.D) ([]*memDoc, error) {
+	matched := make([]*memDoc, 0)
+	for _, doc := range c.docs {
+		ok, err := matchDocument(doc.doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, doc)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].seq < matched[j].seq })
+	return matched, nil
+}
+
+func (c *memCollection) insert(d bson.D) error {
+	id, _ := lookupKey(d, "_id")
+	key := idKey(id)
+	if _, exists := c.docs[key]; exists {
+		return duplicateKeyError(c.name, id)
+	}
+	raw, err := bson.Marshal(d)
+	if err != nil {
+		return err
+	}
+	c.seq++
+	c.docs[key] = &memDoc{seq: c.seq, raw: raw, doc: d}
+	return nil
+}
+
+func (c *memCollection) replace(filter, d bson.D, upsert bool) (WriteResult, error) {
+	matched, err := c.match(filter)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	if len(matched) == 0 {
+		if !upsert {
+			return WriteResult{}, nil
+		}
+		if _, ok := lookupKey(d, "_id"); !ok {
+			id, ok := lookupKey(filter, "_id")
+			if !ok || isOperatorDoc(id) {
+				id = primitive.NewObjectID()
+			}
+			d = append(bson.D{{Key: "_id", Value: id}}, d...)
+		}
+		if err := c.insert(d); err != nil {
+			return WriteResult{}, err
+		}
+		return WriteResult{Upserted: 1}, nil
+	}
+
+	old := matched[0]
+	oldId, _ := lookupKey(old.doc, "_id")
+	if id, ok := lookupKey(d, "_id"); !ok {
+		d = append(bson.D{{Key: "_id", Value: oldId}}, d...)
+	} else if !valuesEqual(id, oldId) {
+		return WriteResult{}, fmt.Errorf("mongodb: 集合%s的文档_id不能修改", c.name)
+	}
+	modified, err := c.store(old, d)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	result := WriteResult{Matched: 1}
+	if modified {
+		result.Modified = 1
+	}
+	return result, nil
+}
+
+// store 用d替换old，返回内容是否变化
+func (c *memCollection) store(old *memDoc, d bson.D) (bool, error) {
+	raw, err := bson.Marshal(d)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(raw, old.raw) {
+		return false, nil
+	}
+	id, _ := lookupKey(d, "_id")
+	c.docs[idKey(id)] = &memDoc{seq: old.seq, raw: raw, doc: d}
+	return true, nil
+}
+
+type memIndexView struct {
+	c *memCollection
+}
+
+func (v memIndexView) List(ctx context.Context) ([]IndexSpec, error) {
+	v.c.mu.RLock()
+	defer v.c.mu.RUnlock()
+	return append([]IndexSpec(nil), v.c.indexes...), nil
+}
+
+func (v memIndexView) Create(ctx context.Context, idx Index) error {
+	spec := IndexSpec{Name: idx.Name, Key: idx.Keys, Unique: idx.Unique, Sparse: idx.Sparse, Partial: idx.Partial}
+	if idx.TTL > 0 {
+		ttl := int64(idx.TTL / time.Second)
+		spec.ExpireAfterSeconds = &ttl
+	}
+	v.c.mu.Lock()
+	defer v.c.mu.Unlock()
+	for i := range v.c.indexes {
+		if v.c.indexes[i].Name == idx.Name {
+			v.c.indexes[i] = spec
+			return nil
+		}
+	}
+	v.c.indexes = append(v.c.indexes, spec)
+	return nil
+}
+
+func (v memIndexView) Drop(ctx context.Context, name string) error {
+	v.c.mu.Lock()
+	defer v.c.mu.Unlock()
+	for i := range v.c.indexes {
+		if v.c.indexes[i].Name == name {
+			v.c.indexes = append(v.c.indexes[:i], v.c.indexes[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("mongodb: 集合%s没有索引%s", v.c.name, name)
+}
+
+func duplicateKeyError(collection string, id interface{}) error {
+	return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Code:    11000,
+		Message: fmt.Sprintf("E11000 duplicate key error collection: %s index: _id_ dup key: { _id: %v }", collection, id),
+	}}}
+}
+
+// toDocument 把filter、文档或更新转换为bson.D，嵌套文档也是bson.D
+func toDocument(v interface{}) (bson.D, error) {
+	if v == nil {
+		return bson.D{}, nil
+	}
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var d bson.D
+	if err := bson.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// idKey _id的比较键，数值类型不同但值相同的_id视为同一个
+func idKey(id interface{}) string {
+	if n, ok := toInt64(id); ok {
+		return "n:" + strconv.FormatInt(n, 10)
+	}
+	if s, ok := id.(string); ok {
+		return "s:" + s
+	}
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "_id", Value: id}}, true, false)
+	if err != nil {
+		return fmt.Sprint(id)
+	}
+	return string(data)
+}
+
+func lookupKey(d bson.D, key string) (interface{}, bool) {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+func isOperatorDoc(v interface{}) bool {
+	d, ok := v.(bson.D)
+	return ok && len(d) > 0 && strings.HasPrefix(d[0].Key, "$")
+}
+
+// lookupValues 按路径取值，路径经过数组时取数组中每个元素的值
+func lookupValues(v interface{}, parts []string) []interface{} {
+	if len(parts) == 0 {
+		return []interface{}{v}
+	}
+	switch d := v.(type) {
+	case bson.D:
+		if value, ok := lookupKey(d, parts[0]); ok {
+			return lookupValues(value, parts[1:])
+		}
+	case bson.A:
+		if i, err := strconv.Atoi(parts[0]); err == nil {
+			if i >= 0 && i < len(d) {
+				return lookupValues(d[i], parts[1:])
+			}
+			return nil
+		}
+		var values []interface{}
+		for _, elem := range d {
+			values = append(values, lookupValues(elem, parts)...)
+		}
+		return values
+	}
+	return nil
+}
+
+func firstValue(doc bson.D, path string) (interface{}, bool) {
+	values := lookupValues(doc, strings.Split(path, "."))
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// matchDocument 文档是否匹配filter
+func matchDocument(doc, filter bson.D) (bool, error) {
+	for _, e := range filter {
+		var ok bool
+		var err error
+		switch e.Key {
+		case "$and", "$or", "$nor":
+			ok, err = matchLogical(doc, e.Key, e.Value)
+		default:
+			if strings.HasPrefix(e.Key, "$") {
+				return false, fmt.Errorf("mongodb: 内存后端不支持查询操作符%s", e.Key)
+			}
+			ok, err = matchField(lookupValues(doc, strings.Split(e.Key, ".")), e.Value)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchLogical(doc bson.D, op string, value interface{}) (bool, error) {
+	clauses, ok := value.(bson.A)
+	if !ok || len(clauses) == 0 {
+		return false, fmt.Errorf("mongodb: %s需要非空数组", op)
+	}
+	for _, clause := range clauses {
+		sub, ok := clause.(bson.D)
+		if !ok {
+			return false, fmt.Errorf("mongodb: %s的元素必须是文档", op)
+		}
+		matched, err := matchDocument(doc, sub)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case op == "$and" && !matched:
+			return false, nil
+		case op == "$or" && matched:
+			return true, nil
+		case op == "$nor" && matched:
+			return false, nil
+		}
+	}
+	return op != "$or", nil
+}
+
+// matchField 字段的值是否满足条件，cond是操作符文档或要相等的值
+func matchField(values []interface{}, cond interface{}) (bool, error) {
+	if !isOperatorDoc(cond) {
+		return anyEqual(values, cond), nil
+	}
+	for _, op := range cond.(bson.D) {
+		var ok bool
+		switch op.Key {
+		case "$eq":
+			ok = anyEqual(values, op.Value)
+		case "$ne":
+			ok = !anyEqual(values, op.Value)
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = anyCompare(values, op.Key, op.Value)
+		case "$in", "$nin":
+			list, isArray := op.Value.(bson.A)
+			if !isArray {
+				return false, fmt.Errorf("mongodb: %s需要数组", op.Key)
+			}
+			for _, v := range list {
+				if anyEqual(values, v) {
+					ok = true
+					break
+				}
+			}
+			if op.Key == "$nin" {
+				ok = !ok
+			}
+		case "$exists":
+			ok = (len(values) > 0) == truthy(op.Value)
+		default:
+			return false, fmt.Errorf("mongodb: 内存后端不支持查询操作符%s", op.Key)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// anyEqual 与MongoDB一致：null匹配不存在的字段，数组字段中任一元素相等即匹配
+func anyEqual(values []interface{}, v interface{}) bool {
+	if len(values) == 0 {
+		return v == nil
+	}
+	for _, value := range values {
+		if valuesEqual(value, v) {
+			return true
+		}
+		if arr, ok := value.(bson.A); ok {
+			for _, elem := range arr {
+				if valuesEqual(elem, v) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func anyCompare(values []interface{}, op string, v interface{}) bool {
+	for _, value := range values {
+		candidates := []interface{}{value}
+		if arr, ok := value.(bson.A); ok {
+			candidates = arr
+		}
+		for _, candidate := range candidates {
+			r, ok := compareValues(candidate, v)
+			if !ok {
+				continue
+			}
+			if op == "$gt" && r > 0 || op == "$gte" && r >= 0 || op == "$lt" && r < 0 || op == "$lte" && r <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func truthy(v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	if n, ok := toFloat64(v); ok {
+		return n != 0
+	}
+	return v != nil
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if r, ok := compareValues(a, b); ok {
+		return r == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues 比较同类型的值，数值类型之间可以比较，类型不同时返回false
+func compareValues(a, b interface{}) (int, bool) {
+	if x, ok := toInt64(a); ok {
+		if y, ok := toInt64(b); ok {
+			return compareOrdered(x, y), true
+		}
+	}
+	if x, ok := toFloat64(a); ok {
+		if y, ok := toFloat64(b); ok {
+			return compareOrdered(x, y), true
+		}
+		return 0, false
+	}
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			return compareOrdered(boolInt(x), boolInt(y)), true
+		}
+	case primitive.DateTime:
+		if y, ok := b.(primitive.DateTime); ok {
+			return compareOrdered(x, y), true
+		}
+	case primitive.ObjectID:
+		if y, ok := b.(primitive.ObjectID); ok {
+			return bytes.Compare(x[:], y[:]), true
+		}
+	}
+	return 0, false
+}
+
+// compareForSort 排序比较，不同类型按MongoDB的类型顺序，不存在的字段最小
+func compareForSort(a, b interface{}) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return compareOrdered(ra, rb)
+	}
+	r, _ := compareValues(a, b)
+	return r
+}
+
+func typeRank(v interface{}) int {
+	if _, ok := toFloat64(v); ok {
+		return 1
+	}
+	switch v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return 0
+	case string:
+		return 2
+	case bson.D, bson.M:
+		return 3
+	case bson.A:
+		return 4
+	case primitive.Binary:
+		return 5
+	case primitive.ObjectID:
+		return 6
+	case bool:
+		return 7
+	case primitive.DateTime:
+		return 8
+	}
+	return 9
+}
+
+func compareOrdered[T int | int64 | float64 | primitive.DateTime](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// project 包含字段的投影，_id总是返回
+func project(doc bson.D, projection bson.D) bson.D {
+	out := bson.D{}
+	if id, ok := lookupKey(doc, "_id"); ok {
+		out = append(out, bson.E{Key: "_id", Value: id})
+	}
+	for _, field := range projection {
+		if field.Key == "_id" || !truthy(field.Value) {
+			continue
+		}
+		if value, ok := firstValue(doc, field.Key); ok {
+			out = setPath(out, strings.Split(field.Key, "."), value)
+		}
+	}
+	return out
+}
+
+// applyUpdate 在文档副本上执行$set/$inc/$unset
+func applyUpdate(doc, update bson.D) (bson.D, error) {
+	d := cloneDocument(doc)
+	for _, op := range update {
+		fields, ok := op.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("mongodb: %s需要文档", op.Key)
+		}
+		for _, field := range fields {
+			if field.Key == "_id" {
+				return nil, fmt.Errorf("mongodb: 文档_id不能修改")
+			}
+			parts := strings.Split(field.Key, ".")
+			switch op.Key {
+			case "$set":
+				d = setPath(d, parts, field.Value)
+			case "$unset":
+				d = unsetPath(d, parts)
+			case "$inc":
+				old, _ := firstValue(d, field.Key)
+				sum, err := addNumbers(old, field.Value)
+				if err != nil {
+					return nil, fmt.Errorf("mongodb: $inc %s: %w", field.Key, err)
+				}
+				d = setPath(d, parts, sum)
+			default:
+				return nil, fmt.Errorf("mongodb: 内存后端不支持更新操作符%s", op.Key)
+			}
+		}
+	}
+	return d, nil
+}
+
+func cloneDocument(d bson.D) bson.D {
+	out := make(bson.D, len(d))
+	for i, e := range d {
+		if sub, ok := e.Value.(bson.D); ok {
+			e.Value = cloneDocument(sub)
+		}
+		out[i] = e
+	}
+	return out
+}
+
+func setPath(d bson.D, parts []string, value interface{}) bson.D {
+	for i := range d {
+		if d[i].Key != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			d[i].Value = value
+			return d
+		}
+		sub, _ := d[i].Value.(bson.D)
+		d[i].Value = setPath(sub, parts[1:], value)
+		return d
+	}
+	if len(parts) == 1 {
+		return append(d, bson.E{Key: parts[0], Value: value})
+	}
+	return append(d, bson.E{Key: parts[0], Value: setPath(bson.D{}, parts[1:], value)})
+}
+
+func unsetPath(d bson.D, parts []string) bson.D {
+	for i := range d {
+		if d[i].Key != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			return append(d[:i], d[i+1:]...)
+		}
+		if sub, ok := d[i].Value.(bson.D); ok {
+			d[i].Value = unsetPath(sub, parts[1:])
+		}
+		return d
+	}
+	return d
+}
+
+// addNumbers $inc的结果类型与MongoDB一致：有浮点数时为double，有int64或int32溢出时为int64
+func addNumbers(a, b interface{}) (interface{}, error) {
+	if a == nil {
+		a = int32(0)
+	}
+	if _, ok := toFloat64(a); !ok {
+		return nil, fmt.Errorf("字段不是数值: %v", a)
+	}
+	if _, ok := toFloat64(b); !ok {
+		return nil, fmt.Errorf("增量不是数值: %v", b)
+	}
+	_, aFloat := a.(float64)
+	_, bFloat := b.(float64)
+	if aFloat || bFloat {
+		x, _ := toFloat64(a)
+		y, _ := toFloat64(b)
+		return x + y, nil
+	}
+	x, _ := toInt64(a)
+	y, _ := toInt64(b)
+	sum := x + y
+	_, aInt32 := a.(int32)
+	_, bInt32 := b.(int32)
+	if aInt32 && bInt32 && sum >= math.MinInt32 && sum <= math.MaxInt32 {
+		return int32(sum), nil
+	}
+	return sum, nil
+}
@@ -0,0 +1,515 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gameserver/core/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SchemaVersionField 文档的schema版本字段，没有该字段的旧文档视为版本0
+const SchemaVersionField = "_schema"
+
+// 迁移报告中最多记录的失败文档数
+const maxReportErrors = 10
+
+// MigrateFunc 把文档从上一个版本升级到本版本，直接修改doc
+type MigrateFunc func(doc bson.M) error
+
+// Migration 一个版本的升级
+type Migration struct {
+	Version     int
+	Description string
+	Up          MigrateFunc // 为nil时只补齐默认值
+}
+
+// collectionSchema 集合注册的迁移
+type collectionSchema struct {
+	name       string
+	typ        reflect.Type // 文档结构体，用于读取default标签
+	migrations []Migration  // 版本从1开始连续递增
+}
+
+var (
+	schemas   = make(map[string]*collectionSchema)
+	schemasMu sync.RWMutex
+)
+
+// RegisterMigration 注册T对应集合升级到version的迁移，版本号从1开始连续递增，通常在init中调用
+//
+// 注册了迁移的集合：
+//   - 写入的文档带上最新的schema版本（结构体总是对应最新版本）
+//   - 加载版本较低的文档时依次执行迁移，再按结构体的default标签补齐缺失字段，不写回数据库
+//   - Migrate批量升级数据库中的旧文档，查询条件或索引用到的字段改名时需要先批量升级
+func RegisterMigration[T PersistData](version int, description string, up MigrateFunc) {
+	var t T
+	name := getCollectionName(t)
+	typ := reflect.TypeOf(t)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+	s, ok := schemas[name]
+	if !ok {
+		s = &collectionSchema{name: name, typ: typ}
+		schemas[name] = s
+	}
+	if version != len(s.migrations)+1 {
+		panic(fmt.Sprintf("mongodb: 集合%s的迁移版本应为%d，实际为%d", name, len(s.migrations)+1, version))
+	}
+	s.migrations = append(s.migrations, Migration{Version: version, Description: description, Up: up})
+}
+
+// SchemaVersion 集合当前的schema版本，没有注册迁移时为0
+func SchemaVersion(collection string) int {
+	if s := getSchema(collection); s != nil {
+		return s.latest()
+	}
+	return 0
+}
+
+// MigratedCollections 注册了迁移的集合
+func MigratedCollections() []string {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getSchema(collection string) *collectionSchema {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	return schemas[collection]
+}
+
+func (s *collectionSchema) latest() int {
+	return len(s.migrations)
+}
+
+// upgrade 把文档升级到最新版本并补齐默认值，返回升级前的版本
+// 版本不低于当前代码的文档（如滚动发布时新版本服务写入的）保持不变
+func (s *collectionSchema) upgrade(doc bson.M) (int, error) {
+	from := docVersion(doc)
+	if from >= s.latest() {
+		return from, nil
+	}
+	for _, m := range s.migrations[from:] {
+		if m.Up == nil {
+			continue
+		}
+		if err := m.Up(doc); err != nil {
+			return from, fmt.Errorf("升级到版本%d(%s)失败: %w", m.Version, m.Description, err)
+		}
+	}
+	fillDefaults(doc, s.typ)
+	doc[SchemaVersionField] = s.latest()
+	return from, nil
+}
+
+func docVersion(doc bson.M) int {
+	switch v := doc[SchemaVersionField].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// decodeMigrated 解码文档，版本较低时先升级
+func decodeMigrated(s *collectionSchema, raw bson.Raw, out interface{}) error {
+	if s == nil {
+		return bson.Unmarshal(raw, out)
+	}
+	if value, err := raw.LookupErr(SchemaVersionField); err == nil {
+		if version, ok := value.AsInt64OK(); ok && int(version) >= s.latest() {
+			return bson.Unmarshal(raw, out)
+		}
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	if _, err := s.upgrade(doc); err != nil {
+		return fmt.Errorf("集合%s中ID为%v的文档%w", s.name, doc["_id"], err)
+	}
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, out)
+}
+
+// stampVersion 写入时带上当前schema版本，没有注册迁移的集合原样返回
+func stampVersion(collection string, doc interface{}) (interface{}, error) {
+	s := getSchema(collection)
+	if s == nil {
+		return doc, nil
+	}
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var d bson.D
+	if err := bson.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	for i := range d {
+		if d[i].Key == SchemaVersionField {
+			d[i].Value = s.latest()
+			return d, nil
+		}
+	}
+	return append(d, bson.E{Key: SchemaVersionField, Value: s.latest()}), nil
+}
+
+// RenameField 字段改名，支持a.b形式的嵌套路径，原字段不存在时不做修改
+func RenameField(doc bson.M, from, to string) {
+	if value, ok := RemoveField(doc, from); ok {
+		SetField(doc, to, value)
+	}
+}
+
+// RemoveField 删除字段并返回原来的值
+func RemoveField(doc bson.M, path string) (interface{}, bool) {
+	parent, key := lookupParent(doc, path, false)
+	if parent == nil {
+		return nil, false
+	}
+	value, ok := parent[key]
+	delete(parent, key)
+	return value, ok
+}
+
+// SetField 设置字段，中间的嵌套文档不存在时创建
+func SetField(doc bson.M, path string, value interface{}) {
+	parent, key := lookupParent(doc, path, true)
+	parent[key] = value
+}
+
+func lookupParent(doc bson.M, path string, create bool) (bson.M, string) {
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		sub, ok := toM(doc[part])
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			sub = bson.M{}
+		}
+		doc[part] = sub
+		doc = sub
+	}
+	return doc, parts[len(parts)-1]
+}
+
+func toM(v interface{}) (bson.M, bool) {
+	switch d := v.(type) {
+	case bson.M:
+		return d, true
+	case bson.D:
+		m := make(bson.M, len(d))
+		for _, e := range d {
+			m[e.Key] = e.Value
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// fillDefaults 按结构体的default标签补齐文档中缺失的字段
+func fillDefaults(doc bson.M, typ reflect.Type) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, inline, skip := bsonFieldName(field)
+		if skip {
+			continue
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct && hasDefaults(ft) {
+			if inline {
+				fillDefaults(doc, ft)
+				continue
+			}
+			sub, ok := toM(doc[name])
+			if !ok {
+				if doc[name] != nil {
+					continue
+				}
+				sub = bson.M{}
+			}
+			fillDefaults(sub, ft)
+			doc[name] = sub
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		if _, exists := doc[name]; exists {
+			continue
+		}
+		value, err := parseDefault(tag, ft)
+		if err != nil {
+			log.Error("字段%s.%s的default标签%q无效: %v", typ.Name(), field.Name, tag, err)
+			continue
+		}
+		doc[name] = value
+	}
+}
+
+// bsonFieldName 与驱动相同的字段命名规则：bson标签的名字，没有时为字段名小写
+func bsonFieldName(field reflect.StructField) (name string, inline, skip bool) {
+	parts := strings.Split(field.Tag.Get("bson"), ",")
+	name = parts[0]
+	if name == "-" {
+		return "", false, true
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			inline = true
+		}
+	}
+	return name, inline, false
+}
+
+func hasDefaults(typ reflect.Type) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if _, ok := field.Tag.Lookup("default"); ok && field.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDefault 把default标签转换为字段类型的值
+func parseDefault(tag string, typ reflect.Type) (interface{}, error) {
+	value := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.String:
+		value.SetString(tag)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(tag)
+		if err != nil {
+			return nil, err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(tag, 10, typ.Bits())
+		if err != nil {
+			return nil, err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(tag, 10, typ.Bits())
+		if err != nil {
+			return nil, err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(tag, typ.Bits())
+		if err != nil {
+			return nil, err
+		}
+		value.SetFloat(f)
+	default:
+		return nil, fmt.Errorf("不支持%s类型的默认值", typ)
+	}
+	return value.Interface(), nil
+}
+
+// MigrationReport 一个集合的批量迁移结果
+type MigrationReport struct {
+	Collection   string
+	Version      int            // 目标版本
+	DryRun       bool           // 只统计不写回
+	Scanned      int            // 版本低于目标版本的文档数
+	Migrated     int            // 已写回的文档数，dry-run时为可以写回的文档数
+	Skipped      int            // 写回前文档已被其他操作修改，留给下次加载或迁移处理
+	Failed       int            // 迁移函数返回错误的文档数
+	FromVersions map[int]int    // 升级前的版本 -> 文档数
+	Changes      map[string]int // 字段变化 -> 文档数，+新增 -删除 ~修改
+	Errors       []string       // 前几个失败文档的错误
+}
+
+func (r *MigrationReport) String() string {
+	var sb strings.Builder
+	mode := "migrate"
+	if r.DryRun {
+		mode = "dry-run"
+	}
+	fmt.Fprintf(&sb, "[%s] %s -> v%d: scanned %d, migrated %d, skipped %d, failed %d",
+		mode, r.Collection, r.Version, r.Scanned, r.Migrated, r.Skipped, r.Failed)
+
+	versions := make([]int, 0, len(r.FromVersions))
+	for v := range r.FromVersions {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	for _, v := range versions {
+		fmt.Fprintf(&sb, "\r\n  from v%d: %d", v, r.FromVersions[v])
+	}
+
+	changes := make([]string, 0, len(r.Changes))
+	for change := range r.Changes {
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i][1:] < changes[j][1:] || changes[i][1:] == changes[j][1:] && changes[i] < changes[j]
+	})
+	for _, change := range changes {
+		fmt.Fprintf(&sb, "\r\n  %s: %d", change, r.Changes[change])
+	}
+	for _, err := range r.Errors {
+		fmt.Fprintf(&sb, "\r\n  error: %s", err)
+	}
+	return sb.String()
+}
+
+// Migrate 把集合中版本较低的文档批量升级到最新版本，dryRun为true时只统计字段变化不写回
+// 写回时以文档原来的版本为条件，期间被其他操作修改过的文档会跳过
+func Migrate(ctx context.Context, collection string, dryRun bool) (*MigrationReport, error) {
+	s := getSchema(collection)
+	if s == nil {
+		return nil, fmt.Errorf("mongodb: 集合%s没有注册迁移", collection)
+	}
+	coll, err := getCollection(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MigrationReport{
+		Collection:   collection,
+		Version:      s.latest(),
+		DryRun:       dryRun,
+		FromVersions: make(map[int]int),
+		Changes:      make(map[string]int),
+	}
+	filter := bson.M{"$or": bson.A{
+		bson.M{SchemaVersionField: bson.M{"$lt": s.latest()}},
+		bson.M{SchemaVersionField: bson.M{"$exists": false}},
+	}}
+	err = coll.Find(ctx, Where(filter), func(raw bson.Raw) error {
+		var doc bson.M
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return err
+		}
+		report.Scanned++
+		id := doc["_id"]
+		before := flattenDoc("", doc, make(map[string]interface{}))
+
+		from, err := s.upgrade(doc)
+		if err != nil {
+			report.Failed++
+			if len(report.Errors) < maxReportErrors {
+				report.Errors = append(report.Errors, fmt.Sprintf("_id=%v: %v", id, err))
+			}
+			return nil
+		}
+		report.FromVersions[from]++
+		for _, change := range diffDocs(before, flattenDoc("", doc, make(map[string]interface{}))) {
+			report.Changes[change]++
+		}
+		if dryRun {
+			report.Migrated++
+			return nil
+		}
+
+		opCtx, cancel := withTimeout(ctx)
+		result, err := coll.Replace(opCtx, versionFilter(id, SchemaVersionField, int64(from)), doc, false)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if result.Matched == 0 {
+			report.Skipped++
+		} else {
+			report.Migrated++
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	log.Release("Migrate: %s", report)
+	return report, nil
+}
+
+// MigrateAll 批量升级所有注册了迁移的集合
+func MigrateAll(ctx context.Context, dryRun bool) ([]*MigrationReport, error) {
+	var reports []*MigrationReport
+	for _, collection := range MigratedCollections() {
+		report, err := Migrate(ctx, collection, dryRun)
+		if report != nil {
+			reports = append(reports, report)
+		}
+		if err != nil {
+			return reports, err
+		}
+	}
+	return reports, nil
+}
+
+// flattenDoc 把嵌套文档展开为 a.b 形式的路径
+func flattenDoc(prefix string, doc bson.M, out map[string]interface{}) map[string]interface{} {
+	for key, value := range doc {
+		if key == SchemaVersionField && prefix == "" {
+			continue
+		}
+		if sub, ok := toM(value); ok {
+			flattenDoc(prefix+key+".", sub, out)
+			continue
+		}
+		out[prefix+key] = value
+	}
+	return out
+}
+
+func diffDocs(before, after map[string]interface{}) []string {
+	var changes []string
+	for path, value := range after {
+		old, ok := before[path]
+		switch {
+		case !ok:
+			changes = append(changes, "+"+path)
+		case !reflect.DeepEqual(old, value):
+			changes = append(changes, "~"+path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, "-"+path)
+		}
+	}
+	return changes
+}
@@ -2,8 +2,8 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"gameserver/conf"
 	"reflect"
 	"time"
 
@@ -20,14 +20,32 @@ type PersistData interface {
 	GetPersistId() interface{}
 }
 
+// Mongo MongoDB后端
 type Mongo struct {
-	client   *mongo.Client
-	database *mongo.Database
+	client       *mongo.Client
+	database     *mongo.Database
+	transactions bool // 是否支持多文档事务
 }
 
-var mongoInstance *Mongo
+// 调用方的ctx没有截止时间时，单次操作的默认超时
+var defaultTimeout = 5 * time.Second
 
-// 初始化连接
+// SetDefaultTimeout 设置单次操作的默认超时
+func SetDefaultTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		defaultTimeout = timeout
+	}
+}
+
+// withTimeout ctx已有截止时间时直接使用，否则加上默认超时
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, defaultTimeout)
+}
+
+// 初始化连接，并切换为MongoDB后端
 func Init(uri, dbName string, minPoolSize, maxPoolSize uint64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -46,103 +64,93 @@ func Init(uri, dbName string, minPoolSize, maxPoolSize uint64) error {
 		log.Fatal("init mongodb failed: %v", err)
 		return err
 	}
-	mongoInstance = &Mongo{
-		client:   client,
-		database: client.Database(dbName),
+	m := &Mongo{
+		client:       client,
+		database:     client.Database(dbName),
+		transactions: supportsTransaction(ctx, client),
 	}
-	log.Release("mongodb init dbName: %s, minPoolSize: %d, maxPoolSize: %d", dbName, minPoolSize, maxPoolSize)
+	Use(m)
+	log.Release("mongodb init dbName: %s, minPoolSize: %d, maxPoolSize: %d, transactions: %v",
+		dbName, minPoolSize, maxPoolSize, m.transactions)
 	return nil
 }
 
-// 查询单条
+// UseClient 使用已建立的连接，用于测试或与其他组件共享连接池
+func UseClient(client *mongo.Client, dbName string, transactions bool) {
+	Use(&Mongo{
+		client:       client,
+		database:     client.Database(dbName),
+		transactions: transactions,
+	})
+}
+
+// 查询单条，不存在时返回nil, nil
+// 以下函数使用默认超时，需要传递ctx、投影、排序、分页或事务时使用Repository
 func FindOne[T PersistData](filter interface{}) (*T, error) {
-	collection := getCollectionNameByType[T]()
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	var result T
-	err := mongoInstance.getCollection(collection).FindOne(ctx, filter).Decode(&result)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &result, nil
+	return NewRepository[T]().FindOne(context.Background(), Where(filter))
 }
 
 func FindOneById[T PersistData](id interface{}) (*T, error) {
-	return FindOne[T](bson.M{"_id": id})
+	return NewRepository[T]().FindById(context.Background(), id)
 }
 
 // 查询多条
 func FindAll[T PersistData](filter bson.M) ([]T, error) {
-	collection := getCollectionNameByType[T]()
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	cur, err := mongoInstance.getCollection(collection).Find(ctx, filter)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, err
-	}
-	defer cur.Close(ctx)
-	var results []T
-	for cur.Next(ctx) {
-		var elem T
-		if err := cur.Decode(&elem); err != nil {
-			return nil, err
-		}
-		results = append(results, elem)
-	}
-	return results, cur.Err()
+	return NewRepository[T]().Find(context.Background(), Where(filter))
 }
 
 // 删除
-func DeleteByID[T PersistData](id interface{}) (*mongo.DeleteResult, error) {
-	collection := getCollectionNameByType[T]()
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	result, err := mongoInstance.getCollection(collection).DeleteOne(ctx, bson.M{"_id": id})
+func DeleteByID[T PersistData](id interface{}) (bool, error) {
+	repo := NewRepository[T]()
+	deleted, err := repo.Delete(context.Background(), id)
 	if err != nil {
-		log.Error("DeleteByID: 在集合 %s 中删除ID为 %v 的文档失败: %v", collection, id, err)
-		return result, err
+		log.Error("DeleteByID: 在集合 %s 中删除ID为 %v 的文档失败: %v", repo.Name(), id, err)
+		return false, err
 	}
-	if result.DeletedCount == 0 {
-		log.Debug("DeleteByID: 在集合 %s 中未找到ID为 %v 的文档", collection, id)
+	if !deleted {
+		log.Debug("DeleteByID: 在集合 %s 中未找到ID为 %v 的文档", repo.Name(), id)
 	} else {
-		log.Debug("DeleteByID: 在集合 %s 中成功删除ID为 %v 的文档", collection, id)
+		log.Debug("DeleteByID: 在集合 %s 中成功删除ID为 %v 的文档", repo.Name(), id)
 	}
-	return result, nil
+	return deleted, nil
 }
 
 func Save(doc PersistData) (*mongo.UpdateResult, error) {
-	if mongoInstance == nil {
-		log.Debug("Save: MongoDB未初始化，跳过保存")
-		return nil, nil
-	}
 	id := doc.GetPersistId()
 	collection := getCollectionName(doc)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	coll, err := getCollection(collection)
+	if err != nil {
+		log.Error("Save: 保存集合 %s 中ID为 %v 的文档失败: %v", collection, id, err)
+		return nil, err
+	}
+	ctx, cancel := withTimeout(context.Background())
 	defer cancel()
-	opts := options.Replace().SetUpsert(true)
-	result, err := mongoInstance.getCollection(collection).ReplaceOne(ctx, bson.M{"_id": id}, doc, opts)
+	data, err := stampVersion(collection, doc)
+	if err != nil {
+		log.Error("Save: 序列化集合 %s 中ID为 %v 的文档失败: %v", collection, id, err)
+		return nil, err
+	}
+	result, err := coll.Replace(ctx, bson.M{"_id": id}, data, true)
 	if err != nil {
 		log.Error("Save: 在集合 %s 中保存ID为 %v 的文档失败: %v", collection, id, err)
-		return result, err
+		return nil, err
 	}
-	if result.UpsertedCount > 0 {
+	if result.Upserted > 0 {
 		log.Debug("Save: 在集合 %s 中成功插入ID为 %v 的文档", collection, id)
-	} else if result.ModifiedCount > 0 {
+	} else if result.Modified > 0 {
 		log.Debug("Save: 在集合 %s 中成功更新ID为 %v 的文档", collection, id)
 	} else {
 		log.Debug("Save: 在集合 %s 中保存ID为 %v 的文档，但无变化", collection, id)
 	}
-	return result, nil
+	return &mongo.UpdateResult{
+		MatchedCount:  result.Matched,
+		ModifiedCount: result.Modified,
+		UpsertedCount: result.Upserted,
+	}, nil
 }
 
 // BulkSave 批量保存文档
-// 使用MongoDB的BulkWrite功能，支持upsert
+// 按_id批量替换，不存在时插入
 func BulkSave(docs []PersistData) (*mongo.BulkWriteResult, error) {
 	if len(docs) == 0 {
 		return nil, nil
@@ -150,34 +158,43 @@ func BulkSave(docs []PersistData) (*mongo.BulkWriteResult, error) {
 
 	// 获取集合名称
 	collection := getCollectionName(docs[0])
+	coll, err := getCollection(collection)
+	if err != nil {
+		return nil, fmt.Errorf("批量保存失败: %w", err)
+	}
 
-	// 创建批量写入模型
-	models := make([]mongo.WriteModel, 0, len(docs))
-
+	replacements := make([]Replacement, 0, len(docs))
 	for _, doc := range docs {
-		model := mongo.NewReplaceOneModel().
-			SetFilter(bson.M{"_id": doc.GetPersistId()}).
-			SetReplacement(doc).SetUpsert(true)
-
-		models = append(models, model)
+		data, err := stampVersion(collection, doc)
+		if err != nil {
+			return nil, fmt.Errorf("批量保存失败: %w", err)
+		}
+		replacements = append(replacements, Replacement{Id: doc.GetPersistId(), Doc: data})
 	}
 
 	// 执行批量写入
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := withTimeout(context.Background())
 	defer cancel()
 
-	result, err := mongoInstance.getCollection(collection).BulkWrite(ctx, models)
+	result, err := coll.BulkReplace(ctx, replacements)
 	if err != nil {
 		return nil, fmt.Errorf("批量保存失败: %w", err)
 	}
 
-	log.Debug("集合:%s, 批量保存成功: 插入%d个, 更新%d个, ", collection, result.UpsertedCount, result.ModifiedCount)
-	return result, nil
+	log.Debug("集合:%s, 批量保存成功: 插入%d个, 更新%d个, ", collection, result.Upserted, result.Modified)
+	return &mongo.BulkWriteResult{
+		MatchedCount:  result.Matched,
+		ModifiedCount: result.Modified,
+		UpsertedCount: result.Upserted,
+	}, nil
 }
 
-// 获取集合
-func (m *Mongo) getCollection(collection string) *mongo.Collection {
-	return mongoInstance.database.Collection(collection)
+// 获取当前后端的集合
+func getCollection(name string) (Collection, error) {
+	if current == nil {
+		return nil, ErrNotInitialized
+	}
+	return current.Collection(name), nil
 }
 
 // 获取泛型T对应的collection名称
@@ -194,84 +211,116 @@ func getCollectionName(t PersistData) string {
 	return typ.Name()
 }
 
-func CreateIndexes(configs conf.MongoIndexConfigs) error {
-	for _, idxConf := range configs.Indexes {
-		coll := mongoInstance.database.Collection(idxConf.Collection)
-		log.Debug("CreateIndexes: 处理集合 %s 的索引配置", idxConf.Collection)
-		// 1. 获取现有索引
-		cursor, err := coll.Indexes().List(context.Background())
-		if err != nil {
-			log.Error("CreateIndexes: 获取集合 %s 的索引列表失败: %v", idxConf.Collection, err)
-			return err
-		}
-		var existingIndexes []bson.M
-		if err = cursor.All(context.Background(), &existingIndexes); err != nil {
-			log.Error("CreateIndexes: 解析集合 %s 的索引列表失败: %v", idxConf.Collection, err)
+// Collection 获取集合
+func (m *Mongo) Collection(name string) Collection {
+	return &mongoCollection{coll: m.database.Collection(name)}
+}
+
+// Close 断开连接
+func (m *Mongo) Close(ctx context.Context) error {
+	return m.client.Disconnect(ctx)
+}
+
+// mongoCollection MongoDB集合
+type mongoCollection struct {
+	coll *mongo.Collection
+}
+
+func (c *mongoCollection) Find(ctx context.Context, q *Query, fn func(doc bson.Raw) error) error {
+	cur, err := c.coll.Find(ctx, q.filter, q.findOptions())
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		if err := fn(cur.Current); err != nil {
 			return err
 		}
-		log.Debug("CreateIndexes: 集合 %s 当前有 %d 个索引", idxConf.Collection, len(existingIndexes))
+	}
+	return cur.Err()
+}
 
-		// 2. 构建配置中索引的唯一标识（key+unique）
-		type indexSignature struct {
-			Keys   bson.D
-			Unique bool
-		}
-		configIndexMap := map[string]indexSignature{}
-		createdCount := 0
-		for _, create := range idxConf.Create {
-			keys := bson.D{}
-			for k, v := range create.Keys {
-				keys = append(keys, bson.E{Key: k, Value: v})
-			}
-			sig := indexSignature{Keys: keys, Unique: create.Unique}
-			// 用 keys 的 JSON 作为 map key
-			keyBytes, _ := bson.MarshalExtJSON(keys, false, false)
-			configIndexMap[string(keyBytes)+fmt.Sprint(create.Unique)] = sig
-
-			// 创建索引（如果不存在）
-			opts := options.Index().SetUnique(create.Unique)
-			indexName, err := coll.Indexes().CreateOne(context.Background(), mongo.IndexModel{
-				Keys:    keys,
-				Options: opts,
-			})
-			if err != nil {
-				log.Error("CreateIndexes: 在集合 %s 中创建索引失败: %v", idxConf.Collection, err)
-				continue
-			}
-			createdCount++
-			log.Debug("CreateIndexes: 在集合 %s 中成功创建索引: %s", idxConf.Collection, indexName)
-		}
-		log.Debug("CreateIndexes: 在集合 %s 中成功创建了 %d 个索引", idxConf.Collection, createdCount)
-
-		// 3. 删除配置中没有的索引（_id_ 除外）
-		deletedCount := 0
-		for _, idx := range existingIndexes {
-			name, _ := idx["name"].(string)
-			if name == "_id_" {
-				continue
-			}
-			keys, _ := idx["key"].(bson.M)
-			unique, _ := idx["unique"].(bool)
-			// 转换为 bson.D
-			keysD := bson.D{}
-			for k, v := range keys {
-				keysD = append(keysD, bson.E{Key: k, Value: v})
-			}
-			keyBytes, _ := bson.MarshalExtJSON(keysD, false, false)
-			mapKey := string(keyBytes) + fmt.Sprint(unique)
-			if _, ok := configIndexMap[mapKey]; !ok {
-				// 配置中没有，删除
-				_, err := coll.Indexes().DropOne(context.Background(), name)
-				if err != nil && err != mongo.ErrNilDocument {
-					log.Error("CreateIndexes: 删除集合 %s 的索引 %s 失败: %v", idxConf.Collection, name, err)
-					continue
-				}
-				deletedCount++
-				log.Debug("CreateIndexes: 从集合 %s 中删除了未配置的索引: %s", idxConf.Collection, name)
-			}
-		}
-		log.Debug("CreateIndexes: 从集合 %s 中删除了 %d 个未配置的索引", idxConf.Collection, deletedCount)
+func (c *mongoCollection) Count(ctx context.Context, filter interface{}) (int64, error) {
+	return c.coll.CountDocuments(ctx, filter)
+}
+
+func (c *mongoCollection) Insert(ctx context.Context, doc interface{}) error {
+	_, err := c.coll.InsertOne(ctx, doc)
+	return err
+}
+
+func (c *mongoCollection) Replace(ctx context.Context, filter, doc interface{}, upsert bool) (WriteResult, error) {
+	result, err := c.coll.ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(upsert))
+	if err != nil {
+		return WriteResult{}, err
 	}
-	log.Debug("CreateIndexes: 索引配置处理完成")
-	return nil
+	return WriteResult{Matched: result.MatchedCount, Modified: result.ModifiedCount, Upserted: result.UpsertedCount}, nil
+}
+
+func (c *mongoCollection) BulkReplace(ctx context.Context, docs []Replacement) (WriteResult, error) {
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": doc.Id}).
+			SetReplacement(doc.Doc).SetUpsert(true))
+	}
+	result, err := c.coll.BulkWrite(ctx, models)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	return WriteResult{Matched: result.MatchedCount, Modified: result.ModifiedCount, Upserted: result.UpsertedCount}, nil
+}
+
+func (c *mongoCollection) Update(ctx context.Context, filter interface{}, update bson.D, many bool) (WriteResult, error) {
+	var result *mongo.UpdateResult
+	var err error
+	if many {
+		result, err = c.coll.UpdateMany(ctx, filter, update)
+	} else {
+		result, err = c.coll.UpdateOne(ctx, filter, update)
+	}
+	if err != nil {
+		return WriteResult{}, err
+	}
+	return WriteResult{Matched: result.MatchedCount, Modified: result.ModifiedCount, Upserted: result.UpsertedCount}, nil
+}
+
+func (c *mongoCollection) Delete(ctx context.Context, filter interface{}) (int64, error) {
+	result, err := c.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (c *mongoCollection) Indexes() IndexView {
+	return mongoIndexView{view: c.coll.Indexes()}
+}
+
+type mongoIndexView struct {
+	view mongo.IndexView
+}
+
+func (v mongoIndexView) List(ctx context.Context) ([]IndexSpec, error) {
+	var specs []IndexSpec
+	cur, err := v.view.List(ctx)
+	if err == nil {
+		err = cur.All(ctx, &specs)
+	}
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 26 {
+		// 26 NamespaceNotFound：集合还不存在，视为没有索引
+		return nil, nil
+	}
+	return specs, err
+}
+
+func (v mongoIndexView) Create(ctx context.Context, idx Index) error {
+	_, err := v.view.CreateOne(ctx, indexModel(idx))
+	return err
+}
+
+func (v mongoIndexView) Drop(ctx context.Context, name string) error {
+	_, err := v.view.DropOne(ctx, name)
+	return err
 }
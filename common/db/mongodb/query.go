@@ -0,0 +1,150 @@
+package mongodb
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Query 查询条件与选项
+//
+//	mongodb.Where(bson.M{"player_id": id}).Desc("create_time").Page(1, 20)
+type Query struct {
+	filter     interface{}
+	sort       bson.D
+	projection bson.D
+	skip       int64
+	limit      int64
+}
+
+// Where 创建查询，filter为nil时匹配所有文档
+func Where(filter interface{}) *Query {
+	if filter == nil {
+		filter = bson.M{}
+	}
+	return &Query{filter: filter}
+}
+
+// Asc 按字段升序，多次调用时按调用顺序排序
+func (q *Query) Asc(field string) *Query {
+	q.sort = append(q.sort, bson.E{Key: field, Value: 1})
+	return q
+}
+
+// Desc 按字段降序
+func (q *Query) Desc(field string) *Query {
+	q.sort = append(q.sort, bson.E{Key: field, Value: -1})
+	return q
+}
+
+// Select 只返回指定字段，_id总是返回，未返回的字段为零值
+func (q *Query) Select(fields ...string) *Query {
+	for _, field := range fields {
+		q.projection = append(q.projection, bson.E{Key: field, Value: 1})
+	}
+	return q
+}
+
+// withSchema 有schema的集合投影时带上版本字段，否则部分文档会被当作旧版本执行升级
+func (q *Query) withSchema(s *collectionSchema) *Query {
+	if s == nil || len(q.projection) == 0 {
+		return q
+	}
+	for _, e := range q.projection {
+		if e.Key == SchemaVersionField {
+			return q
+		}
+	}
+	projected := *q
+	projected.projection = append(append(bson.D{}, q.projection...), bson.E{Key: SchemaVersionField, Value: 1})
+	return &projected
+}
+
+// Skip 跳过前n条
+func (q *Query) Skip(n int64) *Query {
+	q.skip = n
+	return q
+}
+
+// Limit 最多返回n条，0表示不限制
+func (q *Query) Limit(n int64) *Query {
+	q.limit = n
+	return q
+}
+
+// Page 分页，page从1开始
+func (q *Query) Page(page, size int64) *Query {
+	if page < 1 {
+		page = 1
+	}
+	q.skip = (page - 1) * size
+	q.limit = size
+	return q
+}
+
+func (q *Query) findOptions() *options.FindOptions {
+	opts := options.Find()
+	if len(q.sort) > 0 {
+		opts.SetSort(q.sort)
+	}
+	if len(q.projection) > 0 {
+		opts.SetProjection(q.projection)
+	}
+	if q.skip > 0 {
+		opts.SetSkip(q.skip)
+	}
+	if q.limit > 0 {
+		opts.SetLimit(q.limit)
+	}
+	return opts
+}
+
+// Update 部分更新，只修改指定字段，不会覆盖其他字段
+//
+//	mongodb.NewUpdate().Set("status", 1).Inc("player_info.balance", 600)
+type Update struct {
+	set   bson.D
+	inc   bson.D
+	unset bson.D
+}
+
+// NewUpdate 创建部分更新
+func NewUpdate() *Update {
+	return &Update{}
+}
+
+// Set 设置字段值（$set）
+func (u *Update) Set(field string, value interface{}) *Update {
+	u.set = append(u.set, bson.E{Key: field, Value: value})
+	return u
+}
+
+// Inc 字段增加delta，delta可以为负（$inc）
+func (u *Update) Inc(field string, delta interface{}) *Update {
+	u.inc = append(u.inc, bson.E{Key: field, Value: delta})
+	return u
+}
+
+// Unset 删除字段（$unset）
+func (u *Update) Unset(field string) *Update {
+	u.unset = append(u.unset, bson.E{Key: field, Value: ""})
+	return u
+}
+
+// IsEmpty 没有任何修改
+func (u *Update) IsEmpty() bool {
+	return len(u.set) == 0 && len(u.inc) == 0 && len(u.unset) == 0
+}
+
+func (u *Update) document() bson.D {
+	var doc bson.D
+	if len(u.set) > 0 {
+		doc = append(doc, bson.E{Key: "$set", Value: u.set})
+	}
+	if len(u.inc) > 0 {
+		doc = append(doc, bson.E{Key: "$inc", Value: u.inc})
+	}
+	if len(u.unset) > 0 {
+		doc = append(doc, bson.E{Key: "$unset", Value: u.unset})
+	}
+	return doc
+}
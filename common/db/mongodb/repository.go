@@ -0,0 +1,293 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gameserver/core/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongo "go.mongodb.org/mongo-driver/mongo"
+)
+
+// VersionField 乐观并发控制使用的版本字段
+const VersionField = "version"
+
+var (
+	// ErrNotInitialized MongoDB未初始化
+	ErrNotInitialized = errors.New("mongodb: 未初始化")
+	// ErrVersionConflict 文档版本与期望不一致，已被其他操作修改
+	ErrVersionConflict = errors.New("mongodb: 版本冲突，文档已被其他操作修改")
+	// ErrNoTransaction MongoDB不是副本集或分片集群，不支持多文档事务
+	ErrNoTransaction = errors.New("mongodb: 当前部署不支持事务")
+)
+
+// Versioned 带版本号的文档，版本号对应数据库中的version字段
+// 通过SaveVersioned/UpdateVersioned写入时，只有数据库中的版本与内存中一致才会成功，成功后版本号加一
+type Versioned interface {
+	GetVersion() int64
+	SetVersion(version int64)
+}
+
+// Repository 文档类型T对应集合的仓储
+// 所有方法都使用调用方传入的ctx，ctx没有截止时间时使用默认超时；在WithTransaction中使用事务的ctx即可参与事务
+type Repository[T PersistData] struct {
+	name string
+}
+
+// NewRepository 创建仓储，集合名为T的类型名，与FindOne/Save等函数一致
+func NewRepository[T PersistData]() *Repository[T] {
+	return &Repository[T]{name: getCollectionNameByType[T]()}
+}
+
+// NewRepositoryWithName 使用指定集合名创建仓储
+func NewRepositoryWithName[T PersistData](name string) *Repository[T] {
+	return &Repository[T]{name: name}
+}
+
+// Name 集合名
+func (r *Repository[T]) Name() string {
+	return r.name
+}
+
+func (r *Repository[T]) collection() (Collection, error) {
+	return getCollection(r.name)
+}
+
+// FindOne 查询单条，不存在时返回nil, nil
+func (r *Repository[T]) FindOne(ctx context.Context, q *Query) (*T, error) {
+	coll, err := r.collection()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	schema := getSchema(r.name)
+	one := *q.withSchema(schema)
+	one.limit = 1
+	var result *T
+	err = coll.Find(ctx, &one, func(raw bson.Raw) error {
+		result = new(T)
+		return decodeMigrated(schema, raw, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindById 按_id查询，不存在时返回nil, nil
+func (r *Repository[T]) FindById(ctx context.Context, id interface{}) (*T, error) {
+	return r.FindOne(ctx, Where(bson.M{"_id": id}))
+}
+
+// Find 查询多条
+func (r *Repository[T]) Find(ctx context.Context, q *Query) ([]T, error) {
+	coll, err := r.collection()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	schema := getSchema(r.name)
+	var results []T
+	err = coll.Find(ctx, q.withSchema(schema), func(raw bson.Raw) error {
+		var elem T
+		if err := decodeMigrated(schema, raw, &elem); err != nil {
+			return err
+		}
+		results = append(results, elem)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Count 统计匹配filter的文档数
+func (r *Repository[T]) Count(ctx context.Context, filter interface{}) (int64, error) {
+	coll, err := r.collection()
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+	return coll.Count(ctx, filter)
+}
+
+// FindPage 分页查询，同时返回不考虑分页时的总数
+func (r *Repository[T]) FindPage(ctx context.Context, q *Query) ([]T, int64, error) {
+	total, err := r.Count(ctx, q.filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	results, err := r.Find(ctx, q)
+	return results, total, err
+}
+
+// Insert 插入新文档，_id已存在时返回错误
+func (r *Repository[T]) Insert(ctx context.Context, doc T) error {
+	coll, err := r.collection()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	data, err := stampVersion(r.name, doc)
+	if err != nil {
+		return err
+	}
+	return coll.Insert(ctx, data)
+}
+
+// Save 整体替换文档，不存在时插入
+func (r *Repository[T]) Save(ctx context.Context, doc T) error {
+	coll, err := r.collection()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	data, err := stampVersion(r.name, doc)
+	if err != nil {
+		return err
+	}
+	_, err = coll.Replace(ctx, bson.M{"_id": doc.GetPersistId()}, data, true)
+	return err
+}
+
+// Update 按_id部分更新，返回是否找到文档
+func (r *Repository[T]) Update(ctx context.Context, id interface{}, u *Update) (bool, error) {
+	coll, err := r.collection()
+	if err != nil {
+		return false, err
+	}
+	if u.IsEmpty() {
+		return false, fmt.Errorf("mongodb: 集合%s的更新内容为空", r.name)
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := coll.Update(ctx, bson.M{"_id": id}, u.document(), false)
+	if err != nil {
+		return false, err
+	}
+	return result.Matched > 0, nil
+}
+
+// UpdateMany 部分更新所有匹配filter的文档，返回匹配的数量
+func (r *Repository[T]) UpdateMany(ctx context.Context, filter interface{}, u *Update) (int64, error) {
+	coll, err := r.collection()
+	if err != nil {
+		return 0, err
+	}
+	if u.IsEmpty() {
+		return 0, fmt.Errorf("mongodb: 集合%s的更新内容为空", r.name)
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := coll.Update(ctx, filter, u.document(), true)
+	if err != nil {
+		return 0, err
+	}
+	return result.Matched, nil
+}
+
+// Delete 按_id删除，返回是否删除了文档
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) (bool, error) {
+	coll, err := r.collection()
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	deleted, err := coll.Delete(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+// SaveVersioned 带版本校验地整体替换文档，doc必须实现Versioned
+// 版本为0时视为新文档插入；数据库中的版本与doc不一致时返回ErrVersionConflict，doc的版本保持不变
+func (r *Repository[T]) SaveVersioned(ctx context.Context, doc *T) error {
+	versioned, ok := any(doc).(Versioned)
+	if !ok {
+		return fmt.Errorf("mongodb: %T未实现Versioned", doc)
+	}
+	coll, err := r.collection()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	id := (*doc).GetPersistId()
+	version := versioned.GetVersion()
+	versioned.SetVersion(version + 1)
+	data, err := stampVersion(r.name, doc)
+	if err != nil {
+		versioned.SetVersion(version)
+		return err
+	}
+	result, err := coll.Replace(ctx, versionFilter(id, VersionField, version), data, version == 0)
+	if err == nil && result.Matched == 0 && result.Upserted == 0 {
+		err = ErrVersionConflict
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		// 版本为0但文档已存在且版本不为0，upsert插入时_id冲突
+		err = ErrVersionConflict
+	}
+	if err != nil {
+		versioned.SetVersion(version)
+		if errors.Is(err, ErrVersionConflict) {
+			log.Debug("SaveVersioned: 集合 %s 中ID为 %v 的文档版本 %d 已过期", r.name, id, version)
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateVersioned 带版本校验地部分更新，数据库中的版本等于version时才修改并把版本加一，否则返回ErrVersionConflict
+func (r *Repository[T]) UpdateVersioned(ctx context.Context, id interface{}, version int64, u *Update) error {
+	coll, err := r.collection()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	update := &Update{set: u.set, unset: u.unset}
+	update.inc = append(append(bson.D{}, u.inc...), bson.E{Key: VersionField, Value: int64(1)})
+	result, err := coll.Update(ctx, bson.D{{Key: "_id", Value: id}, {Key: VersionField, Value: version}}, update.document(), false)
+	if err != nil {
+		return err
+	}
+	if result.Matched == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// versionFilter 按_id和版本字段匹配文档，版本为0时同时匹配没有该字段的旧文档
+func versionFilter(id interface{}, field string, version int64) bson.D {
+	if version == 0 {
+		return bson.D{{Key: "_id", Value: id}, {Key: "$or", Value: bson.A{
+			bson.M{field: 0},
+			bson.M{field: bson.M{"$exists": false}},
+		}}}
+	}
+	return bson.D{{Key: "_id", Value: id}, {Key: field, Value: version}}
+}
@@ -0,0 +1,53 @@
+package mongodb
+
+import (
+	"context"
+
+	"gameserver/core/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongo "go.mongodb.org/mongo-driver/mongo"
+)
+
+// WithTransaction 在多文档事务中执行fn，fn中的仓储操作必须使用传入的ctx
+// fn返回错误时事务回滚；遇到临时错误时驱动会自动重试整个fn，因此fn中不要有数据库以外的副作用，
+// 内存状态应在WithTransaction成功返回后再修改
+// 事务要求MongoDB以副本集或分片集群部署，单机部署时不执行fn并返回ErrNoTransaction，
+// 可以接受非原子写入的调用方自行判断该错误后直接执行fn
+func WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if current == nil {
+		return ErrNotInitialized
+	}
+	return current.WithTransaction(ctx, fn)
+}
+
+// WithTransaction MongoDB的多文档事务
+func (m *Mongo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !m.transactions {
+		return ErrNoTransaction
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// supportsTransaction 副本集成员或mongos才支持事务
+func supportsTransaction(ctx context.Context, client *mongo.Client) bool {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		log.Error("检测MongoDB部署类型失败，按不支持事务处理: %v", err)
+		return false
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid"
+}
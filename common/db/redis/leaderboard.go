@@ -0,0 +1,136 @@
+package redis
+
+import (
+	"errors"
+
+	goredis "github.com/go-redis/redis"
+)
+
+// Leaderboard 基于有序集合的排行榜，分数高的在前，排名从1开始
+type Leaderboard struct {
+	key string
+}
+
+// RankEntry 排行榜中的一项
+type RankEntry struct {
+	Member string
+	Score  float64
+	Rank   int64
+}
+
+// NewLeaderboard 创建排行榜，name相同的排行榜共享数据
+func NewLeaderboard(name string) *Leaderboard {
+	return &Leaderboard{key: Key("rank", name)}
+}
+
+// SetScore 设置分数
+func (b *Leaderboard) SetScore(member string, score float64) error {
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	return c.ZAdd(b.key, goredis.Z{Score: score, Member: member}).Err()
+}
+
+// AddScore 增加分数，返回增加后的分数
+func (b *Leaderboard) AddScore(member string, delta float64) (float64, error) {
+	c, err := getClient()
+	if err != nil {
+		return 0, err
+	}
+	return c.ZIncrBy(b.key, delta, member).Result()
+}
+
+// Get 成员的分数和排名，不在榜上时ok为false
+func (b *Leaderboard) Get(member string) (entry RankEntry, ok bool, err error) {
+	c, err := getClient()
+	if err != nil {
+		return entry, false, err
+	}
+	pipe := c.Pipeline()
+	scoreCmd := pipe.ZScore(b.key, member)
+	rankCmd := pipe.ZRevRank(b.key, member)
+	if _, err = pipe.Exec(); err != nil && !errors.Is(err, Nil) {
+		return entry, false, err
+	}
+	score, err := scoreCmd.Result()
+	if errors.Is(err, Nil) {
+		return entry, false, nil
+	}
+	if err != nil {
+		return entry, false, err
+	}
+	rank, err := rankCmd.Result()
+	if err != nil {
+		return entry, false, err
+	}
+	return RankEntry{Member: member, Score: score, Rank: rank + 1}, true, nil
+}
+
+// Top 前n名
+func (b *Leaderboard) Top(n int64) ([]RankEntry, error) {
+	return b.Range(1, n)
+}
+
+// Range 排名在[from, to]之间的成员
+func (b *Leaderboard) Range(from, to int64) ([]RankEntry, error) {
+	c, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+	if from < 1 {
+		from = 1
+	}
+	if to < from {
+		return nil, nil
+	}
+	items, err := c.ZRevRangeWithScores(b.key, from-1, to-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]RankEntry, 0, len(items))
+	for i, item := range items {
+		entries = append(entries, RankEntry{Member: item.Member.(string), Score: item.Score, Rank: from + int64(i)})
+	}
+	return entries, nil
+}
+
+// Around 成员前后各n名，不在榜上时返回空
+func (b *Leaderboard) Around(member string, n int64) ([]RankEntry, error) {
+	entry, ok, err := b.Get(member)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return b.Range(entry.Rank-n, entry.Rank+n)
+}
+
+// Remove 从榜上移除
+func (b *Leaderboard) Remove(members ...string) error {
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	args := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		args = append(args, member)
+	}
+	return c.ZRem(b.key, args...).Err()
+}
+
+// Count 榜上的成员数
+func (b *Leaderboard) Count() (int64, error) {
+	c, err := getClient()
+	if err != nil {
+		return 0, err
+	}
+	return c.ZCard(b.key).Result()
+}
+
+// Clear 清空排行榜，用于赛季重置
+func (b *Leaderboard) Clear() error {
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	return c.Del(b.key).Err()
+}
@@ -0,0 +1,146 @@
+package redis
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	goredis "github.com/go-redis/redis"
+)
+
+var (
+	// ErrLockNotAcquired 锁被其他持有者占用
+	ErrLockNotAcquired = errors.New("redis: lock not acquired")
+	// ErrLockNotHeld 锁已过期或被其他持有者拿走
+	ErrLockNotHeld = errors.New("redis: lock not held")
+	// ErrStaleFence 令牌比已经写入过的令牌旧，持有者的锁已经失效
+	ErrStaleFence = errors.New("redis: stale fencing token")
+)
+
+// 加锁成功时递增令牌，令牌计数不过期，保证同一把锁的令牌单调递增
+var acquireScript = goredis.NewScript(`
+if redis.call('set', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('incr', KEYS[2])
+end
+return 0`)
+
+var refreshScript = goredis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('pexpire', KEYS[1], ARGV[2])
+end
+return 0`)
+
+// 值等于ARGV[1]时删除，用于释放锁和清除在线状态
+var delIfEqualScript = goredis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('del', KEYS[1])
+end
+return 0`)
+
+// 令牌不小于记录的最大值时通过并记录
+var fenceScript = goredis.NewScript(`
+local current = tonumber(redis.call('get', KEYS[1]) or '0')
+local token = tonumber(ARGV[1])
+if token < current then
+	return 0
+end
+redis.call('set', KEYS[1], token)
+return 1`)
+
+// Lock 分布式锁
+// 锁可能因为持有者停顿超过TTL而失效，写共享资源时用Token配合CheckFence拒绝旧持有者的写入
+type Lock struct {
+	name  string
+	owner string
+	token int64
+}
+
+// Acquire 尝试加锁，被占用时返回ErrLockNotAcquired
+func Acquire(name string, ttl time.Duration) (*Lock, error) {
+	c, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+	owner := uuid.NewString()
+	token, err := acquireScript.Run(c, []string{Key("lock", name), Key("lock", name, "fence")},
+		owner, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if token == 0 {
+		return nil, ErrLockNotAcquired
+	}
+	return &Lock{name: name, owner: owner, token: token}, nil
+}
+
+// AcquireWait 加锁，被占用时每隔retry重试，最多等待wait
+func AcquireWait(name string, ttl, wait, retry time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		lock, err := Acquire(name, ttl)
+		if !errors.Is(err, ErrLockNotAcquired) || time.Now().Add(retry).After(deadline) {
+			return lock, err
+		}
+		time.Sleep(retry)
+	}
+}
+
+// Name 锁的名称
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Token 加锁时得到的令牌，同一把锁后加锁的令牌更大
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+// Refresh 续期，锁已经失效时返回ErrLockNotHeld
+func (l *Lock) Refresh(ttl time.Duration) error {
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	ok, err := refreshScript.Run(c, []string{Key("lock", l.name)}, l.owner, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Release 释放锁，锁已经失效时返回ErrLockNotHeld，不会释放其他持有者的锁
+func (l *Lock) Release() error {
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	ok, err := delIfEqualScript.Run(c, []string{Key("lock", l.name)}, l.owner).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// CheckFence 写资源resource前检查令牌，比已经通过的令牌旧时返回ErrStaleFence
+// 同一个资源只能使用同一把锁的令牌
+func CheckFence(resource string, token int64) error {
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	ok, err := fenceScript.Run(c, []string{Key("fence", resource)}, token).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrStaleFence
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Presence 在线状态，记录玩家在哪个节点上
+// 节点需要在TTL内调用Refresh续期，节点宕机后玩家在TTL后自动视为离线
+type Presence struct {
+	name string
+	ttl  time.Duration
+}
+
+// NewPresence 创建在线状态
+func NewPresence(name string, ttl time.Duration) *Presence {
+	return &Presence{name: name, ttl: ttl}
+}
+
+func (p *Presence) key(playerId int64) string {
+	return Key("online", p.name, strconv.FormatInt(playerId, 10))
+}
+
+// Online 标记玩家在节点node上在线，覆盖之前的节点
+func (p *Presence) Online(playerId int64, node string) error {
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	return c.Set(p.key(playerId), node, p.ttl).Err()
+}
+
+// Refresh 续期，已经过期时返回false，需要重新调用Online
+func (p *Presence) Refresh(playerId int64) (bool, error) {
+	c, err := getClient()
+	if err != nil {
+		return false, err
+	}
+	return c.Expire(p.key(playerId), p.ttl).Result()
+}
+
+// Offline 标记玩家离线，只有玩家还在节点node上时才清除，避免顶号后新节点的状态被旧节点清掉
+func (p *Presence) Offline(playerId int64, node string) error {
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	return delIfEqualScript.Run(c, []string{p.key(playerId)}, node).Err()
+}
+
+// Lookup 玩家所在的节点，不在线时ok为false
+func (p *Presence) Lookup(playerId int64) (node string, ok bool, err error) {
+	c, err := getClient()
+	if err != nil {
+		return "", false, err
+	}
+	node, err = c.Get(p.key(playerId)).Result()
+	if errors.Is(err, Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return node, true, nil
+}
+
+// LookupMany 批量查询，结果中只包含在线的玩家
+func (p *Presence) LookupMany(playerIds []int64) (map[int64]string, error) {
+	c, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+	if len(playerIds) == 0 {
+		return map[int64]string{}, nil
+	}
+	keys := make([]string, 0, len(playerIds))
+	for _, id := range playerIds {
+		keys = append(keys, p.key(id))
+	}
+	values, err := c.MGet(keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[int64]string, len(values))
+	for i, value := range values {
+		if node, ok := value.(string); ok {
+			nodes[playerIds[i]] = node
+		}
+	}
+	return nodes, nil
+}
@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"sync"
+
+	"gameserver/core/log"
+
+	goredis "github.com/go-redis/redis"
+)
+
+// Publish 发布消息，返回收到消息的订阅者数量
+// channel会加上key前缀，只在同一个前缀的服之间互通
+func Publish(channel string, message interface{}) (int64, error) {
+	c, err := getClient()
+	if err != nil {
+		return 0, err
+	}
+	return c.Publish(Key("chan", channel), message).Result()
+}
+
+// Subscription 订阅
+type Subscription struct {
+	pubsub *goredis.PubSub
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Subscribe 订阅频道，handler在订阅自己的goroutine中按顺序调用
+// 需要修改模块内数据时，在handler中通过ChanRPC或skeleton切回模块的goroutine
+func Subscribe(handler func(channel, payload string), channels ...string) (*Subscription, error) {
+	c, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(channels))
+	names := make(map[string]string, len(channels))
+	for _, channel := range channels {
+		key := Key("chan", channel)
+		keys = append(keys, key)
+		names[key] = channel
+	}
+
+	pubsub := c.Subscribe(keys...)
+	// 等待订阅确认，之后发布的消息一定能收到
+	if _, err := pubsub.Receive(); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	s := &Subscription{pubsub: pubsub, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		for msg := range pubsub.Channel() {
+			s.dispatch(handler, names[msg.Channel], msg.Payload)
+		}
+	}()
+	return s, nil
+}
+
+// dispatch 调用handler，panic不会中断订阅
+func (s *Subscription) dispatch(handler func(channel, payload string), channel, payload string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("redis subscription handler panic, channel: %s, %v", channel, r)
+		}
+	}()
+	handler(channel, payload)
+}
+
+// Close 取消订阅，等待正在执行的handler返回
+func (s *Subscription) Close() error {
+	var err error
+	s.once.Do(func() {
+		err = s.pubsub.Close()
+		<-s.done
+	})
+	return err
+}
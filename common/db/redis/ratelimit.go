@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"time"
+
+	goredis "github.com/go-redis/redis"
+)
+
+// 第一次计数时设置窗口的过期时间，返回计数和窗口剩余毫秒数
+var rateScript = goredis.NewScript(`
+local count = redis.call('incr', KEYS[1])
+if count == 1 then
+	redis.call('pexpire', KEYS[1], ARGV[1])
+end
+return {count, redis.call('pttl', KEYS[1])}`)
+
+// RateLimiter 固定窗口计数限流，多个进程共享计数
+type RateLimiter struct {
+	name   string
+	limit  int64
+	window time.Duration
+}
+
+// NewRateLimiter 每个key在window内最多允许limit次
+func NewRateLimiter(name string, limit int64, window time.Duration) *RateLimiter {
+	return &RateLimiter{name: name, limit: limit, window: window}
+}
+
+// Allow 计数一次，超过限制时返回false和到窗口结束还需要等待的时间
+func (r *RateLimiter) Allow(key string) (bool, time.Duration, error) {
+	c, err := getClient()
+	if err != nil {
+		return false, 0, err
+	}
+	result, err := rateScript.Run(c, []string{Key("rate", r.name, key)}, r.window.Milliseconds()).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	values := result.([]interface{})
+	count, ttl := values[0].(int64), values[1].(int64)
+	if count > r.limit {
+		return false, time.Duration(ttl) * time.Millisecond, nil
+	}
+	return true, 0, nil
+}
+
+// Reset 清除key的计数
+func (r *RateLimiter) Reset(key string) error {
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	return c.Del(Key("rate", r.name, key)).Err()
+}
@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"errors"
+	"time"
+
+	"gameserver/core/log"
+
+	goredis "github.com/go-redis/redis"
+)
+
+// ErrNotInitialized 没有调用Init或UseClient
+var ErrNotInitialized = errors.New("redis: not initialized")
+
+// Nil key不存在
+var Nil = goredis.Nil
+
+var (
+	client *goredis.Client
+	prefix string // 所有key的前缀，多个服共用一个Redis时区分
+)
+
+// Init 初始化连接池，连接失败时返回错误，Redis不可用不影响不依赖它的功能
+func Init(addr, password string, db, poolSize, minIdleConns int, keyPrefix string) error {
+	c := goredis.NewClient(&goredis.Options{
+		Addr:         addr,
+		Password:     password,
+		DB:           db,
+		PoolSize:     poolSize,
+		MinIdleConns: minIdleConns,
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
+	})
+	if err := c.Ping().Err(); err != nil {
+		c.Close()
+		log.Error("init redis failed: %v", err)
+		return err
+	}
+	UseClient(c, keyPrefix)
+	log.Release("redis init addr: %s, db: %d, poolSize: %d, prefix: %s", addr, db, poolSize, keyPrefix)
+	return nil
+}
+
+// UseClient 使用已建立的连接，用于测试或与其他组件共享连接池
+func UseClient(c *goredis.Client, keyPrefix string) {
+	client = c
+	prefix = keyPrefix
+}
+
+// Client 底层连接，没有封装的命令直接使用，key需要自己加Key前缀
+func Client() *goredis.Client {
+	return client
+}
+
+// Close 关闭连接池
+func Close() error {
+	if client == nil {
+		return nil
+	}
+	err := client.Close()
+	client = nil
+	return err
+}
+
+// Key 加上前缀的key
+func Key(parts ...string) string {
+	key := prefix
+	for _, part := range parts {
+		if key != "" {
+			key += ":"
+		}
+		key += part
+	}
+	return key
+}
+
+func getClient() (*goredis.Client, error) {
+	if client == nil {
+		return nil, ErrNotInitialized
+	}
+	return client, nil
+}
@@ -3,6 +3,8 @@ package event_dispatcher
 import (
 	"gameserver/common/event_dispatcher/internal"
 	"gameserver/core/chanrpc"
+	"gameserver/core/log"
+	"time"
 )
 
 var (
@@ -11,5 +13,32 @@ var (
 )
 
 func RegisterDispatcher(dispatcher *chanrpc.Server) {
+	dispatcher.Register(internal.IdleSentinel, func(args []interface{}) {})
 	internal.Dispatchers = append(internal.Dispatchers, dispatcher)
 }
+
+// WaitIdle 等待事件分发及所有模块处理完已入队的消息（包括正在执行的），超时返回false
+// 依次向事件分发和各模块发送哨兵调用：事件分发的哨兵返回时，它转发给模块的消息都已入队，
+// 模块的哨兵返回时这些消息都已处理完
+func WaitIdle(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := internal.ChanRPC.Call0(internal.IdleSentinel); err != nil {
+			log.Error("wait event dispatcher idle: %v", err)
+			return
+		}
+		for _, dispatcher := range internal.Dispatchers {
+			if err := dispatcher.Call0(internal.IdleSentinel); err != nil {
+				log.Error("wait dispatcher idle: %v", err)
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
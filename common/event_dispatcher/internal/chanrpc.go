@@ -1,17 +1,24 @@
 package internal
 
 import (
+	"gameserver/common/broadcast"
 	"gameserver/core/chanrpc"
 	"gameserver/core/gate"
 )
 
 var Dispatchers []*chanrpc.Server
 
+// IdleSentinel 空调用，排在已入队的消息之后，返回时前面的消息都已处理完
+const IdleSentinel = "IdleSentinel"
+
 func init() {
 	skeleton.RegisterChanRPC("NewAgent", rpcNewAgent)
 	skeleton.RegisterChanRPC("CloseAgent", rpcCloseAgent)
+	skeleton.RegisterChanRPC(IdleSentinel, rpcIdleSentinel)
 }
 
+func rpcIdleSentinel(args []interface{}) {}
+
 func rpcNewAgent(args []interface{}) {
 	a := args[0].(gate.Agent)
 	for _, dispatcher := range Dispatchers {
@@ -22,6 +29,8 @@ func rpcNewAgent(args []interface{}) {
 
 func rpcCloseAgent(args []interface{}) {
 	a := args[0].(gate.Agent)
+	// 先退出所有广播频道，各模块的下线通知不会再发给已断开的连接
+	broadcast.UnsubscribeAll(a)
 	for _, dispatcher := range Dispatchers {
 		dispatcher.Go("CloseAgent", a)
 	}
@@ -0,0 +1,227 @@
+package gateway
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gameserver/core/chanrpc"
+	"gameserver/core/cluster"
+	"gameserver/core/gate"
+	"gameserver/core/log"
+	"gameserver/core/network/protobuf"
+)
+
+// Backend 后端节点，接收网关转发的消息
+type Backend struct {
+	Node         *cluster.Node
+	Processor    *protobuf.Processor
+	AgentChanRPC *chanrpc.Server // 代理连接的NewAgent、CloseAgent通知，与gate.Gate的AgentChanRPC相同
+
+	mu     sync.Mutex
+	agents map[sessionKey]*remoteAgent
+}
+
+type sessionKey struct {
+	gateway string
+	id      uint64
+}
+
+// Serve 在node上注册会话服务，网关转发的消息经processor路由到模块
+func Serve(node *cluster.Node, processor *protobuf.Processor, agentChanRPC *chanrpc.Server) *Backend {
+	b := &Backend{
+		Node:         node,
+		Processor:    processor,
+		AgentChanRPC: agentChanRPC,
+		agents:       make(map[sessionKey]*remoteAgent),
+	}
+	// 按收到的顺序处理，保证同一个会话的消息有序
+	rpc := chanrpc.NewServer(10000)
+	rpc.Register("Open", b.rpcOpen)
+	rpc.Register("Forward", b.rpcForward)
+	rpc.Register("Bind", b.rpcBind)
+	rpc.Register("Close", b.rpcClose)
+	go run(rpc)
+	node.Register(sessionService, rpc)
+	return b
+}
+
+// SessionNum 网关会话数
+func (b *Backend) SessionNum() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.agents)
+}
+
+func (b *Backend) agent(gateway string, id uint64) *remoteAgent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.agents[sessionKey{gateway, id}]
+}
+
+// rpcOpen 网关建立会话，会话已在其他后端登录时带有UserData
+func (b *Backend) rpcOpen(args []interface{}) {
+	key := sessionKey{args[0].(string), args[1].(uint64)}
+	a := &remoteAgent{backend: b, key: key, remoteAddr: sessionAddr(args[2].(string)), userData: args[3]}
+	b.mu.Lock()
+	old := b.agents[key]
+	b.agents[key] = a
+	b.mu.Unlock()
+	if old != nil {
+		b.closeAgent(old)
+	}
+	if b.AgentChanRPC != nil {
+		b.AgentChanRPC.Go("NewAgent", a)
+	}
+}
+
+func (b *Backend) rpcForward(args []interface{}) {
+	a := b.agent(args[0].(string), args[1].(uint64))
+	if a == nil {
+		log.Debug("gateway session %v/%v not found", args[0], args[1])
+		return
+	}
+	msg, err := b.Processor.Unmarshal(args[2].([]byte))
+	if err != nil {
+		log.Debug("unmarshal message error: %v", err)
+		a.Close()
+		return
+	}
+	if err := b.Processor.Route(msg, a); err != nil {
+		log.Debug("route message error: %v", err)
+		a.Close()
+	}
+}
+
+// rpcBind 会话在其他后端登录或登出
+func (b *Backend) rpcBind(args []interface{}) {
+	if a := b.agent(args[0].(string), args[1].(uint64)); a != nil {
+		a.mu.Lock()
+		a.userData = args[2]
+		a.mu.Unlock()
+	}
+}
+
+func (b *Backend) rpcClose(args []interface{}) {
+	key := sessionKey{args[0].(string), args[1].(uint64)}
+	b.mu.Lock()
+	a := b.agents[key]
+	delete(b.agents, key)
+	b.mu.Unlock()
+	if a != nil {
+		b.closeAgent(a)
+	}
+}
+
+func (b *Backend) closeAgent(a *remoteAgent) {
+	a.closed.Store(true)
+	if b.AgentChanRPC != nil {
+		if err := b.AgentChanRPC.Call0("CloseAgent", a); err != nil {
+			log.Error("chanrpc error: %v", err)
+		}
+	}
+}
+
+// sessionAddr 客户端在网关上的地址
+type sessionAddr string
+
+func (s sessionAddr) Network() string {
+	return "gateway"
+}
+
+func (s sessionAddr) String() string {
+	return string(s)
+}
+
+// remoteAgent 网关会话在后端的代理连接
+type remoteAgent struct {
+	backend    *Backend
+	key        sessionKey
+	remoteAddr net.Addr
+	mu         sync.Mutex
+	userData   interface{}
+	rtt        atomic.Int64
+	closed     atomic.Bool
+}
+
+var _ gate.Agent = (*remoteAgent)(nil)
+
+func (a *remoteAgent) call(id string, args ...interface{}) {
+	if a.closed.Load() {
+		return
+	}
+	args = append([]interface{}{a.key.id}, args...)
+	if err := a.backend.Node.GoNode(a.key.gateway, gatewayService, id, args...); err != nil {
+		log.Debug("gateway %v session %d %v: %v", a.key.gateway, a.key.id, id, err)
+	}
+}
+
+func (a *remoteAgent) WriteMsg(msg interface{}) {
+	data, err := a.backend.Processor.Marshal(msg)
+	if err != nil {
+		log.Error("marshal message %T error: %v", msg, err)
+		return
+	}
+	a.WriteData(data...)
+}
+
+func (a *remoteAgent) WriteData(data ...[]byte) {
+	a.call("Push", bytes.Join(data, nil))
+}
+
+func (a *remoteAgent) LocalAddr() net.Addr {
+	return sessionAddr(a.key.gateway)
+}
+
+func (a *remoteAgent) RemoteAddr() net.Addr {
+	return a.remoteAddr
+}
+
+func (a *remoteAgent) Close() {
+	a.call("Kick", false)
+}
+
+func (a *remoteAgent) Destroy() {
+	a.call("Kick", true)
+}
+
+func (a *remoteAgent) UserData() interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.userData
+}
+
+// SetUserData 通知网关，网关据此放行需要登录的消息，并同步给该会话的其他后端
+func (a *remoteAgent) SetUserData(data interface{}) {
+	a.mu.Lock()
+	a.userData = data
+	a.mu.Unlock()
+	a.call("Bind", a.backend.Node.Name, data)
+}
+
+func (a *remoteAgent) RTT() time.Duration {
+	return time.Duration(a.rtt.Load())
+}
+
+// ObserveRTT 按TCP的SRTT平滑，新样本占1/8，心跳经过网关转发，包含网关到后端的延迟
+func (a *remoteAgent) ObserveRTT(rtt time.Duration) {
+	if rtt <= 0 {
+		return
+	}
+	for {
+		old := a.rtt.Load()
+		smoothed := int64(rtt)
+		if old > 0 {
+			smoothed = old + (int64(rtt)-old)/8
+		}
+		if a.rtt.CompareAndSwap(old, smoothed) {
+			return
+		}
+	}
+}
+
+func (a *remoteAgent) SetIdleTimeout(d time.Duration) {
+	a.call("IdleTimeout", int64(d))
+}
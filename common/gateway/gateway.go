@@ -0,0 +1,286 @@
+// Package gateway 网关模式
+//
+// 网关节点只保持客户端连接，收到的消息不解析，按消息所属的模块选择后端节点原样转发；
+// 后端节点为每个网关会话创建代理连接(gate.Agent)，消息经本地的Processor和中间件路由到模块，
+// 模块写给代理连接的消息按会话id推回网关，再写给客户端
+//
+// 消息所属的模块按proto文件所在的目录确定，与handler_generator的约定一致，
+// 模块名即注册表中的服务名。一个会话第一次转发时固定到一个后端节点，之后该节点提供的模块都转发到这个节点，
+// 其他模块转发到提供它的节点。未登录时网关不知道账号，登录消息按会话id分散到提供登录模块的节点，
+// 登录成功的节点拥有该玩家，后端Bind后会话固定到这个节点，游戏消息都转发给它；
+// 同一个玩家在其他节点登录时，由新的节点通知原来的节点断开并等它下线存盘（顶号），同一时刻只在一个节点上。
+// 后端在代理连接上设置UserData时把它发给网关，网关在其他后端上建立会话时
+// 带上UserData，已建立的会话同步更新，各后端的代理连接看到同一个登录身份。未登录的会话只能发送匿名消息
+//
+// UserData经集群用gob编码，自定义类型需要调用gob.Register注册
+package gateway
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gameserver/core/chanrpc"
+	"gameserver/core/cluster"
+	"gameserver/core/gate"
+	"gameserver/core/log"
+	"gameserver/core/network/protobuf"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	gatewayService = "gateway" // 网关节点提供，后端推送消息和控制会话
+	sessionService = "session" // 后端节点提供，网关转发消息和会话的建立、关闭
+)
+
+var (
+	ErrUnknownMessage = errors.New("gateway: unknown message")
+	ErrNoBackend      = errors.New("gateway: no backend available")
+)
+
+// Gateway 网关节点
+type Gateway struct {
+	Node         *cluster.Node
+	LittleEndian bool
+	Anonymous    func(id uint32) bool // 未登录的会话可以发送的消息
+	AgentChanRPC *chanrpc.Server      // 设置为gate.Gate的AgentChanRPC
+
+	routes   map[uint32]string // 消息id -> 模块
+	mu       sync.Mutex
+	sessions map[uint64]*session
+	agents   map[gate.Agent]*session
+	seq      atomic.Uint64
+}
+
+type session struct {
+	mu     sync.Mutex
+	id     uint64
+	agent  gate.Agent
+	node   string          // 固定的后端节点
+	user   interface{}     // 后端设置的UserData，为nil时未登录
+	opened map[string]bool // 已经建立会话的后端节点
+}
+
+// New 创建网关，按processor中注册的消息建立路由表，并在node上注册网关服务
+func New(node *cluster.Node, processor *protobuf.Processor, littleEndian bool, anonymous func(id uint32) bool) *Gateway {
+	g := &Gateway{
+		Node:         node,
+		LittleEndian: littleEndian,
+		Anonymous:    anonymous,
+		routes:       make(map[uint32]string),
+		sessions:     make(map[uint64]*session),
+		agents:       make(map[gate.Agent]*session),
+	}
+	// 会话id在网关内唯一，重启后从新的起点开始，避免与后端上未清理的旧会话冲突
+	g.seq.Store(uint64(time.Now().UnixNano()))
+	processor.Range(func(id uint16, t reflect.Type) {
+		m := reflect.New(t.Elem()).Interface().(proto.Message)
+		g.routes[protobuf.GetId(m)] = path.Dir(m.ProtoReflect().Descriptor().ParentFile().Path())
+	})
+
+	g.AgentChanRPC = chanrpc.NewServer(1000)
+	g.AgentChanRPC.Register("NewAgent", g.rpcNewAgent)
+	g.AgentChanRPC.Register("CloseAgent", g.rpcCloseAgent)
+	go run(g.AgentChanRPC)
+
+	rpc := chanrpc.NewServer(10000)
+	rpc.Register("Push", g.rpcPush)
+	rpc.Register("Kick", g.rpcKick)
+	rpc.Register("Bind", g.rpcBind)
+	rpc.Register("IdleTimeout", g.rpcIdleTimeout)
+	go run(rpc)
+	node.Register(gatewayService, rpc)
+	return g
+}
+
+func run(s *chanrpc.Server) {
+	for ci := range s.ChanCall {
+		s.Exec(ci)
+	}
+}
+
+// Route 消息所属的模块
+func (g *Gateway) Route(id uint32) (string, bool) {
+	module, ok := g.routes[id]
+	return module, ok
+}
+
+// SessionNum 会话数
+func (g *Gateway) SessionNum() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.sessions)
+}
+
+// Forward 设置为gate.Gate的Forward，在连接的读goroutine中执行，同一个会话的消息按顺序转发
+func (g *Gateway) Forward(a gate.Agent, data []byte) error {
+	if len(data) < 4 {
+		return errors.New("protobuf data too short")
+	}
+	var id uint32
+	if g.LittleEndian {
+		id = binary.LittleEndian.Uint32(data)
+	} else {
+		id = binary.BigEndian.Uint32(data)
+	}
+	module, ok := g.routes[id]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownMessage, id)
+	}
+
+	s := g.open(a)
+	s.mu.Lock()
+	if s.user == nil && (g.Anonymous == nil || !g.Anonymous(id)) {
+		s.mu.Unlock()
+		log.Debug("gateway session %d not logged in, drop message %v", s.id, id)
+		return nil
+	}
+	node, err := g.pick(s, module)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	open := !s.opened[node]
+	s.opened[node] = true
+	user := s.user
+	s.mu.Unlock()
+
+	if open {
+		if err := g.Node.GoNode(node, sessionService, "Open", g.Node.Name, s.id, a.RemoteAddr().String(), user); err != nil {
+			return err
+		}
+	}
+	return g.Node.GoNode(node, sessionService, "Forward", g.Node.Name, s.id, data)
+}
+
+// pick 选择模块所在的后端节点，固定的节点提供该模块时总是转发到该节点，否则按会话id在已连接的节点中选择
+// 第一次转发时固定节点，登录后固定到拥有该玩家的节点，固定的节点断开后转发失败，由客户端重连重新登录
+func (g *Gateway) pick(s *session, module string) (string, error) {
+	providers := g.Node.Registry.Providers(module)
+	for _, name := range providers {
+		if name == s.node {
+			return name, nil
+		}
+	}
+
+	links := make(map[string]bool)
+	for _, name := range g.Node.Links() {
+		links[name] = true
+	}
+	var live []string
+	for _, name := range providers {
+		if links[name] {
+			live = append(live, name)
+		}
+	}
+	if len(live) == 0 {
+		return "", fmt.Errorf("%w: %v", ErrNoBackend, module)
+	}
+	node := live[s.id%uint64(len(live))]
+	if s.node == "" {
+		s.node = node
+	}
+	return node, nil
+}
+
+func (g *Gateway) session(id uint64) *session {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.sessions[id]
+}
+
+// open 连接对应的会话，不存在时创建
+// NewAgent是异步通知的，连接的第一条消息可能先到，两处都可能创建
+func (g *Gateway) open(a gate.Agent) *session {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if s, ok := g.agents[a]; ok {
+		return s
+	}
+	s := &session{id: g.seq.Add(1), agent: a, opened: make(map[string]bool)}
+	g.sessions[s.id] = s
+	g.agents[a] = s
+	return s
+}
+
+func (g *Gateway) rpcNewAgent(args []interface{}) {
+	g.open(args[0].(gate.Agent))
+}
+
+// rpcCloseAgent 客户端断开时关闭所有后端上的会话
+func (g *Gateway) rpcCloseAgent(args []interface{}) {
+	a := args[0].(gate.Agent)
+	g.mu.Lock()
+	s := g.agents[a]
+	delete(g.agents, a)
+	if s != nil {
+		delete(g.sessions, s.id)
+	}
+	g.mu.Unlock()
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for node := range s.opened {
+		if err := g.Node.GoNode(node, sessionService, "Close", g.Node.Name, s.id); err != nil {
+			log.Debug("gateway close session %d on %v: %v", s.id, node, err)
+		}
+	}
+}
+
+// rpcPush 后端推送给客户端的消息
+func (g *Gateway) rpcPush(args []interface{}) {
+	if s := g.session(args[0].(uint64)); s != nil {
+		s.agent.WriteData(args[1].([]byte))
+	}
+}
+
+// rpcKick 后端关闭客户端连接
+func (g *Gateway) rpcKick(args []interface{}) {
+	s := g.session(args[0].(uint64))
+	if s == nil {
+		return
+	}
+	if args[1].(bool) {
+		s.agent.Destroy()
+	} else {
+		s.agent.Close()
+	}
+}
+
+// rpcBind 后端登录或登出，登录后会话固定到该后端，其他已建立会话的后端同步UserData
+func (g *Gateway) rpcBind(args []interface{}) {
+	s := g.session(args[0].(uint64))
+	if s == nil {
+		return
+	}
+	node, user := args[1].(string), args[2]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = user
+	if user != nil {
+		s.node = node
+	}
+	for other := range s.opened {
+		if other == node {
+			continue
+		}
+		if err := g.Node.GoNode(other, sessionService, "Bind", g.Node.Name, s.id, user); err != nil {
+			log.Debug("gateway bind session %d on %v: %v", s.id, other, err)
+		}
+	}
+}
+
+func (g *Gateway) rpcIdleTimeout(args []interface{}) {
+	if s := g.session(args[0].(uint64)); s != nil {
+		s.agent.SetIdleTimeout(time.Duration(args[1].(int64)))
+	}
+}
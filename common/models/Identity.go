@@ -0,0 +1,113 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gameserver/common/db/mongodb"
+	"gameserver/common/msg/message"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Identity 绑定到用户上的登录身份
+// 用户首次登录使用的身份记录在User.OpenId中，之后绑定的身份各有一条Identity
+type Identity struct {
+	Id         string            `bson:"_id"` // AccountId(ServerId, Key)，与用这个身份首次登录时的账号id相同
+	AccountId  string            `bson:"AccountId"`
+	PlayerId   int64             `bson:"PlayerId"`
+	ServerId   int32             `bson:"ServerId"`
+	Type       message.LoginType `bson:"Type"`
+	Key        string            `bson:"Key"` // IdentityKey
+	CreateTime int64             `bson:"CreateTime"`
+}
+
+func (i Identity) GetPersistId() interface{} {
+	return i.Id
+}
+
+// Indexes 按用户查询绑定的身份
+func (i Identity) Indexes() []mongodb.Index {
+	return []mongodb.Index{
+		{Keys: mongodb.Keys("AccountId")},
+	}
+}
+
+func init() {
+	mongodb.RegisterIndexes[Identity]()
+}
+
+// IdentityKey 登录身份在服内的唯一标识
+// 抖音、微信直接使用openid，与早期的账号保持兼容；游客和账号密码加上前缀，不会与openid冲突
+func IdentityKey(loginType message.LoginType, openId string) string {
+	switch loginType {
+	case message.LoginType_Guest:
+		return "guest:" + openId
+	case message.LoginType_Account:
+		return "account:" + openId
+	}
+	return openId
+}
+
+// AccountId 用户id，由服务器id和首次登录的身份组成
+func AccountId(serverId int32, identityKey string) string {
+	return fmt.Sprintf("%d_%s", serverId, identityKey)
+}
+
+// FindUserByIdentity 按登录身份查找用户，先查绑定的身份，再查首次登录的身份，都没有时返回nil
+func FindUserByIdentity(ctx context.Context, serverId int32, loginType message.LoginType, openId string) (*User, error) {
+	key := IdentityKey(loginType, openId)
+	identity, err := mongodb.NewRepository[Identity]().FindById(ctx, AccountId(serverId, key))
+	if err != nil {
+		return nil, err
+	}
+	users := mongodb.NewRepository[User]()
+	if identity != nil {
+		return users.FindById(ctx, identity.AccountId)
+	}
+	return users.FindOne(ctx, mongodb.Where(bson.M{"OpenId": key, "ServerId": serverId}))
+}
+
+// BindIdentity 把登录身份绑定到用户，之后用这个身份登录进入同一个角色
+// 身份已被任意用户使用，或用户已经有同类型的身份时返回Result_Duplicate
+func BindIdentity(ctx context.Context, user User, loginType message.LoginType, openId string) (message.Result, error) {
+	if user.Platform == loginType {
+		return message.Result_Duplicate, nil
+	}
+	identities := mongodb.NewRepository[Identity]()
+	n, err := identities.Count(ctx, bson.M{"AccountId": user.AccountId, "Type": loginType})
+	if err != nil {
+		return message.Result_Fail, err
+	}
+	if n > 0 {
+		return message.Result_Duplicate, nil
+	}
+
+	existing, err := FindUserByIdentity(ctx, user.ServerId, loginType, openId)
+	if err != nil {
+		return message.Result_Fail, err
+	}
+	if existing != nil {
+		return message.Result_Duplicate, nil
+	}
+
+	key := IdentityKey(loginType, openId)
+	err = identities.Insert(ctx, Identity{
+		Id:         AccountId(user.ServerId, key),
+		AccountId:  user.AccountId,
+		PlayerId:   user.PlayerId,
+		ServerId:   user.ServerId,
+		Type:       loginType,
+		Key:        key,
+		CreateTime: time.Now().Unix(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return message.Result_Duplicate, nil
+	}
+	if err != nil {
+		return message.Result_Fail, err
+	}
+	return message.Result_Success, nil
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+
+	"gameserver/common/db/mongodb"
+)
+
+// SanctionType 处罚类型
+type SanctionType int32
+
+const (
+	SanctionBan  SanctionType = 1 // 封号，不能登录
+	SanctionMute SanctionType = 2 // 禁言，不能发送聊天和操作类消息
+)
+
+func (t SanctionType) String() string {
+	switch t {
+	case SanctionBan:
+		return "ban"
+	case SanctionMute:
+		return "mute"
+	}
+	return fmt.Sprintf("SanctionType(%d)", int32(t))
+}
+
+// Sanction 玩家的处罚记录，同一个玩家每种处罚只有一条，重复处罚时覆盖
+type Sanction struct {
+	Id         string       `bson:"_id"` // SanctionId(Type, PlayerId)
+	PlayerId   int64        `bson:"PlayerId"`
+	Type       SanctionType `bson:"Type"`
+	Reason     string       `bson:"Reason"`
+	Operator   string       `bson:"Operator"`
+	CreateTime int64        `bson:"CreateTime"`
+	ExpireTime int64        `bson:"ExpireTime"` // unix秒，0表示永久
+}
+
+func (s Sanction) GetPersistId() interface{} {
+	return s.Id
+}
+
+// Indexes 客服按玩家查询处罚
+func (s Sanction) Indexes() []mongodb.Index {
+	return []mongodb.Index{
+		{Keys: mongodb.Keys("PlayerId")},
+	}
+}
+
+// Active 处罚在now时是否生效
+func (s Sanction) Active(now int64) bool {
+	return s.ExpireTime == 0 || s.ExpireTime > now
+}
+
+// SanctionId 处罚记录的id
+func SanctionId(t SanctionType, playerId int64) string {
+	return fmt.Sprintf("%s_%d", t, playerId)
+}
+
+// Whitelist 维护模式下允许登录的玩家
+type Whitelist struct {
+	PlayerId   int64  `bson:"_id"`
+	Operator   string `bson:"Operator"`
+	Remark     string `bson:"Remark"`
+	CreateTime int64  `bson:"CreateTime"`
+}
+
+func (w Whitelist) GetPersistId() interface{} {
+	return w.PlayerId
+}
+
+func init() {
+	mongodb.RegisterIndexes[Sanction]()
+}
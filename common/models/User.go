@@ -1,6 +1,10 @@
 package models
 
-import "gameserver/common/msg/message"
+import (
+	"encoding/gob"
+	"gameserver/common/db/mongodb"
+	"gameserver/common/msg/message"
+)
 
 type Platform int32
 
@@ -17,3 +21,19 @@ type User struct {
 func (u User) GetPersistId() interface{} {
 	return u.AccountId
 }
+
+// Indexes 按openId登录查询，playerId全局唯一
+func (u User) Indexes() []mongodb.Index {
+	return []mongodb.Index{
+		{Keys: mongodb.Keys("ServerId", "OpenId"), Unique: true},
+		{Keys: mongodb.Keys("PlayerId"), Unique: true},
+	}
+}
+
+func init() {
+	mongodb.RegisterIndexes[User]()
+	// 版本1：补齐默认值；OpenId/ServerId是登录查询条件，改名时需要先用migrate命令批量升级
+	mongodb.RegisterMigration[User](1, "补齐默认值", nil)
+	// 网关模式下作为连接的UserData在节点间传递
+	gob.Register(User{})
+}
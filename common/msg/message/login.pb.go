@@ -26,9 +26,11 @@ const (
 type LoginType int32
 
 const (
-	LoginType_None   LoginType = 0
-	LoginType_DouYin LoginType = 1
-	LoginType_WeChat LoginType = 2
+	LoginType_None    LoginType = 0
+	LoginType_DouYin  LoginType = 1
+	LoginType_WeChat  LoginType = 2
+	LoginType_Guest   LoginType = 3 // 游客，用设备id登录
+	LoginType_Account LoginType = 4 // 用户名密码
 )
 
 // Enum value maps for LoginType.
@@ -37,11 +39,15 @@ var (
 		0: "None",
 		1: "DouYin",
 		2: "WeChat",
+		3: "Guest",
+		4: "Account",
 	}
 	LoginType_value = map[string]int32{
-		"None":   0,
-		"DouYin": 1,
-		"WeChat": 2,
+		"None":    0,
+		"DouYin":  1,
+		"WeChat":  2,
+		"Guest":   3,
+		"Account": 4,
 	}
 )
 
@@ -74,8 +80,12 @@ func (LoginType) EnumDescriptor() ([]byte, []int) {
 
 type S2C_Login struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	LoginResult   int32                  `protobuf:"varint,1,opt,name=login_result,json=loginResult,proto3" json:"login_result,omitempty"` // 为0登录成功，其他都登录失败
+	LoginResult   int32                  `protobuf:"varint,1,opt,name=login_result,json=loginResult,proto3" json:"login_result,omitempty"` // 1登录成功，-1登录失败，-2封号，-3维护中
 	PlayerInfo    *PlayerInfo            `protobuf:"bytes,2,opt,name=playerInfo,proto3" json:"playerInfo,omitempty"`
+	SessionToken  string                 `protobuf:"bytes,3,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`     // 断线重连时用C2S_ResumeSession恢复登录
+	SessionExpire int64                  `protobuf:"varint,4,opt,name=session_expire,json=sessionExpire,proto3" json:"session_expire,omitempty"` // 令牌过期时间，unix秒
+	Reason        string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`                                     // 封号原因
+	BanExpire     int64                  `protobuf:"varint,6,opt,name=ban_expire,json=banExpire,proto3" json:"ban_expire,omitempty"`             // 封号结束时间，unix秒，0表示永久
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -124,12 +134,44 @@ func (x *S2C_Login) GetPlayerInfo() *PlayerInfo {
 	return nil
 }
 
+func (x *S2C_Login) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
+func (x *S2C_Login) GetSessionExpire() int64 {
+	if x != nil {
+		return x.SessionExpire
+	}
+	return 0
+}
+
+func (x *S2C_Login) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *S2C_Login) GetBanExpire() int64 {
+	if x != nil {
+		return x.BanExpire
+	}
+	return 0
+}
+
 // client -> server -> toDouyinCheck
 type C2S_Login struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	LoginType     LoginType              `protobuf:"varint,1,opt,name=login_type,json=loginType,proto3,enum=LoginType" json:"login_type,omitempty"`
 	ServerId      int32                  `protobuf:"varint,2,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
-	Code          string                 `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	Code          string                 `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`                         // 抖音、微信的登录code
+	DeviceId      string                 `protobuf:"bytes,4,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"` // 游客登录的设备id
+	Username      string                 `protobuf:"bytes,5,opt,name=username,proto3" json:"username,omitempty"`                 // 账号登录的用户名
+	Password      string                 `protobuf:"bytes,6,opt,name=password,proto3" json:"password,omitempty"`                 // 账号登录的密码
+	Register      bool                   `protobuf:"varint,7,opt,name=register,proto3" json:"register,omitempty"`                // 账号登录时用户名不存在则注册
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -185,8 +227,40 @@ func (x *C2S_Login) GetCode() string {
 	return ""
 }
 
+func (x *C2S_Login) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *C2S_Login) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *C2S_Login) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *C2S_Login) GetRegister() bool {
+	if x != nil {
+		return x.Register
+	}
+	return false
+}
+
+// 心跳，服务器用往返时间测量RTT，超过空闲超时没有收到任何消息时断开连接
 type C2S_Heart struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientTime    int64                  `protobuf:"varint,1,opt,name=client_time,json=clientTime,proto3" json:"client_time,omitempty"` // 客户端时间，毫秒，S2C_Heart中原样返回
+	ServerTime    int64                  `protobuf:"varint,2,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"` // 上一个S2C_Heart中的server_time，没有时为0
+	HoldMs        int32                  `protobuf:"varint,3,opt,name=hold_ms,json=holdMs,proto3" json:"hold_ms,omitempty"`             // 收到上一个S2C_Heart到发送本消息经过的毫秒数
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -221,8 +295,32 @@ func (*C2S_Heart) Descriptor() ([]byte, []int) {
 	return file_login_login_proto_rawDescGZIP(), []int{2}
 }
 
+func (x *C2S_Heart) GetClientTime() int64 {
+	if x != nil {
+		return x.ClientTime
+	}
+	return 0
+}
+
+func (x *C2S_Heart) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
+func (x *C2S_Heart) GetHoldMs() int32 {
+	if x != nil {
+		return x.HoldMs
+	}
+	return 0
+}
+
 type S2C_Heart struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientTime    int64                  `protobuf:"varint,1,opt,name=client_time,json=clientTime,proto3" json:"client_time,omitempty"` // C2S_Heart中的client_time
+	ServerTime    int64                  `protobuf:"varint,2,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"` // 服务器时间，毫秒，下一个C2S_Heart中带回
+	RttMs         int32                  `protobuf:"varint,3,opt,name=rtt_ms,json=rttMs,proto3" json:"rtt_ms,omitempty"`                // 服务器测得的平滑RTT，还没有测量时为0
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -257,30 +355,610 @@ func (*S2C_Heart) Descriptor() ([]byte, []int) {
 	return file_login_login_proto_rawDescGZIP(), []int{3}
 }
 
+func (x *S2C_Heart) GetClientTime() int64 {
+	if x != nil {
+		return x.ClientTime
+	}
+	return 0
+}
+
+func (x *S2C_Heart) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
+func (x *S2C_Heart) GetRttMs() int32 {
+	if x != nil {
+		return x.RttMs
+	}
+	return 0
+}
+
+// 服务器即将关闭（停服/滚动重启），客户端收到后按提示重连
+type S2C_ServerClosing struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Reason           string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	ReconnectDelayMs int32                  `protobuf:"varint,2,opt,name=reconnect_delay_ms,json=reconnectDelayMs,proto3" json:"reconnect_delay_ms,omitempty"` // 建议的重连等待时间
+	ReconnectAddr    string                 `protobuf:"bytes,3,opt,name=reconnect_addr,json=reconnectAddr,proto3" json:"reconnect_addr,omitempty"`             // 为空表示重连原地址
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *S2C_ServerClosing) Reset() {
+	*x = S2C_ServerClosing{}
+	mi := &file_login_login_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *S2C_ServerClosing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*S2C_ServerClosing) ProtoMessage() {}
+
+func (x *S2C_ServerClosing) ProtoReflect() protoreflect.Message {
+	mi := &file_login_login_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use S2C_ServerClosing.ProtoReflect.Descriptor instead.
+func (*S2C_ServerClosing) Descriptor() ([]byte, []int) {
+	return file_login_login_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *S2C_ServerClosing) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *S2C_ServerClosing) GetReconnectDelayMs() int32 {
+	if x != nil {
+		return x.ReconnectDelayMs
+	}
+	return 0
+}
+
+func (x *S2C_ServerClosing) GetReconnectAddr() string {
+	if x != nil {
+		return x.ReconnectAddr
+	}
+	return ""
+}
+
+// 系统公告/跑马灯，服务器主动推送
+type S2C_Announcement struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Content       string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	Time          int64                  `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"` // 发布时间，unix秒
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *S2C_Announcement) Reset() {
+	*x = S2C_Announcement{}
+	mi := &file_login_login_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *S2C_Announcement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*S2C_Announcement) ProtoMessage() {}
+
+func (x *S2C_Announcement) ProtoReflect() protoreflect.Message {
+	mi := &file_login_login_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use S2C_Announcement.ProtoReflect.Descriptor instead.
+func (*S2C_Announcement) Descriptor() ([]byte, []int) {
+	return file_login_login_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *S2C_Announcement) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *S2C_Announcement) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+// 把另一个登录身份绑定到当前账号，绑定后用任意一个身份登录都进入同一个角色
+// 字段与C2S_Login相同，绑定账号密码时会注册该用户名
+type C2S_BindAccount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LoginType     LoginType              `protobuf:"varint,1,opt,name=login_type,json=loginType,proto3,enum=LoginType" json:"login_type,omitempty"`
+	Code          string                 `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	DeviceId      string                 `protobuf:"bytes,3,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Username      string                 `protobuf:"bytes,4,opt,name=username,proto3" json:"username,omitempty"`
+	Password      string                 `protobuf:"bytes,5,opt,name=password,proto3" json:"password,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *C2S_BindAccount) Reset() {
+	*x = C2S_BindAccount{}
+	mi := &file_login_login_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *C2S_BindAccount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*C2S_BindAccount) ProtoMessage() {}
+
+func (x *C2S_BindAccount) ProtoReflect() protoreflect.Message {
+	mi := &file_login_login_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use C2S_BindAccount.ProtoReflect.Descriptor instead.
+func (*C2S_BindAccount) Descriptor() ([]byte, []int) {
+	return file_login_login_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *C2S_BindAccount) GetLoginType() LoginType {
+	if x != nil {
+		return x.LoginType
+	}
+	return LoginType_None
+}
+
+func (x *C2S_BindAccount) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *C2S_BindAccount) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *C2S_BindAccount) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *C2S_BindAccount) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type S2C_BindAccount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        Result                 `protobuf:"varint,1,opt,name=result,proto3,enum=Result" json:"result,omitempty"` // Duplicate表示该身份已被其他账号使用或当前账号已绑定同类型身份
+	LoginType     LoginType              `protobuf:"varint,2,opt,name=login_type,json=loginType,proto3,enum=LoginType" json:"login_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *S2C_BindAccount) Reset() {
+	*x = S2C_BindAccount{}
+	mi := &file_login_login_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *S2C_BindAccount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*S2C_BindAccount) ProtoMessage() {}
+
+func (x *S2C_BindAccount) ProtoReflect() protoreflect.Message {
+	mi := &file_login_login_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use S2C_BindAccount.ProtoReflect.Descriptor instead.
+func (*S2C_BindAccount) Descriptor() ([]byte, []int) {
+	return file_login_login_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *S2C_BindAccount) GetResult() Result {
+	if x != nil {
+		return x.Result
+	}
+	return Result_Success
+}
+
+func (x *S2C_BindAccount) GetLoginType() LoginType {
+	if x != nil {
+		return x.LoginType
+	}
+	return LoginType_None
+}
+
+// 凭S2C_Login中的令牌恢复登录，不再请求登录平台
+// 成功时与登录相同返回S2C_Login，并下发新的令牌
+type C2S_ResumeSession struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *C2S_ResumeSession) Reset() {
+	*x = C2S_ResumeSession{}
+	mi := &file_login_login_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *C2S_ResumeSession) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*C2S_ResumeSession) ProtoMessage() {}
+
+func (x *C2S_ResumeSession) ProtoReflect() protoreflect.Message {
+	mi := &file_login_login_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use C2S_ResumeSession.ProtoReflect.Descriptor instead.
+func (*C2S_ResumeSession) Descriptor() ([]byte, []int) {
+	return file_login_login_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *C2S_ResumeSession) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+// 恢复登录失败，客户端应重新用C2S_Login登录
+type S2C_ResumeSession struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        Result                 `protobuf:"varint,1,opt,name=result,proto3,enum=Result" json:"result,omitempty"` // Illegal表示令牌无效或已过期
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *S2C_ResumeSession) Reset() {
+	*x = S2C_ResumeSession{}
+	mi := &file_login_login_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *S2C_ResumeSession) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*S2C_ResumeSession) ProtoMessage() {}
+
+func (x *S2C_ResumeSession) ProtoReflect() protoreflect.Message {
+	mi := &file_login_login_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use S2C_ResumeSession.ProtoReflect.Descriptor instead.
+func (*S2C_ResumeSession) Descriptor() ([]byte, []int) {
+	return file_login_login_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *S2C_ResumeSession) GetResult() Result {
+	if x != nil {
+		return x.Result
+	}
+	return Result_Success
+}
+
+// 服务器主动踢下线，收到后连接会被关闭
+type S2C_Kick struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	BanExpire     int64                  `protobuf:"varint,2,opt,name=ban_expire,json=banExpire,proto3" json:"ban_expire,omitempty"` // 因封号被踢时为封号结束时间，0表示永久
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *S2C_Kick) Reset() {
+	*x = S2C_Kick{}
+	mi := &file_login_login_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *S2C_Kick) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*S2C_Kick) ProtoMessage() {}
+
+func (x *S2C_Kick) ProtoReflect() protoreflect.Message {
+	mi := &file_login_login_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use S2C_Kick.ProtoReflect.Descriptor instead.
+func (*S2C_Kick) Descriptor() ([]byte, []int) {
+	return file_login_login_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *S2C_Kick) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *S2C_Kick) GetBanExpire() int64 {
+	if x != nil {
+		return x.BanExpire
+	}
+	return 0
+}
+
+// 禁言期间发送的受限消息被丢弃
+type S2C_Muted struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	Expire        int64                  `protobuf:"varint,2,opt,name=expire,proto3" json:"expire,omitempty"` // 禁言结束时间，unix秒，0表示永久
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *S2C_Muted) Reset() {
+	*x = S2C_Muted{}
+	mi := &file_login_login_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *S2C_Muted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*S2C_Muted) ProtoMessage() {}
+
+func (x *S2C_Muted) ProtoReflect() protoreflect.Message {
+	mi := &file_login_login_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use S2C_Muted.ProtoReflect.Descriptor instead.
+func (*S2C_Muted) Descriptor() ([]byte, []int) {
+	return file_login_login_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *S2C_Muted) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *S2C_Muted) GetExpire() int64 {
+	if x != nil {
+		return x.Expire
+	}
+	return 0
+}
+
+// 服务器满员时登录进入排队，定时推送排队位置，排到后直接进入登录流程返回S2C_Login
+type S2C_LoginQueue struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Position      int32                  `protobuf:"varint,1,opt,name=position,proto3" json:"position,omitempty"`                       // 从1开始
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`                             // 排队总人数
+	WaitSecond    int32                  `protobuf:"varint,3,opt,name=wait_second,json=waitSecond,proto3" json:"wait_second,omitempty"` // 预计等待时间，0表示无法估算
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *S2C_LoginQueue) Reset() {
+	*x = S2C_LoginQueue{}
+	mi := &file_login_login_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *S2C_LoginQueue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*S2C_LoginQueue) ProtoMessage() {}
+
+func (x *S2C_LoginQueue) ProtoReflect() protoreflect.Message {
+	mi := &file_login_login_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use S2C_LoginQueue.ProtoReflect.Descriptor instead.
+func (*S2C_LoginQueue) Descriptor() ([]byte, []int) {
+	return file_login_login_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *S2C_LoginQueue) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *S2C_LoginQueue) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *S2C_LoginQueue) GetWaitSecond() int32 {
+	if x != nil {
+		return x.WaitSecond
+	}
+	return 0
+}
+
 var File_login_login_proto protoreflect.FileDescriptor
 
 const file_login_login_proto_rawDesc = "" +
 	"\n" +
-	"\x11login/login.proto\x1a\x10message_id.proto\x1a\x11game/player.proto\"b\n" +
+	"\x11login/login.proto\x1a\x10message_id.proto\x1a\x11game/player.proto\"\xe5\x01\n" +
 	"\tS2C_Login\x12!\n" +
 	"\flogin_result\x18\x01 \x01(\x05R\vloginResult\x12+\n" +
 	"\n" +
 	"playerInfo\x18\x02 \x01(\v2\v.PlayerInfoR\n" +
-	"playerInfo:\x05\x80\xb5\x18\xc9\x01\"m\n" +
+	"playerInfo\x12#\n" +
+	"\rsession_token\x18\x03 \x01(\tR\fsessionToken\x12%\n" +
+	"\x0esession_expire\x18\x04 \x01(\x03R\rsessionExpire\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12\x1d\n" +
+	"\n" +
+	"ban_expire\x18\x06 \x01(\x03R\tbanExpire:\x05\x80\xb5\x18\xc9\x01\"\xde\x01\n" +
 	"\tC2S_Login\x12)\n" +
 	"\n" +
 	"login_type\x18\x01 \x01(\x0e2\n" +
 	".LoginTypeR\tloginType\x12\x1b\n" +
 	"\tserver_id\x18\x02 \x01(\x05R\bserverId\x12\x12\n" +
-	"\x04code\x18\x03 \x01(\tR\x04code:\x04\x80\xb5\x18e\"\x11\n" +
-	"\tC2S_Heart:\x04\x80\xb5\x18f\"\x12\n" +
-	"\tS2C_Heart:\x05\x80\xb5\x18\xca\x01*-\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\x12\x1b\n" +
+	"\tdevice_id\x18\x04 \x01(\tR\bdeviceId\x12\x1a\n" +
+	"\busername\x18\x05 \x01(\tR\busername\x12\x1a\n" +
+	"\bpassword\x18\x06 \x01(\tR\bpassword\x12\x1a\n" +
+	"\bregister\x18\a \x01(\bR\bregister:\x04\x80\xb5\x18e\"l\n" +
+	"\tC2S_Heart\x12\x1f\n" +
+	"\vclient_time\x18\x01 \x01(\x03R\n" +
+	"clientTime\x12\x1f\n" +
+	"\vserver_time\x18\x02 \x01(\x03R\n" +
+	"serverTime\x12\x17\n" +
+	"\ahold_ms\x18\x03 \x01(\x05R\x06holdMs:\x04\x80\xb5\x18f\"k\n" +
+	"\tS2C_Heart\x12\x1f\n" +
+	"\vclient_time\x18\x01 \x01(\x03R\n" +
+	"clientTime\x12\x1f\n" +
+	"\vserver_time\x18\x02 \x01(\x03R\n" +
+	"serverTime\x12\x15\n" +
+	"\x06rtt_ms\x18\x03 \x01(\x05R\x05rttMs:\x05\x80\xb5\x18\xca\x01\"\x87\x01\n" +
+	"\x11S2C_ServerClosing\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\x12,\n" +
+	"\x12reconnect_delay_ms\x18\x02 \x01(\x05R\x10reconnectDelayMs\x12%\n" +
+	"\x0ereconnect_addr\x18\x03 \x01(\tR\rreconnectAddr:\x05\x80\xb5\x18\xcb\x01\"G\n" +
+	"\x10S2C_Announcement\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\x03R\x04time:\x05\x80\xb5\x18\xcc\x01\"\xab\x01\n" +
+	"\x0fC2S_BindAccount\x12)\n" +
+	"\n" +
+	"login_type\x18\x01 \x01(\x0e2\n" +
+	".LoginTypeR\tloginType\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\tR\x04code\x12\x1b\n" +
+	"\tdevice_id\x18\x03 \x01(\tR\bdeviceId\x12\x1a\n" +
+	"\busername\x18\x04 \x01(\tR\busername\x12\x1a\n" +
+	"\bpassword\x18\x05 \x01(\tR\bpassword:\x04\x80\xb5\x18g\"d\n" +
+	"\x0fS2C_BindAccount\x12\x1f\n" +
+	"\x06result\x18\x01 \x01(\x0e2\a.ResultR\x06result\x12)\n" +
+	"\n" +
+	"login_type\x18\x02 \x01(\x0e2\n" +
+	".LoginTypeR\tloginType:\x05\x80\xb5\x18\xcd\x01\"/\n" +
+	"\x11C2S_ResumeSession\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token:\x04\x80\xb5\x18h\";\n" +
+	"\x11S2C_ResumeSession\x12\x1f\n" +
+	"\x06result\x18\x01 \x01(\x0e2\a.ResultR\x06result:\x05\x80\xb5\x18\xce\x01\"H\n" +
+	"\bS2C_Kick\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\x12\x1d\n" +
+	"\n" +
+	"ban_expire\x18\x02 \x01(\x03R\tbanExpire:\x05\x80\xb5\x18\xcf\x01\"B\n" +
+	"\tS2C_Muted\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\x12\x16\n" +
+	"\x06expire\x18\x02 \x01(\x03R\x06expire:\x05\x80\xb5\x18\xd0\x01\"j\n" +
+	"\x0eS2C_LoginQueue\x12\x1a\n" +
+	"\bposition\x18\x01 \x01(\x05R\bposition\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\x12\x1f\n" +
+	"\vwait_second\x18\x03 \x01(\x05R\n" +
+	"waitSecond:\x05\x80\xb5\x18\xd1\x01*E\n" +
 	"\tLoginType\x12\b\n" +
 	"\x04None\x10\x00\x12\n" +
 	"\n" +
 	"\x06DouYin\x10\x01\x12\n" +
 	"\n" +
-	"\x06WeChat\x10\x02B\x0eZ\f./../messageb\x06proto3"
+	"\x06WeChat\x10\x02\x12\t\n" +
+	"\x05Guest\x10\x03\x12\v\n" +
+	"\aAccount\x10\x04B\x0eZ\f./../messageb\x06proto3"
 
 var (
 	file_login_login_proto_rawDescOnce sync.Once
@@ -295,23 +973,37 @@ func file_login_login_proto_rawDescGZIP() []byte {
 }
 
 var file_login_login_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_login_login_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_login_login_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_login_login_proto_goTypes = []any{
-	(LoginType)(0),     // 0: LoginType
-	(*S2C_Login)(nil),  // 1: S2C_Login
-	(*C2S_Login)(nil),  // 2: C2S_Login
-	(*C2S_Heart)(nil),  // 3: C2S_Heart
-	(*S2C_Heart)(nil),  // 4: S2C_Heart
-	(*PlayerInfo)(nil), // 5: PlayerInfo
+	(LoginType)(0),            // 0: LoginType
+	(*S2C_Login)(nil),         // 1: S2C_Login
+	(*C2S_Login)(nil),         // 2: C2S_Login
+	(*C2S_Heart)(nil),         // 3: C2S_Heart
+	(*S2C_Heart)(nil),         // 4: S2C_Heart
+	(*S2C_ServerClosing)(nil), // 5: S2C_ServerClosing
+	(*S2C_Announcement)(nil),  // 6: S2C_Announcement
+	(*C2S_BindAccount)(nil),   // 7: C2S_BindAccount
+	(*S2C_BindAccount)(nil),   // 8: S2C_BindAccount
+	(*C2S_ResumeSession)(nil), // 9: C2S_ResumeSession
+	(*S2C_ResumeSession)(nil), // 10: S2C_ResumeSession
+	(*S2C_Kick)(nil),          // 11: S2C_Kick
+	(*S2C_Muted)(nil),         // 12: S2C_Muted
+	(*S2C_LoginQueue)(nil),    // 13: S2C_LoginQueue
+	(*PlayerInfo)(nil),        // 14: PlayerInfo
+	(Result)(0),               // 15: Result
 }
 var file_login_login_proto_depIdxs = []int32{
-	5, // 0: S2C_Login.playerInfo:type_name -> PlayerInfo
-	0, // 1: C2S_Login.login_type:type_name -> LoginType
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	14, // 0: S2C_Login.playerInfo:type_name -> PlayerInfo
+	0,  // 1: C2S_Login.login_type:type_name -> LoginType
+	0,  // 2: C2S_BindAccount.login_type:type_name -> LoginType
+	15, // 3: S2C_BindAccount.result:type_name -> Result
+	0,  // 4: S2C_BindAccount.login_type:type_name -> LoginType
+	15, // 5: S2C_ResumeSession.result:type_name -> Result
+	6,  // [6:6] is the sub-list for method output_type
+	6,  // [6:6] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_login_login_proto_init() }
@@ -327,7 +1019,7 @@ func file_login_login_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_login_login_proto_rawDesc), len(file_login_login_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   4,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
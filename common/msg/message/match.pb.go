@@ -453,7 +453,7 @@ const file_match_match_proto_rawDesc = "" +
 	"\x0fS2C_CancelMatch\x12\x16\n" +
 	"\x06result\x18\x01 \x01(\bR\x06result:\x05\x80\xb5\x18\x93\x03\"6\n" +
 	"\x11S2C_PlayerOffline\x12\x1a\n" +
-	"\bplayerId\x18\x01 \x01(\x03R\bplayerId:\x05\x80\xb5\x18\x93\x03\"X\n" +
+	"\bplayerId\x18\x01 \x01(\x03R\bplayerId:\x05\x80\xb5\x18\x95\x03\"X\n" +
 	"\x15C2S_RecordGameOperate\x12\x16\n" +
 	"\x06roomId\x18\x01 \x01(\x03R\x06roomId\x12 \n" +
 	"\voperateInfo\x18\x02 \x01(\tR\voperateInfo:\x05\x80\xb5\x18\xb0\x02\"@\n" +
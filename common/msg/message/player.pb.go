@@ -21,8 +21,8 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// C2S 800--900
-// S2C 900--1000
+// C2S 800--899
+// S2C 900--999
 type Result int32
 
 const (
@@ -0,0 +1,112 @@
+package msg
+
+import (
+	"errors"
+	"gameserver/common/models"
+	"gameserver/common/msg/message"
+	"gameserver/common/sanction"
+	"gameserver/common/utils"
+	"gameserver/core/gate"
+	"gameserver/core/log"
+	"gameserver/core/network/protobuf"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// LatencyStats 消息处理耗时统计，可通过console命令msglatency查看
+var LatencyStats = protobuf.NewLatencyStats()
+
+// 日志中需要脱敏的字段
+var logMaskers = map[string]protobuf.Masker{
+	"code":     utils.MaskSecret,
+	"token":    utils.MaskSecret,
+	"password": utils.MaskAll,
+	"phone":    utils.MaskPhoneNumber,
+}
+
+// 未登录也允许处理的消息
+var anonymousMsgs = map[uint32]bool{}
+
+// 禁言期间不能发送的消息，新增聊天类消息时加到这里
+var mutedMsgs = []proto.Message{
+	&message.C2S_ModifyName{},
+	&message.C2S_RecordGameOperate{},
+}
+
+func init() {
+	for _, m := range []proto.Message{
+		&message.C2S_Login{},
+		&message.C2S_ResumeSession{},
+		&message.C2S_Heart{},
+	} {
+		anonymousMsgs[protobuf.GetId(m)] = true
+	}
+
+	Processor.Use(
+		protobuf.Recover(),
+		protobuf.Latency(LatencyStats),
+		protobuf.Logging(logMaskers),
+		requireLogin,
+		protobuf.Validate(),
+	)
+
+	for _, m := range mutedMsgs {
+		Processor.UseFor(m, rejectMuted)
+	}
+
+	Processor.UseFor(&message.C2S_GetRechargeRecords{}, protobuf.Check(func(m proto.Message) error {
+		if m.(*message.C2S_GetRechargeRecords).Limit < 0 {
+			return errors.New("limit must not be negative")
+		}
+		return nil
+	}))
+	Processor.UseFor(&message.C2S_RecordGameOperate{}, protobuf.Check(func(m proto.Message) error {
+		msg := m.(*message.C2S_RecordGameOperate)
+		if msg.RoomId <= 0 {
+			return errors.New("invalid room id")
+		}
+		if msg.OperateInfo == "" {
+			return errors.New("empty operate info")
+		}
+		return nil
+	}))
+}
+
+// Anonymous 未登录的连接是否可以发送该消息，网关模式下在网关节点检查
+func Anonymous(id uint32) bool {
+	return anonymousMsgs[id]
+}
+
+// requireLogin 未登录的连接只能发送anonymousMsgs中的消息
+func requireLogin(id uint32, next protobuf.MsgHandler) protobuf.MsgHandler {
+	if anonymousMsgs[id] {
+		return next
+	}
+	return func(args []interface{}) {
+		agent, ok := args[1].(gate.Agent)
+		if !ok || agent.UserData() == nil {
+			log.Debug("message %v dropped: not login, agent: %v", id, args[1])
+			return
+		}
+		next(args)
+	}
+}
+
+// rejectMuted 禁言中的玩家发送的消息被丢弃，并告知禁言原因和结束时间
+func rejectMuted(id uint32, next protobuf.MsgHandler) protobuf.MsgHandler {
+	return func(args []interface{}) {
+		agent, _ := args[1].(gate.Agent)
+		if agent != nil {
+			if user, ok := agent.UserData().(models.User); ok {
+				if mute := sanction.Muted(user.PlayerId); mute != nil {
+					agent.WriteMsg(&message.S2C_Muted{
+						Reason: mute.Reason,
+						Expire: mute.ExpireTime,
+					})
+					return
+				}
+			}
+		}
+		next(args)
+	}
+}
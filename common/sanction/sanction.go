@@ -0,0 +1,156 @@
+// Package sanction 封号、禁言和维护模式白名单
+//
+// 处罚记录保存在MongoDB中，登录时直接查库；禁言在每条受限消息上检查，查询结果缓存一分钟，
+// 其他节点上的禁言和解禁最多延迟一分钟生效
+package sanction
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gameserver/common/cache"
+	"gameserver/common/db/mongodb"
+	"gameserver/common/models"
+	"gameserver/core/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	sanctionRepo  = mongodb.NewRepository[models.Sanction]()
+	whitelistRepo = mongodb.NewRepository[models.Whitelist]()
+
+	// 没有禁言时缓存nil，避免每条消息都查库
+	mutes = cache.New(cache.Options[int64, *models.Sanction]{
+		Name:    "mute",
+		MaxSize: 100000,
+		TTL:     time.Minute,
+		Loader:  loadMute,
+	})
+
+	maintenance atomic.Bool
+)
+
+func loadMute(playerId int64) (*models.Sanction, bool, error) {
+	s, err := Find(playerId, models.SanctionMute)
+	return s, err == nil, err
+}
+
+// Ban 封号，duration为0表示永久，已有的封号被覆盖
+func Ban(playerId int64, reason, operator string, duration time.Duration) (models.Sanction, error) {
+	return impose(models.SanctionBan, playerId, reason, operator, duration)
+}
+
+// Mute 禁言，duration为0表示永久，已有的禁言被覆盖
+func Mute(playerId int64, reason, operator string, duration time.Duration) (models.Sanction, error) {
+	return impose(models.SanctionMute, playerId, reason, operator, duration)
+}
+
+func impose(t models.SanctionType, playerId int64, reason, operator string, duration time.Duration) (models.Sanction, error) {
+	now := time.Now()
+	s := models.Sanction{
+		Id:         models.SanctionId(t, playerId),
+		PlayerId:   playerId,
+		Type:       t,
+		Reason:     reason,
+		Operator:   operator,
+		CreateTime: now.Unix(),
+	}
+	if duration > 0 {
+		s.ExpireTime = now.Add(duration).Unix()
+	}
+	if err := sanctionRepo.Save(context.Background(), s); err != nil {
+		return s, err
+	}
+	if t == models.SanctionMute {
+		mutes.Invalidate(playerId)
+	}
+	log.Release("sanction %v player %d by %s, expire: %d, reason: %s", t, playerId, operator, s.ExpireTime, reason)
+	return s, nil
+}
+
+// Lift 解除处罚，没有该处罚时返回false
+func Lift(playerId int64, t models.SanctionType) (bool, error) {
+	deleted, err := sanctionRepo.Delete(context.Background(), models.SanctionId(t, playerId))
+	if t == models.SanctionMute {
+		mutes.Invalidate(playerId)
+	}
+	return deleted, err
+}
+
+// Find 查询生效中的处罚，没有或已过期时返回nil
+func Find(playerId int64, t models.SanctionType) (*models.Sanction, error) {
+	s, err := sanctionRepo.FindById(context.Background(), models.SanctionId(t, playerId))
+	if err != nil || s == nil || !s.Active(time.Now().Unix()) {
+		return nil, err
+	}
+	return s, nil
+}
+
+// List 玩家的所有处罚记录，包括已过期的
+func List(playerId int64) ([]models.Sanction, error) {
+	return sanctionRepo.Find(context.Background(), mongodb.Where(bson.M{"PlayerId": playerId}))
+}
+
+// Muted 玩家当前的禁言，没有禁言时返回nil，查询失败时不拦截
+func Muted(playerId int64) *models.Sanction {
+	s, _, err := mutes.Load(playerId)
+	if err != nil {
+		log.Error("load mute failed: %d, %v", playerId, err)
+		return nil
+	}
+	if s != nil && !s.Active(time.Now().Unix()) {
+		mutes.Invalidate(playerId)
+		return nil
+	}
+	return s
+}
+
+// SetMaintenance 开关维护模式，维护期间只有白名单中的玩家可以登录
+func SetMaintenance(on bool) {
+	maintenance.Store(on)
+	log.Release("maintenance mode: %v", on)
+}
+
+// Maintenance 是否处于维护模式
+func Maintenance() bool {
+	return maintenance.Load()
+}
+
+// AllowLogin 维护模式下玩家是否可以登录，新玩家没有playerId，维护期间不能注册
+func AllowLogin(playerId int64) (bool, error) {
+	if !Maintenance() {
+		return true, nil
+	}
+	if playerId == 0 {
+		return false, nil
+	}
+	return Whitelisted(playerId)
+}
+
+// Whitelisted 玩家是否在白名单中，白名单中的玩家登录时也不用排队
+func Whitelisted(playerId int64) (bool, error) {
+	w, err := whitelistRepo.FindById(context.Background(), playerId)
+	return w != nil, err
+}
+
+// AddWhitelist 加入维护白名单
+func AddWhitelist(playerId int64, operator, remark string) error {
+	return whitelistRepo.Save(context.Background(), models.Whitelist{
+		PlayerId:   playerId,
+		Operator:   operator,
+		Remark:     remark,
+		CreateTime: time.Now().Unix(),
+	})
+}
+
+// RemoveWhitelist 移出维护白名单，不在白名单中时返回false
+func RemoveWhitelist(playerId int64) (bool, error) {
+	return whitelistRepo.Delete(context.Background(), playerId)
+}
+
+// Whitelist 维护白名单
+func Whitelist() ([]models.Whitelist, error) {
+	return whitelistRepo.Find(context.Background(), mongodb.Where(bson.M{}))
+}
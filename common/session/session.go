@@ -0,0 +1,161 @@
+// Package session 签发和校验登录会话令牌，断线重连时凭令牌恢复登录，不需要再请求登录平台
+//
+// 令牌格式为 base64(claims) "." base64(HMAC-SHA256)，签名密钥由密钥和签发时间所在的轮换周期派生，
+// 每个周期更换一次，只接受当前和上一个周期的密钥，泄露的周期密钥最多两个周期后失效
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"gameserver/common/msg/message"
+)
+
+var (
+	// ErrInvalidToken 令牌格式错误、签名不匹配或密钥已轮换掉
+	ErrInvalidToken = errors.New("session: invalid token")
+	// ErrTokenExpired 令牌已过期
+	ErrTokenExpired = errors.New("session: token expired")
+	// ErrRenewLimit 距离登录平台认证已超过最长有效期，需要重新登录
+	ErrRenewLimit = errors.New("session: renewal limit reached")
+)
+
+// DefaultMaxLifetime 令牌续期的默认上限
+const DefaultMaxLifetime = 7 * 24 * time.Hour
+
+// Claims 令牌中记录的登录身份
+type Claims struct {
+	ServerId  int32             `json:"sid"`
+	LoginType message.LoginType `json:"typ"`
+	OpenId    string            `json:"oid"`  // 登录平台返回的openid，按IdentityKey查找用户
+	IssuedAt  int64             `json:"iat"`  // 签发时间，unix秒
+	ExpireAt  int64             `json:"exp"`  // 过期时间，unix秒
+	AuthAt    int64             `json:"auth"` // 在登录平台认证的时间，续期时保持不变，unix秒
+}
+
+// Signer 签发和校验令牌，多个节点使用相同的secret时可以互相校验
+type Signer struct {
+	secret      []byte
+	ttl         time.Duration
+	rotate      time.Duration
+	maxLifetime time.Duration
+	now         func() time.Time
+}
+
+// NewSigner 创建Signer，rotate小于ttl时按ttl轮换，保证未过期的令牌的密钥不会被轮换掉
+func NewSigner(secret []byte, ttl, rotate time.Duration) *Signer {
+	if rotate < ttl {
+		rotate = ttl
+	}
+	if rotate < time.Second {
+		rotate = time.Second
+	}
+	return &Signer{
+		secret:      secret,
+		ttl:         ttl,
+		rotate:      rotate,
+		maxLifetime: DefaultMaxLifetime,
+		now:         time.Now,
+	}
+}
+
+// SetMaxLifetime 设置续期的上限，从登录平台认证开始计算，超过后只能重新登录
+func (s *Signer) SetMaxLifetime(d time.Duration) {
+	s.maxLifetime = d
+}
+
+// SetClock 替换时钟，用于测试
+func (s *Signer) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Issue 在登录平台认证后签发令牌，返回令牌和过期时间
+func (s *Signer) Issue(serverId int32, loginType message.LoginType, openId string) (string, int64) {
+	return s.issue(Claims{
+		ServerId:  serverId,
+		LoginType: loginType,
+		OpenId:    openId,
+		AuthAt:    s.now().Unix(),
+	})
+}
+
+// Renew 恢复会话时用校验过的claims签发新的令牌，认证时间不变，
+// 过期时间不超过认证时间加上最长有效期，已达到上限时返回ErrRenewLimit
+func (s *Signer) Renew(claims Claims) (string, int64, error) {
+	if s.now().Unix() >= s.limit(claims) {
+		return "", 0, ErrRenewLimit
+	}
+	token, expire := s.issue(claims)
+	return token, expire, nil
+}
+
+// limit 令牌最晚的过期时间
+func (s *Signer) limit(claims Claims) int64 {
+	return claims.AuthAt + int64(s.maxLifetime/time.Second)
+}
+
+func (s *Signer) issue(claims Claims) (string, int64) {
+	now := s.now()
+	claims.IssuedAt = now.Unix()
+	claims.ExpireAt = now.Add(s.ttl).Unix()
+	if limit := s.limit(claims); claims.ExpireAt > limit {
+		claims.ExpireAt = limit
+	}
+	payload, _ := json.Marshal(claims)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	sig := s.sign(s.epoch(claims.IssuedAt), encoded)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sig), claims.ExpireAt
+}
+
+// Verify 校验令牌，签名错误或密钥已轮换掉时返回ErrInvalidToken，过期时返回ErrTokenExpired
+func (s *Signer) Verify(token string) (Claims, error) {
+	var claims Claims
+	encoded, sigPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(payload, &claims) != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	now := s.now().Unix()
+	epoch, current := s.epoch(claims.IssuedAt), s.epoch(now)
+	if epoch > current || epoch < current-1 {
+		return Claims{}, ErrInvalidToken
+	}
+	if !hmac.Equal(sig, s.sign(epoch, encoded)) {
+		return Claims{}, ErrInvalidToken
+	}
+	if now >= claims.ExpireAt {
+		return Claims{}, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+// epoch 时间所在的轮换周期
+func (s *Signer) epoch(unix int64) int64 {
+	return unix / int64(s.rotate/time.Second)
+}
+
+// sign 用周期密钥签名，周期密钥为HMAC(secret, epoch)
+func (s *Signer) sign(epoch int64, payload string) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(epoch))
+	keyMac := hmac.New(sha256.New, s.secret)
+	keyMac.Write(buf[:])
+
+	mac := hmac.New(sha256.New, keyMac.Sum(nil))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -26,7 +26,12 @@ func HttpGet(ctx context.Context, uri string, query string, protocol string, dom
 }
 
 func HttpDo(ctx context.Context, uri string, method string, bodyStr string, protocol string, domain string) (string, error) {
-	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s", protocol, domain, uri), strings.NewReader(bodyStr))
+	return HttpDoUrl(ctx, method, fmt.Sprintf("%s://%s%s", protocol, domain, uri), bodyStr)
+}
+
+// HttpDoUrl 请求完整的url，GET请求的参数直接拼在url上
+func HttpDoUrl(ctx context.Context, method string, url string, bodyStr string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(bodyStr))
 	if err != nil {
 		println(err.Error())
 		return "", err
@@ -3,6 +3,7 @@ package utils
 import (
 	"context"
 	"regexp"
+	"strings"
 )
 
 var phoneNumberReg = "(\\d{3})\\d{4}(\\d{4})"
@@ -14,3 +15,19 @@ func MaskPhoneNumber(ctx context.Context, data string) string {
 	}
 	return reg.ReplaceAllString(data, "$1****$2")
 }
+
+// MaskSecret 用于code、token等凭证，只保留首尾各两个字符
+func MaskSecret(ctx context.Context, data string) string {
+	if len(data) <= 8 {
+		return strings.Repeat("*", len(data))
+	}
+	return data[:2] + strings.Repeat("*", len(data)-4) + data[len(data)-2:]
+}
+
+// MaskAll 用于密码，不保留任何字符，也不暴露长度
+func MaskAll(ctx context.Context, data string) string {
+	if data == "" {
+		return ""
+	}
+	return "******"
+}
@@ -2,7 +2,9 @@ package conf
 
 import (
 	"encoding/json"
+	lconf "gameserver/core/conf"
 	"gameserver/core/log"
+	"gameserver/core/network"
 	"os"
 	"sync"
 )
@@ -23,13 +25,24 @@ func Instance() *JsonConf {
 }
 
 var Server struct {
-	LogLevel    string
-	LogPath     string
+	LogLevel string
+	LogPath  string
+	Log      struct {
+		Format       string // text或json
+		MaxSizeMB    int64
+		RotateHours  int
+		MaxBackups   int
+		MaxAgeDays   int
+		ModuleLevels map[string]string // 模块 -> 级别，模块名与log.Module的参数一致
+	}
 	WSAddr      string
 	CertFile    string
 	KeyFile     string
 	TCPAddr     string
+	KCPAddr     string
+	KCP         network.KCPSetting
 	MaxConnNum  int
+	IdleSecond  int // 连接超过该时间没有收到任何消息（包括心跳）时断开，0表示不检查
 	ConsolePort int
 	ProfilePath string
 	MachineID   int64
@@ -40,32 +53,71 @@ var Server struct {
 	Actor struct {
 		TimeoutMillisecond int
 	}
+	GameConfig struct {
+		WatchSecond int // 轮询conf/config目录的间隔，文件变化后自动重新加载，0表示不监听
+	}
+	Drain struct {
+		TimeoutSecond    int    // 停服排空等待时间，0表示立即断开
+		ReconnectDelayMs int32  // 下发给客户端的建议重连等待时间
+		ReconnectAddr    string // 下发给客户端的重连地址，为空表示原地址
+	}
 	DouYinInfo struct {
-		Appid     string
-		Secret    string
-		IsSandBox int
+		Appid      string
+		Secret     string
+		IsSandBox  int
+		Url        string // jscode2session地址
+		SandBoxUrl string // 沙盒环境的jscode2session地址，IsSandBox为1时使用
+	}
+	Login struct {
+		Guest   bool // 允许游客用设备id登录，设备id是唯一凭证，默认关闭
+		Account bool // 允许用户名密码登录和注册，默认关闭
+
+		Maintenance bool // 启动时进入维护模式，只有白名单中的玩家可以登录，可用maintenance命令切换
+
+		SessionSecret            string // 会话令牌的签名密钥，多个登录节点需配置相同的值，为空时启动时随机生成
+		SessionTTLSecond         int    // 会话令牌有效期
+		SessionRotateSecond      int    // 签名密钥的轮换周期，不小于有效期
+		SessionMaxLifetimeSecond int    // 恢复会话续期的上限，从登录平台认证开始计算，0表示默认7天
 	}
+	LoginQueue struct {
+		MaxOnline       int   // 在线人数上限，达到后新的登录排队，0表示不限制
+		TickSecond      int   // 放行排队玩家和推送排队位置的间隔
+		VipLevel        int32 // 不低于该VIP等级的玩家优先放行，0表示不按VIP优先
+		ReconnectSecond int   // 下线后在这段时间内重新登录的玩家优先放行
+	}
+	WeChatInfo struct {
+		Appid  string
+		Secret string
+		Url    string // jscode2session地址
+	}
+	Cluster struct {
+		NodeName      string              // 本节点名，在注册表中唯一
+		ListenAddr    string              // 节点间通信的监听地址，为空时使用注册表中本节点的地址
+		ConnAddrs     []string            // 不在注册表中、总是连接的节点地址
+		Secret        string              // 节点间握手的共享密钥，所有节点配置相同的值，为空时只能监听127.0.0.1
+		RegistryFile  string              // 服务注册表文件，设置时代替Nodes，可用cluster reload命令重新加载
+		CallTimeoutMs int                 // 跨节点调用的超时
+		Nodes         []lconf.ClusterNode // 静态服务注册表
+	}
+	Gateway struct {
+		Enabled bool // 网关模式：只保持客户端连接，消息按模块转发到注册表中的后端节点
+	}
+	Storage string // 持久化后端：mongodb（默认）或memory，memory不需要数据库，停服后数据丢失，只用于开发和测试
 	MongoDB struct {
 		Host        string
 		Database    string
 		MinPoolSize uint64
 		MaxPoolSize uint64
+		TimeoutMs   int // 单次操作的默认超时，调用方传入的ctx带截止时间时以ctx为准
+	}
+	Redis struct {
+		Addr         string // 为空时不连接Redis，跨进程共享的功能不可用
+		Password     string
+		DB           int
+		PoolSize     int
+		MinIdleConns int
+		KeyPrefix    string // 所有key的前缀，多个服共用一个Redis时区分
 	}
-}
-
-var MongoIndexConf MongoIndexConfigs
-
-type MongoIndexConfigs struct {
-	Indexes []IndexConfig `json:"indexes"`
-}
-
-type IndexConfig struct {
-	Collection string              `json:"collection"`
-	Create     []IndexCreateConfig `json:"create"`
-}
-type IndexCreateConfig struct {
-	Keys   map[string]int `json:"keys"`
-	Unique bool           `json:"unique"`
 }
 
 func (j *JsonConf) Init(baseDir string) {
@@ -80,15 +132,4 @@ func (j *JsonConf) Init(baseDir string) {
 		log.Fatal("解析server.json失败: %v", err)
 	}
 
-	// 从mongo_index.json加载Index配置
-	indexPath := baseDir + "/mongo_index.json"
-	indexData, err := os.ReadFile(indexPath)
-	if err != nil {
-		log.Fatal("读取mongo_index.json失败: %v", err)
-	}
-
-	err = json.Unmarshal(indexData, &MongoIndexConf)
-	if err != nil {
-		log.Fatal("解析mongo_index.json失败: %v", err)
-	}
 }
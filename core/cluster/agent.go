@@ -0,0 +1,218 @@
+package cluster
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"sync"
+	"time"
+
+	"gameserver/core/log"
+	"gameserver/core/network"
+)
+
+// 握手超时，连接建立后对端在这段时间内没有完成握手时断开
+const handshakeTimeout = 10 * time.Second
+
+// nonceLen 握手挑战的字节数
+const nonceLen = 32
+
+// handshakeMaxMsgLen 握手完成前单个包的最大字节数，未认证的对端不能让本节点分配大块内存或解码大包
+const handshakeMaxMsgLen = 1024
+
+type result struct {
+	ret []interface{}
+	err error
+}
+
+// Agent 到其他节点的一条连接，主动连接和被动接受的连接一样双向调用
+type Agent struct {
+	node  *Node
+	conn  *network.TCPConn
+	nonce []byte // 发给对端的挑战
+	peer  string // 对端声明的节点名，应答校验通过前未登记
+	name  string // 握手后的对端节点名
+
+	mu      sync.Mutex
+	pending map[uint64]chan result
+	closed  bool
+}
+
+func (n *Node) newAgent(conn *network.TCPConn) network.Agent {
+	a := new(Agent)
+	a.node = n
+	a.conn = conn
+	a.pending = make(map[uint64]chan result)
+	return a
+}
+
+func (a *Agent) Run() {
+	a.conn.SetMaxMsgLen(handshakeMaxMsgLen)
+	a.nonce = make([]byte, nonceLen)
+	if _, err := rand.Read(a.nonce); err != nil {
+		log.Error("cluster handshake nonce: %v", err)
+		return
+	}
+	if err := a.send(&packet{Type: packetHandshake, Node: a.node.Name, Nonce: a.nonce}); err != nil {
+		log.Error("cluster handshake: %v", err)
+		return
+	}
+
+	handshake := time.AfterFunc(handshakeTimeout, func() {
+		log.Release("cluster handshake timeout: %v", a.conn.RemoteAddr())
+		a.conn.Close()
+	})
+	defer handshake.Stop()
+
+	for {
+		data, err := a.conn.ReadMsg()
+		if err != nil {
+			log.Debug("cluster read message: %v", err)
+			break
+		}
+		p, err := decodePacket(data)
+		if err != nil {
+			log.Error("cluster decode packet from %v: %v", a.conn.RemoteAddr(), err)
+			break
+		}
+
+		if a.name == "" {
+			if p.Type == packetHandshake && a.peer == "" {
+				if !a.challenge(p) {
+					break
+				}
+				continue
+			}
+			if p.Type != packetAuth || a.peer == "" || !a.handshake(p) {
+				break
+			}
+			handshake.Stop()
+			a.conn.SetMaxMsgLen(0)
+			continue
+		}
+
+		switch p.Type {
+		case packetReply:
+			a.reply(p)
+		case packetGo, packetCall0, packetCall1, packetCallN:
+			a.node.serve(a, p)
+		default:
+			log.Error("cluster unexpected packet %v from %v", p.Type, a.name)
+		}
+	}
+}
+
+func (a *Agent) OnClose() {
+	a.mu.Lock()
+	a.closed = true
+	pending := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+
+	for _, ch := range pending {
+		ch <- result{err: ErrNodeDown}
+	}
+	if a.name != "" {
+		a.node.removeLink(a)
+		log.Release("cluster node %v disconnected", a.name)
+	}
+}
+
+// challenge 收到对端的节点名和挑战，用共享密钥签名应答
+func (a *Agent) challenge(p *packet) bool {
+	if p.Node == "" || p.Node == a.node.Name {
+		log.Error("cluster handshake from %v: invalid node name %q", a.conn.RemoteAddr(), p.Node)
+		return false
+	}
+	if len(p.Nonce) != nonceLen {
+		log.Error("cluster handshake from %v: invalid nonce", a.conn.RemoteAddr())
+		return false
+	}
+	a.peer = p.Node
+	if err := a.send(&packet{Type: packetAuth, Auth: a.node.proof(p.Nonce, a.node.Name, a.peer)}); err != nil {
+		log.Error("cluster handshake: %v", err)
+		return false
+	}
+	return true
+}
+
+// handshake 校验对端对本端挑战的应答
+func (a *Agent) handshake(p *packet) bool {
+	if subtle.ConstantTimeCompare([]byte(p.Auth), []byte(a.node.proof(a.nonce, a.peer, a.node.Name))) != 1 {
+		log.Error("cluster handshake from %v: node %v auth failed", a.conn.RemoteAddr(), a.peer)
+		return false
+	}
+	a.name = a.peer
+	a.node.addLink(a)
+	log.Release("cluster node %v connected: %v", a.name, a.conn.RemoteAddr())
+	if a.node.OnLink != nil {
+		a.node.OnLink(a.name)
+	}
+	return true
+}
+
+// Name 对端节点名
+func (a *Agent) Name() string {
+	return a.name
+}
+
+func (a *Agent) send(p *packet) error {
+	data, err := encodePacket(p)
+	if err != nil {
+		return err
+	}
+	return a.conn.WriteMsg(data)
+}
+
+// call 发送调用并等待返回
+func (a *Agent) call(p *packet, timeout time.Duration) ([]interface{}, error) {
+	p.Seq = a.node.seq.Add(1)
+	ch := make(chan result, 1)
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil, ErrNodeDown
+	}
+	a.pending[p.Seq] = ch
+	a.mu.Unlock()
+
+	if err := a.send(p); err != nil {
+		a.take(p.Seq)
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.ret, r.err
+	case <-timer.C:
+		if a.take(p.Seq) == nil {
+			// 超时的同时收到了返回
+			r := <-ch
+			return r.ret, r.err
+		}
+		return nil, ErrTimeout
+	}
+}
+
+// take 取出等待返回的调用，已返回或已超时时返回nil
+func (a *Agent) take(seq uint64) chan result {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ch := a.pending[seq]
+	delete(a.pending, seq)
+	return ch
+}
+
+func (a *Agent) reply(p *packet) {
+	ch := a.take(p.Seq)
+	if ch == nil {
+		return
+	}
+	r := result{ret: p.Ret}
+	if p.Err != "" {
+		r.err = errors.New(p.Err)
+	}
+	ch <- r
+}
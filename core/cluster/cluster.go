@@ -1,65 +1,129 @@
+// Package cluster 节点间的服务调用
+//
+// 每个进程是一个节点，模块把自己的chanrpc.Server按服务名注册到集群，
+// 其他节点按服务名以Go/Call的方式调用，服务所在的节点由注册表决定
 package cluster
 
 import (
-	"gameserver/core/conf"
-	"gameserver/core/network"
-	"math"
 	"time"
-)
 
-var (
-	server  *network.TCPServer
-	clients []*network.TCPClient
+	"gameserver/core/chanrpc"
+	"gameserver/core/conf"
+	"gameserver/core/log"
 )
 
+var node = new(Node)
+
+// Register 注册本节点提供的服务，需要在Init之前调用
+func Register(service string, s *chanrpc.Server) {
+	node.Register(service, s)
+}
+
 func Init() {
-	if conf.ListenAddr != "" {
-		server = new(network.TCPServer)
-		server.Addr = conf.ListenAddr
-		server.MaxConnNum = int(math.MaxInt32)
-		server.PendingWriteNum = conf.PendingWriteNum
-		server.LenMsgLen = 4
-		server.MaxMsgLen = math.MaxUint32
-		server.NewAgent = newAgent
-
-		server.Start()
+	registry := NewRegistry(conf.ClusterNodes)
+	if conf.ClusterRegistry != "" {
+		var err error
+		if registry, err = LoadRegistry(conf.ClusterRegistry); err != nil {
+			log.Fatal("load cluster registry: %v", err)
+		}
 	}
 
-	for _, addr := range conf.ConnAddrs {
-		client := new(network.TCPClient)
-		client.Addr = addr
-		client.ConnNum = 1
-		client.ConnectInterval = 3 * time.Second
-		client.PendingWriteNum = conf.PendingWriteNum
-		client.LenMsgLen = 4
-		client.MaxMsgLen = math.MaxUint32
-		client.NewAgent = newAgent
-
-		client.Start()
-		clients = append(clients, client)
+	node.Name = conf.NodeName
+	node.ListenAddr = conf.ListenAddr
+	node.ConnAddrs = conf.ConnAddrs
+	node.Secret = conf.ClusterSecret
+	node.Registry = registry
+	node.CallTimeout = conf.CallTimeout
+	node.ConnectInterval = 3 * time.Second
+	node.PendingWriteNum = conf.PendingWriteNum
+	if self, ok := registry.Node(node.Name); ok && node.ListenAddr == "" {
+		node.ListenAddr = self.Addr
+	}
+	if node.ListenAddr == "" && len(node.ConnAddrs) == 0 && len(registry.Nodes()) == 0 {
+		return
 	}
+	node.Start()
 }
 
 func Destroy() {
-	if server != nil {
-		server.Close()
+	node.Close()
+}
+
+// Reload 重新读取注册表文件，没有配置注册表文件时使用静态配置
+func Reload() error {
+	nodes := conf.ClusterNodes
+	if conf.ClusterRegistry != "" {
+		var err error
+		if nodes, err = readRegistry(conf.ClusterRegistry); err != nil {
+			return err
+		}
 	}
+	node.Reload(nodes)
+	return nil
+}
+
+// Default 本进程的节点，Init之前也可以注册服务
+func Default() *Node {
+	return node
+}
+
+// Self 本节点名，没有启动集群时为空
+func Self() string {
+	return node.Name
+}
 
-	for _, client := range clients {
-		client.Close()
+// OnLink 与其他节点握手成功后调用，需要在Init之前设置
+func OnLink(f func(name string)) {
+	node.OnLink = f
+}
+
+// Providers 注册表中提供该服务的节点名
+func Providers(service string) []string {
+	if node.Registry == nil {
+		return nil
 	}
+	return node.Registry.Providers(service)
 }
 
-type Agent struct {
-	conn *network.TCPConn
+// Links 已连接的节点名
+func Links() []string {
+	return node.Links()
 }
 
-func newAgent(conn *network.TCPConn) network.Agent {
-	a := new(Agent)
-	a.conn = conn
-	return a
+// Nodes 注册表中的节点
+func Nodes() []conf.ClusterNode {
+	if node.Registry == nil {
+		return nil
+	}
+	return node.Registry.Nodes()
 }
 
-func (a *Agent) Run() {}
+// Go 调用服务的函数，不等待返回
+func Go(service, id string, args ...interface{}) error {
+	return node.Go(service, id, args...)
+}
 
-func (a *Agent) OnClose() {}
+// Call0 调用服务的函数，等待执行完成
+func Call0(service, id string, args ...interface{}) error {
+	return node.Call0(service, id, args...)
+}
+
+// Call1 调用服务的函数，返回一个值
+func Call1(service, id string, args ...interface{}) (interface{}, error) {
+	return node.Call1(service, id, args...)
+}
+
+// CallN 调用服务的函数，返回多个值
+func CallN(service, id string, args ...interface{}) ([]interface{}, error) {
+	return node.CallN(service, id, args...)
+}
+
+// GoNode 调用指定节点上服务的函数，不等待返回
+func GoNode(name, service, id string, args ...interface{}) error {
+	return node.GoNode(name, service, id, args...)
+}
+
+// CallNode 调用指定节点上服务的函数，返回多个值
+func CallNode(name, service, id string, args ...interface{}) ([]interface{}, error) {
+	return node.CallNode(name, service, id, args...)
+}
@@ -0,0 +1,122 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// 包类型
+const (
+	packetHandshake uint8 = iota + 1 // 节点名和随机挑战
+	packetAuth                       // 对对端挑战的应答
+	packetGo                         // 不需要返回
+	packetCall0                      // 返回error
+	packetCall1                      // 返回interface{}
+	packetCallN                      // 返回[]interface{}
+	packetReply
+)
+
+// packet 节点间传输的包，用gob编码
+// 参数和返回值中的protobuf消息按消息名和字节编码，其他自定义类型需要调用gob.Register注册
+type packet struct {
+	Type uint8
+	Seq  uint64
+
+	// 握手
+	Node  string
+	Nonce []byte
+	Auth  string
+
+	// 调用
+	Service string
+	Id      string
+	Args    []interface{}
+
+	// 返回
+	Ret []interface{}
+	Err string
+}
+
+// protoArg protobuf消息在gob中的表示
+type protoArg struct {
+	Name string
+	Data []byte
+}
+
+func init() {
+	gob.Register(protoArg{})
+}
+
+func encodePacket(p *packet) ([]byte, error) {
+	var err error
+	if p.Args, err = wrapArgs(p.Args); err != nil {
+		return nil, err
+	}
+	if p.Ret, err = wrapArgs(p.Ret); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodePacket(data []byte) (*packet, error) {
+	p := new(packet)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(p); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.Args, err = unwrapArgs(p.Args); err != nil {
+		return nil, err
+	}
+	if p.Ret, err = unwrapArgs(p.Ret); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func wrapArgs(args []interface{}) ([]interface{}, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	wrapped := make([]interface{}, len(args))
+	for i, arg := range args {
+		m, ok := arg.(proto.Message)
+		if !ok {
+			wrapped[i] = arg
+			continue
+		}
+		data, err := proto.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		wrapped[i] = protoArg{Name: string(m.ProtoReflect().Descriptor().FullName()), Data: data}
+	}
+	return wrapped, nil
+}
+
+func unwrapArgs(args []interface{}) ([]interface{}, error) {
+	for i, arg := range args {
+		pa, ok := arg.(protoArg)
+		if !ok {
+			continue
+		}
+		mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(pa.Name))
+		if err != nil {
+			return nil, fmt.Errorf("unknown message %v: %v", pa.Name, err)
+		}
+		m := mt.New().Interface()
+		if err := proto.Unmarshal(pa.Data, m); err != nil {
+			return nil, err
+		}
+		args[i] = m
+	}
+	return args, nil
+}
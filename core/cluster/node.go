@@ -0,0 +1,417 @@
+package cluster
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gameserver/core/chanrpc"
+	"gameserver/core/conf"
+	"gameserver/core/log"
+	"gameserver/core/network"
+)
+
+const (
+	defaultMaxMsgLen  = 16 * 1024 * 1024
+	defaultMaxConnNum = 1024
+)
+
+var (
+	ErrServiceUnavailable = errors.New("cluster: service unavailable")
+	ErrNodeDown           = errors.New("cluster: node down")
+	ErrTimeout            = errors.New("cluster: call timeout")
+)
+
+// Node 集群中的一个节点
+// 节点之间用一条TCP连接双向调用，连接建立后双方先交换节点名和随机挑战，再用共享密钥对对端的挑战签名应答，
+// 签名只对这条连接有效，截获后不能重放。握手成功后按节点名登记。本节点提供的服务是本地的chanrpc.Server，其他节点按服务名调用
+//
+// 为避免两个节点互相连接，只主动连接注册表中提供服务的节点；双方都提供服务时由名字较小的一方连接
+type Node struct {
+	Name            string
+	ListenAddr      string
+	ConnAddrs       []string
+	Secret          string
+	Registry        *Registry
+	CallTimeout     time.Duration
+	ConnectInterval time.Duration
+	PendingWriteNum int
+	MaxMsgLen       uint32            // 握手后单个包的最大字节数，握手完成前只允许handshakeMaxMsgLen
+	MaxConnNum      int               // 监听的最大连接数，包括未完成握手的连接
+	OnLink          func(name string) // 与其他节点握手成功后调用，在连接的读goroutine中执行
+
+	mu       sync.Mutex
+	server   *network.TCPServer
+	clients  map[string]*network.TCPClient // 地址 -> 客户端
+	links    map[string]*Agent             // 节点名 -> 握手成功的连接
+	services map[string]*chanrpc.Server
+	seq      atomic.Uint64
+}
+
+// Register 把本地的chanrpc.Server注册为服务，其他节点可以按服务名调用
+// 只有字符串id的函数可以被远程调用
+func (n *Node) Register(service string, s *chanrpc.Server) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.services == nil {
+		n.services = make(map[string]*chanrpc.Server)
+	}
+	if _, ok := n.services[service]; ok {
+		log.Fatal("cluster service %v is already registered", service)
+	}
+	n.services[service] = s
+}
+
+// Start 开始监听并连接注册表中的节点，断开后自动重连
+func (n *Node) Start() {
+	if n.Registry == nil {
+		n.Registry = NewRegistry(nil)
+	}
+	if n.CallTimeout <= 0 {
+		n.CallTimeout = 3 * time.Second
+	}
+	if n.ConnectInterval <= 0 {
+		n.ConnectInterval = 3 * time.Second
+	}
+	if n.MaxMsgLen == 0 {
+		n.MaxMsgLen = defaultMaxMsgLen
+	}
+	if n.MaxConnNum <= 0 {
+		n.MaxConnNum = defaultMaxConnNum
+	}
+
+	n.mu.Lock()
+	n.clients = make(map[string]*network.TCPClient)
+	n.links = make(map[string]*Agent)
+	n.mu.Unlock()
+
+	if n.ListenAddr != "" {
+		if n.Secret == "" && !isLoopback(n.ListenAddr) {
+			log.Fatal("cluster listen on %v without a secret, only loopback address is allowed", n.ListenAddr)
+		}
+		n.server = new(network.TCPServer)
+		n.server.Addr = n.ListenAddr
+		n.server.MaxConnNum = n.MaxConnNum
+		n.server.PendingWriteNum = n.PendingWriteNum
+		n.server.LenMsgLen = 4
+		n.server.MaxMsgLen = n.MaxMsgLen
+		n.server.NewAgent = n.newAgent
+
+		n.server.Start()
+	}
+
+	n.connect()
+}
+
+// Reload 替换注册表，连接新增的节点，断开不再需要连接的节点
+func (n *Node) Reload(nodes []conf.ClusterNode) {
+	if n.Registry == nil {
+		n.Registry = NewRegistry(nil)
+	}
+	n.Registry.Set(nodes)
+	n.connect()
+}
+
+// Close 断开所有连接，未返回的调用返回ErrNodeDown
+func (n *Node) Close() {
+	if n.server != nil {
+		n.server.Close()
+	}
+
+	n.mu.Lock()
+	clients := n.clients
+	n.clients = nil
+	n.mu.Unlock()
+	for _, client := range clients {
+		client.Close()
+	}
+}
+
+// connect 按注册表调整主动连接的节点
+func (n *Node) connect() {
+	targets := make(map[string]struct{})
+	for _, addr := range n.ConnAddrs {
+		targets[addr] = struct{}{}
+	}
+	self, _ := n.Registry.Node(n.Name)
+	for _, peer := range n.Registry.Nodes() {
+		if peer.Name == n.Name || peer.Addr == "" || len(peer.Services) == 0 {
+			continue
+		}
+		if len(self.Services) > 0 && n.Name > peer.Name {
+			continue
+		}
+		targets[peer.Addr] = struct{}{}
+	}
+
+	n.mu.Lock()
+	if n.clients == nil {
+		n.mu.Unlock()
+		return
+	}
+	var removed []*network.TCPClient
+	for addr, client := range n.clients {
+		if _, ok := targets[addr]; !ok {
+			delete(n.clients, addr)
+			removed = append(removed, client)
+		}
+	}
+	for addr := range targets {
+		if _, ok := n.clients[addr]; ok {
+			continue
+		}
+		client := new(network.TCPClient)
+		client.Addr = addr
+		client.ConnNum = 1
+		client.ConnectInterval = n.ConnectInterval
+		client.AutoReconnect = true
+		client.PendingWriteNum = n.PendingWriteNum
+		client.LenMsgLen = 4
+		client.MaxMsgLen = n.MaxMsgLen
+		client.NewAgent = n.newAgent
+
+		client.Start()
+		n.clients[addr] = client
+	}
+	n.mu.Unlock()
+
+	for _, client := range removed {
+		client.Close()
+	}
+}
+
+// auth 握手签名，没有配置密钥时为空
+// proof 用共享密钥对挑战签名，prover是应答方，verifier是发出挑战的一方，签名不能反射回发出挑战的一方
+func (n *Node) proof(nonce []byte, prover, verifier string) string {
+	if n.Secret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(n.Secret))
+	mac.Write(nonce)
+	mac.Write([]byte{0})
+	mac.Write([]byte(prover))
+	mac.Write([]byte{0})
+	mac.Write([]byte(verifier))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isLoopback 监听地址是否只在本机可达
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// addLink 登记握手成功的连接，同名的旧连接被断开
+func (n *Node) addLink(a *Agent) {
+	n.mu.Lock()
+	old := n.links[a.name]
+	n.links[a.name] = a
+	n.mu.Unlock()
+	if old != nil {
+		log.Release("cluster node %v reconnected, close the old link %v", a.name, old.conn.RemoteAddr())
+		old.conn.Close()
+	}
+}
+
+func (n *Node) removeLink(a *Agent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.links[a.name] == a {
+		delete(n.links, a.name)
+	}
+}
+
+// Links 已连接的节点名
+func (n *Node) Links() []string {
+	n.mu.Lock()
+	names := make([]string, 0, len(n.links))
+	for name := range n.links {
+		names = append(names, name)
+	}
+	n.mu.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+func (n *Node) service(name string) *chanrpc.Server {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.services[name]
+}
+
+func (n *Node) link(name string) *Agent {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.links[name]
+}
+
+// pick 选择提供服务的节点，本节点提供时直接本地调用，否则按注册表顺序选第一个已连接的节点
+// 注册表中没有该服务时只能本地调用
+func (n *Node) pick(service string) (*chanrpc.Server, *Agent, error) {
+	var providers []string
+	if n.Registry != nil {
+		providers = n.Registry.Providers(service)
+	}
+	if local := n.service(service); local != nil {
+		if len(providers) == 0 {
+			return local, nil, nil
+		}
+		for _, name := range providers {
+			if name == n.Name {
+				return local, nil, nil
+			}
+		}
+	}
+	for _, name := range providers {
+		if a := n.link(name); a != nil {
+			return nil, a, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: %v", ErrServiceUnavailable, service)
+}
+
+// pickNode 指定节点上的服务，节点是本节点时本地调用
+func (n *Node) pickNode(node, service string) (*chanrpc.Server, *Agent, error) {
+	if node == n.Name {
+		if local := n.service(service); local != nil {
+			return local, nil, nil
+		}
+		return nil, nil, fmt.Errorf("%w: %v", ErrServiceUnavailable, service)
+	}
+	if a := n.link(node); a != nil {
+		return nil, a, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %v", ErrNodeDown, node)
+}
+
+// GoNode 调用指定节点上服务的函数，不等待返回
+func (n *Node) GoNode(node, service, id string, args ...interface{}) error {
+	local, a, err := n.pickNode(node, service)
+	if err != nil {
+		return err
+	}
+	if local != nil {
+		local.Go(id, args...)
+		return nil
+	}
+	return a.send(&packet{Type: packetGo, Service: service, Id: id, Args: args})
+}
+
+// CallNode 调用指定节点上服务的函数，返回多个值
+func (n *Node) CallNode(node, service, id string, args ...interface{}) ([]interface{}, error) {
+	local, a, err := n.pickNode(node, service)
+	if err != nil {
+		return nil, err
+	}
+	if local != nil {
+		return local.CallN(id, args...)
+	}
+	return a.call(&packet{Type: packetCallN, Service: service, Id: id, Args: args}, n.CallTimeout)
+}
+
+// Go 调用服务的函数，不等待返回
+func (n *Node) Go(service, id string, args ...interface{}) error {
+	local, a, err := n.pick(service)
+	if err != nil {
+		return err
+	}
+	if local != nil {
+		local.Go(id, args...)
+		return nil
+	}
+	return a.send(&packet{Type: packetGo, Service: service, Id: id, Args: args})
+}
+
+// Call0 调用服务的函数，等待执行完成
+func (n *Node) Call0(service, id string, args ...interface{}) error {
+	local, a, err := n.pick(service)
+	if err != nil {
+		return err
+	}
+	if local != nil {
+		return local.Call0(id, args...)
+	}
+	_, err = a.call(&packet{Type: packetCall0, Service: service, Id: id, Args: args}, n.CallTimeout)
+	return err
+}
+
+// Call1 调用服务的函数，返回一个值
+func (n *Node) Call1(service, id string, args ...interface{}) (interface{}, error) {
+	local, a, err := n.pick(service)
+	if err != nil {
+		return nil, err
+	}
+	if local != nil {
+		return local.Call1(id, args...)
+	}
+	ret, err := a.call(&packet{Type: packetCall1, Service: service, Id: id, Args: args}, n.CallTimeout)
+	if err != nil || len(ret) == 0 {
+		return nil, err
+	}
+	return ret[0], nil
+}
+
+// CallN 调用服务的函数，返回多个值
+func (n *Node) CallN(service, id string, args ...interface{}) ([]interface{}, error) {
+	local, a, err := n.pick(service)
+	if err != nil {
+		return nil, err
+	}
+	if local != nil {
+		return local.CallN(id, args...)
+	}
+	return a.call(&packet{Type: packetCallN, Service: service, Id: id, Args: args}, n.CallTimeout)
+}
+
+// serve 执行其他节点的调用，Go按收到的顺序投递，Call在单独的goroutine中等待返回
+func (n *Node) serve(a *Agent, p *packet) {
+	s := n.service(p.Service)
+	if s == nil {
+		if p.Type != packetGo {
+			a.send(&packet{Type: packetReply, Seq: p.Seq, Err: fmt.Sprintf("%v: %v", ErrServiceUnavailable, p.Service)})
+		}
+		return
+	}
+	if p.Type == packetGo {
+		s.Go(p.Id, p.Args...)
+		return
+	}
+
+	go func() {
+		reply := &packet{Type: packetReply, Seq: p.Seq}
+		var err error
+		switch p.Type {
+		case packetCall0:
+			err = s.Call0(p.Id, p.Args...)
+		case packetCall1:
+			var ret interface{}
+			ret, err = s.Call1(p.Id, p.Args...)
+			reply.Ret = []interface{}{ret}
+		case packetCallN:
+			reply.Ret, err = s.CallN(p.Id, p.Args...)
+		default:
+			err = fmt.Errorf("unknown packet type %v", p.Type)
+		}
+		if err != nil {
+			reply.Err = err.Error()
+		}
+		if err := a.send(reply); err != nil {
+			// 返回值无法编码时把错误返回给调用方，避免等到超时
+			a.send(&packet{Type: packetReply, Seq: p.Seq, Err: err.Error()})
+		}
+	}()
+}
@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"gameserver/core/conf"
+)
+
+// Registry 服务注册表，记录每个节点的地址和它提供的服务
+// 同一个服务可以由多个节点提供，调用时按注册表中的顺序选第一个可用的节点
+type Registry struct {
+	mu    sync.RWMutex
+	nodes []conf.ClusterNode
+}
+
+// NewRegistry 用静态配置创建注册表
+func NewRegistry(nodes []conf.ClusterNode) *Registry {
+	r := new(Registry)
+	r.Set(nodes)
+	return r
+}
+
+// LoadRegistry 从json文件加载注册表，文件内容为ClusterNode数组
+func LoadRegistry(path string) (*Registry, error) {
+	nodes, err := readRegistry(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRegistry(nodes), nil
+}
+
+func readRegistry(path string) ([]conf.ClusterNode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []conf.ClusterNode
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("parse registry %v: %v", path, err)
+	}
+	return nodes, nil
+}
+
+// Set 替换注册表中的所有节点
+func (r *Registry) Set(nodes []conf.ClusterNode) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nodes = append([]conf.ClusterNode(nil), nodes...)
+}
+
+// Nodes 所有节点
+func (r *Registry) Nodes() []conf.ClusterNode {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]conf.ClusterNode(nil), r.nodes...)
+}
+
+// Node 按名字查找节点
+func (r *Registry) Node(name string) (conf.ClusterNode, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, n := range r.nodes {
+		if n.Name == name {
+			return n, true
+		}
+	}
+	return conf.ClusterNode{}, false
+}
+
+// Providers 提供该服务的节点名，按注册表中的顺序
+func (r *Registry) Providers(service string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var names []string
+	for _, n := range r.nodes {
+		for _, s := range n.Services {
+			if s == service {
+				names = append(names, n.Name)
+				break
+			}
+		}
+	}
+	return names
+}
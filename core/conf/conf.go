@@ -1,12 +1,20 @@
 package conf
 
+import "time"
+
 var (
 	LenStackBuf = 4096
 
 	// log
-	LogLevel string
-	LogPath  string
-	LogFlag  int
+	LogLevel          string
+	LogPath           string
+	LogFlag           int
+	LogFormat         string            // text或json
+	LogMaxSize        int64             // 单个日志文件的最大字节数，0表示不按大小轮转
+	LogRotateInterval time.Duration     // 按时间轮转的周期，0表示不按时间轮转
+	LogMaxBackups     int               // 保留的历史日志文件数，0表示不限
+	LogMaxAge         time.Duration     // 历史日志文件的保留时间，0表示不限
+	LogModuleLevels   map[string]string // 模块 -> 级别，覆盖LogLevel
 
 	// console
 	ConsolePort   int
@@ -14,7 +22,19 @@ var (
 	ProfilePath   string
 
 	// cluster
+	NodeName        string // 本节点名，握手时发给对端，在注册表中唯一
 	ListenAddr      string
-	ConnAddrs       []string
+	ConnAddrs       []string // 不在注册表中的节点地址，总是连接
 	PendingWriteNum int
+	ClusterSecret   string        // 节点间握手的共享密钥，为空时不校验，只允许监听本机回环地址
+	ClusterNodes    []ClusterNode // 静态服务注册表
+	ClusterRegistry string        // 服务注册表文件，设置时代替ClusterNodes
+	CallTimeout     = 3 * time.Second
 )
+
+// ClusterNode 注册表中的一个节点和它提供的服务
+type ClusterNode struct {
+	Name     string
+	Addr     string
+	Services []string
+}
@@ -3,11 +3,15 @@ package console
 import (
 	"fmt"
 	"gameserver/core/chanrpc"
+	"gameserver/core/cluster"
 	"gameserver/core/conf"
 	"gameserver/core/log"
+	"gameserver/core/network"
 	"os"
 	"path"
 	"runtime/pprof"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -15,6 +19,9 @@ var commands = []Command{
 	new(CommandHelp),
 	new(CommandCPUProf),
 	new(CommandProf),
+	new(CommandHandover),
+	new(CommandCluster),
+	new(CommandLogLevel),
 }
 
 type Command interface {
@@ -213,3 +220,130 @@ func (c *CommandProf) run(args []string) string {
 
 	return fn
 }
+
+// handover
+type CommandHandover struct{}
+
+func (c *CommandHandover) name() string {
+	return "handover"
+}
+
+func (c *CommandHandover) help() string {
+	return "start a new process with inherited listeners and drain this one"
+}
+
+func (c *CommandHandover) run([]string) string {
+	p, err := network.StartInheritor()
+	if err != nil {
+		return err.Error()
+	}
+	log.Release("handover listeners to process %v", p.Pid)
+
+	// 新进程接管监听后，当前进程走正常的关闭排空流程
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err.Error()
+	}
+	if err := self.Signal(os.Interrupt); err != nil {
+		return fmt.Sprintf("new process %v started, stop this process manually: %v", p.Pid, err)
+	}
+	return fmt.Sprintf("new process %v started, draining", p.Pid)
+}
+
+// cluster
+type CommandCluster struct{}
+
+func (c *CommandCluster) name() string {
+	return "cluster"
+}
+
+func (c *CommandCluster) help() string {
+	return "show cluster nodes or reload the registry"
+}
+
+func (c *CommandCluster) usage() string {
+	return "Usage: cluster [reload]\r\n" +
+		"  reload - reload the registry file and connect to new nodes"
+}
+
+func (c *CommandCluster) run(args []string) string {
+	if len(args) > 0 {
+		if args[0] != "reload" {
+			return c.usage()
+		}
+		if err := cluster.Reload(); err != nil {
+			return err.Error()
+		}
+	}
+
+	links := make(map[string]bool)
+	for _, name := range cluster.Links() {
+		links[name] = true
+	}
+	lines := []string{fmt.Sprintf("node: %v, linked: %v", conf.NodeName, len(links))}
+	for _, n := range cluster.Nodes() {
+		state := "down"
+		if n.Name == conf.NodeName {
+			state = "self"
+		} else if links[n.Name] {
+			state = "up"
+			delete(links, n.Name)
+		}
+		lines = append(lines, fmt.Sprintf("%v %v %v [%v]", n.Name, n.Addr, state, strings.Join(n.Services, ",")))
+	}
+	// 通过ConnAddrs连接或对端主动连接、不在注册表中的节点
+	for _, name := range cluster.Links() {
+		if links[name] {
+			lines = append(lines, fmt.Sprintf("%v - up []", name))
+		}
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// loglevel
+type CommandLogLevel struct{}
+
+func (c *CommandLogLevel) name() string {
+	return "loglevel"
+}
+
+func (c *CommandLogLevel) help() string {
+	return "show or change log levels"
+}
+
+func (c *CommandLogLevel) usage() string {
+	return "Usage: loglevel [level] | loglevel <module> <level|reset>\r\n" +
+		"  level - debug, release, warn, error or fatal"
+}
+
+func (c *CommandLogLevel) run(args []string) string {
+	var err error
+	switch len(args) {
+	case 0:
+	case 1:
+		err = log.SetLevel(args[0])
+	case 2:
+		if args[1] == "reset" {
+			err = log.SetModuleLevel(args[0], "")
+		} else {
+			err = log.SetModuleLevel(args[0], args[1])
+		}
+	default:
+		return c.usage()
+	}
+	if err != nil {
+		return err.Error() + "\r\n" + c.usage()
+	}
+
+	lines := []string{"level: " + log.Level()}
+	modules := log.ModuleLevels()
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%v: %v", name, modules[name]))
+	}
+	return strings.Join(lines, "\r\n")
+}
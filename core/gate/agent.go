@@ -2,14 +2,22 @@ package gate
 
 import (
 	"net"
+	"time"
 )
 
 type Agent interface {
 	WriteMsg(msg interface{})
+	WriteData(data ...[]byte)
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
 	Close()
 	Destroy()
 	UserData() interface{}
 	SetUserData(data interface{})
+	// RTT 心跳测得的平滑往返时间，还没有测量时为0
+	RTT() time.Duration
+	// ObserveRTT 记录一次心跳测得的往返时间
+	ObserveRTT(rtt time.Duration)
+	// SetIdleTimeout 设置本连接的空闲超时，0表示使用Gate.IdleTimeout，Gate.IdleTimeout为0时不检查
+	SetIdleTimeout(d time.Duration)
 }
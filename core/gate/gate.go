@@ -4,11 +4,21 @@ import (
 	"gameserver/core/chanrpc"
 	"gameserver/core/log"
 	"gameserver/core/network"
+	"gameserver/core/timer"
 	"net"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// server tcp/websocket/kcp服务的公共操作
+type server interface {
+	Start()
+	StopAccept()
+	Close()
+}
+
 type Gate struct {
 	MaxConnNum      int
 	PendingWriteNum int
@@ -26,6 +36,24 @@ type Gate struct {
 	TCPAddr      string
 	LenMsgLen    int
 	LittleEndian bool
+
+	// kcp
+	KCPAddr    string
+	KCPSetting network.KCPSetting
+
+	// drain
+	DrainTimeout time.Duration // 排空等待时间，为0时收到关闭信号立即断开所有连接
+	ClosingMsg   interface{}   // 排空开始时广播给所有客户端的消息
+
+	// idle
+	IdleTimeout time.Duration // 超过该时间没有收到任何消息时断开连接，0表示不检查
+
+	// gateway
+	Forward func(a Agent, data []byte) error // 网关模式：收到的消息不在本进程解析路由，原样转发到后端节点，返回错误时断开连接
+
+	agents      map[*agent]struct{}
+	mutexAgents sync.Mutex
+	idleWheel   *timer.Wheel[*agent]
 }
 
 func (gate *Gate) Run(closeSig chan bool) {
@@ -40,11 +68,7 @@ func (gate *Gate) Run(closeSig chan bool) {
 		wsServer.CertFile = gate.CertFile
 		wsServer.KeyFile = gate.KeyFile
 		wsServer.NewAgent = func(conn *network.WSConn) network.Agent {
-			a := &agent{conn: conn, gate: gate}
-			if gate.AgentChanRPC != nil {
-				gate.AgentChanRPC.Go("NewAgent", a)
-			}
-			return a
+			return gate.newAgent(conn)
 		}
 	}
 
@@ -58,35 +82,167 @@ func (gate *Gate) Run(closeSig chan bool) {
 		tcpServer.MaxMsgLen = gate.MaxMsgLen
 		tcpServer.LittleEndian = gate.LittleEndian
 		tcpServer.NewAgent = func(conn *network.TCPConn) network.Agent {
-			a := &agent{conn: conn, gate: gate}
-			if gate.AgentChanRPC != nil {
-				gate.AgentChanRPC.Go("NewAgent", a)
-			}
-			return a
+			return gate.newAgent(conn)
+		}
+	}
+
+	var kcpServer *network.KCPServer
+	if gate.KCPAddr != "" {
+		kcpServer = new(network.KCPServer)
+		kcpServer.Addr = gate.KCPAddr
+		kcpServer.MaxConnNum = gate.MaxConnNum
+		kcpServer.PendingWriteNum = gate.PendingWriteNum
+		kcpServer.MaxMsgLen = gate.MaxMsgLen
+		kcpServer.KCPSetting = gate.KCPSetting
+		kcpServer.NewAgent = func(conn *network.KCPConn) network.Agent {
+			return gate.newAgent(conn)
 		}
 	}
 
+	var servers []server
 	if wsServer != nil {
-		wsServer.Start()
+		servers = append(servers, wsServer)
 	}
 	if tcpServer != nil {
-		tcpServer.Start()
+		servers = append(servers, tcpServer)
+	}
+	if kcpServer != nil {
+		servers = append(servers, kcpServer)
+	}
+
+	gate.mutexAgents.Lock()
+	gate.agents = make(map[*agent]struct{})
+	gate.mutexAgents.Unlock()
+
+	stopIdle := make(chan struct{})
+	if gate.IdleTimeout > 0 {
+		// 每格为超时的1/8，一圈覆盖两倍的默认超时，单独调长超时的连接到期后重新加入
+		tick := gate.IdleTimeout / 8
+		if tick < 10*time.Millisecond {
+			tick = 10 * time.Millisecond
+		} else if tick > time.Second {
+			tick = time.Second
+		}
+		gate.idleWheel = timer.NewWheel[*agent](tick, int(2*gate.IdleTimeout/tick)+1)
+		go gate.checkIdle(stopIdle)
+	}
+
+	for _, s := range servers {
+		s.Start()
 	}
 	<-closeSig
-	if wsServer != nil {
-		wsServer.Close()
+	if gate.DrainTimeout > 0 {
+		gate.drain(servers)
 	}
-	if tcpServer != nil {
-		tcpServer.Close()
+	for _, s := range servers {
+		s.Close()
 	}
+	close(stopIdle)
 }
 
 func (gate *Gate) OnDestroy() {}
 
+func (gate *Gate) newAgent(conn network.Conn) *agent {
+	a := &agent{conn: conn, gate: gate}
+	a.lastActive.Store(time.Now().UnixNano())
+	gate.mutexAgents.Lock()
+	gate.agents[a] = struct{}{}
+	gate.mutexAgents.Unlock()
+	if gate.idleWheel != nil {
+		gate.idleWheel.Add(a, gate.IdleTimeout)
+	}
+
+	if gate.AgentChanRPC != nil {
+		gate.AgentChanRPC.Go("NewAgent", a)
+	}
+	return a
+}
+
+// AgentNum 当前连接数
+func (gate *Gate) AgentNum() int {
+	gate.mutexAgents.Lock()
+	defer gate.mutexAgents.Unlock()
+	return len(gate.agents)
+}
+
+// Broadcast 向所有连接发送消息
+func (gate *Gate) Broadcast(msg interface{}) {
+	gate.mutexAgents.Lock()
+	agents := make([]*agent, 0, len(gate.agents))
+	for a := range gate.agents {
+		agents = append(agents, a)
+	}
+	gate.mutexAgents.Unlock()
+
+	for _, a := range agents {
+		a.WriteMsg(msg)
+	}
+}
+
+// drain 停止接受新连接，通知客户端后等待其自行断开，超时后由调用方强制关闭
+func (gate *Gate) drain(servers []server) {
+	for _, s := range servers {
+		s.StopAccept()
+	}
+
+	if gate.ClosingMsg != nil {
+		gate.Broadcast(gate.ClosingMsg)
+	}
+
+	log.Release("gate draining, %v agents remain, timeout %v", gate.AgentNum(), gate.DrainTimeout)
+	deadline := time.Now().Add(gate.DrainTimeout)
+	for time.Now().Before(deadline) {
+		if gate.AgentNum() == 0 {
+			log.Release("gate drained")
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	log.Release("gate drain timeout, force closing %v agents", gate.AgentNum())
+}
+
+// checkIdle 时间轮到期时检查连接的截止时间，还没到的按剩余时间重新加入
+// 收到消息只更新时间，不移动时间轮中的位置，连接数多时开销也只与到期的连接数有关
+func (gate *Gate) checkIdle(stop chan struct{}) {
+	ticker := time.NewTicker(gate.idleWheel.Tick())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		for _, a := range gate.idleWheel.Advance() {
+			if a.closed.Load() {
+				continue
+			}
+			idle := now.Sub(time.Unix(0, a.lastActive.Load()))
+			timeout := a.timeout()
+			if idle < timeout {
+				gate.idleWheel.Add(a, timeout-idle)
+				// 与OnClose并发时，保证关闭的连接不会留在时间轮中
+				if a.closed.Load() {
+					gate.idleWheel.Remove(a)
+				}
+				continue
+			}
+			log.Debug("close idle agent: %v, idle %v", a.RemoteAddr(), idle)
+			a.Destroy()
+		}
+	}
+}
+
 type agent struct {
 	conn     network.Conn
 	gate     *Gate
 	userData interface{}
+
+	lastActive  atomic.Int64 // 最近一次收到消息的时间，UnixNano
+	idleTimeout atomic.Int64 // 本连接的空闲超时，0表示使用Gate.IdleTimeout
+	rtt         atomic.Int64 // 平滑后的往返时间
+	closed      atomic.Bool
 }
 
 func (a *agent) Run() {
@@ -96,7 +252,15 @@ func (a *agent) Run() {
 			log.Debug("read message: %v", err)
 			break
 		}
+		a.lastActive.Store(time.Now().UnixNano())
 
+		if a.gate.Forward != nil {
+			if err := a.gate.Forward(a, data); err != nil {
+				log.Debug("forward message error: %v", err)
+				break
+			}
+			continue
+		}
 		if a.gate.Processor != nil {
 			msg, err := a.gate.Processor.Unmarshal(data)
 			if err != nil {
@@ -113,6 +277,14 @@ func (a *agent) Run() {
 }
 
 func (a *agent) OnClose() {
+	a.closed.Store(true)
+	a.gate.mutexAgents.Lock()
+	delete(a.gate.agents, a)
+	a.gate.mutexAgents.Unlock()
+	if a.gate.idleWheel != nil {
+		a.gate.idleWheel.Remove(a)
+	}
+
 	if a.gate.AgentChanRPC != nil {
 		err := a.gate.AgentChanRPC.Call0("CloseAgent", a)
 		if err != nil {
@@ -135,6 +307,14 @@ func (a *agent) WriteMsg(msg interface{}) {
 	}
 }
 
+// WriteData 发送已序列化的消息，广播时同一份数据可写给多个连接
+func (a *agent) WriteData(data ...[]byte) {
+	err := a.conn.WriteMsg(data...)
+	if err != nil {
+		log.Error("write data error: %v", err)
+	}
+}
+
 func (a *agent) LocalAddr() net.Addr {
 	return a.conn.LocalAddr()
 }
@@ -158,3 +338,44 @@ func (a *agent) UserData() interface{} {
 func (a *agent) SetUserData(data interface{}) {
 	a.userData = data
 }
+
+func (a *agent) RTT() time.Duration {
+	return time.Duration(a.rtt.Load())
+}
+
+// ObserveRTT 按TCP的SRTT平滑，新样本占1/8
+func (a *agent) ObserveRTT(rtt time.Duration) {
+	if rtt <= 0 {
+		return
+	}
+	for {
+		old := a.rtt.Load()
+		smoothed := int64(rtt)
+		if old > 0 {
+			smoothed = old + (int64(rtt)-old)/8
+		}
+		if a.rtt.CompareAndSwap(old, smoothed) {
+			return
+		}
+	}
+}
+
+func (a *agent) SetIdleTimeout(d time.Duration) {
+	a.idleTimeout.Store(int64(d))
+	if a.gate.idleWheel == nil || a.closed.Load() {
+		return
+	}
+	// 按新的超时重新计算到期时间，调短时立即生效
+	idle := time.Since(time.Unix(0, a.lastActive.Load()))
+	a.gate.idleWheel.Add(a, a.timeout()-idle)
+	if a.closed.Load() {
+		a.gate.idleWheel.Remove(a)
+	}
+}
+
+func (a *agent) timeout() time.Duration {
+	if d := time.Duration(a.idleTimeout.Load()); d > 0 {
+		return d
+	}
+	return a.gate.IdleTimeout
+}
@@ -1,12 +1,18 @@
 package log
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"path"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,99 +20,322 @@ import (
 const (
 	debugLevel   = 0
 	releaseLevel = 1
-	errorLevel   = 2
-	fatalLevel   = 3
+	warnLevel    = 2
+	errorLevel   = 3
+	fatalLevel   = 4
 )
 
 const (
 	printDebugLevel   = "[debug  ] "
 	printReleaseLevel = "[release] "
+	printWarnLevel    = "[warn   ] "
 	printErrorLevel   = "[error  ] "
 	printFatalLevel   = "[fatal  ] "
 )
 
+var levelNames = []string{"debug", "release", "warn", "error", "fatal"}
+
+// 输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options 日志配置，New只设置级别、目录和flag，其余保持默认：文本格式、不轮转
+type Options struct {
+	Level          string
+	Path           string // 日志目录，为空时输出到标准输出
+	Flag           int
+	Format         string            // text或json
+	MaxSize        int64             // 单个文件的最大字节数，0表示不按大小轮转
+	RotateInterval time.Duration     // 按时间轮转的周期，如24h，按本地时间对齐，0表示不按时间轮转
+	MaxBackups     int               // 保留的历史文件数，0表示不限
+	MaxAge         time.Duration     // 历史文件的保留时间，0表示不限
+	ModuleLevels   map[string]string // 模块 -> 级别，覆盖全局级别
+}
+
+// output 日志的输出目标和级别，同一个Logger派生的子Logger共用
+type output struct {
+	level   atomic.Int32
+	modules atomic.Pointer[map[string]int] // 写时复制
+	flag    int
+	json    bool
+	mu      sync.Mutex
+	writer  io.Writer
+	file    *rotateWriter
+	base    *log.Logger
+	closed  atomic.Bool
+}
+
+// Logger 可以绑定字段的日志，With和Module返回子Logger，与父Logger共用输出和级别
 type Logger struct {
-	level      int
-	baseLogger *log.Logger
-	baseFile   *os.File
+	out    *output // 为nil时使用当前导出的全局Logger的输出
+	module string
+	fields []interface{} // key, value交替
 }
 
-func New(strLevel string, pathname string, flag int) (*Logger, error) {
-	// level
-	var level int
+func parseLevel(strLevel string) (int, error) {
 	switch strings.ToLower(strLevel) {
 	case "debug":
-		level = debugLevel
-	case "release":
-		level = releaseLevel
+		return debugLevel, nil
+	case "release", "info":
+		return releaseLevel, nil
+	case "warn":
+		return warnLevel, nil
 	case "error":
-		level = errorLevel
+		return errorLevel, nil
 	case "fatal":
-		level = fatalLevel
-	default:
-		return nil, errors.New("unknown level: " + strLevel)
+		return fatalLevel, nil
 	}
+	return 0, errors.New("unknown level: " + strLevel)
+}
 
-	// logger
-	var baseLogger *log.Logger
-	var baseFile *os.File
-	if pathname != "" {
-		now := time.Now()
-
-		filename := fmt.Sprintf("%d%02d%02d_%02d_%02d_%02d.log",
-			now.Year(),
-			now.Month(),
-			now.Day(),
-			now.Hour(),
-			now.Minute(),
-			now.Second())
+func New(strLevel string, pathname string, flag int) (*Logger, error) {
+	return NewWithOptions(Options{Level: strLevel, Path: pathname, Flag: flag})
+}
 
-		file, err := os.Create(path.Join(pathname, filename))
-		if err != nil {
+// NewWithOptions 按配置创建Logger
+func NewWithOptions(opts Options) (*Logger, error) {
+	// level
+	level, err := parseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	out := &output{flag: opts.Flag}
+	out.level.Store(int32(level))
+	modules := make(map[string]int)
+	for module, strLevel := range opts.ModuleLevels {
+		if modules[module], err = parseLevel(strLevel); err != nil {
 			return nil, err
 		}
+	}
+	out.modules.Store(&modules)
 
-		baseLogger = log.New(file, "", flag)
-		baseFile = file
+	switch strings.ToLower(opts.Format) {
+	case "", FormatText:
+	case FormatJSON:
+		out.json = true
+	default:
+		return nil, errors.New("unknown log format: " + opts.Format)
+	}
+
+	// writer
+	if opts.Path != "" {
+		w := &rotateWriter{
+			dir:        opts.Path,
+			maxSize:    opts.MaxSize,
+			interval:   opts.RotateInterval,
+			maxBackups: opts.MaxBackups,
+			maxAge:     opts.MaxAge,
+		}
+		if err := w.open(time.Now()); err != nil {
+			return nil, err
+		}
+		out.file = w
+		out.writer = w
 	} else {
-		baseLogger = log.New(os.Stdout, "", flag)
+		out.writer = os.Stdout
 	}
+	out.base = log.New(out.writer, "", opts.Flag)
 
-	// new
-	logger := new(Logger)
-	logger.level = level
-	logger.baseLogger = baseLogger
-	logger.baseFile = baseFile
+	return &Logger{out: out}, nil
+}
 
-	return logger, nil
+func (logger *Logger) output() *output {
+	if logger.out != nil {
+		return logger.out
+	}
+	return gLogger.Load().out
 }
 
 // It's dangerous to call the method on logging
 func (logger *Logger) Close() {
-	if logger.baseFile != nil {
-		logger.baseFile.Close()
+	out := logger.output()
+	if out.closed.Swap(true) {
+		return
+	}
+	if out.file != nil {
+		out.file.Close()
+	}
+}
+
+// With 绑定字段的子Logger，kv为key, value交替，如 With("playerId", id, "actor", actorId)
+func (logger *Logger) With(kv ...interface{}) *Logger {
+	if len(kv)%2 != 0 {
+		kv = append(kv, "!MISSING")
+	}
+	child := *logger
+	child.fields = append(append(make([]interface{}, 0, len(logger.fields)+len(kv)), logger.fields...), kv...)
+	return &child
+}
+
+// Module 模块的子Logger，输出时带module字段，级别按SetModuleLevel的设置覆盖
+func (logger *Logger) Module(name string) *Logger {
+	child := logger.With("module", name)
+	child.module = name
+	return child
+}
+
+// SetLevel 运行时修改级别，对该Logger派生的所有子Logger生效
+func (logger *Logger) SetLevel(strLevel string) error {
+	level, err := parseLevel(strLevel)
+	if err != nil {
+		return err
+	}
+	logger.output().level.Store(int32(level))
+	return nil
+}
+
+// Level 当前级别
+func (logger *Logger) Level() string {
+	return levelNames[logger.output().level.Load()]
+}
+
+// SetModuleLevel 设置模块的级别，strLevel为空时取消覆盖
+func (logger *Logger) SetModuleLevel(module string, strLevel string) error {
+	out := logger.output()
+	level := -1
+	if strLevel != "" {
+		var err error
+		if level, err = parseLevel(strLevel); err != nil {
+			return err
+		}
+	}
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	modules := make(map[string]int)
+	for k, v := range *out.modules.Load() {
+		modules[k] = v
+	}
+	if level < 0 {
+		delete(modules, module)
+	} else {
+		modules[module] = level
+	}
+	out.modules.Store(&modules)
+	return nil
+}
+
+// ModuleLevels 覆盖了全局级别的模块
+func (logger *Logger) ModuleLevels() map[string]string {
+	levels := make(map[string]string)
+	for k, v := range *logger.output().modules.Load() {
+		levels[k] = levelNames[v]
 	}
+	return levels
+}
 
-	logger.baseLogger = nil
-	logger.baseFile = nil
+// DebugEnabled 是否输出debug级别，参数开销大的debug日志先检查，避免级别关闭时白白格式化
+func (logger *Logger) DebugEnabled() bool {
+	return logger.enabled(logger.output(), debugLevel)
+}
+
+func (logger *Logger) enabled(out *output, level int) bool {
+	if logger.module != "" {
+		if l, ok := (*out.modules.Load())[logger.module]; ok {
+			return level >= l
+		}
+	}
+	return level >= int(out.level.Load())
 }
 
 func (logger *Logger) doPrintf(level int, printLevel string, format string, a ...interface{}) {
-	if level < logger.level {
+	out := logger.output()
+	if !logger.enabled(out, level) {
 		return
 	}
-	if logger.baseLogger == nil {
+	if out.closed.Load() {
 		panic("logger closed")
 	}
 
-	format = printLevel + format
-	logger.baseLogger.Output(3, fmt.Sprintf(format, a...))
+	msg := fmt.Sprintf(format, a...)
+	if out.json {
+		out.writeJSON(level, msg, logger.fields)
+	} else {
+		out.base.Output(3, printLevel+msg+formatFields(logger.fields))
+	}
 
 	if level == fatalLevel {
 		os.Exit(1)
 	}
 }
 
+// formatFields 文本格式的字段，追加在消息后面，如 playerId=1 module=match
+func formatFields(fields []interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for i := 0; i+1 < len(fields); i += 2 {
+		b.WriteByte(' ')
+		b.WriteString(fmt.Sprint(fields[i]))
+		b.WriteByte('=')
+		s := fmt.Sprint(fields[i+1])
+		if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+			s = strconv.Quote(s)
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// writeJSON 一行一个JSON对象，字段和time、level、caller、msg重名时后者生效
+func (out *output) writeJSON(level int, msg string, fields []interface{}) {
+	entry := make(map[string]interface{}, 4+len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		v := fields[i+1]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		entry[fmt.Sprint(fields[i])] = v
+	}
+	entry["time"] = time.Now().Format("2006-01-02T15:04:05.000Z07:00")
+	entry["level"] = levelNames[level]
+	entry["msg"] = msg
+	if out.flag&(log.Lshortfile|log.Llongfile) != 0 {
+		// runtime.Caller(0)是writeJSON，依次是doPrintf、Debug等、调用者
+		if _, file, line, ok := runtime.Caller(3); ok {
+			if out.flag&log.Lshortfile != 0 {
+				file = file[strings.LastIndexByte(file, '/')+1:]
+			}
+			entry["caller"] = file + ":" + strconv.Itoa(line)
+		}
+	}
+
+	data := marshalEntry(entry)
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.writer.Write(append(data, '\n'))
+}
+
+// marshalEntry 固定time、level、msg在前，其余字段按key排序，不能序列化的值按%v输出
+func marshalEntry(entry map[string]interface{}) []byte {
+	keys := make([]string, 0, len(entry))
+	for k := range entry {
+		switch k {
+		case "time", "level", "msg":
+		default:
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	keys = append([]string{"time", "level", "msg"}, keys...)
+
+	buf := []byte{'{'}
+	for i, k := range keys {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(entry[k])
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(entry[k]))
+		}
+		buf = append(append(append(buf, key...), ':'), value...)
+	}
+	return append(buf, '}')
+}
+
 func (logger *Logger) Debug(format string, a ...interface{}) {
 	logger.doPrintf(debugLevel, printDebugLevel, format, a...)
 }
@@ -115,6 +344,10 @@ func (logger *Logger) Release(format string, a ...interface{}) {
 	logger.doPrintf(releaseLevel, printReleaseLevel, format, a...)
 }
 
+func (logger *Logger) Warn(format string, a ...interface{}) {
+	logger.doPrintf(warnLevel, printWarnLevel, format, a...)
+}
+
 func (logger *Logger) Error(format string, a ...interface{}) {
 	logger.doPrintf(errorLevel, printErrorLevel, format, a...)
 }
@@ -123,31 +356,73 @@ func (logger *Logger) Fatal(format string, a ...interface{}) {
 	logger.doPrintf(fatalLevel, printFatalLevel, format, a...)
 }
 
-var gLogger, _ = New("debug", "", log.LstdFlags)
+var gLogger atomic.Pointer[Logger]
+
+func init() {
+	logger, _ := New("debug", "", log.LstdFlags)
+	gLogger.Store(logger)
+}
 
 // It's dangerous to call the method on logging
 func Export(logger *Logger) {
 	if logger != nil {
-		gLogger = logger
+		gLogger.Store(logger)
 	}
 }
 
+// root 包级别With、Module返回的子Logger不固定输出，Export后跟随新的全局Logger
+var root = &Logger{}
+
 func Debug(format string, a ...interface{}) {
-	gLogger.doPrintf(debugLevel, printDebugLevel, format, a...)
+	gLogger.Load().doPrintf(debugLevel, printDebugLevel, format, a...)
 }
 
 func Release(format string, a ...interface{}) {
-	gLogger.doPrintf(releaseLevel, printReleaseLevel, format, a...)
+	gLogger.Load().doPrintf(releaseLevel, printReleaseLevel, format, a...)
+}
+
+func Warn(format string, a ...interface{}) {
+	gLogger.Load().doPrintf(warnLevel, printWarnLevel, format, a...)
 }
 
 func Error(format string, a ...interface{}) {
-	gLogger.doPrintf(errorLevel, printErrorLevel, format, a...)
+	gLogger.Load().doPrintf(errorLevel, printErrorLevel, format, a...)
 }
 
 func Fatal(format string, a ...interface{}) {
-	gLogger.doPrintf(fatalLevel, printFatalLevel, format, a...)
+	gLogger.Load().doPrintf(fatalLevel, printFatalLevel, format, a...)
+}
+
+// With 全局Logger绑定字段的子Logger
+func With(kv ...interface{}) *Logger {
+	return root.With(kv...)
+}
+
+// Module 全局Logger的模块子Logger，可以在包初始化时创建
+func Module(name string) *Logger {
+	return root.Module(name)
+}
+
+func SetLevel(strLevel string) error {
+	return root.SetLevel(strLevel)
+}
+
+func Level() string {
+	return root.Level()
+}
+
+func DebugEnabled() bool {
+	return root.DebugEnabled()
+}
+
+func SetModuleLevel(module string, strLevel string) error {
+	return root.SetModuleLevel(module, strLevel)
+}
+
+func ModuleLevels() map[string]string {
+	return root.ModuleLevels()
 }
 
 func Close() {
-	gLogger.Close()
+	gLogger.Load().Close()
 }
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 日志文件按创建时间命名，同一秒内轮转多次时加序号
+var logFilePattern = regexp.MustCompile(`^\d{8}_\d{2}_\d{2}_\d{2}(\.\d+)?\.log$`)
+
+// rotateWriter 按大小和时间轮转的日志文件，轮转后按数量和时间清理历史文件
+type rotateWriter struct {
+	dir        string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time // 当前文件所属的轮转周期
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	now := time.Now()
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize ||
+		w.interval > 0 && !w.periodStart(now).Equal(w.period) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotateWriter) open(now time.Time) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate(now)
+}
+
+// rotate 关闭当前文件，创建新的文件并清理过期的历史文件
+func (w *rotateWriter) rotate(now time.Time) error {
+	name := fmt.Sprintf("%d%02d%02d_%02d_%02d_%02d",
+		now.Year(),
+		now.Month(),
+		now.Day(),
+		now.Hour(),
+		now.Minute(),
+		now.Second())
+	filename := path.Join(w.dir, name+".log")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			break
+		}
+		filename = path.Join(w.dir, fmt.Sprintf("%s.%d.log", name, i))
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file = file
+	w.size = 0
+	if w.interval > 0 {
+		w.period = w.periodStart(now)
+	}
+	w.cleanup(path.Base(filename), now)
+	return nil
+}
+
+// periodStart 当前轮转周期的起点，按本地时间对齐，如24h的周期从本地零点开始
+// time.Truncate按绝对时间对齐，在UTC以外的时区需要先加上时区偏移
+func (w *rotateWriter) periodStart(now time.Time) time.Time {
+	_, offset := now.Zone()
+	shift := time.Duration(offset) * time.Second
+	return now.Add(shift).Truncate(w.interval).Add(-shift)
+}
+
+// cleanup 删除超过保留数量或保留时间的历史文件，不包括当前文件
+func (w *rotateWriter) cleanup(current string, now time.Time) {
+	if w.maxBackups <= 0 && w.maxAge <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return
+	}
+	type backup struct {
+		name    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == current || !logFilePattern.MatchString(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{e.Name(), info.ModTime()})
+	}
+	// 新的在前
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].name > backups[j].name
+		}
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+	for i, b := range backups {
+		if w.maxBackups > 0 && i >= w.maxBackups || w.maxAge > 0 && now.Sub(b.modTime) > w.maxAge {
+			os.Remove(path.Join(w.dir, b.name))
+		}
+	}
+}
+
+func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -48,6 +48,8 @@ func (s *Skeleton) Run(closeSig chan bool) {
 	for {
 		select {
 		case <-closeSig:
+			// 关闭前先执行完已入队的调用，避免丢失进行中的业务
+			s.execPending()
 			s.commandServer.Close()
 			s.server.Close()
 			for !s.g.Idle() || !s.client.Idle() {
@@ -69,6 +71,19 @@ func (s *Skeleton) Run(closeSig chan bool) {
 	}
 }
 
+func (s *Skeleton) execPending() {
+	for {
+		select {
+		case ci := <-s.server.ChanCall:
+			s.server.Exec(ci)
+		case ri := <-s.client.ChanAsynRet:
+			s.client.Cb(ri)
+		default:
+			return
+		}
+	}
+}
+
 func (s *Skeleton) AfterFunc(d time.Duration, cb func()) *timer.Timer {
 	if s.TimerDispatcherLen == 0 {
 		panic("invalid TimerDispatcherLen")
@@ -0,0 +1,719 @@
+// Package kcp 实现KCP可靠UDP协议（参考ikcp），只负责协议状态机，收发由调用方通过output和Input驱动
+// 协议格式与ikcp保持一致，使用消息模式，可直接与标准kcp客户端互通
+package kcp
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	rtoNdl     = 30 // nodelay模式下的最小rto
+	rtoMin     = 100
+	rtoDef     = 200
+	rtoMax     = 60000
+	cmdPush    = 81 // 数据
+	cmdAck     = 82 // ack
+	cmdWask    = 83 // 询问对端窗口
+	cmdWins    = 84 // 告知本端窗口
+	askSend    = 1
+	askTell    = 2
+	wndSnd     = 32
+	wndRcv     = 128 // 需不小于最大分片数
+	mtuDef     = 1400
+	interval   = 100
+	deadLink   = 20
+	threshInit = 2
+	threshMin  = 2
+	probeInit  = 7000   // 窗口探测初始间隔
+	probeLimit = 120000 // 窗口探测最大间隔
+
+	// Overhead 每个分片的头部长度
+	Overhead = 24
+)
+
+var refTime = time.Now()
+
+func currentMs() uint32 {
+	return uint32(time.Since(refTime) / time.Millisecond)
+}
+
+func timediff(later, earlier uint32) int32 {
+	return int32(later - earlier)
+}
+
+type segment struct {
+	conv     uint32
+	cmd      uint8
+	frg      uint8
+	wnd      uint16
+	ts       uint32
+	sn       uint32
+	una      uint32
+	rto      uint32
+	xmit     uint32
+	resendts uint32
+	fastack  uint32
+	data     []byte
+}
+
+// encode 写入头部，返回写入长度
+func (seg *segment) encode(ptr []byte) int {
+	binary.LittleEndian.PutUint32(ptr, seg.conv)
+	ptr[4] = seg.cmd
+	ptr[5] = seg.frg
+	binary.LittleEndian.PutUint16(ptr[6:], seg.wnd)
+	binary.LittleEndian.PutUint32(ptr[8:], seg.ts)
+	binary.LittleEndian.PutUint32(ptr[12:], seg.sn)
+	binary.LittleEndian.PutUint32(ptr[16:], seg.una)
+	binary.LittleEndian.PutUint32(ptr[20:], uint32(len(seg.data)))
+	return Overhead
+}
+
+type ackItem struct {
+	sn uint32
+	ts uint32
+}
+
+// KCP 单个会话的协议状态，非goroutine安全
+type KCP struct {
+	conv, mtu, mss, state  uint32
+	sndUna, sndNxt, rcvNxt uint32
+	ssthresh               uint32
+	rxRttvar, rxSrtt       int32
+	rxRto, rxMinrto        uint32
+	sndWnd, rcvWnd, rmtWnd uint32
+	cwnd, probe            uint32
+	interval, tsFlush      uint32
+	nodelay, updated       uint32
+	tsProbe, probeWait     uint32
+	deadLink, incr         uint32
+	fastresend             int32
+	nocwnd                 int32
+
+	sndQueue []segment
+	rcvQueue []segment
+	sndBuf   []segment
+	rcvBuf   []segment
+	acklist  []ackItem
+
+	buffer []byte
+	output func(buf []byte)
+}
+
+// NewKCP conv需通信双方一致，output用于发送底层数据包
+func NewKCP(conv uint32, output func(buf []byte)) *KCP {
+	kcp := new(KCP)
+	kcp.conv = conv
+	kcp.sndWnd = wndSnd
+	kcp.rcvWnd = wndRcv
+	kcp.rmtWnd = wndRcv
+	kcp.mtu = mtuDef
+	kcp.mss = kcp.mtu - Overhead
+	kcp.buffer = make([]byte, kcp.mtu)
+	kcp.rxRto = rtoDef
+	kcp.rxMinrto = rtoMin
+	kcp.interval = interval
+	kcp.tsFlush = interval
+	kcp.ssthresh = threshInit
+	kcp.deadLink = deadLink
+	kcp.output = output
+	return kcp
+}
+
+func removeFront(q []segment, n int) []segment {
+	newn := copy(q, q[n:])
+	for i := newn; i < len(q); i++ {
+		q[i] = segment{}
+	}
+	return q[:newn]
+}
+
+// PeekSize 下一条完整消息的长度，没有完整消息时返回-1
+func (kcp *KCP) PeekSize() int {
+	if len(kcp.rcvQueue) == 0 {
+		return -1
+	}
+
+	seg := &kcp.rcvQueue[0]
+	if seg.frg == 0 {
+		return len(seg.data)
+	}
+	if len(kcp.rcvQueue) < int(seg.frg)+1 {
+		return -1
+	}
+
+	length := 0
+	for k := range kcp.rcvQueue {
+		seg := &kcp.rcvQueue[k]
+		length += len(seg.data)
+		if seg.frg == 0 {
+			break
+		}
+	}
+	return length
+}
+
+// Recv 读取一条完整消息，返回长度；没有消息返回-1，buffer不足返回-2
+func (kcp *KCP) Recv(buffer []byte) int {
+	peeksize := kcp.PeekSize()
+	if peeksize < 0 {
+		return -1
+	}
+	if peeksize > len(buffer) {
+		return -2
+	}
+
+	fastRecover := len(kcp.rcvQueue) >= int(kcp.rcvWnd)
+
+	n, count := 0, 0
+	for k := range kcp.rcvQueue {
+		seg := &kcp.rcvQueue[k]
+		copy(buffer[n:], seg.data)
+		n += len(seg.data)
+		count++
+		if seg.frg == 0 {
+			break
+		}
+	}
+	if count > 0 {
+		kcp.rcvQueue = removeFront(kcp.rcvQueue, count)
+	}
+
+	kcp.moveRcvBuf()
+
+	// 接收窗口从满变为可用，主动告知对端
+	if len(kcp.rcvQueue) < int(kcp.rcvWnd) && fastRecover {
+		kcp.probe |= askTell
+	}
+	return n
+}
+
+// moveRcvBuf 把rcvBuf中连续的分片移动到rcvQueue
+func (kcp *KCP) moveRcvBuf() {
+	count := 0
+	for k := range kcp.rcvBuf {
+		seg := &kcp.rcvBuf[k]
+		if seg.sn == kcp.rcvNxt && len(kcp.rcvQueue)+count < int(kcp.rcvWnd) {
+			kcp.rcvNxt++
+			count++
+		} else {
+			break
+		}
+	}
+	if count > 0 {
+		kcp.rcvQueue = append(kcp.rcvQueue, kcp.rcvBuf[:count]...)
+		kcp.rcvBuf = removeFront(kcp.rcvBuf, count)
+	}
+}
+
+// Send 发送一条消息，超过mss会被分片；buffer为空返回-1，分片过多返回-2
+func (kcp *KCP) Send(buffer []byte) int {
+	if len(buffer) == 0 {
+		return -1
+	}
+
+	count := (len(buffer) + int(kcp.mss) - 1) / int(kcp.mss)
+	if count > 255 || count > int(wndRcv) {
+		return -2
+	}
+
+	for i := 0; i < count; i++ {
+		size := len(buffer)
+		if size > int(kcp.mss) {
+			size = int(kcp.mss)
+		}
+		seg := segment{data: make([]byte, size)}
+		copy(seg.data, buffer[:size])
+		seg.frg = uint8(count - i - 1)
+		kcp.sndQueue = append(kcp.sndQueue, seg)
+		buffer = buffer[size:]
+	}
+	return 0
+}
+
+func (kcp *KCP) updateAck(rtt int32) {
+	if kcp.rxSrtt == 0 {
+		kcp.rxSrtt = rtt
+		kcp.rxRttvar = rtt / 2
+	} else {
+		delta := rtt - kcp.rxSrtt
+		if delta < 0 {
+			delta = -delta
+		}
+		kcp.rxRttvar = (3*kcp.rxRttvar + delta) / 4
+		kcp.rxSrtt = (7*kcp.rxSrtt + rtt) / 8
+		if kcp.rxSrtt < 1 {
+			kcp.rxSrtt = 1
+		}
+	}
+
+	rto := uint32(kcp.rxSrtt) + max(kcp.interval, uint32(4*kcp.rxRttvar))
+	kcp.rxRto = min(max(kcp.rxMinrto, rto), rtoMax)
+}
+
+func (kcp *KCP) shrinkBuf() {
+	if len(kcp.sndBuf) > 0 {
+		kcp.sndUna = kcp.sndBuf[0].sn
+	} else {
+		kcp.sndUna = kcp.sndNxt
+	}
+}
+
+func (kcp *KCP) parseAck(sn uint32) {
+	if timediff(sn, kcp.sndUna) < 0 || timediff(sn, kcp.sndNxt) >= 0 {
+		return
+	}
+
+	for k := range kcp.sndBuf {
+		seg := &kcp.sndBuf[k]
+		if sn == seg.sn {
+			copy(kcp.sndBuf[k:], kcp.sndBuf[k+1:])
+			kcp.sndBuf[len(kcp.sndBuf)-1] = segment{}
+			kcp.sndBuf = kcp.sndBuf[:len(kcp.sndBuf)-1]
+			break
+		}
+		if timediff(sn, seg.sn) < 0 {
+			break
+		}
+	}
+}
+
+func (kcp *KCP) parseFastack(sn uint32) {
+	if timediff(sn, kcp.sndUna) < 0 || timediff(sn, kcp.sndNxt) >= 0 {
+		return
+	}
+
+	for k := range kcp.sndBuf {
+		seg := &kcp.sndBuf[k]
+		if timediff(sn, seg.sn) < 0 {
+			break
+		} else if sn != seg.sn {
+			seg.fastack++
+		}
+	}
+}
+
+func (kcp *KCP) parseUna(una uint32) {
+	count := 0
+	for k := range kcp.sndBuf {
+		if timediff(una, kcp.sndBuf[k].sn) > 0 {
+			count++
+		} else {
+			break
+		}
+	}
+	if count > 0 {
+		kcp.sndBuf = removeFront(kcp.sndBuf, count)
+	}
+}
+
+func (kcp *KCP) parseData(newseg segment) {
+	sn := newseg.sn
+	if timediff(sn, kcp.rcvNxt+kcp.rcvWnd) >= 0 || timediff(sn, kcp.rcvNxt) < 0 {
+		return
+	}
+
+	insertIdx := 0
+	repeat := false
+	for i := len(kcp.rcvBuf) - 1; i >= 0; i-- {
+		seg := &kcp.rcvBuf[i]
+		if seg.sn == sn {
+			repeat = true
+			break
+		}
+		if timediff(sn, seg.sn) > 0 {
+			insertIdx = i + 1
+			break
+		}
+	}
+
+	if !repeat {
+		// 输入缓冲由调用方复用，这里需要拷贝
+		data := make([]byte, len(newseg.data))
+		copy(data, newseg.data)
+		newseg.data = data
+
+		kcp.rcvBuf = append(kcp.rcvBuf, segment{})
+		copy(kcp.rcvBuf[insertIdx+1:], kcp.rcvBuf[insertIdx:])
+		kcp.rcvBuf[insertIdx] = newseg
+	}
+
+	kcp.moveRcvBuf()
+}
+
+// Input 输入一个底层数据包，conv不匹配返回-1，数据不完整返回-2，命令非法返回-3
+func (kcp *KCP) Input(data []byte) int {
+	prevUna := kcp.sndUna
+	var maxack uint32
+	flag := false
+
+	if len(data) < Overhead {
+		return -1
+	}
+
+	current := currentMs()
+	for len(data) >= Overhead {
+		conv := binary.LittleEndian.Uint32(data)
+		cmd := data[4]
+		frg := data[5]
+		wnd := binary.LittleEndian.Uint16(data[6:])
+		ts := binary.LittleEndian.Uint32(data[8:])
+		sn := binary.LittleEndian.Uint32(data[12:])
+		una := binary.LittleEndian.Uint32(data[16:])
+		length := binary.LittleEndian.Uint32(data[20:])
+		data = data[Overhead:]
+
+		if conv != kcp.conv {
+			return -1
+		}
+		if uint32(len(data)) < length {
+			return -2
+		}
+		if cmd != cmdPush && cmd != cmdAck && cmd != cmdWask && cmd != cmdWins {
+			return -3
+		}
+
+		kcp.rmtWnd = uint32(wnd)
+		kcp.parseUna(una)
+		kcp.shrinkBuf()
+
+		switch cmd {
+		case cmdAck:
+			if rtt := timediff(current, ts); rtt >= 0 {
+				kcp.updateAck(rtt)
+			}
+			kcp.parseAck(sn)
+			kcp.shrinkBuf()
+			if !flag {
+				flag = true
+				maxack = sn
+			} else if timediff(sn, maxack) > 0 {
+				maxack = sn
+			}
+		case cmdPush:
+			if timediff(sn, kcp.rcvNxt+kcp.rcvWnd) < 0 {
+				kcp.acklist = append(kcp.acklist, ackItem{sn: sn, ts: ts})
+				if timediff(sn, kcp.rcvNxt) >= 0 {
+					kcp.parseData(segment{
+						conv: conv,
+						cmd:  cmd,
+						frg:  frg,
+						wnd:  wnd,
+						ts:   ts,
+						sn:   sn,
+						una:  una,
+						data: data[:length],
+					})
+				}
+			}
+		case cmdWask:
+			kcp.probe |= askTell
+		}
+
+		data = data[length:]
+	}
+
+	if flag {
+		kcp.parseFastack(maxack)
+	}
+
+	// 拥塞窗口增长
+	if timediff(kcp.sndUna, prevUna) > 0 && kcp.cwnd < kcp.rmtWnd {
+		mss := kcp.mss
+		if kcp.cwnd < kcp.ssthresh {
+			kcp.cwnd++
+			kcp.incr += mss
+		} else {
+			if kcp.incr < mss {
+				kcp.incr = mss
+			}
+			kcp.incr += (mss*mss)/kcp.incr + mss/16
+			if (kcp.cwnd+1)*mss <= kcp.incr {
+				kcp.cwnd = (kcp.incr + mss - 1) / mss
+			}
+		}
+		if kcp.cwnd > kcp.rmtWnd {
+			kcp.cwnd = kcp.rmtWnd
+			kcp.incr = kcp.rmtWnd * mss
+		}
+	}
+	return 0
+}
+
+func (kcp *KCP) wndUnused() uint16 {
+	if len(kcp.rcvQueue) < int(kcp.rcvWnd) {
+		return uint16(int(kcp.rcvWnd) - len(kcp.rcvQueue))
+	}
+	return 0
+}
+
+// Flush 发送ack、窗口探测以及待发送/需重传的数据，ackOnly为true时只发送ack
+func (kcp *KCP) Flush(ackOnly bool) {
+	var seg segment
+	seg.conv = kcp.conv
+	seg.cmd = cmdAck
+	seg.wnd = kcp.wndUnused()
+	seg.una = kcp.rcvNxt
+
+	buffer := kcp.buffer
+	offset := 0
+	makeSpace := func(space int) {
+		if offset+space > int(kcp.mtu) {
+			kcp.output(buffer[:offset])
+			offset = 0
+		}
+	}
+	flushBuffer := func() {
+		if offset > 0 {
+			kcp.output(buffer[:offset])
+			offset = 0
+		}
+	}
+
+	// ack
+	for _, ack := range kcp.acklist {
+		makeSpace(Overhead)
+		seg.sn, seg.ts = ack.sn, ack.ts
+		offset += seg.encode(buffer[offset:])
+	}
+	kcp.acklist = kcp.acklist[:0]
+
+	if ackOnly {
+		flushBuffer()
+		return
+	}
+
+	current := currentMs()
+
+	// 对端窗口为0时定期探测
+	if kcp.rmtWnd == 0 {
+		if kcp.probeWait == 0 {
+			kcp.probeWait = probeInit
+			kcp.tsProbe = current + kcp.probeWait
+		} else if timediff(current, kcp.tsProbe) >= 0 {
+			if kcp.probeWait < probeInit {
+				kcp.probeWait = probeInit
+			}
+			kcp.probeWait += kcp.probeWait / 2
+			if kcp.probeWait > probeLimit {
+				kcp.probeWait = probeLimit
+			}
+			kcp.tsProbe = current + kcp.probeWait
+			kcp.probe |= askSend
+		}
+	} else {
+		kcp.tsProbe = 0
+		kcp.probeWait = 0
+	}
+
+	if kcp.probe&askSend != 0 {
+		seg.cmd = cmdWask
+		makeSpace(Overhead)
+		offset += seg.encode(buffer[offset:])
+	}
+	if kcp.probe&askTell != 0 {
+		seg.cmd = cmdWins
+		makeSpace(Overhead)
+		offset += seg.encode(buffer[offset:])
+	}
+	kcp.probe = 0
+
+	cwnd := min(kcp.sndWnd, kcp.rmtWnd)
+	if kcp.nocwnd == 0 {
+		cwnd = min(kcp.cwnd, cwnd)
+	}
+
+	// sndQueue -> sndBuf
+	count := 0
+	for k := range kcp.sndQueue {
+		if timediff(kcp.sndNxt, kcp.sndUna+cwnd) >= 0 {
+			break
+		}
+		newseg := kcp.sndQueue[k]
+		newseg.conv = kcp.conv
+		newseg.cmd = cmdPush
+		newseg.sn = kcp.sndNxt
+		kcp.sndBuf = append(kcp.sndBuf, newseg)
+		kcp.sndNxt++
+		count++
+	}
+	if count > 0 {
+		kcp.sndQueue = removeFront(kcp.sndQueue, count)
+	}
+
+	resent := uint32(kcp.fastresend)
+	if kcp.fastresend <= 0 {
+		resent = 0xffffffff
+	}
+	rtomin := kcp.rxRto >> 3
+	if kcp.nodelay != 0 {
+		rtomin = 0
+	}
+
+	change, lost := false, false
+	for k := range kcp.sndBuf {
+		segment := &kcp.sndBuf[k]
+		needsend := false
+		if segment.xmit == 0 {
+			needsend = true
+			segment.rto = kcp.rxRto
+			segment.resendts = current + segment.rto + rtomin
+		} else if timediff(current, segment.resendts) >= 0 {
+			needsend = true
+			if kcp.nodelay == 0 {
+				segment.rto += max(segment.rto, kcp.rxRto)
+			} else {
+				segment.rto += segment.rto / 2
+			}
+			segment.resendts = current + segment.rto
+			lost = true
+		} else if segment.fastack >= resent {
+			needsend = true
+			segment.fastack = 0
+			segment.resendts = current + segment.rto
+			change = true
+		}
+
+		if needsend {
+			segment.xmit++
+			segment.ts = current
+			segment.wnd = seg.wnd
+			segment.una = kcp.rcvNxt
+
+			makeSpace(Overhead + len(segment.data))
+			offset += segment.encode(buffer[offset:])
+			offset += copy(buffer[offset:], segment.data)
+
+			if segment.xmit >= kcp.deadLink {
+				kcp.state = 0xffffffff
+			}
+		}
+	}
+	flushBuffer()
+
+	// 快速重传，认为网络轻微拥塞
+	if change {
+		inflight := kcp.sndNxt - kcp.sndUna
+		kcp.ssthresh = max(inflight/2, threshMin)
+		kcp.cwnd = kcp.ssthresh + resent
+		kcp.incr = kcp.cwnd * kcp.mss
+	}
+	// 超时重传，认为网络严重拥塞
+	if lost {
+		kcp.ssthresh = max(kcp.cwnd/2, threshMin)
+		kcp.cwnd = 1
+		kcp.incr = kcp.mss
+	}
+	if kcp.cwnd < 1 {
+		kcp.cwnd = 1
+		kcp.incr = kcp.mss
+	}
+}
+
+// Update 需要按interval周期性调用
+func (kcp *KCP) Update() {
+	current := currentMs()
+	if kcp.updated == 0 {
+		kcp.updated = 1
+		kcp.tsFlush = current
+	}
+
+	slap := timediff(current, kcp.tsFlush)
+	if slap >= 10000 || slap < -10000 {
+		kcp.tsFlush = current
+		slap = 0
+	}
+
+	if slap >= 0 {
+		kcp.tsFlush += kcp.interval
+		if timediff(current, kcp.tsFlush) >= 0 {
+			kcp.tsFlush = current + kcp.interval
+		}
+		kcp.Flush(false)
+	}
+}
+
+// SetMtu 设置mtu，包含kcp头部
+func (kcp *KCP) SetMtu(mtu int) int {
+	if mtu < 50 {
+		return -1
+	}
+	kcp.mtu = uint32(mtu)
+	kcp.mss = kcp.mtu - Overhead
+	kcp.buffer = make([]byte, mtu)
+	return 0
+}
+
+// NoDelay 参数与ikcp_nodelay一致，小于0表示不修改
+// nodelay: 是否启用nodelay；interval: 内部刷新间隔(ms)；resend: 快速重传阈值，0关闭；nc: 是否关闭拥塞控制
+func (kcp *KCP) NoDelay(nodelay, interval, resend, nc int) {
+	if nodelay >= 0 {
+		kcp.nodelay = uint32(nodelay)
+		if nodelay != 0 {
+			kcp.rxMinrto = rtoNdl
+		} else {
+			kcp.rxMinrto = rtoMin
+		}
+	}
+	if interval >= 0 {
+		kcp.interval = uint32(min(max(interval, 10), 5000))
+	}
+	if resend >= 0 {
+		kcp.fastresend = int32(resend)
+	}
+	if nc >= 0 {
+		kcp.nocwnd = int32(nc)
+	}
+}
+
+// WndSize 设置发送/接收窗口（分片数），小于等于0表示不修改
+func (kcp *KCP) WndSize(sndwnd, rcvwnd int) {
+	if sndwnd > 0 {
+		kcp.sndWnd = uint32(sndwnd)
+	}
+	if rcvwnd > 0 {
+		kcp.rcvWnd = uint32(max(rcvwnd, wndRcv))
+	}
+}
+
+// WaitSnd 等待发送（含未确认）的分片数
+func (kcp *KCP) WaitSnd() int {
+	return len(kcp.sndBuf) + len(kcp.sndQueue)
+}
+
+// GetConv 会话id
+func (kcp *KCP) GetConv() uint32 {
+	return kcp.conv
+}
+
+// IsDead 某个分片重传次数过多，认为链路已断开
+func (kcp *KCP) IsDead() bool {
+	return kcp.state == 0xffffffff
+}
+
+// GetConv 读取数据包中的conv
+func GetConv(data []byte) uint32 {
+	if len(data) < Overhead {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(data)
+}
+
+// IsFirstPacket 数据包中是否包含会话的第一个数据分片，用于服务端判断新会话
+func IsFirstPacket(data []byte) bool {
+	for len(data) >= Overhead {
+		cmd := data[4]
+		sn := binary.LittleEndian.Uint32(data[12:])
+		length := binary.LittleEndian.Uint32(data[20:])
+		if cmd == cmdPush && sn == 0 {
+			return true
+		}
+		data = data[Overhead:]
+		if uint32(len(data)) < length {
+			return false
+		}
+		data = data[length:]
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package network
+
+import (
+	"gameserver/core/log"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+type KCPClient struct {
+	sync.Mutex
+	Addr            string
+	ConnNum         int
+	ConnectInterval time.Duration
+	PendingWriteNum int
+	MaxMsgLen       uint32
+	AutoReconnect   bool
+	NewAgent        func(*KCPConn) Agent
+	KCPSetting
+	conns     map[*KCPConn]struct{}
+	wg        sync.WaitGroup
+	closeFlag bool
+}
+
+func (client *KCPClient) Start() {
+	client.init()
+
+	for i := 0; i < client.ConnNum; i++ {
+		client.wg.Add(1)
+		go client.connect()
+	}
+}
+
+func (client *KCPClient) init() {
+	client.Lock()
+	defer client.Unlock()
+
+	if client.ConnNum <= 0 {
+		client.ConnNum = 1
+		log.Release("invalid ConnNum, reset to %v", client.ConnNum)
+	}
+	if client.ConnectInterval <= 0 {
+		client.ConnectInterval = 3 * time.Second
+		log.Release("invalid ConnectInterval, reset to %v", client.ConnectInterval)
+	}
+	if client.PendingWriteNum <= 0 {
+		client.PendingWriteNum = 100
+		log.Release("invalid PendingWriteNum, reset to %v", client.PendingWriteNum)
+	}
+	if client.MaxMsgLen <= 0 {
+		client.MaxMsgLen = 4096
+		log.Release("invalid MaxMsgLen, reset to %v", client.MaxMsgLen)
+	}
+	if client.NewAgent == nil {
+		log.Fatal("NewAgent must not be nil")
+	}
+	if client.conns != nil {
+		log.Fatal("client is running")
+	}
+	client.KCPSetting.init()
+
+	client.conns = make(map[*KCPConn]struct{})
+	client.closeFlag = false
+}
+
+func (client *KCPClient) dial() net.Conn {
+	for {
+		conn, err := net.Dial("udp", client.Addr)
+		if err == nil || client.closeFlag {
+			return conn
+		}
+
+		log.Release("connect to %v error: %v", client.Addr, err)
+		time.Sleep(client.ConnectInterval)
+		continue
+	}
+}
+
+func (client *KCPClient) connect() {
+	defer client.wg.Done()
+
+reconnect:
+	conn := client.dial()
+	if conn == nil {
+		return
+	}
+
+	output := func(b []byte) {
+		conn.Write(b)
+	}
+	kcpConn := newKCPConn(rand.Uint32(), output, conn.LocalAddr(), conn.RemoteAddr(),
+		&client.KCPSetting, client.PendingWriteNum, client.MaxMsgLen)
+
+	client.Lock()
+	if client.closeFlag {
+		client.Unlock()
+		conn.Close()
+		return
+	}
+	client.conns[kcpConn] = struct{}{}
+	client.Unlock()
+
+	go func() {
+		buf := make([]byte, 65536)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				kcpConn.Destroy()
+				return
+			}
+			kcpConn.input(buf[:n])
+		}
+	}()
+	go func() {
+		ticker := time.NewTicker(time.Duration(client.Interval) * time.Millisecond)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if !kcpConn.update(now) {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	agent := client.NewAgent(kcpConn)
+	agent.Run()
+
+	// cleanup
+	kcpConn.Close()
+	<-kcpConn.die
+	client.Lock()
+	delete(client.conns, kcpConn)
+	client.Unlock()
+	agent.OnClose()
+
+	if client.AutoReconnect {
+		time.Sleep(client.ConnectInterval)
+		goto reconnect
+	}
+}
+
+func (client *KCPClient) Close() {
+	client.Lock()
+	client.closeFlag = true
+	for kcpConn := range client.conns {
+		kcpConn.Destroy()
+	}
+	client.conns = nil
+	client.Unlock()
+
+	client.wg.Wait()
+}
@@ -0,0 +1,256 @@
+package network
+
+import (
+	"errors"
+	"gameserver/core/log"
+	"gameserver/core/network/kcp"
+	"net"
+	"sync"
+	"time"
+)
+
+// 主动关闭后等待对端确认剩余数据的最长时间
+const kcpCloseLinger = 3 * time.Second
+
+// KCPSetting kcp参数，含义与ikcp_nodelay/ikcp_wndsize一致
+// 低延迟推荐: NoDelay=true Interval=10 Resend=2 NoCongestion=true
+type KCPSetting struct {
+	NoDelay           bool
+	Interval          int // 内部刷新间隔(ms)
+	Resend            int // 快速重传阈值，0表示关闭
+	NoCongestion      bool
+	SndWnd            int // 发送窗口(分片数)
+	RcvWnd            int // 接收窗口(分片数)
+	MTU               int
+	IdleTimeoutSecond int // 超过该时间未收到任何数据则断开
+}
+
+func (s *KCPSetting) init() {
+	if s.Interval <= 0 {
+		s.Interval = 20
+		log.Release("invalid KCP Interval, reset to %v", s.Interval)
+	}
+	if s.SndWnd <= 0 {
+		s.SndWnd = 128
+		log.Release("invalid KCP SndWnd, reset to %v", s.SndWnd)
+	}
+	if s.RcvWnd <= 0 {
+		s.RcvWnd = 128
+		log.Release("invalid KCP RcvWnd, reset to %v", s.RcvWnd)
+	}
+	if s.MTU <= 0 {
+		s.MTU = 1400
+		log.Release("invalid KCP MTU, reset to %v", s.MTU)
+	}
+	if s.IdleTimeoutSecond <= 0 {
+		s.IdleTimeoutSecond = 30
+		log.Release("invalid KCP IdleTimeoutSecond, reset to %v", s.IdleTimeoutSecond)
+	}
+}
+
+func (s *KCPSetting) apply(k *kcp.KCP) {
+	nodelay, nc := 0, 0
+	if s.NoDelay {
+		nodelay = 1
+	}
+	if s.NoCongestion {
+		nc = 1
+	}
+	k.NoDelay(nodelay, s.Interval, s.Resend, nc)
+	k.WndSize(s.SndWnd, s.RcvWnd)
+	k.SetMtu(s.MTU)
+}
+
+type KCPConn struct {
+	sync.Mutex
+	kcp             *kcp.KCP
+	localAddr       net.Addr
+	remoteAddr      net.Addr
+	readSignal      chan struct{}
+	die             chan struct{}
+	closeFlag       bool
+	destroyFlag     bool
+	pendingWriteNum int
+	maxMsgLen       uint32
+	idleTimeout     time.Duration
+	lastRecvTime    time.Time
+	closeTime       time.Time
+}
+
+func newKCPConn(conv uint32, output func([]byte), localAddr, remoteAddr net.Addr,
+	setting *KCPSetting, pendingWriteNum int, maxMsgLen uint32) *KCPConn {
+	kcpConn := new(KCPConn)
+	kcpConn.kcp = kcp.NewKCP(conv, output)
+	setting.apply(kcpConn.kcp)
+	kcpConn.localAddr = localAddr
+	kcpConn.remoteAddr = remoteAddr
+	kcpConn.readSignal = make(chan struct{}, 1)
+	kcpConn.die = make(chan struct{})
+	kcpConn.pendingWriteNum = pendingWriteNum
+	kcpConn.maxMsgLen = maxMsgLen
+	kcpConn.idleTimeout = time.Duration(setting.IdleTimeoutSecond) * time.Second
+	kcpConn.lastRecvTime = time.Now()
+
+	return kcpConn
+}
+
+// input 输入底层udp数据包
+func (kcpConn *KCPConn) input(data []byte) {
+	kcpConn.Lock()
+	defer kcpConn.Unlock()
+	if kcpConn.destroyFlag {
+		return
+	}
+
+	if kcpConn.kcp.Input(data) < 0 {
+		return
+	}
+	kcpConn.lastRecvTime = time.Now()
+
+	if kcpConn.kcp.PeekSize() >= 0 {
+		select {
+		case kcpConn.readSignal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// update 驱动kcp刷新，连接已销毁时返回false
+func (kcpConn *KCPConn) update(now time.Time) bool {
+	kcpConn.Lock()
+	defer kcpConn.Unlock()
+	if kcpConn.destroyFlag {
+		return false
+	}
+
+	kcpConn.kcp.Update()
+
+	switch {
+	case kcpConn.kcp.IsDead():
+		log.Debug("close conn: dead link")
+	case now.Sub(kcpConn.lastRecvTime) > kcpConn.idleTimeout:
+		log.Debug("close conn: idle timeout")
+	case kcpConn.closeFlag && (kcpConn.kcp.WaitSnd() == 0 || now.Sub(kcpConn.closeTime) > kcpCloseLinger):
+	default:
+		return true
+	}
+
+	kcpConn.doDestroy()
+	return false
+}
+
+func (kcpConn *KCPConn) doDestroy() {
+	if kcpConn.destroyFlag {
+		return
+	}
+
+	kcpConn.closeFlag = true
+	kcpConn.destroyFlag = true
+	close(kcpConn.die)
+}
+
+func (kcpConn *KCPConn) Destroy() {
+	kcpConn.Lock()
+	defer kcpConn.Unlock()
+
+	kcpConn.doDestroy()
+}
+
+// Close 不再发送新数据，已发送的数据被确认（或超时）后销毁
+func (kcpConn *KCPConn) Close() {
+	kcpConn.Lock()
+	defer kcpConn.Unlock()
+	if kcpConn.closeFlag {
+		return
+	}
+
+	kcpConn.closeFlag = true
+	kcpConn.closeTime = time.Now()
+}
+
+func (kcpConn *KCPConn) doWrite(b []byte) {
+	if kcpConn.kcp.WaitSnd() >= kcpConn.pendingWriteNum {
+		log.Debug("close conn: send queue full")
+		kcpConn.doDestroy()
+		return
+	}
+
+	kcpConn.kcp.Send(b)
+	kcpConn.kcp.Flush(false)
+}
+
+func (kcpConn *KCPConn) LocalAddr() net.Addr {
+	return kcpConn.localAddr
+}
+
+func (kcpConn *KCPConn) RemoteAddr() net.Addr {
+	return kcpConn.remoteAddr
+}
+
+// goroutine not safe
+func (kcpConn *KCPConn) ReadMsg() ([]byte, error) {
+	for {
+		kcpConn.Lock()
+		if n := kcpConn.kcp.PeekSize(); n >= 0 {
+			if uint32(n) > kcpConn.maxMsgLen {
+				kcpConn.Unlock()
+				return nil, errors.New("message too long")
+			}
+			b := make([]byte, n)
+			kcpConn.kcp.Recv(b)
+			kcpConn.Unlock()
+			return b, nil
+		}
+		destroyed := kcpConn.destroyFlag
+		kcpConn.Unlock()
+
+		if destroyed {
+			return nil, errors.New("kcp conn closed")
+		}
+
+		select {
+		case <-kcpConn.readSignal:
+		case <-kcpConn.die:
+		}
+	}
+}
+
+// 消息边界由kcp保证，不需要长度头
+func (kcpConn *KCPConn) WriteMsg(args ...[]byte) error {
+	kcpConn.Lock()
+	defer kcpConn.Unlock()
+	if kcpConn.closeFlag {
+		return nil
+	}
+
+	// get len
+	var msgLen uint32
+	for i := 0; i < len(args); i++ {
+		msgLen += uint32(len(args[i]))
+	}
+
+	// check len
+	if msgLen > kcpConn.maxMsgLen {
+		return errors.New("message too long")
+	} else if msgLen < 1 {
+		return errors.New("message too short")
+	}
+
+	// kcp.Send会拷贝数据
+	if len(args) == 1 {
+		kcpConn.doWrite(args[0])
+		return nil
+	}
+
+	// merge the args
+	msg := make([]byte, msgLen)
+	l := 0
+	for i := 0; i < len(args); i++ {
+		copy(msg[l:], args[i])
+		l += len(args[i])
+	}
+
+	kcpConn.doWrite(msg)
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package network
+
+import (
+	"gameserver/core/log"
+	"gameserver/core/network/kcp"
+	"net"
+	"sync"
+	"time"
+)
+
+// KCPServer 所有会话共用一个udp socket，按对端地址区分会话
+// 客户端发出第一个数据包后服务端才会创建连接
+type KCPServer struct {
+	Addr            string
+	MaxConnNum      int
+	PendingWriteNum int
+	MaxMsgLen       uint32
+	NewAgent        func(*KCPConn) Agent
+	KCPSetting
+	udpConn    net.PacketConn
+	conns      map[string]*KCPConn
+	mutexConns sync.Mutex
+	acceptFlag bool
+	closeChan  chan struct{}
+	wgLn       sync.WaitGroup
+	wgConns    sync.WaitGroup
+}
+
+func (server *KCPServer) Start() {
+	server.init()
+	server.wgLn.Add(2)
+	go server.run()
+	go server.runUpdate()
+}
+
+func (server *KCPServer) init() {
+	udpConn, err := ListenPacket(server.Addr)
+	if err != nil {
+		log.Fatal("%v", err)
+	}
+
+	if server.MaxConnNum <= 0 {
+		server.MaxConnNum = 100
+		log.Release("invalid MaxConnNum, reset to %v", server.MaxConnNum)
+	}
+	if server.PendingWriteNum <= 0 {
+		server.PendingWriteNum = 100
+		log.Release("invalid PendingWriteNum, reset to %v", server.PendingWriteNum)
+	}
+	if server.MaxMsgLen <= 0 {
+		server.MaxMsgLen = 4096
+		log.Release("invalid MaxMsgLen, reset to %v", server.MaxMsgLen)
+	}
+	if server.NewAgent == nil {
+		log.Fatal("NewAgent must not be nil")
+	}
+	server.KCPSetting.init()
+
+	server.udpConn = udpConn
+	server.conns = make(map[string]*KCPConn)
+	server.acceptFlag = true
+	server.closeChan = make(chan struct{})
+}
+
+func (server *KCPServer) run() {
+	defer server.wgLn.Done()
+
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := server.udpConn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-server.closeChan:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			log.Release("read udp error: %v", err)
+			continue
+		}
+		data := buf[:n]
+		if n < kcp.Overhead {
+			continue
+		}
+
+		key := addr.String()
+		conv := kcp.GetConv(data)
+		server.mutexConns.Lock()
+		kcpConn, ok := server.conns[key]
+		server.mutexConns.Unlock()
+		if ok {
+			if kcpConn.kcp.GetConv() == conv {
+				kcpConn.input(data)
+				continue
+			}
+			// 同一地址的客户端重新建立了会话
+			if !kcp.IsFirstPacket(data) {
+				continue
+			}
+			kcpConn.Destroy()
+		} else if !kcp.IsFirstPacket(data) {
+			continue
+		}
+
+		server.mutexConns.Lock()
+		if !server.acceptFlag {
+			server.mutexConns.Unlock()
+			continue
+		}
+		if len(server.conns) >= server.MaxConnNum {
+			server.mutexConns.Unlock()
+			log.Debug("too many connections")
+			continue
+		}
+		remoteAddr := addr
+		output := func(b []byte) {
+			server.udpConn.WriteTo(b, remoteAddr)
+		}
+		kcpConn = newKCPConn(conv, output, server.udpConn.LocalAddr(), addr,
+			&server.KCPSetting, server.PendingWriteNum, server.MaxMsgLen)
+		server.conns[key] = kcpConn
+		server.mutexConns.Unlock()
+
+		server.wgConns.Add(1)
+
+		kcpConn.input(data)
+		agent := server.NewAgent(kcpConn)
+		go func() {
+			agent.Run()
+
+			// cleanup
+			kcpConn.Close()
+			agent.OnClose()
+
+			server.wgConns.Done()
+		}()
+	}
+}
+
+// runUpdate 统一驱动所有会话，避免每个连接一个定时器
+func (server *KCPServer) runUpdate() {
+	defer server.wgLn.Done()
+
+	ticker := time.NewTicker(time.Duration(server.Interval) * time.Millisecond)
+	defer ticker.Stop()
+
+	var conns []*KCPConn
+	var keys []string
+	for {
+		select {
+		case <-server.closeChan:
+			return
+		case now := <-ticker.C:
+			conns, keys = conns[:0], keys[:0]
+			server.mutexConns.Lock()
+			for key, kcpConn := range server.conns {
+				keys = append(keys, key)
+				conns = append(conns, kcpConn)
+			}
+			server.mutexConns.Unlock()
+
+			for i, kcpConn := range conns {
+				if kcpConn.update(now) {
+					continue
+				}
+				server.mutexConns.Lock()
+				if server.conns[keys[i]] == kcpConn {
+					delete(server.conns, keys[i])
+				}
+				server.mutexConns.Unlock()
+			}
+		}
+	}
+}
+
+// StopAccept 不再创建新会话，已建立的会话不受影响
+func (server *KCPServer) StopAccept() {
+	server.mutexConns.Lock()
+	server.acceptFlag = false
+	server.mutexConns.Unlock()
+	forgetPacketConn(server.Addr)
+}
+
+func (server *KCPServer) ConnNum() int {
+	server.mutexConns.Lock()
+	defer server.mutexConns.Unlock()
+	return len(server.conns)
+}
+
+func (server *KCPServer) Close() {
+	server.StopAccept()
+
+	server.mutexConns.Lock()
+	for _, kcpConn := range server.conns {
+		kcpConn.Destroy()
+	}
+	server.conns = make(map[string]*KCPConn)
+	server.mutexConns.Unlock()
+	server.wgConns.Wait()
+
+	close(server.closeChan)
+	server.udpConn.Close()
+	server.wgLn.Wait()
+}
@@ -0,0 +1,181 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"gameserver/core/log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 子进程通过该环境变量继承父进程的监听socket，格式: addr=fd;addr=fd，udp socket的addr带"udp:"前缀
+const EnvInheritListeners = "LEAF_INHERIT_LISTENERS"
+
+// fileSocket 可以复制出文件描述符的监听socket，net.TCPListener、net.UnixListener和net.UDPConn都实现了
+type fileSocket interface {
+	File() (*os.File, error)
+}
+
+var (
+	listenersMutex sync.Mutex
+	listeners      = make(map[string]fileSocket)
+	inherited      map[string]uintptr
+)
+
+func packetKey(addr string) string {
+	return "udp:" + addr
+}
+
+func parseInherited() {
+	if inherited != nil {
+		return
+	}
+
+	inherited = make(map[string]uintptr)
+	env := os.Getenv(EnvInheritListeners)
+	if env == "" {
+		return
+	}
+	for _, item := range strings.Split(env, ";") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		fd, err := strconv.ParseUint(kv[1], 10, 64)
+		if err != nil {
+			log.Error("invalid inherited listener %v: %v", item, err)
+			continue
+		}
+		inherited[kv[0]] = uintptr(fd)
+	}
+}
+
+// Listen 优先使用从父进程继承的socket，否则新建监听
+func Listen(addr string) (net.Listener, error) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+
+	parseInherited()
+
+	var ln net.Listener
+	if fd, ok := inherited[addr]; ok {
+		delete(inherited, addr)
+		f := os.NewFile(fd, addr)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherit listener %v: %v", addr, err)
+		}
+		log.Release("inherit listener %v from parent process", addr)
+		ln = l
+	} else {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		ln = l
+	}
+
+	if fl, ok := ln.(fileSocket); ok {
+		listeners[addr] = fl
+	}
+	return ln, nil
+}
+
+// ListenPacket 优先使用从父进程继承的udp socket，否则新建
+// 交接后父子进程共用同一个socket直到父进程排空关闭，期间数据包可能被任一进程读到，
+// 不属于本进程会话的包被丢弃，由KCP重传恢复
+func ListenPacket(addr string) (net.PacketConn, error) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+
+	parseInherited()
+
+	key := packetKey(addr)
+	var conn net.PacketConn
+	if fd, ok := inherited[key]; ok {
+		delete(inherited, key)
+		f := os.NewFile(fd, key)
+		c, err := net.FilePacketConn(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherit packet conn %v: %v", addr, err)
+		}
+		log.Release("inherit packet conn %v from parent process", addr)
+		conn = c
+	} else {
+		c, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		conn = c
+	}
+
+	if fs, ok := conn.(fileSocket); ok {
+		listeners[key] = fs
+	}
+	return conn, nil
+}
+
+func forgetListener(addr string) {
+	listenersMutex.Lock()
+	delete(listeners, addr)
+	listenersMutex.Unlock()
+}
+
+func forgetPacketConn(addr string) {
+	forgetListener(packetKey(addr))
+}
+
+// StartInheritor 启动一个新的进程并把当前所有监听socket交给它
+// 新进程就绪后，当前进程应进入排空流程并退出
+func StartInheritor() (*os.Process, error) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+
+	if len(listeners) == 0 {
+		return nil, errors.New("no listener to hand over")
+	}
+
+	var files []*os.File
+	var pairs []string
+	for addr, ln := range listeners {
+		f, err := ln.File()
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, fmt.Errorf("dup listener %v: %v", addr, err)
+		}
+		// ExtraFiles 从fd 3开始依次编号
+		pairs = append(pairs, fmt.Sprintf("%v=%v", addr, 3+len(files)))
+		files = append(files, f)
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	var env []string
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, EnvInheritListeners+"=") {
+			env = append(env, e)
+		}
+	}
+	env = append(env, EnvInheritListeners+"="+strings.Join(pairs, ";"))
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	attr := &os.ProcAttr{
+		Dir:   wd,
+		Env:   env,
+		Files: append([]*os.File{os.Stdin, os.Stdout, os.Stderr}, files...),
+	}
+	return os.StartProcess(os.Args[0], os.Args, attr)
+}
@@ -0,0 +1,225 @@
+package protobuf
+
+import (
+	"context"
+	"fmt"
+	"gameserver/core/conf"
+	"gameserver/core/log"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Middleware 包装消息处理函数，args与MsgHandler一致: [msg, userData]
+// 不调用next即表示拦截该消息
+type Middleware func(id uint32, next MsgHandler) MsgHandler
+
+// Masker 字段脱敏函数，签名与utils.MaskPhoneNumber一致
+type Masker func(ctx context.Context, data string) string
+
+// Validator 消息自带的参数校验
+type Validator interface {
+	Validate() error
+}
+
+// Recover 捕获处理函数的panic，避免直接路由的handler拖垮连接goroutine
+func Recover() Middleware {
+	return func(id uint32, next MsgHandler) MsgHandler {
+		return func(args []interface{}) {
+			defer func() {
+				if r := recover(); r != nil {
+					if conf.LenStackBuf > 0 {
+						buf := make([]byte, conf.LenStackBuf)
+						l := runtime.Stack(buf, false)
+						log.Error("handle message %v panic: %v: %s", id, r, buf[:l])
+					} else {
+						log.Error("handle message %v panic: %v", id, r)
+					}
+				}
+			}()
+			next(args)
+		}
+	}
+}
+
+// Logging 以debug级别输出消息内容，maskers的key为proto字段名，嵌套消息中的同名字段同样会被脱敏
+// debug级别关闭时不格式化消息，也不创建子Logger
+func Logging(maskers map[string]Masker) Middleware {
+	return func(id uint32, next MsgHandler) MsgHandler {
+		return func(args []interface{}) {
+			if !log.DebugEnabled() {
+				next(args)
+				return
+			}
+			if m, ok := args[0].(proto.Message); ok {
+				log.With("msgId", id, "agent", args[1]).Debug("recv message %v: %v",
+					m.ProtoReflect().Descriptor().Name(), FormatMessage(m, maskers))
+			}
+			next(args)
+		}
+	}
+}
+
+// FormatMessage 格式化消息，字符串字段按maskers脱敏
+func FormatMessage(m proto.Message, maskers map[string]Masker) string {
+	if len(maskers) > 0 {
+		m = proto.Clone(m)
+		maskMessage(m.ProtoReflect(), maskers)
+	}
+	return prototext.MarshalOptions{}.Format(m)
+}
+
+func maskMessage(m protoreflect.Message, maskers map[string]Masker) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Kind() == protoreflect.MessageKind:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				maskMessage(list.Get(i).Message(), maskers)
+			}
+		case fd.IsMap():
+		case fd.Kind() == protoreflect.MessageKind:
+			maskMessage(v.Message(), maskers)
+		case fd.Kind() == protoreflect.StringKind && !fd.IsList():
+			if masker, ok := maskers[string(fd.Name())]; ok {
+				m.Set(fd, protoreflect.ValueOfString(masker(context.Background(), v.String())))
+			}
+		}
+		return true
+	})
+}
+
+// Validate 消息实现了Validator时先校验，不通过则丢弃
+func Validate() Middleware {
+	return func(id uint32, next MsgHandler) MsgHandler {
+		return func(args []interface{}) {
+			if v, ok := args[0].(Validator); ok {
+				if err := v.Validate(); err != nil {
+					log.Debug("message %v validate failed: %v, agent: %v", id, err, args[1])
+					return
+				}
+			}
+			next(args)
+		}
+	}
+}
+
+// Check 使用自定义函数校验参数，一般通过UseFor注册到具体消息
+func Check(check func(msg proto.Message) error) Middleware {
+	return func(id uint32, next MsgHandler) MsgHandler {
+		return func(args []interface{}) {
+			if m, ok := args[0].(proto.Message); ok {
+				if err := check(m); err != nil {
+					log.Debug("message %v check failed: %v, agent: %v", id, err, args[1])
+					return
+				}
+			}
+			next(args)
+		}
+	}
+}
+
+// Latency 统计处理耗时
+func Latency(stats *LatencyStats) Middleware {
+	return func(id uint32, next MsgHandler) MsgHandler {
+		return func(args []interface{}) {
+			start := time.Now()
+			defer func() {
+				var name string
+				if m, ok := args[0].(proto.Message); ok {
+					name = string(m.ProtoReflect().Descriptor().Name())
+				}
+				stats.observe(id, name, time.Since(start))
+			}()
+			next(args)
+		}
+	}
+}
+
+// 耗时分桶上界，最后一个桶为超出最大上界的部分
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+type latencyHistogram struct {
+	name    string
+	buckets []int64
+	count   int64
+	total   time.Duration
+	max     time.Duration
+}
+
+// LatencyStats 按消息id统计处理耗时分布
+type LatencyStats struct {
+	mutex sync.Mutex
+	stats map[uint32]*latencyHistogram
+}
+
+func NewLatencyStats() *LatencyStats {
+	return &LatencyStats{stats: make(map[uint32]*latencyHistogram)}
+}
+
+func (s *LatencyStats) observe(id uint32, name string, d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	h, ok := s.stats[id]
+	if !ok {
+		h = &latencyHistogram{name: name, buckets: make([]int64, len(latencyBuckets)+1)}
+		s.stats[id] = h
+	}
+	i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+	h.buckets[i]++
+	h.count++
+	h.total += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Reset 清空统计
+func (s *LatencyStats) Reset() {
+	s.mutex.Lock()
+	s.stats = make(map[uint32]*latencyHistogram)
+	s.mutex.Unlock()
+}
+
+// Report 输出各消息的耗时分布，用于console查看
+func (s *LatencyStats) Report() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ids := make([]uint32, 0, len(s.stats))
+	for id := range s.stats {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%-6v %-28v %8v %10v %10v", "id", "name", "count", "avg", "max"))
+	for _, bound := range latencyBuckets {
+		b.WriteString(fmt.Sprintf(" %7v", "<="+bound.String()))
+	}
+	b.WriteString(fmt.Sprintf(" %7v", ">"+latencyBuckets[len(latencyBuckets)-1].String()))
+	for _, id := range ids {
+		h := s.stats[id]
+		b.WriteString(fmt.Sprintf("\r\n%-6v %-28v %8v %10v %10v", id, h.name, h.count,
+			h.total/time.Duration(h.count), h.max))
+		for _, n := range h.buckets {
+			b.WriteString(fmt.Sprintf(" %7v", n))
+		}
+	}
+	return b.String()
+}
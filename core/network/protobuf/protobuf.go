@@ -9,6 +9,7 @@ import (
 	"gameserver/core/log"
 	"math"
 	"reflect"
+	"sync/atomic"
 
 	"google.golang.org/protobuf/proto"
 )
@@ -17,9 +18,12 @@ import (
 // | id | protobuf message |
 // -------------------------
 type Processor struct {
-	littleEndian bool
-	msgInfo      map[uint32]*MsgInfo
-	msgID        map[reflect.Type]uint32
+	littleEndian   bool
+	msgInfo        map[uint32]*MsgInfo
+	msgID          map[reflect.Type]uint32
+	middlewares    []Middleware
+	msgMiddlewares map[uint32][]Middleware
+	version        atomic.Uint64 // 中间件或处理函数变化时加一，已组装的调用链随之失效
 }
 
 type MsgInfo struct {
@@ -27,6 +31,13 @@ type MsgInfo struct {
 	msgRouter     *chanrpc.Server
 	msgHandler    MsgHandler
 	msgRawHandler MsgHandler
+	chained       atomic.Pointer[chainCache]
+}
+
+// chainCache 组装好的中间件调用链
+type chainCache struct {
+	version uint64
+	handler MsgHandler
 }
 
 type MsgHandler func([]interface{})
@@ -41,6 +52,7 @@ func NewProcessor() *Processor {
 	p.littleEndian = false
 	p.msgID = make(map[reflect.Type]uint32)
 	p.msgInfo = make(map[uint32]*MsgInfo)
+	p.msgMiddlewares = make(map[uint32][]Middleware)
 	return p
 }
 
@@ -92,6 +104,7 @@ func (p *Processor) SetHandler(msg proto.Message, msgHandler MsgHandler) {
 	}
 
 	p.msgInfo[id].msgHandler = msgHandler
+	p.version.Add(1)
 }
 
 // It's dangerous to call the method on routing or marshaling (unmarshaling)
@@ -103,6 +116,53 @@ func (p *Processor) SetRawHandler(id uint32, msgRawHandler MsgHandler) {
 	p.msgInfo[id].msgRawHandler = msgRawHandler
 }
 
+// It's dangerous to call the method on routing or marshaling (unmarshaling)
+// Use 注册全局中间件，先注册的在外层
+func (p *Processor) Use(middlewares ...Middleware) {
+	p.middlewares = append(p.middlewares, middlewares...)
+	p.version.Add(1)
+}
+
+// It's dangerous to call the method on routing or marshaling (unmarshaling)
+// UseFor 注册指定消息的中间件，位于全局中间件内层
+func (p *Processor) UseFor(msg proto.Message, middlewares ...Middleware) {
+	id := getId(msg)
+	p.msgMiddlewares[id] = append(p.msgMiddlewares[id], middlewares...)
+	p.version.Add(1)
+}
+
+func (p *Processor) chain(id uint32, h MsgHandler) MsgHandler {
+	mws := p.msgMiddlewares[id]
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](id, h)
+	}
+	for i := len(p.middlewares) - 1; i >= 0; i-- {
+		h = p.middlewares[i](id, h)
+	}
+	return h
+}
+
+// cachedChain 第一次使用时组装调用链，之后复用，Use、UseFor、SetHandler后重新组装
+func (p *Processor) cachedChain(cache *atomic.Pointer[chainCache], id uint32, h MsgHandler) MsgHandler {
+	version := p.version.Load()
+	if c := cache.Load(); c != nil && c.version == version {
+		return c.handler
+	}
+	c := &chainCache{version: version, handler: p.chain(id, h)}
+	cache.Store(c)
+	return c.handler
+}
+
+// Wrap 用中间件包装通过chanrpc路由的消息处理函数，在模块goroutine中执行中间件
+// 返回值可直接用于skeleton.RegisterChanRPC
+func (p *Processor) Wrap(msg proto.Message, h func([]interface{})) func([]interface{}) {
+	id := getId(msg)
+	var cache atomic.Pointer[chainCache]
+	return func(args []interface{}) {
+		p.cachedChain(&cache, id, h)(args)
+	}
+}
+
 // goroutine safe
 func (p *Processor) Route(msg interface{}, userData interface{}) error {
 	// raw
@@ -125,7 +185,7 @@ func (p *Processor) Route(msg interface{}, userData interface{}) error {
 	}
 	i := p.msgInfo[id]
 	if i.msgHandler != nil {
-		i.msgHandler([]interface{}{msg, userData})
+		p.cachedChain(&i.chained, id, i.msgHandler)([]interface{}{msg, userData})
 	}
 	if i.msgRouter != nil {
 		i.msgRouter.Go(msgType, msg, userData)
@@ -184,6 +244,11 @@ func (p *Processor) Range(f func(id uint16, t reflect.Type)) {
 	}
 }
 
+// GetId 读取消息定义中的message_id
+func GetId(m proto.Message) uint32 {
+	return getId(m)
+}
+
 func getId(m proto.Message) uint32 {
 	msgDesc := m.ProtoReflect().Descriptor()
 	opts := msgDesc.Options()
@@ -14,6 +14,7 @@ type TCPConn struct {
 	writeChan chan []byte
 	closeFlag bool
 	msgParser *MsgParser
+	maxMsgLen uint32 // 不为0时代替msgParser的最大消息长度，只在读goroutine中访问
 }
 
 func newTCPConn(conn net.Conn, pendingWriteNum int, msgParser *MsgParser) *TCPConn {
@@ -104,6 +105,12 @@ func (tcpConn *TCPConn) RemoteAddr() net.Addr {
 	return tcpConn.conn.RemoteAddr()
 }
 
+// SetMaxMsgLen 限制这条连接读取的消息长度，不超过MsgParser的设置，0时使用MsgParser的设置
+// 只能在读消息的goroutine中调用，如握手完成前先用较小的限制
+func (tcpConn *TCPConn) SetMaxMsgLen(maxMsgLen uint32) {
+	tcpConn.maxMsgLen = maxMsgLen
+}
+
 func (tcpConn *TCPConn) ReadMsg() ([]byte, error) {
 	return tcpConn.msgParser.Read(tcpConn)
 }
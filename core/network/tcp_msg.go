@@ -91,7 +91,11 @@ func (p *MsgParser) Read(conn *TCPConn) ([]byte, error) {
 	}
 
 	// check len
-	if msgLen > p.maxMsgLen {
+	maxMsgLen := p.maxMsgLen
+	if conn.maxMsgLen != 0 && conn.maxMsgLen < maxMsgLen {
+		maxMsgLen = conn.maxMsgLen
+	}
+	if msgLen > maxMsgLen {
 		return nil, errors.New("message too long")
 	} else if msgLen < p.minMsgLen {
 		return nil, errors.New("message too short")
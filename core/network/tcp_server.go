@@ -32,7 +32,7 @@ func (server *TCPServer) Start() {
 }
 
 func (server *TCPServer) init() {
-	ln, err := net.Listen("tcp", server.Addr)
+	ln, err := Listen(server.Addr)
 	if err != nil {
 		log.Fatal("%v", err)
 	}
@@ -113,9 +113,21 @@ func (server *TCPServer) run() {
 	}
 }
 
-func (server *TCPServer) Close() {
+// StopAccept 停止接受新连接，已建立的连接不受影响
+func (server *TCPServer) StopAccept() {
 	server.ln.Close()
 	server.wgLn.Wait()
+	forgetListener(server.Addr)
+}
+
+func (server *TCPServer) ConnNum() int {
+	server.mutexConns.Lock()
+	defer server.mutexConns.Unlock()
+	return len(server.conns)
+}
+
+func (server *TCPServer) Close() {
+	server.StopAccept()
 
 	server.mutexConns.Lock()
 	for conn := range server.conns {
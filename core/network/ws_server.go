@@ -78,7 +78,7 @@ func (handler *WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (server *WSServer) Start() {
-	ln, err := net.Listen("tcp", server.Addr)
+	ln, err := Listen(server.Addr)
 	if err != nil {
 		log.Fatal("%v", err)
 	}
@@ -141,8 +141,20 @@ func (server *WSServer) Start() {
 	go httpServer.Serve(ln)
 }
 
-func (server *WSServer) Close() {
+// StopAccept 停止接受新连接，已建立的连接不受影响
+func (server *WSServer) StopAccept() {
 	server.ln.Close()
+	forgetListener(server.Addr)
+}
+
+func (server *WSServer) ConnNum() int {
+	server.handler.mutexConns.Lock()
+	defer server.handler.mutexConns.Unlock()
+	return len(server.handler.conns)
+}
+
+func (server *WSServer) Close() {
+	server.StopAccept()
 
 	server.handler.mutexConns.Lock()
 	for conn := range server.handler.conns {
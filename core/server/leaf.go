@@ -8,12 +8,23 @@ import (
 	"gameserver/core/module"
 	"os"
 	"os/signal"
+	"syscall"
 )
 
 func Run(mods ...module.Module) {
 	// logger
 	if conf.LogLevel != "" {
-		logger, err := log.New(conf.LogLevel, conf.LogPath, conf.LogFlag)
+		logger, err := log.NewWithOptions(log.Options{
+			Level:          conf.LogLevel,
+			Path:           conf.LogPath,
+			Flag:           conf.LogFlag,
+			Format:         conf.LogFormat,
+			MaxSize:        conf.LogMaxSize,
+			RotateInterval: conf.LogRotateInterval,
+			MaxBackups:     conf.LogMaxBackups,
+			MaxAge:         conf.LogMaxAge,
+			ModuleLevels:   conf.LogModuleLevels,
+		})
 		if err != nil {
 			panic(err)
 		}
@@ -37,7 +48,7 @@ func Run(mods ...module.Module) {
 
 	// close
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, os.Kill)
+	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGTERM)
 	sig := <-c
 	log.Release("Leaf closing down (signal: %v)", sig)
 	console.Destroy()
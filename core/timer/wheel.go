@@ -0,0 +1,95 @@
+package timer
+
+import (
+	"sync"
+	"time"
+)
+
+// Wheel 时间轮，添加、删除为O(1)，每次前进只处理到期的槽，适合大量经常推迟的超时检查
+// 超过一圈的超时放在最远的槽，到期后由调用方检查真正的截止时间并重新添加
+type Wheel[T comparable] struct {
+	mu    sync.Mutex
+	tick  time.Duration
+	slots []map[T]struct{}
+	where map[T]int
+	pos   int
+}
+
+// NewWheel 创建时间轮，每格tick，共size格，一圈为tick*(size-1)
+func NewWheel[T comparable](tick time.Duration, size int) *Wheel[T] {
+	if size < 2 {
+		size = 2
+	}
+	w := &Wheel[T]{
+		tick:  tick,
+		slots: make([]map[T]struct{}, size),
+		where: make(map[T]int),
+	}
+	for i := range w.slots {
+		w.slots[i] = make(map[T]struct{})
+	}
+	return w
+}
+
+// Tick 每格的时长
+func (w *Wheel[T]) Tick() time.Duration {
+	return w.tick
+}
+
+// Add 在after之后到期，已存在时移动到新的位置
+func (w *Wheel[T]) Add(key T, after time.Duration) {
+	ticks := int((after + w.tick - 1) / w.tick)
+	if ticks < 1 {
+		ticks = 1
+	}
+	if ticks > len(w.slots)-1 {
+		ticks = len(w.slots) - 1
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if slot, ok := w.where[key]; ok {
+		delete(w.slots[slot], key)
+	}
+	slot := (w.pos + ticks) % len(w.slots)
+	w.slots[slot][key] = struct{}{}
+	w.where[key] = slot
+}
+
+// Remove 删除，不存在时返回false
+func (w *Wheel[T]) Remove(key T) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	slot, ok := w.where[key]
+	if !ok {
+		return false
+	}
+	delete(w.slots[slot], key)
+	delete(w.where, key)
+	return true
+}
+
+// Advance 前进一格，返回到期的key，到期的key已从时间轮中删除
+func (w *Wheel[T]) Advance() []T {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pos = (w.pos + 1) % len(w.slots)
+	slot := w.slots[w.pos]
+	if len(slot) == 0 {
+		return nil
+	}
+	expired := make([]T, 0, len(slot))
+	for key := range slot {
+		expired = append(expired, key)
+		delete(w.where, key)
+	}
+	w.slots[w.pos] = make(map[T]struct{})
+	return expired
+}
+
+// Len 时间轮中的key数
+func (w *Wheel[T]) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.where)
+}
@@ -1,10 +1,16 @@
 package internal
 
 import (
+	"gameserver/common/base/actor"
 	"gameserver/common/event_dispatcher"
+	"gameserver/common/gateway"
 	"gameserver/common/msg"
+	"gameserver/common/msg/message"
 	"gameserver/conf"
+	"gameserver/core/cluster"
 	"gameserver/core/gate"
+	"gameserver/core/log"
+	"time"
 )
 
 type Module struct {
@@ -23,7 +29,29 @@ func (m *Module) OnInit() {
 		TCPAddr:         conf.Server.TCPAddr,
 		LenMsgLen:       conf.LenMsgLen,
 		LittleEndian:    conf.LittleEndian,
+		KCPAddr:         conf.Server.KCPAddr,
+		KCPSetting:      conf.Server.KCP,
 		Processor:       msg.Processor,
 		AgentChanRPC:    event_dispatcher.ChanRPC,
+		DrainTimeout:    time.Duration(conf.Server.Drain.TimeoutSecond) * time.Second,
+		IdleTimeout:     time.Duration(conf.Server.IdleSecond) * time.Second,
+		ClosingMsg: &message.S2C_ServerClosing{
+			Reason:           "server closing",
+			ReconnectDelayMs: conf.Server.Drain.ReconnectDelayMs,
+			ReconnectAddr:    conf.Server.Drain.ReconnectAddr,
+		},
 	}
+	if conf.Server.Gateway.Enabled {
+		g := gateway.New(cluster.Default(), msg.Processor, conf.LittleEndian, msg.Anonymous)
+		m.Gate.Forward = g.Forward
+		m.Gate.AgentChanRPC = g.AgentChanRPC
+	}
+}
+
+// OnDestroy gate最先销毁，此时所有连接已断开，等待各模块处理完下线事件后统一保存actor数据
+func (m *Module) OnDestroy() {
+	if !event_dispatcher.WaitIdle(m.DrainTimeout) {
+		log.Error("wait modules idle timeout, some offline events may be lost")
+	}
+	actor.SaveAllActorData()
 }
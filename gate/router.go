@@ -12,6 +12,8 @@ import (
 func InitRouter() {
 	// 模块间使用 ChanRPC 通讯，消息路由也不例外
 	msg.Processor.SetRouter(&message.C2S_Login{}, login.External.ChanRPC)
+	msg.Processor.SetRouter(&message.C2S_ResumeSession{}, login.External.ChanRPC)
+	msg.Processor.SetRouter(&message.C2S_BindAccount{}, login.External.ChanRPC)
 	msg.Processor.SetRouter(&message.C2S_GetRechargeRecords{}, game.External.ChanRPC)
 	msg.Processor.SetRouter(&message.C2S_GetRechargeConfigs{}, game.External.ChanRPC)
 	msg.Processor.SetRouter(&message.C2S_RechargeRequest{}, game.External.ChanRPC)
@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"gameserver/common/base/actor"
 	"gameserver/common/config"
 	"gameserver/common/db/mongodb"
+	"gameserver/common/db/redis"
 	"gameserver/common/event_dispatcher"
+	"gameserver/common/gateway"
+	"gameserver/common/msg"
 	"gameserver/common/schedule"
 	"gameserver/common/utils"
 	"gameserver/conf"
+	"gameserver/core/cluster"
 	lconf "gameserver/core/conf"
+	"gameserver/core/log"
 	"gameserver/core/module"
 	"gameserver/core/server"
 	"gameserver/gate"
@@ -20,6 +26,7 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"runtime"
+	"time"
 )
 
 func main() {
@@ -46,6 +53,11 @@ func main() {
 
 	Init()
 
+	if conf.Server.Gateway.Enabled {
+		// 网关模式只运行gate，消息转发到后端节点
+		Run()
+		return
+	}
 	Run(game.External, login.External, match.External, rank.External)
 }
 
@@ -58,6 +70,13 @@ func Run(external ...module.External) {
 		e.InitExternal()
 		modules = append(modules, e.GetModule())
 	}
+	if !conf.Server.Gateway.Enabled {
+		// 可以拆分到其他节点的服务，由集群注册表决定在哪个节点调用
+		cluster.Register("match", match.External.ChanRPC)
+		cluster.Register("rank", rank.External.ChanRPC)
+		// 接收网关转发的客户端消息
+		gateway.Serve(cluster.Default(), msg.Processor, event_dispatcher.ChanRPC)
+	}
 	server.Run(modules...)
 }
 
@@ -66,17 +85,53 @@ func Init() {
 	lconf.LogLevel = conf.Server.LogLevel
 	lconf.LogPath = conf.Server.LogPath
 	lconf.LogFlag = conf.LogFlag
+	lconf.LogFormat = conf.Server.Log.Format
+	lconf.LogMaxSize = conf.Server.Log.MaxSizeMB << 20
+	lconf.LogRotateInterval = time.Duration(conf.Server.Log.RotateHours) * time.Hour
+	lconf.LogMaxBackups = conf.Server.Log.MaxBackups
+	lconf.LogMaxAge = time.Duration(conf.Server.Log.MaxAgeDays) * 24 * time.Hour
+	lconf.LogModuleLevels = conf.Server.Log.ModuleLevels
 	lconf.ConsolePort = conf.Server.ConsolePort
 	lconf.ProfilePath = conf.Server.ProfilePath
+	lconf.NodeName = conf.Server.Cluster.NodeName
+	lconf.ListenAddr = conf.Server.Cluster.ListenAddr
+	lconf.ConnAddrs = conf.Server.Cluster.ConnAddrs
+	lconf.PendingWriteNum = conf.PendingWriteNum
+	lconf.ClusterSecret = conf.Server.Cluster.Secret
+	lconf.ClusterNodes = conf.Server.Cluster.Nodes
+	lconf.ClusterRegistry = conf.Server.Cluster.RegistryFile
+	if conf.Server.Cluster.CallTimeoutMs > 0 {
+		lconf.CallTimeout = time.Duration(conf.Server.Cluster.CallTimeoutMs) * time.Millisecond
+	}
 
-	config.InitGlobalConfig("./conf/config")
+	if err := config.InitGlobalConfig("./conf/config"); err != nil {
+		log.Fatal("加载配置失败: %v", err)
+	}
+	if conf.Server.GameConfig.WatchSecond > 0 {
+		config.Watch(time.Duration(conf.Server.GameConfig.WatchSecond) * time.Second)
+	}
 
 	// 初始化雪花算法
 	utils.InitSnowflake(conf.Server.MachineID)
 
-	// 初始化mongodb
-	mongodb.Init(conf.Server.MongoDB.Host, conf.Server.MongoDB.Database, conf.Server.MongoDB.MinPoolSize, conf.Server.MongoDB.MaxPoolSize)
-	mongodb.CreateIndexes(conf.MongoIndexConf)
+	// 初始化持久化后端
+	switch conf.Server.Storage {
+	case "", "mongodb":
+		mongodb.SetDefaultTimeout(time.Duration(conf.Server.MongoDB.TimeoutMs) * time.Millisecond)
+		mongodb.Init(conf.Server.MongoDB.Host, conf.Server.MongoDB.Database, conf.Server.MongoDB.MinPoolSize, conf.Server.MongoDB.MaxPoolSize)
+	case "memory":
+		mongodb.UseMemory()
+		log.Release("storage: memory, data will be lost on shutdown")
+	default:
+		log.Fatal("unknown storage: %s", conf.Server.Storage)
+	}
+	mongodb.EnsureIndexes(context.Background())
+
+	// 初始化Redis
+	if conf.Server.Redis.Addr != "" {
+		redis.Init(conf.Server.Redis.Addr, conf.Server.Redis.Password, conf.Server.Redis.DB,
+			conf.Server.Redis.PoolSize, conf.Server.Redis.MinIdleConns, conf.Server.Redis.KeyPrefix)
+	}
 
 	// 初始化actor
 	actor.Init(conf.Server.Actor.TimeoutMillisecond)
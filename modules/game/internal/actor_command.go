@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"fmt"
+	"gameserver/common/base/actor"
+	"sort"
+	"strings"
+)
+
+func registerActorCommands() {
+	skeleton.RegisterCommand("actors", "local actors by group, 'actors [drain]' drain migrates actors to other nodes", commandActors)
+}
+
+func commandActors(args []interface{}) interface{} {
+	if len(args) > 0 {
+		if args[0] != "drain" {
+			return "usage: actors [drain]"
+		}
+		// 没有注册迁移的Actor（如玩家、房间）留在本节点，随玩家下线、房间结束自然退出
+		migrated := actor.Drain()
+		return fmt.Sprintf("%d actors migrated, %d actors remain", migrated, len(actor.GetAllTaskHandlers()))
+	}
+
+	counts := make(map[string]int)
+	for id := range actor.GetAllTaskHandlers() {
+		group := id
+		if i := strings.LastIndex(id, "_"); i > 0 {
+			group = id[:i]
+		}
+		counts[group]++
+	}
+	groups := make([]string, 0, len(counts))
+	for group := range counts {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	lines := make([]string, 0, len(groups))
+	for _, group := range groups {
+		lines = append(lines, fmt.Sprintf("%s: %d", group, counts[group]))
+	}
+	return strings.Join(lines, "\r\n")
+}
@@ -3,14 +3,16 @@ package internal
 import (
 	"reflect"
 
+	"gameserver/common/msg"
 	"gameserver/common/msg/message"
 	"gameserver/modules/game/internal/handlers"
 
 	"google.golang.org/protobuf/proto"
 )
 
-func handleMsg(m proto.Message, h interface{}) {
-	skeleton.RegisterChanRPC(reflect.TypeOf(m), h)
+// handleMsg 注册消息处理函数，经由msg.Processor的中间件链执行
+func handleMsg(m proto.Message, h func([]interface{})) {
+	skeleton.RegisterChanRPC(reflect.TypeOf(m), msg.Processor.Wrap(m, h))
 }
 
 func InitHandler() {
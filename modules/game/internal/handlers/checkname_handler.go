@@ -26,7 +26,6 @@ func C2S_CheckNameHandler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到C2S_CheckName消息: %v, agent: %v", msg, agent)
 	playerName := msg.Name
 	result := managers.GetUserManager().CheckName(playerName)
 	agent.WriteMsg(&message.S2C_CheckName{
@@ -15,7 +15,7 @@ func C2S_GetPlayerInfoHandler(args []interface{}) {
 		return
 	}
 
-	msg, ok := args[0].(*message.C2S_GetPlayerInfo)
+	_, ok := args[0].(*message.C2S_GetPlayerInfo)
 	if !ok {
 		log.Error("C2S_GetPlayerInfoHandler: 消息类型错误")
 		return
@@ -33,7 +33,6 @@ func C2S_GetPlayerInfoHandler(args []interface{}) {
 		})
 	}()
 
-	log.Debug("收到C2S_GetPlayerInfo消息: %v, agent: %v", msg, agent)
 	playerId := agent.UserData().(models.User).PlayerId
 	userManager := managers.GetUserManager()
 	p := managers.GetUserManager().GetPlayer(playerId)
@@ -20,8 +20,6 @@ func C2S_GetRechargeConfigsHandler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到获取充值配置请求, agent: %v", agent)
-
 	// 调用充值管理器获取配置
 	rechargeManager := managers.GetRechargeManager()
 	configs := rechargeManager.GetRechargeConfigs()
@@ -34,10 +32,10 @@ func C2S_GetRechargeConfigsHandler(args []interface{}) {
 			Name:        config.Name,
 			Amount:      config.Amount,
 			Bonus:       config.Bonus,
-			Currency:    config.Currency,
+			Currency:    string(config.Currency),
 			Description: config.Description,
-			IsActive:    config.Is,
-			SortOrder:   int32(config.Sort),
+			IsActive:    config.IsActive,
+			SortOrder:   int32(config.SortOrder),
 		}
 		pbConfigs = append(pbConfigs, pbConfig)
 	}
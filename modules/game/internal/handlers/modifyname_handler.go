@@ -27,7 +27,6 @@ func C2S_ModifyNameHandler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到C2S_ModifyName消息: %v, agent: %v", msg, agent)
 	userManager := managers.GetUserManager()
 	playerId := agent.UserData().(models.User).PlayerId
 	p := userManager.GetPlayer(playerId)
@@ -27,8 +27,6 @@ func C2S_RechargeRequestHandler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到充值请求: %v, agent: %v", msg, agent)
-
 	// 获取玩家ID
 	userData := agent.UserData()
 	if userData == nil {
@@ -3,6 +3,7 @@ package player
 import (
 	"fmt"
 	"gameserver/common/base/actor"
+	"gameserver/common/broadcast"
 	"gameserver/common/db/mongodb"
 	"gameserver/common/models"
 	"gameserver/common/msg/message"
@@ -22,10 +23,31 @@ type Player struct {
 	agent              gate.Agent         `bson:"-"`
 }
 
+// logger game模块的日志
+var logger = log.Module("game")
+
 func (p Player) GetPersistId() interface{} {
 	return p.PlayerId
 }
 
+func init() {
+	// 玩家绑定在本节点的客户端连接上，留在登录的节点上
+	actor.RegisterGroup(actor.Player, actor.GroupOptions{Pinned: true})
+	actor.HandleMessage(actor.Player, "Takeover", handleTakeover)
+	// 版本1：引入schema版本前保存的玩家，补齐player_info中后来增加的字段
+	mongodb.RegisterMigration[Player](1, "补齐player_info默认值", nil)
+}
+
+// handleTakeover 玩家在其他节点登录，断开本节点的连接，由CloseAgent走正常的下线流程
+// 在玩家Actor中执行，不能等待UserManager，登录的节点等本节点的玩家Actor停止
+func handleTakeover(uniqueID interface{}, args []interface{}) (interface{}, error) {
+	if p, ok := actor.GetActor[Player](actor.Player, uniqueID); ok {
+		p.SendToClient(&message.S2C_Kick{Reason: "账号在其他地方登录"})
+		p.CloseAgent()
+	}
+	return nil, nil
+}
+
 // 玩家模块
 func InitPlayer(agent gate.Agent, isNew bool) *Player {
 	user := agent.UserData().(models.User)
@@ -33,7 +55,7 @@ func InitPlayer(agent gate.Agent, isNew bool) *Player {
 
 	// 检查是否已存在Actor
 	if existingPlayer, ok := actor.GetActor[Player](actor.Player, playerId); ok {
-		log.Error("玩家Actor已存在，可能是离线未正常清理: %v", playerId)
+		logger.With("playerId", playerId).Error("玩家Actor已存在，可能是离线未正常清理")
 		// 异步停止旧的Actor，避免在TaskHandler上下文中调用Stop造成死锁
 		go func() {
 			existingPlayer.Stop()
@@ -43,7 +65,7 @@ func InitPlayer(agent gate.Agent, isNew bool) *Player {
 	// 初始化玩家数据
 	p, err := initPlayerData(playerId, user, isNew)
 	if err != nil {
-		log.Error("初始化玩家数据失败: %v", err)
+		logger.With("playerId", playerId).Error("初始化玩家数据失败: %v", err)
 		return nil
 	}
 
@@ -131,13 +153,15 @@ func (p *Player) doInitTeam() {
 		if !ok {
 			return
 		}
-		// todo 重连房间
+		// 重连时新的连接重新加入队伍和房间频道
 		if teamActor.RoomId > 0 {
+			broadcast.Subscribe(p.agent, broadcast.Team(p.TeamId), broadcast.Room(teamActor.RoomId))
 			return
 		}
 	}
 	teamInfo := team.InitTeam(p.agent)
 	p.TeamId = teamInfo.TeamId
+	broadcast.Subscribe(p.agent, broadcast.Team(p.TeamId))
 	// 直接调用，避免在TaskHandler上下文中再次调用SendTask造成死锁
 	// teamInfo.doJoinTeam(p.PlayerId)
 }
@@ -146,6 +170,11 @@ func (p *Player) SendToClient(message proto.Message) {
 	p.agent.WriteMsg(message)
 }
 
+// Agent 玩家当前的连接
+func (p *Player) Agent() gate.Agent {
+	return p.agent
+}
+
 func (p *Player) CloseAgent() {
 	p.agent.Close()
 }
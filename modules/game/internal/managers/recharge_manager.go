@@ -1,16 +1,16 @@
 package managers
 
 import (
+	"context"
 	"fmt"
 	"gameserver/common/base/actor"
+	"gameserver/common/cache"
 	config "gameserver/common/config/generated"
 	"gameserver/common/db/mongodb"
 	"gameserver/common/msg/message"
 	"gameserver/core/gate"
-	"gameserver/core/log"
 	"gameserver/modules/game/internal/managers/player"
 	"gameserver/modules/game/internal/models/recharge"
-	"sort"
 	"sync"
 	"time"
 
@@ -20,11 +20,15 @@ import (
 // RechargeManager 使用TaskHandler实现，确保充值操作按顺序执行
 type RechargeManager struct {
 	*actor.TaskHandler
+	records *cache.Cache[string, *recharge.RechargeRecord] // 充值记录缓存，只缓存已落地的记录
 }
 
 var (
 	rechargeManager     *RechargeManager
 	rechargeManagerOnce sync.Once
+
+	rechargeRecordRepo = mongodb.NewRepository[recharge.RechargeRecord]()
+	playerRepo         = mongodb.NewRepository[player.Player]()
 )
 
 func GetRechargeManager() *RechargeManager {
@@ -37,6 +41,13 @@ func GetRechargeManager() *RechargeManager {
 
 // Init 初始化RechargeManager
 func (m *RechargeManager) Init() {
+	// 回调通常在下单后几分钟内到达
+	m.records = cache.New(cache.Options[string, *recharge.RechargeRecord]{
+		Name:    "recharge_record",
+		MaxSize: 10000,
+		TTL:     10 * time.Minute,
+		Loader:  cache.ById[string](rechargeRecordRepo),
+	})
 	// 初始化TaskHandler
 	m.TaskHandler = actor.InitTaskHandler(actor.Recharge, "1", m)
 	m.TaskHandler.Start()
@@ -47,12 +58,6 @@ func (m *RechargeManager) Stop() {
 	m.TaskHandler.Stop()
 }
 
-// 全局缓存
-var (
-	rechargeConfigCache sync.Map // 充值配置缓存
-	rechargeRecordCache sync.Map // 充值记录缓存
-)
-
 // 充值请求
 type RechargeRequest struct {
 	PlayerId  int64                   `json:"player_id"`
@@ -111,8 +116,8 @@ func (m *RechargeManager) doHandleRechargeRequest(req *RechargeRequest, agent ga
 	)
 
 	// 4. 保存充值记录到数据库
-	if _, err := mongodb.Save(rechargeRecord); err != nil {
-		log.Error("保存充值记录失败: %v", err)
+	if err := rechargeRecordRepo.SaveVersioned(context.Background(), rechargeRecord); err != nil {
+		logger.Error("保存充值记录失败: %v", err)
 		return &message.S2C_RechargeResponse{
 			Success: false,
 			Message: "创建充值订单失败",
@@ -120,13 +125,12 @@ func (m *RechargeManager) doHandleRechargeRequest(req *RechargeRequest, agent ga
 	}
 
 	// 5. 更新缓存
-	m.updateRechargeRecordCache(rechargeRecord)
+	m.records.Set(rechargeRecord.Id, rechargeRecord)
 
 	// 6. 生成支付信息
 	paymentInfo := m.generatePaymentInfo(rechargeRecord)
 
-	log.Debug("充值请求处理成功: PlayerId=%d, Amount=%d, OrderId=%s",
-		req.PlayerId, req.Amount, rechargeRecord.Id)
+	logger.With("playerId", req.PlayerId, "orderId", rechargeRecord.Id).Debug("充值请求处理成功: Amount=%d", req.Amount)
 
 	return &message.S2C_RechargeResponse{
 		Success:    true,
@@ -159,107 +163,145 @@ func (m *RechargeManager) doHandlePaymentCallback(orderId, transactionId string,
 	// 1. 查找充值记录
 	rechargeRecord := m.getRechargeRecord(orderId)
 	if rechargeRecord == nil {
-		log.Error("支付回调：充值记录不存在: %s", orderId)
+		logger.With("orderId", orderId).Error("支付回调：充值记录不存在")
 		return nil
 	}
 
 	// 2. 检查订单状态
 	if rechargeRecord.Status != recharge.RechargeStatus_Pending {
-		log.Error("支付回调：订单状态异常: %s, Status: %d", orderId, rechargeRecord.Status)
+		logger.With("orderId", orderId).Error("支付回调：订单状态异常, Status: %d", rechargeRecord.Status)
 		return nil
 	}
 
-	// 3. 更新订单状态
+	// 3. 更新订单状态，内存中的记录在事务提交后再替换
 	now := time.Now().Unix()
+	updated := *rechargeRecord
+	updated.UpdateTime = now
+	var totalAmount int64
 	if success {
-		rechargeRecord.Status = recharge.RechargeStatus_Success
-		rechargeRecord.TransactionId = transactionId
-		rechargeRecord.CompleteTime = now
-
-		// 4. 更新玩家数据
-		if err := m.updatePlayerBalance(rechargeRecord); err != nil {
-			log.Error("更新玩家余额失败: %v", err)
-			return err
+		updated.Status = recharge.RechargeStatus_Success
+		updated.TransactionId = transactionId
+		updated.CompleteTime = now
+
+		// 充值金额（包含赠送）
+		totalAmount = updated.Amount
+		if config := m.getRechargeConfig(updated.ConfigId); config != nil {
+			totalAmount += config.Bonus
 		}
 	} else {
-		rechargeRecord.Status = recharge.RechargeStatus_Failed
+		updated.Status = recharge.RechargeStatus_Failed
 	}
 
-	rechargeRecord.UpdateTime = now
-
-	// 5. 保存更新后的记录
-	if _, err := mongodb.Save(rechargeRecord); err != nil {
-		log.Error("保存充值记录失败: %v", err)
+	// 4. 订单状态与玩家余额在同一个事务中修改，订单版本号防止重复回调重复发放
+	saved, err := m.commitRecharge(updated, totalAmount, success)
+	if err != nil {
+		// 缓存的记录可能已过期，下次从数据库重新读取
+		m.records.Invalidate(orderId)
+		logger.With("orderId", orderId).Error("支付回调处理失败: %v", err)
 		return err
 	}
 
-	// 6. 更新缓存
-	m.updateRechargeRecordCache(rechargeRecord)
+	// 5. 更新缓存，离线玩家的缓存已过期
+	m.records.Set(saved.Id, &saved)
+	if success {
+		GetUserManager().InvalidateOfflinePlayer(saved.PlayerId)
+		logger.With("playerId", saved.PlayerId, "orderId", orderId).Debug("玩家充值成功: Amount=%d, TotalAmount=%d",
+			saved.Amount, totalAmount)
+	}
 
-	log.Debug("支付回调处理完成: OrderId=%s, Success=%v", orderId, success)
+	logger.With("orderId", orderId).Debug("支付回调处理完成: Success=%v", success)
 	return nil
 }
 
-// 更新玩家余额
-func (m *RechargeManager) updatePlayerBalance(rechargeRecord *recharge.RechargeRecord) error {
-	// 1. 获取玩家实例
-	playerInstance := GetUserManager().GetPlayer(rechargeRecord.PlayerId)
-	if playerInstance == nil {
-		return fmt.Errorf("玩家不在线: %d", rechargeRecord.PlayerId)
+// commitRecharge 提交订单和余额的事务，返回保存后的订单
+// 在线玩家在玩家Actor中提交并修改内存数据，与玩家Actor中的修改和下线保存按顺序执行，
+// 整文档保存不会用提交前的余额覆盖$inc的结果；Actor已停止时玩家已下线保存，直接提交
+func (m *RechargeManager) commitRecharge(updated recharge.RechargeRecord, totalAmount int64, success bool) (recharge.RechargeRecord, error) {
+	commit := func() (recharge.RechargeRecord, error) {
+		var saved recharge.RechargeRecord
+		err := mongodb.WithTransaction(context.Background(), func(ctx context.Context) error {
+			// 事务可能重试，每次都从修改前的版本开始
+			saved = updated
+			if err := rechargeRecordRepo.SaveVersioned(ctx, &saved); err != nil {
+				return err
+			}
+			if success {
+				return m.addPlayerBalance(ctx, &saved, totalAmount)
+			}
+			return nil
+		})
+		return saved, err
 	}
-
-	// 2. 计算充值金额（包含赠送）
-	totalAmount := rechargeRecord.Amount
-	if config := m.getRechargeConfig(rechargeRecord.ConfigId); config != nil {
-		totalAmount += int64(config.Bonus)
+	if !success {
+		return commit()
 	}
 
-	// 3. 更新玩家数据
-	playerInstance.PlayerInfo.Balance += totalAmount
-	playerInstance.PlayerInfo.TotalRecharge += rechargeRecord.Amount
-
-	// 4. 计算VIP等级
-	m.updateVipLevel(playerInstance)
+	if playerInstance := GetUserManager().GetPlayer(updated.PlayerId); playerInstance != nil {
+		response := playerInstance.SendTask(func() *actor.Response {
+			saved, err := commit()
+			if err == nil {
+				playerInstance.PlayerInfo.Balance += totalAmount
+				playerInstance.PlayerInfo.TotalRecharge += saved.Amount
+				m.updateVipLevel(playerInstance)
+			}
+			return &actor.Response{Result: []interface{}{saved, err}}
+		})
+		if response.Error == nil {
+			err, _ := response.Result[1].(error)
+			return response.Result[0].(recharge.RechargeRecord), err
+		}
+		// Actor停止时任务可能已经提交，再次提交时订单版本冲突，不会重复发放
+	}
+	return commit()
+}
 
-	// 5. 保存到数据库
-	if _, err := mongodb.Save(playerInstance); err != nil {
+// addPlayerBalance 在充值事务中增加玩家余额与累计充值，玩家不在线也可以到账
+func (m *RechargeManager) addPlayerBalance(ctx context.Context, rechargeRecord *recharge.RechargeRecord, totalAmount int64) error {
+	p, err := playerRepo.FindOne(ctx, mongodb.Where(bson.M{"_id": rechargeRecord.PlayerId}).
+		Select("player_info.total_recharge", "player_info.vip_level"))
+	if err != nil {
 		return err
 	}
+	if p == nil || p.PlayerInfo == nil {
+		return fmt.Errorf("玩家不存在: %d", rechargeRecord.PlayerId)
+	}
 
-	// 6. 发送充值成功通知（简化版本，直接发送文本消息）
-	log.Debug("玩家充值成功: PlayerId=%d, Amount=%d, TotalAmount=%d, Balance=%d",
-		rechargeRecord.PlayerId, rechargeRecord.Amount, totalAmount, playerInstance.PlayerInfo.Balance)
-
-	return nil
+	update := mongodb.NewUpdate().
+		Inc("player_info.balance", totalAmount).
+		Inc("player_info.total_recharge", rechargeRecord.Amount)
+	if vipLevel := calcVipLevel(p.PlayerInfo.TotalRecharge + rechargeRecord.Amount); vipLevel > p.PlayerInfo.VipLevel {
+		update.Set("player_info.vip_level", vipLevel)
+	}
+	_, err = playerRepo.Update(ctx, rechargeRecord.PlayerId, update)
+	return err
 }
 
 // 更新VIP等级
 func (m *RechargeManager) updateVipLevel(playerInstance *player.Player) {
-	totalRecharge := playerInstance.PlayerInfo.TotalRecharge
+	newVipLevel := calcVipLevel(playerInstance.PlayerInfo.TotalRecharge)
+	if newVipLevel > playerInstance.PlayerInfo.VipLevel {
+		playerInstance.PlayerInfo.VipLevel = newVipLevel
+		logger.With("playerId", playerInstance.PlayerId).Debug("玩家VIP等级提升: VipLevel=%d", newVipLevel)
+	}
+}
 
+// calcVipLevel 根据累计充值计算VIP等级
+func calcVipLevel(totalRecharge int64) int32 {
 	// todo 使用配置表
 	// 简单的VIP等级计算逻辑
-	var newVipLevel int32
 	switch {
 	case totalRecharge >= 1000000: // 10000元
-		newVipLevel = 5
+		return 5
 	case totalRecharge >= 500000: // 5000元
-		newVipLevel = 4
+		return 4
 	case totalRecharge >= 200000: // 2000元
-		newVipLevel = 3
+		return 3
 	case totalRecharge >= 100000: // 1000元
-		newVipLevel = 2
+		return 2
 	case totalRecharge >= 50000: // 500元
-		newVipLevel = 1
-	default:
-		newVipLevel = 0
-	}
-
-	if newVipLevel > playerInstance.PlayerInfo.VipLevel {
-		playerInstance.PlayerInfo.VipLevel = newVipLevel
-		log.Debug("玩家VIP等级提升: PlayerId=%d, VipLevel=%d",
-			playerInstance.PlayerId, newVipLevel)
+		return 1
 	}
+	return 0
 }
 
 // 生成支付信息
@@ -278,59 +320,26 @@ func (m *RechargeManager) getRechargeConfig(configId string) *config.Recharge {
 		return nil
 	}
 
-	// 从缓存获取
-	if value, exists := rechargeConfigCache.Load(configId); exists {
-		if config, ok := value.(*config.Recharge); ok {
-			return config
-		}
-	}
-
-	// 从配置系统获取
+	// 直接读配置表，热更新后立即生效
 	config, exists := config.GetRechargeConfig(configId)
 	if !exists {
-		log.Error("获取充值配置失败: %s", configId)
+		logger.Error("获取充值配置失败: %s", configId)
 		return nil
 	}
-
-	// 更新缓存
-	m.updateRechargeConfigCache(config)
-
 	return config
 }
 
 // 获取充值记录
 func (m *RechargeManager) getRechargeRecord(orderId string) *recharge.RechargeRecord {
-	// 从缓存获取
-	if value, exists := rechargeRecordCache.Load(orderId); exists {
-		if record, ok := value.(*recharge.RechargeRecord); ok {
-			return record
-		}
-	}
-
-	// 从数据库获取
-	record, err := mongodb.FindOneById[recharge.RechargeRecord](orderId)
+	// 缓存未命中时从数据库获取
+	record, _, err := m.records.Load(orderId)
 	if err != nil {
-		log.Error("获取充值记录失败: %v", err)
+		logger.Error("获取充值记录失败: %v", err)
 		return nil
 	}
-
-	if record != nil {
-		m.updateRechargeRecordCache(record)
-	}
-
 	return record
 }
 
-// 更新充值配置缓存
-func (m *RechargeManager) updateRechargeConfigCache(config *config.Recharge) {
-	rechargeConfigCache.Store(config.Id, config)
-}
-
-// 更新充值记录缓存
-func (m *RechargeManager) updateRechargeRecordCache(record *recharge.RechargeRecord) {
-	rechargeRecordCache.Store(record.Id, record)
-}
-
 // GetRechargeConfigs 获取充值配置列表 - 异步执行
 func (m *RechargeManager) GetRechargeConfigs() []*config.Recharge {
 	response := m.SendTask(func() *actor.Response {
@@ -350,33 +359,8 @@ func (m *RechargeManager) GetRechargeConfigs() []*config.Recharge {
 
 // doGetRechargeConfigs 获取充值配置列表的同步实现
 func (m *RechargeManager) doGetRechargeConfigs() []*config.Recharge {
-	// 从配置系统获取所有配置
-	configs, exists := config.GetAllRechargeConfigs()
-	if !exists {
-		log.Error("获取充值配置列表失败")
-		return nil
-	}
-
-	// 转换为切片
-	var configList []*config.Recharge
-	for _, config := range configs {
-		// 只返回激活的配置
-		if config.Is {
-			configList = append(configList, config)
-		}
-	}
-
-	// 按排序字段排序
-	sort.Slice(configList, func(i, j int) bool {
-		return configList[i].Sort < configList[j].Sort
-	})
-
-	// 更新缓存
-	for _, config := range configList {
-		m.updateRechargeConfigCache(config)
-	}
-
-	return configList
+	// 上架的档位，生成代码加载时已按sort_order排好序
+	return config.ListRechargeByIsActive(true)
 }
 
 // GetPlayerRechargeRecords 获取玩家充值记录 - 异步执行
@@ -398,18 +382,14 @@ func (m *RechargeManager) GetPlayerRechargeRecords(playerId int64, limit int) []
 
 // doGetPlayerRechargeRecords 获取玩家充值记录的同步实现
 func (m *RechargeManager) doGetPlayerRechargeRecords(playerId int64, limit int) []recharge.RechargeRecord {
-	query := bson.M{"player_id": playerId}
-	recordsResult, err := mongodb.FindAll[recharge.RechargeRecord](query)
+	// 最新的在前，limit为0时不限制数量
+	query := mongodb.Where(bson.M{"player_id": playerId}).Desc("create_time").Limit(int64(limit))
+	recordsResult, err := rechargeRecordRepo.Find(context.Background(), query)
 	if err != nil {
-		log.Error("获取玩家充值记录失败: %v", err)
+		logger.With("playerId", playerId).Error("获取玩家充值记录失败: %v", err)
 		return nil
 	}
 
-	// 限制数量
-	if limit > 0 && len(recordsResult) > limit {
-		recordsResult = recordsResult[:limit]
-	}
-
 	return recordsResult
 }
 
@@ -2,6 +2,7 @@ package team
 
 import (
 	"gameserver/common/base/actor"
+	"gameserver/common/broadcast"
 	"gameserver/common/db/mongodb"
 	"gameserver/common/models"
 	"gameserver/common/utils"
@@ -12,12 +13,16 @@ import (
 
 type Team struct {
 	*actor.TaskHandler `bson:"-"`
-	TeamId             int64   `bson:"_id"`
-	LeaderId           int64   `bson:"leader_id"`
-	TeamMembers        []int64 `bson:"team_members"`
-	RoomId             int64   `bson:"room_id"`
+	TeamId             int64       `bson:"_id"`
+	LeaderId           int64       `bson:"leader_id"`
+	TeamMembers        []int64     `bson:"team_members"`
+	RoomId             int64       `bson:"room_id"`
+	logger             *log.Logger `bson:"-"` // 绑定了队伍id的日志
 }
 
+// logger game模块的日志
+var logger = log.Module("game")
+
 func (t Team) GetPersistId() interface{} {
 	return t.TeamId
 }
@@ -28,11 +33,12 @@ func InitTeam(agent gate.Agent) *Team {
 	playerId := user.PlayerId
 
 	teamId := utils.FlakeId()
-	log.Debug("开始初始化队伍，玩家ID: %d, 队伍ID: %d", playerId, teamId)
+	logger.With("playerId", playerId, "teamId", teamId).Debug("开始初始化队伍")
 
 	team := &Team{
 		TeamId:   teamId,
 		LeaderId: playerId,
+		logger:   logger.With("teamId", teamId),
 	}
 	// 注册Actor
 	team.TaskHandler = actor.InitTaskHandler(actor.Team, teamId, team)
@@ -48,20 +54,22 @@ func (t *Team) Stop() {
 	t.TaskHandler.Stop()
 }
 
-func (t *Team) JoinTeam(playerId int64) {
+// JoinTeam 玩家加入队伍，agent为玩家当前的连接，在线时加入队伍频道
+func (t *Team) JoinTeam(playerId int64, agent gate.Agent) {
 	t.SendTask(func() *actor.Response {
-		t.doJoinTeam(playerId)
+		t.doJoinTeam(playerId, agent)
 		return nil
 	})
 }
 
-func (t *Team) doJoinTeam(playerId int64) {
+func (t *Team) doJoinTeam(playerId int64, agent gate.Agent) {
 	if t.LeaderId == 0 {
 		t.LeaderId = playerId
 	}
 
 	t.TeamMembers = append(t.TeamMembers, playerId)
-	log.Debug("玩家 %d 成功加入队伍 %d，当前成员数量: %d", playerId, t.TeamId, len(t.TeamMembers))
+	broadcast.Subscribe(agent, broadcast.Team(t.TeamId))
+	t.logger.With("playerId", playerId).Debug("玩家成功加入队伍，当前成员数量: %d", len(t.TeamMembers))
 }
 
 func (t *Team) JoinRoom(roomId int64) {
@@ -73,7 +81,7 @@ func (t *Team) JoinRoom(roomId int64) {
 
 func (t *Team) doJoinRoom(roomId int64) {
 	t.RoomId = roomId
-	log.Debug("队伍 %d 成功加入房间 %d", t.TeamId, roomId)
+	t.logger.With("roomId", roomId).Debug("队伍成功加入房间")
 }
 
 func (t *Team) LeaveRoom() {
@@ -85,37 +93,43 @@ func (t *Team) LeaveRoom() {
 
 func (t *Team) doLeaveRoom() {
 	t.RoomId = 0
-	log.Debug("队伍 %d 成功离开房间", t.TeamId)
+	t.logger.Debug("队伍成功离开房间")
 }
 
-func (t *Team) LeaveTeam(playerId int64) {
+// LeaveTeam 玩家离开队伍，agent为玩家当前的连接，离线时为nil，断线的连接已退出所有频道
+func (t *Team) LeaveTeam(playerId int64, agent gate.Agent) {
 	t.SendTask(func() *actor.Response {
-		t.doLeaveTeam(playerId)
+		t.doLeaveTeam(playerId, agent)
 		return nil
 	})
 }
 
-func (t *Team) doLeaveTeam(playerId int64) {
-	log.Debug("玩家 %d 请求离开队伍 %d", playerId, t.TeamId)
+func (t *Team) doLeaveTeam(playerId int64, agent gate.Agent) {
+	logger := t.logger.With("playerId", playerId)
+	logger.Debug("玩家请求离开队伍")
+	if agent != nil {
+		broadcast.Unsubscribe(agent, broadcast.Team(t.TeamId))
+	}
 
 	// 检查是否是队长离开
 	if t.IsLeader(playerId) {
 		t.LeaderId = 0
-		log.Debug("队伍 %d 的队长 %d 离开，队长职位空缺", t.TeamId, playerId)
+		logger.Debug("队长离开，队长职位空缺")
 	}
 
 	// 从成员列表中移除
 	for i, v := range t.TeamMembers {
 		if v == playerId {
 			t.TeamMembers = append(t.TeamMembers[:i], t.TeamMembers[i+1:]...)
-			log.Debug("从队伍 %d 中移除玩家 %d", t.TeamId, playerId)
+			logger.Debug("从队伍中移除玩家")
 			break
 		}
 	}
 
 	// 检查队伍是否为空
 	if len(t.TeamMembers) == 0 {
-		log.Debug("队伍 %d 已无成员，停止队伍Actor", t.TeamId)
+		logger.Debug("队伍已无成员，停止队伍Actor")
+		broadcast.CloseChannel(broadcast.Team(t.TeamId))
 		t.Stop()
 		mongodb.DeleteByID[Team](t.TeamId)
 		return
@@ -124,10 +138,10 @@ func (t *Team) doLeaveTeam(playerId int64) {
 	// 如果队长职位空缺，选择第一个成员作为新队长
 	if t.LeaderId == 0 && len(t.TeamMembers) > 0 {
 		t.LeaderId = t.TeamMembers[0]
-		log.Debug("队伍 %d 选择新队长: %d", t.TeamId, t.LeaderId)
+		logger.Debug("队伍选择新队长: %d", t.LeaderId)
 	}
 
-	log.Debug("玩家 %d 离开队伍 %d 完成，剩余成员数量: %d", playerId, t.TeamId, len(t.TeamMembers))
+	logger.Debug("玩家离开队伍完成，剩余成员数量: %d", len(t.TeamMembers))
 }
 
 // IsMember 检查玩家是否是队伍成员
@@ -2,7 +2,7 @@ package managers
 
 import (
 	"gameserver/common/base/actor"
-	"gameserver/core/log"
+	"gameserver/common/broadcast"
 	"gameserver/modules/game/internal/managers/team"
 	"sync"
 
@@ -115,18 +115,7 @@ func (t *TeamManager) SendMessage(teamId int64, msg proto.Message) {
 	})
 }
 
-// doSendMessage 发送消息给队伍的同步实现
+// doSendMessage 发送消息给队伍的同步实现，在线成员已订阅队伍频道
 func (t *TeamManager) doSendMessage(teamId int64, msg proto.Message) {
-	team, ok := actor.GetActor[team.Team](actor.Team, teamId)
-	if !ok {
-		return
-	}
-	for _, member := range team.TeamMembers {
-		p := GetUserManager().GetPlayer(member)
-		if p == nil {
-			log.Debug("玩家 %d 不在线", member)
-			continue
-		}
-		p.SendToClient(msg)
-	}
+	broadcast.Publish(broadcast.Team(teamId), msg)
 }
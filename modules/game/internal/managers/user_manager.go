@@ -1,29 +1,48 @@
 package managers
 
 import (
+	"context"
 	"fmt"
 	"gameserver/common/base/actor"
+	"gameserver/common/broadcast"
+	"gameserver/common/cache"
 	"gameserver/common/db/mongodb"
 	"gameserver/common/models"
 	"gameserver/common/msg/message"
+	"gameserver/common/sanction"
 	"gameserver/common/utils"
+	"gameserver/core/cluster"
 	"gameserver/core/gate"
 	"gameserver/core/log"
 	"gameserver/modules/game/internal/managers/player"
 	"math/rand"
+	"slices"
 	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/proto"
 )
 
+// S2C_Login.LoginResult
+const (
+	loginSuccess     int32 = 1
+	loginFailed      int32 = -1
+	loginBanned      int32 = -2
+	loginMaintenance int32 = -3
+)
+
+// takeoverTimeout 顶号时等待其他节点上的玩家下线的时间
+const takeoverTimeout = 5 * time.Second
+
 // UserManager 使用BaseActor实现，确保缓存操作按顺序执行
 type UserManager struct {
 	*actor.TaskHandler
-	memCache        map[string]*models.User  // 用户缓存
-	playerCache     map[int64]*player.Player // 玩家缓存
-	nameCache       map[string]bool          // 名称缓存，key: playerName, value: bool (true表示已存在)
-	nameBloomFilter *utils.BloomFilter       // 布隆过滤器，用于快速判断名称是否可能重复
+	users           *cache.Cache[string, models.User]   // 在线用户，登录和下线时间写回数据库
+	players         *cache.Cache[int64, *player.Player] // 在线玩家，由玩家Actor负责存盘
+	offlinePlayers  *cache.Cache[int64, *player.Player] // 离线玩家，只读，从数据库加载
+	names           *cache.Cache[string, bool]          // 名称缓存，key: playerName, value: bool (true表示已存在)
+	nameBloomFilter *utils.BloomFilter                  // 布隆过滤器，用于快速判断名称是否可能重复
 }
 
 var (
@@ -31,6 +50,9 @@ var (
 	userManagerOnce sync.Once
 )
 
+// logger game模块的日志，级别可以用 log.SetModuleLevel("game", ...) 单独调整
+var logger = log.Module("game")
+
 func GetUserManager() *UserManager {
 	userManagerOnce.Do(func() {
 		userManager = &UserManager{}
@@ -40,9 +62,26 @@ func GetUserManager() *UserManager {
 }
 
 func (m *UserManager) Init() {
-	m.memCache = make(map[string]*models.User)
-	m.playerCache = make(map[int64]*player.Player)
-	m.nameCache = make(map[string]bool)
+	// 在线的用户和玩家不能被淘汰，下线时移除
+	m.users = cache.New(cache.Options[string, models.User]{
+		Name:   "user",
+		Writer: cache.SaveAll[string, models.User](),
+	})
+	m.players = cache.New(cache.Options[int64, *player.Player]{
+		Name: "player",
+	})
+	m.offlinePlayers = cache.New(cache.Options[int64, *player.Player]{
+		Name:    "offline_player",
+		MaxSize: 1000,
+		TTL:     5 * time.Minute,
+		Loader:  cache.ById[int64](playerRepo),
+	})
+	m.names = cache.New(cache.Options[string, bool]{
+		Name:    "player_name",
+		MaxSize: 100000,
+		TTL:     30 * time.Minute,
+		Loader:  loadNameExists,
+	})
 	m.TaskHandler = actor.InitTaskHandler(actor.User, "1", m)
 	// 假设最多支持100万个名称，误判率控制在1%以内
 	m.nameBloomFilter = utils.NewBloomFilter(1000000, 7)
@@ -56,76 +95,223 @@ func (m *UserManager) Stop() {
 }
 
 // UserLogin 用户登录 - 异步执行
-func (m *UserManager) UserLogin(agent gate.Agent, openId string, serverId int32, loginType message.LoginType) {
+// token为登录模块签发的会话令牌，登录成功时随S2C_Login下发
+func (m *UserManager) UserLogin(agent gate.Agent, openId string, serverId int32, loginType message.LoginType, token string, tokenExpire int64) {
 	m.SendTask(func() *actor.Response {
-		m.doUserLogin(agent, openId, serverId, loginType)
+		m.doUserLogin(agent, openId, serverId, loginType, token, tokenExpire)
 		return nil
 	})
 }
 
 // userLoginSync 用户登录的同步实现
-func (m *UserManager) doUserLogin(agent gate.Agent, openId string, serverId int32, loginType message.LoginType) {
-	// 1. 优先从缓存查找用户（检测顶号操作）
-	accountId := fmt.Sprintf("%d_%s", serverId, openId)
-	if existingUser, exists := m.getUserFromCache(accountId); exists {
-		log.Debug("UserLogin: user already online (顶号操作): %s", accountId)
-		// 处理顶号逻辑：先让旧用户下线
-		m.doUserOffline(*existingUser)
+func (m *UserManager) doUserLogin(agent gate.Agent, openId string, serverId int32, loginType message.LoginType, token string, tokenExpire int64) {
+	// 1. 按登录身份查找用户，绑定过的身份也能找到原来的用户
+	user, err := models.FindUserByIdentity(context.Background(), serverId, loginType, openId)
+	if err != nil {
+		logger.Error("UserLogin find user failed: %v", err)
+		return
 	}
 
-	// 2. 从数据库查询用户
-	user, err := mongodb.FindOne[models.User](bson.M{"OpenId": openId, "ServerId": serverId})
-	if err != nil {
-		log.Error("UserLogin find user failed: %v", err)
+	// 2. 封号和维护检查，被拒绝时不影响已经在线的连接
+	if !m.checkLoginAllowed(agent, user) {
 		return
 	}
 
+	// 3. 用户已在线（顶号操作）
+	if user != nil {
+		if existingUser, exists := m.users.Get(user.AccountId); exists {
+			logger.With("accountId", user.AccountId).Debug("UserLogin: user already online (顶号操作)")
+			// 处理顶号逻辑：先让旧用户下线，下线时写回的数据需要重新读取
+			m.doUserOffline(existingUser)
+			if user, err = models.FindUserByIdentity(context.Background(), serverId, loginType, openId); err != nil || user == nil {
+				logger.With("accountId", existingUser.AccountId).Error("UserLogin reload user failed: %v", err)
+				return
+			}
+		}
+		// 玩家在其他节点上在线，等那边下线存盘后再加载，同一个玩家只在一个节点上
+		if !m.takeoverRemote(user.PlayerId) {
+			agent.WriteMsg(&message.S2C_Login{LoginResult: loginFailed})
+			agent.Close()
+			return
+		}
+	}
+
 	isNew := user == nil
 	if isNew {
-		// 新注册流程
+		// 新注册流程，账号id由首次登录的身份组成
+		key := models.IdentityKey(loginType, openId)
 		user = &models.User{
-			AccountId: accountId,
-			OpenId:    openId,
+			AccountId: models.AccountId(serverId, key),
+			OpenId:    key,
 			ServerId:  serverId,
 			PlayerId:  utils.FlakeId(),
 			Platform:  loginType,
 		}
 		if _, err := mongodb.Save(user); err != nil {
-			log.Error("Failed to save new user [openId: %s, serverId: %d]: %v", openId, serverId, err)
+			logger.With("openId", openId, "serverId", serverId).Error("Failed to save new user: %v", err)
 			return
 		}
-		log.Debug("UserLogin new user: %v", user)
+		logger.Debug("UserLogin new user: %v", user)
 	} else {
 		// 老用户流程
-		log.Debug("UserLogin old user: %v", user)
+		logger.Debug("UserLogin old user: %v", user)
 	}
 
 	user.LoginTime = time.Now().Unix()
-	log.Debug("user login: %s", user.AccountId)
+	logger.With("accountId", user.AccountId).Debug("user login")
 
 	// 设置用户数据到agent
 	agent.SetUserData(*user)
 
-	// 更新缓存
-	m.updateUserCache(user)
+	// 更新缓存，登录时间延迟写回
+	m.users.Store(user.AccountId, *user)
+	// 上线后以玩家Actor中的数据为准
+	m.offlinePlayers.Invalidate(user.PlayerId)
 
 	// 调用玩家登录
 	p := player.Login(agent, isNew)
 	if p == nil {
 		agent.WriteMsg(&message.S2C_Login{
-			LoginResult: -1,
+			LoginResult: loginFailed,
 		})
 		agent.Close()
-		log.Debug("UserLogin failed: %v", p)
+		logger.Debug("UserLogin failed: %v", p)
 		return
 	}
-	m.updatePlayerCache(p)
+	m.players.Set(p.PlayerId, p)
+	broadcast.Subscribe(agent, broadcast.World, broadcast.Server(serverId))
 	p.SendToClient(&message.S2C_Login{
-		LoginResult: 1,
-		PlayerInfo:  p.PlayerInfo.ToMsgPlayerInfo(),
+		LoginResult:   loginSuccess,
+		PlayerInfo:    p.PlayerInfo.ToMsgPlayerInfo(),
+		SessionToken:  token,
+		SessionExpire: tokenExpire,
 	})
 }
 
+// takeoverRemote 玩家在其他节点上时让那边断开连接，等玩家Actor停止（下线存盘之后）的通知到达
+// 没有在其他节点上或那个节点已经断开时返回true，超时或通知失败时返回false，拒绝本次登录
+func (m *UserManager) takeoverRemote(playerId int64) bool {
+	node := actor.Locate(actor.Player, playerId)
+	if node == cluster.Self() {
+		return true
+	}
+	l := logger.With("playerId", playerId, "node", node)
+	l.Debug("UserLogin: player online on other node (顶号操作)")
+	if !slices.Contains(cluster.Links(), node) {
+		// 节点已经断开，目录中的登记是断开前的
+		l.Warn("UserLogin takeover: node not linked")
+		return true
+	}
+	if _, err := actor.Request(actor.Player, playerId, "Takeover"); err == actor.ErrActorNotFound {
+		return true
+	} else if err != nil {
+		l.Error("UserLogin takeover failed: %v", err)
+		return false
+	}
+	for deadline := time.Now().Add(takeoverTimeout); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if actor.Locate(actor.Player, playerId) != node {
+			return true
+		}
+	}
+	l.Error("UserLogin takeover timeout")
+	return false
+}
+
+// checkLoginAllowed 检查封号和维护模式，不允许登录时回复原因并断开连接
+// 新用户还没有玩家，不会被封号，维护期间也不能注册
+func (m *UserManager) checkLoginAllowed(agent gate.Agent, user *models.User) bool {
+	reject := func(resp *message.S2C_Login) bool {
+		agent.WriteMsg(resp)
+		agent.Close()
+		return false
+	}
+
+	var playerId int64
+	if user != nil {
+		playerId = user.PlayerId
+		ban, err := sanction.Find(playerId, models.SanctionBan)
+		if err != nil {
+			logger.With("playerId", playerId).Error("UserLogin find ban failed: %v", err)
+			return reject(&message.S2C_Login{LoginResult: loginFailed})
+		}
+		if ban != nil {
+			logger.With("playerId", playerId).Debug("UserLogin banned: %s", ban.Reason)
+			return reject(&message.S2C_Login{
+				LoginResult: loginBanned,
+				Reason:      ban.Reason,
+				BanExpire:   ban.ExpireTime,
+			})
+		}
+	}
+
+	allowed, err := sanction.AllowLogin(playerId)
+	if err != nil {
+		logger.With("playerId", playerId).Error("UserLogin find whitelist failed: %v", err)
+		return reject(&message.S2C_Login{LoginResult: loginFailed})
+	}
+	if !allowed {
+		return reject(&message.S2C_Login{LoginResult: loginMaintenance})
+	}
+	return true
+}
+
+// Kick 把msg发给在线玩家后断开连接，玩家不在线时返回false
+// 在UserManager的Actor中执行并阻塞等待结果，不能在UserManager的任务中调用
+func (m *UserManager) Kick(playerId int64, msg proto.Message) bool {
+	response := m.SendTask(func() *actor.Response {
+		return &actor.Response{
+			Result: []interface{}{m.doKick(playerId, msg)},
+		}
+	})
+
+	if response != nil && len(response.Result) > 0 {
+		if kicked, ok := response.Result[0].(bool); ok {
+			return kicked
+		}
+	}
+	return false
+}
+
+// doKick 断开连接后由CloseAgent走正常的下线流程
+func (m *UserManager) doKick(playerId int64, msg proto.Message) bool {
+	p, ok := m.players.Get(playerId)
+	if !ok {
+		return false
+	}
+	p.SendToClient(msg)
+	p.CloseAgent()
+	logger.With("playerId", playerId).Debug("kick player")
+	return true
+}
+
+// BindIdentity 把登录身份绑定到用户 - 异步执行
+func (m *UserManager) BindIdentity(user models.User, loginType message.LoginType, openId string) message.Result {
+	response := m.SendTask(func() *actor.Response {
+		result := m.doBindIdentity(user, loginType, openId)
+		return &actor.Response{
+			Result: []interface{}{result},
+		}
+	})
+
+	if response != nil && len(response.Result) > 0 {
+		if result, ok := response.Result[0].(message.Result); ok {
+			return result
+		}
+	}
+	return message.Result_Fail
+}
+
+// doBindIdentity 绑定登录身份的同步实现，与登录在同一个Actor中执行，避免同一个身份同时登录和绑定
+func (m *UserManager) doBindIdentity(user models.User, loginType message.LoginType, openId string) message.Result {
+	result, err := models.BindIdentity(context.Background(), user, loginType, openId)
+	if err != nil {
+		logger.With("accountId", user.AccountId, "loginType", loginType).Error("BindIdentity failed: %v", err)
+		return message.Result_Fail
+	}
+	logger.With("accountId", user.AccountId, "loginType", loginType).Debug("BindIdentity result: %v", result)
+	return result
+}
+
 // ModifyName 修改名称 - 异步执行
 func (m *UserManager) ModifyName(playerId int64, name string) message.Result {
 	response := m.SendTask(func() *actor.Response {
@@ -145,8 +331,7 @@ func (m *UserManager) ModifyName(playerId int64, name string) message.Result {
 
 // modifyNameSync 修改名称的同步实现
 func (m *UserManager) doModifyName(playerId int64, name string) message.Result {
-	p := m.getPlayerFromCache(playerId)
-	if p != nil {
+	if p, ok := m.players.Get(playerId); ok {
 		result := p.ModifyName(name)
 		if result == message.Result_Success {
 			m.AddNameToCache(name)
@@ -165,18 +350,38 @@ func (m *UserManager) UserOffline(user models.User) {
 	})
 }
 
+// savePlayer 在玩家Actor中保存玩家，Actor已停止时直接保存
+func savePlayer(p *player.Player) error {
+	response := p.SendTask(func() *actor.Response {
+		_, err := mongodb.Save(p)
+		return &actor.Response{Result: []interface{}{err}}
+	})
+	if response.Error != nil {
+		_, err := mongodb.Save(p)
+		return err
+	}
+	err, _ := response.Result[0].(error)
+	return err
+}
+
 // UserOfflineSync 玩家下线处理的同步实现
 func (m *UserManager) doUserOffline(user models.User) {
 	// 先从缓存获取玩家信息
-	p := m.getPlayerFromCache(user.PlayerId)
-	if p != nil {
+	if p, ok := m.players.Get(user.PlayerId); ok {
+		// 下线立即落地，Actor停止后不会再被定时保存
+		// 在玩家Actor中保存，与充值等在玩家Actor中修改的数据按顺序执行
+		if err := savePlayer(p); err != nil {
+			logger.With("playerId", user.PlayerId).Error("User offline save player failed: %v", err)
+		}
+		m.offlinePlayers.Invalidate(user.PlayerId)
+
 		// 异步停止玩家Actor，避免在TaskHandler上下文中调用Stop造成死锁
 		go func() {
 			p.Stop()
 		}()
 
 		// 清理玩家缓存
-		m.removePlayerCache(user.PlayerId)
+		m.players.Remove(user.PlayerId)
 
 		// todo 玩家离线是否需要离开队伍？有可能需要重连房间
 		// teamInfo, ok := actor.GetActor[team.Team](actor.Team, p.TeamId)
@@ -184,15 +389,22 @@ func (m *UserManager) doUserOffline(user models.User) {
 		// 	return
 		// }
 		// // 直接调用，避免在TaskHandler上下文中再次调用SendTask造成死锁
-		// teamInfo.doLeaveTeam(p.PlayerId)
+		// teamInfo.doLeaveTeam(p.PlayerId, nil)
 
 		p.CloseAgent()
 	}
 
-	// 清理用户缓存
-	m.removeUserCache(user.AccountId)
+	// 清理用户缓存，下线时间和未写回的登录时间一起落地
+	if cached, ok := m.users.Get(user.AccountId); ok {
+		user = cached
+	}
+	user.LastOfflineTime = time.Now().Unix()
+	m.users.Store(user.AccountId, user)
+	if err := m.users.Remove(user.AccountId); err != nil {
+		logger.With("accountId", user.AccountId).Error("User offline save user failed: %v", err)
+	}
 
-	log.Debug("User offline: %s, PlayerId: %d", user.AccountId, user.PlayerId)
+	logger.With("accountId", user.AccountId, "playerId", user.PlayerId).Debug("User offline")
 }
 
 // CheckName 检查名称 - 异步执行
@@ -225,34 +437,25 @@ func (m *UserManager) checkNameSync(playerName string) message.Result {
 		return message.Result_Success
 	}
 
-	// 3. 检查内存缓存
-	if value, exists := m.nameCache[playerName]; exists {
-		if isDuplicate := value; isDuplicate {
-			return message.Result_Duplicate
-		} else {
-			return message.Result_Success
-		}
-	}
-
-	// 4. 缓存未命中，查询数据库
-	existingPlayer, err := mongodb.FindOne[player.Player](bson.M{"player_info.player_name": playerName})
+	// 3. 检查内存缓存，未命中时查询数据库
+	isDuplicate, _, err := m.names.Load(playerName)
 	if err != nil {
-		log.Error("CheckName query database failed: %v", err)
+		logger.Error("CheckName query database failed: %v", err)
 		return message.Result_Fail
 	}
-
-	// 5. 更新缓存和布隆过滤器
-	if existingPlayer != nil {
-		// 名称已存在
-		m.nameCache[playerName] = true
-		m.nameBloomFilter.Add(playerName)
+	if isDuplicate {
 		return message.Result_Duplicate
-	} else {
-		// 名称可用
-		m.nameCache[playerName] = false
-		m.nameBloomFilter.Add(playerName)
-		return message.Result_Success
 	}
+	return message.Result_Success
+}
+
+// loadNameExists 从数据库查询名称是否已被使用，不存在的名称也缓存下来
+func loadNameExists(playerName string) (bool, bool, error) {
+	n, err := playerRepo.Count(context.Background(), bson.M{"player_info.player_name": playerName})
+	if err != nil {
+		return false, false, err
+	}
+	return n > 0, true, nil
 }
 
 // 校验玩家名称合法性
@@ -288,34 +491,32 @@ func (m *UserManager) GetUserByOpenId(openId string, serverId int32) (models.Use
 func (m *UserManager) doGetUserByOpenId(openId string, serverId int32) (models.User, bool) {
 	accountId := fmt.Sprintf("%d_%s", serverId, openId)
 
-	// 1. 优先从缓存获取
-	if user, exists := m.getUserFromCache(accountId); exists {
-		return *user, true
+	// 1. 优先从在线用户中获取
+	if user, exists := m.users.Get(accountId); exists {
+		return user, true
 	}
 
 	// 2. 缓存不存在，从数据库查询
 	user, err := mongodb.FindOne[models.User](bson.M{"OpenId": openId, "ServerId": serverId})
 	if err != nil {
-		log.Error("GetUserByOpenId query database failed for openId %s, serverId %d: %v", openId, serverId, err)
+		logger.With("openId", openId, "serverId", serverId).Error("GetUserByOpenId query database failed: %v", err)
 		return models.User{}, false
 	}
 
 	if user == nil {
-		log.Debug("User not found in database for openId %s, serverId %d", openId, serverId)
+		logger.With("openId", openId, "serverId", serverId).Debug("User not found in database")
 		return models.User{}, false
 	}
 
-	// 3. 查询到用户，更新缓存
-	log.Debug("GetUserByOpenId found user in database: %s, updating cache", accountId)
-	m.updateUserCache(user)
-
+	// 3. 不在线的用户不放入缓存，缓存中只有在线用户
+	logger.With("accountId", accountId).Debug("GetUserByOpenId found user in database")
 	return *user, true
 }
 
 // GetUser 通过accountId获取用户（仅从缓存获取）
 func (m *UserManager) GetUser(accountId string) (models.User, bool) {
 	response := m.SendTask(func() *actor.Response {
-		user, exists := m.getUserFromCache(accountId)
+		user, exists := m.users.Get(accountId)
 		return &actor.Response{
 			Result: []interface{}{user, exists},
 		}
@@ -335,9 +536,10 @@ func (m *UserManager) GetUser(accountId string) (models.User, bool) {
 func (m *UserManager) GetUsers() []models.User {
 	response := m.SendTask(func() *actor.Response {
 		users := []models.User{}
-		for _, user := range m.memCache {
-			users = append(users, *user)
-		}
+		m.users.Range(func(_ string, user models.User) bool {
+			users = append(users, user)
+			return true
+		})
 		return &actor.Response{
 			Result: []interface{}{users},
 		}
@@ -361,27 +563,22 @@ func (m *UserManager) ClearAllCache() {
 // clearAllCacheSync 强制清理所有缓存的同步实现
 func (m *UserManager) doClearAllCache() {
 	// 统计清理前的数量
-	userCount := 0
-	playerCount := 0
-
-	for range m.memCache {
-		userCount++
-	}
+	userCount := m.users.Len()
+	playerCount := m.players.Len()
 
-	for range m.playerCache {
-		playerCount++
+	// 清理所有缓存，用户数据先写回
+	if err := m.users.Clear(); err != nil {
+		logger.Error("Clear user cache failed: %v", err)
 	}
-
-	// 清理所有缓存
-	m.memCache = make(map[string]*models.User)
-	m.playerCache = make(map[int64]*player.Player)
-	log.Debug("Cleared all caches - users: %d, players: %d", userCount, playerCount)
+	m.players.Clear()
+	m.offlinePlayers.Clear()
+	logger.Debug("Cleared all caches - users: %d, players: %d", userCount, playerCount)
 }
 
 // IsUserOnline 检查用户是否在线
 func (m *UserManager) IsUserOnline(accountId string) bool {
 	response := m.SendTask(func() *actor.Response {
-		_, exists := m.memCache[accountId]
+		_, exists := m.users.Get(accountId)
 		return &actor.Response{
 			Result: []interface{}{exists},
 		}
@@ -394,54 +591,28 @@ func (m *UserManager) IsUserOnline(accountId string) bool {
 	return false
 }
 
-// 更新用户缓存
-func (m *UserManager) updateUserCache(user *models.User) {
-	m.memCache[user.AccountId] = user
-}
-
-// 移除用户缓存
-func (m *UserManager) removeUserCache(accountId string) {
-	if _, exists := m.memCache[accountId]; exists {
-		delete(m.memCache, accountId)
-		log.Debug("Removed user cache: %s", accountId)
-	}
-}
-
-// 从缓存获取用户（内部方法）
-func (m *UserManager) getUserFromCache(accountId string) (*models.User, bool) {
-	if user, exists := m.memCache[accountId]; exists {
-		return user, true
-	}
-	return nil, false
-}
-
-// 更新玩家缓存
-func (m *UserManager) updatePlayerCache(playerInstance *player.Player) {
-	m.playerCache[playerInstance.PlayerId] = playerInstance
-}
-
-// 从缓存获取玩家
-func (m *UserManager) getPlayerFromCache(playerId int64) *player.Player {
-	if playerInstance, exists := m.playerCache[playerId]; exists {
-		return playerInstance
-	}
-	return nil
-}
-
-// 移除玩家缓存
-func (m *UserManager) removePlayerCache(playerId int64) {
-	if _, exists := m.playerCache[playerId]; exists {
-		delete(m.playerCache, playerId)
-		log.Debug("Removed player cache: %d", playerId)
+// OnlineCount 在线玩家数 - 异步执行，在之前提交的登录处理完之后统计
+func (m *UserManager) OnlineCount() int {
+	response := m.SendTask(func() *actor.Response {
+		return &actor.Response{
+			Result: []interface{}{m.players.Len()},
+		}
+	})
+	if response != nil && len(response.Result) > 0 {
+		if count, ok := response.Result[0].(int); ok {
+			return count
+		}
 	}
+	return 0
 }
 
 // GetPlayers 获取所有缓存的玩家
 func (m *UserManager) GetPlayers() []*player.Player {
 	var players []*player.Player
-	for _, playerInstance := range m.playerCache {
+	m.players.Range(func(_ int64, playerInstance *player.Player) bool {
 		players = append(players, playerInstance)
-	}
+		return true
+	})
 	return players
 }
 
@@ -474,93 +645,85 @@ func (m *UserManager) GetRandomPlayer(exceptPlayerId []int64) *player.Player {
 // GetPlayer 获取缓存的玩家（优先从缓存获取，缓存没有则从Actor获取）
 func (m *UserManager) GetPlayer(playerId int64) *player.Player {
 	// 优先从缓存获取
-	if cachedPlayer := m.getPlayerFromCache(playerId); cachedPlayer != nil {
+	if cachedPlayer, ok := m.players.Get(playerId); ok {
 		return cachedPlayer
 	}
 
 	// 缓存中没有，从Actor获取
 	if actorPlayer, ok := actor.GetActor[player.Player](actor.Player, playerId); ok {
 		// 获取到后更新缓存
-		m.updatePlayerCache(actorPlayer)
+		m.players.Set(playerId, actorPlayer)
 		return actorPlayer
 	}
 
 	return nil
 }
 
-// GetOfflinePlayer 获取离线玩家
+// GetOfflinePlayer 获取离线玩家，数据只读，最多缓存5分钟
 func (m *UserManager) GetOfflinePlayer(playerId int64) *player.Player {
-	player, err := mongodb.FindOneById[player.Player](playerId)
+	player, exists, err := m.offlinePlayers.Load(playerId)
 	if err != nil {
-		log.Error("获取非在线玩家Info异常: %v, err: %v", playerId, err)
+		logger.With("playerId", playerId).Error("获取非在线玩家Info异常: %v", err)
 		return nil
 	}
-	if player == nil {
-		log.Error("获取非在线玩家Info玩家数据不存在: %v", playerId)
+	if !exists {
+		logger.With("playerId", playerId).Error("获取非在线玩家Info玩家数据不存在")
 		return nil
 	}
 	return player
 }
 
+// InvalidateOfflinePlayer 玩家数据在数据库中被直接修改后，丢弃缓存的离线数据
+func (m *UserManager) InvalidateOfflinePlayer(playerId int64) {
+	m.offlinePlayers.Invalidate(playerId)
+}
+
 // GetPlayerCacheStats 获取玩家缓存统计信息
 func (m *UserManager) GetPlayerCacheStats() map[string]interface{} {
-	count := 0
-	for _, _ = range m.playerCache {
-		count++
-	}
+	count := m.players.Len()
 
 	return map[string]interface{}{
 		"cached_players":    count,
 		"player_cache_size": count,
+		"offline_players":   m.offlinePlayers.Stats(),
 	}
 }
 
 // GetCacheStats 获取缓存统计信息
 func (m *UserManager) GetCacheStats() map[string]interface{} {
-	userCount := 0
-	playerCount := 0
-	nameCount := 0
-
-	for _, _ = range m.memCache {
-		userCount++
-	}
-
-	for _, _ = range m.playerCache {
-		playerCount++
-	}
-
-	for _, _ = range m.nameCache {
-		nameCount++
-	}
+	userCount := m.users.Len()
+	playerCount := m.players.Len()
+	nameCount := m.names.Len()
 
 	return map[string]interface{}{
 		"online_users":     userCount,
 		"cached_players":   playerCount,
 		"cached_names":     nameCount,
 		"total_cache_size": userCount + playerCount + nameCount,
+		"name_cache":       m.names.Stats(),
 	}
 }
 
 // AddNameToCache 添加名称到缓存（用于预加载或批量导入）
 func (m *UserManager) AddNameToCache(playerName string) {
-	m.nameCache[playerName] = true
+	m.names.Set(playerName, true)
 	m.nameBloomFilter.Add(playerName)
 }
 
 // RemoveNameFromCache 从名称缓存中移除（用于清理过期数据）
 func (m *UserManager) RemoveNameFromCache(playerName string) {
-	delete(m.nameCache, playerName)
+	m.names.Invalidate(playerName)
 	// 注意：布隆过滤器不支持删除，这里只清理内存缓存
 }
 
 // PreloadNames 预加载名称到缓存（启动时调用，从数据库加载所有已存在的名称）
 func (m *UserManager) PreloadNames() {
-	log.Debug("Starting to preload player names from database...")
+	logger.Debug("Starting to preload player names from database...")
 
 	// 查询所有玩家名称
 	players, err := mongodb.FindAll[player.Player](bson.M{})
 	if err != nil {
-		log.Error("Failed to preload names: %v", err)
+		logger.Error("Failed to preload names: %v", err)
 		return
 	}
 
@@ -573,5 +736,5 @@ func (m *UserManager) PreloadNames() {
 		}
 	}
 
-	log.Debug("Preloaded %d names from database", count)
+	logger.Debug("Preloaded %d names from database", count)
 }
@@ -1,9 +1,12 @@
 package recharge
 
 import (
+	"gameserver/common/db/mongodb"
 	"gameserver/common/msg/message"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+
 	"github.com/google/uuid"
 )
 
@@ -34,6 +37,7 @@ type RechargeRecord struct {
 	CompleteTime  int64                   `bson:"complete_time"`  // 完成时间
 	Description   string                  `bson:"description"`    // 充值描述
 	Extra         map[string]interface{}  `bson:"extra"`          // 扩展字段
+	Version       int64                   `bson:"version"`        // 版本号，防止重复回调并发修改订单
 }
 
 // 获取持久化ID
@@ -41,6 +45,31 @@ func (r RechargeRecord) GetPersistId() interface{} {
 	return r.Id
 }
 
+// Indexes 玩家充值记录按时间倒序查询；同一平台的交易流水号唯一，防止重复到账
+func (r RechargeRecord) Indexes() []mongodb.Index {
+	return []mongodb.Index{
+		{Keys: mongodb.Keys("player_id", "-create_time")},
+		{Keys: mongodb.Keys("status")},
+		{
+			Keys:    mongodb.Keys("platform", "transaction_id"),
+			Unique:  true,
+			Partial: bson.D{{Key: "transaction_id", Value: bson.M{"$gt": ""}}},
+		},
+	}
+}
+
+func init() {
+	mongodb.RegisterIndexes[RechargeRecord]()
+}
+
+func (r *RechargeRecord) GetVersion() int64 {
+	return r.Version
+}
+
+func (r *RechargeRecord) SetVersion(version int64) {
+	r.Version = version
+}
+
 // 创建新的充值记录
 func NewRechargeRecord(playerId int64, accountId string, amount int64, platform message.PaymentPlatform, configId string) *RechargeRecord {
 	now := time.Now().Unix()
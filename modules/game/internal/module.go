@@ -1,9 +1,20 @@
 package internal
 
 import (
+	"context"
+	"fmt"
 	"gameserver/common"
 	"gameserver/common/base/actor"
+	"gameserver/common/broadcast"
+	"gameserver/common/cache"
+	"gameserver/common/config"
+	"gameserver/common/db/mongodb"
+	"gameserver/common/msg"
+	"gameserver/common/sanction"
+	"gameserver/conf"
+	"gameserver/core/log"
 	"gameserver/core/module"
+	"strings"
 )
 
 var (
@@ -18,8 +29,149 @@ type Module struct {
 func (m *Module) OnInit() {
 	m.Skeleton = skeleton
 	InitHandler()
+	skeleton.RegisterCommand("msglatency", "message handle latency, 'msglatency reset' to clear", commandMsgLatency)
+	skeleton.RegisterCommand("announce", "send announcement to all online players, 'announce <content>'", commandAnnounce)
+	skeleton.RegisterCommand("broadcast", "broadcast channel stats", commandBroadcast)
+	skeleton.RegisterCommand("config", "game config version, 'config reload' or 'config rollback'", commandConfig)
+	skeleton.RegisterCommand("migrate", "upgrade old documents, 'migrate [dry|run] [collection]'", commandMigrate)
+	skeleton.RegisterCommand("index", "mongodb index drift, 'index [diff|apply|prune] [collection]'", commandIndex)
+	skeleton.RegisterCommand("cache", "cache stats, 'cache flush' to write back dirty entries", commandCache)
+	registerSanctionCommands()
+	registerActorCommands()
+	sanction.SetMaintenance(conf.Server.Login.Maintenance)
+}
+
+func commandMsgLatency(args []interface{}) interface{} {
+	if len(args) > 0 && args[0] == "reset" {
+		msg.LatencyStats.Reset()
+		return "reset done"
+	}
+	return msg.LatencyStats.Report()
+}
+
+func commandAnnounce(args []interface{}) interface{} {
+	if len(args) == 0 {
+		return "usage: announce <content>"
+	}
+	words := make([]string, 0, len(args))
+	for _, arg := range args {
+		words = append(words, arg.(string))
+	}
+	n := broadcast.Announce(strings.Join(words, " "))
+	return fmt.Sprintf("announced to %d agents", n)
+}
+
+func commandBroadcast(args []interface{}) interface{} {
+	return broadcast.GetService().Report()
+}
+
+func commandCache(args []interface{}) interface{} {
+	if len(args) > 0 {
+		if args[0] != "flush" {
+			return "usage: cache [flush]"
+		}
+		if err := cache.FlushAll(); err != nil {
+			return err.Error()
+		}
+	}
+	return cache.Report()
+}
+
+func commandConfig(args []interface{}) interface{} {
+	if len(args) > 0 {
+		var err error
+		switch args[0] {
+		case "reload":
+			err = config.ReloadAll()
+		case "rollback":
+			err = config.Rollback()
+		default:
+			return "usage: config [reload|rollback]"
+		}
+		if err != nil {
+			return err.Error()
+		}
+	}
+
+	snap := config.Current()
+	return fmt.Sprintf("version: %d, loaded at: %s, history: %v\r\nfiles: %v",
+		snap.Version, snap.LoadedAt.Format("2006-01-02 15:04:05"), config.History(), snap.Files())
+}
+
+// commandMigrate 默认只统计，run时写回数据库；不指定集合时处理所有注册了迁移的集合
+func commandMigrate(args []interface{}) interface{} {
+	dryRun := true
+	if len(args) > 0 {
+		switch args[0] {
+		case "dry":
+		case "run":
+			dryRun = false
+		default:
+			return "usage: migrate [dry|run] [collection]"
+		}
+	}
+
+	var reports []*mongodb.MigrationReport
+	var err error
+	if len(args) > 1 {
+		var report *mongodb.MigrationReport
+		report, err = mongodb.Migrate(context.Background(), args[1].(string), dryRun)
+		if report != nil {
+			reports = append(reports, report)
+		}
+	} else {
+		reports, err = mongodb.MigrateAll(context.Background(), dryRun)
+	}
+
+	lines := make([]string, 0, len(reports)+1)
+	for _, report := range reports {
+		lines = append(lines, report.String())
+	}
+	if err != nil {
+		lines = append(lines, err.Error())
+	}
+	if len(lines) == 0 {
+		return "no migrations registered"
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// commandIndex diff只对比；apply创建缺少的并重建定义不同的索引；prune在apply之外删除没有声明的索引
+func commandIndex(args []interface{}) interface{} {
+	action := "diff"
+	var collections []string
+	if len(args) > 0 {
+		action = args[0].(string)
+		for _, arg := range args[1:] {
+			collections = append(collections, arg.(string))
+		}
+	}
+
+	var diffs []*mongodb.IndexDiff
+	var err error
+	switch action {
+	case "diff":
+		diffs, err = mongodb.DiffIndexes(context.Background(), collections...)
+	case "apply", "prune":
+		diffs, err = mongodb.ApplyIndexes(context.Background(), action == "prune", collections...)
+	default:
+		return "usage: index [diff|apply|prune] [collection]"
+	}
+
+	lines := make([]string, 0, len(diffs)+1)
+	for _, diff := range diffs {
+		lines = append(lines, diff.String())
+	}
+	if err != nil {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\r\n")
 }
 
 func (m *Module) OnDestroy() {
 	actor.StopAll()
+	// Actor停止后不会再有修改，写回缓存中剩余的脏数据
+	if err := cache.CloseAll(); err != nil {
+		log.Error("close caches failed: %v", err)
+	}
 }
@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"fmt"
+	"gameserver/common/models"
+	"gameserver/common/msg/message"
+	"gameserver/common/sanction"
+	"gameserver/modules/game/internal/managers"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 控制台操作记录的操作人
+const consoleOperator = "console"
+
+func registerSanctionCommands() {
+	skeleton.RegisterCommand("ban", "ban player and kick, 'ban <playerId> <duration|0> <reason>'", commandBan)
+	skeleton.RegisterCommand("unban", "lift ban, 'unban <playerId>'", commandUnban)
+	skeleton.RegisterCommand("mute", "mute player, 'mute <playerId> <duration|0> <reason>'", commandMute)
+	skeleton.RegisterCommand("unmute", "lift mute, 'unmute <playerId>'", commandUnmute)
+	skeleton.RegisterCommand("sanction", "list sanctions of player, 'sanction <playerId>'", commandSanction)
+	skeleton.RegisterCommand("maintenance", "maintenance mode, 'maintenance [on|off]'", commandMaintenance)
+	skeleton.RegisterCommand("whitelist", "maintenance whitelist, 'whitelist [add <playerId> [remark]|remove <playerId>]'", commandWhitelist)
+}
+
+// parseSanctionArgs 解析 <playerId> <duration|0> <reason>，duration为Go格式如 30m、72h，0表示永久
+func parseSanctionArgs(args []interface{}) (int64, time.Duration, string, error) {
+	if len(args) < 3 {
+		return 0, 0, "", fmt.Errorf("missing arguments")
+	}
+	playerId, err := strconv.ParseInt(args[0].(string), 10, 64)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("invalid player id: %v", args[0])
+	}
+	var duration time.Duration
+	if args[1] != "0" {
+		if duration, err = time.ParseDuration(args[1].(string)); err != nil || duration <= 0 {
+			return 0, 0, "", fmt.Errorf("invalid duration: %v", args[1])
+		}
+	}
+	words := make([]string, 0, len(args)-2)
+	for _, arg := range args[2:] {
+		words = append(words, arg.(string))
+	}
+	return playerId, duration, strings.Join(words, " "), nil
+}
+
+func parsePlayerId(args []interface{}) (int64, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	playerId, err := strconv.ParseInt(args[0].(string), 10, 64)
+	return playerId, err == nil
+}
+
+func formatSanction(s models.Sanction) string {
+	expire := "forever"
+	if s.ExpireTime > 0 {
+		expire = time.Unix(s.ExpireTime, 0).Format("2006-01-02 15:04:05")
+	}
+	state := "active"
+	if !s.Active(time.Now().Unix()) {
+		state = "expired"
+	}
+	return fmt.Sprintf("%v player %d until %s (%s) by %s: %s", s.Type, s.PlayerId, expire, state, s.Operator, s.Reason)
+}
+
+// commandBan 封号后踢掉在线的玩家
+func commandBan(args []interface{}) interface{} {
+	playerId, duration, reason, err := parseSanctionArgs(args)
+	if err != nil {
+		return fmt.Sprintf("%v, usage: ban <playerId> <duration|0> <reason>", err)
+	}
+	ban, err := sanction.Ban(playerId, reason, consoleOperator, duration)
+	if err != nil {
+		return err.Error()
+	}
+	kicked := managers.GetUserManager().Kick(playerId, &message.S2C_Kick{
+		Reason:    ban.Reason,
+		BanExpire: ban.ExpireTime,
+	})
+	return fmt.Sprintf("%s, kicked: %v", formatSanction(ban), kicked)
+}
+
+func commandMute(args []interface{}) interface{} {
+	playerId, duration, reason, err := parseSanctionArgs(args)
+	if err != nil {
+		return fmt.Sprintf("%v, usage: mute <playerId> <duration|0> <reason>", err)
+	}
+	mute, err := sanction.Mute(playerId, reason, consoleOperator, duration)
+	if err != nil {
+		return err.Error()
+	}
+	return formatSanction(mute)
+}
+
+func commandUnban(args []interface{}) interface{} {
+	return liftSanction(args, models.SanctionBan, "usage: unban <playerId>")
+}
+
+func commandUnmute(args []interface{}) interface{} {
+	return liftSanction(args, models.SanctionMute, "usage: unmute <playerId>")
+}
+
+func liftSanction(args []interface{}, t models.SanctionType, usage string) interface{} {
+	playerId, ok := parsePlayerId(args)
+	if !ok {
+		return usage
+	}
+	lifted, err := sanction.Lift(playerId, t)
+	if err != nil {
+		return err.Error()
+	}
+	if !lifted {
+		return fmt.Sprintf("player %d has no %v", playerId, t)
+	}
+	return fmt.Sprintf("%v of player %d lifted", t, playerId)
+}
+
+func commandSanction(args []interface{}) interface{} {
+	playerId, ok := parsePlayerId(args)
+	if !ok {
+		return "usage: sanction <playerId>"
+	}
+	records, err := sanction.List(playerId)
+	if err != nil {
+		return err.Error()
+	}
+	if len(records) == 0 {
+		return fmt.Sprintf("player %d has no sanctions", playerId)
+	}
+	lines := make([]string, 0, len(records))
+	for _, s := range records {
+		lines = append(lines, formatSanction(s))
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// commandMaintenance 切换维护模式，只影响之后的登录，已在线的玩家不会被踢
+func commandMaintenance(args []interface{}) interface{} {
+	if len(args) > 0 {
+		switch args[0] {
+		case "on":
+			sanction.SetMaintenance(true)
+		case "off":
+			sanction.SetMaintenance(false)
+		default:
+			return "usage: maintenance [on|off]"
+		}
+	}
+	return fmt.Sprintf("maintenance: %v", sanction.Maintenance())
+}
+
+func commandWhitelist(args []interface{}) interface{} {
+	if len(args) > 0 {
+		playerId, ok := parsePlayerId(args[1:])
+		if !ok {
+			return "usage: whitelist [add <playerId> [remark]|remove <playerId>]"
+		}
+		switch args[0] {
+		case "add":
+			words := make([]string, 0, len(args))
+			for _, arg := range args[2:] {
+				words = append(words, arg.(string))
+			}
+			if err := sanction.AddWhitelist(playerId, consoleOperator, strings.Join(words, " ")); err != nil {
+				return err.Error()
+			}
+		case "remove":
+			if _, err := sanction.RemoveWhitelist(playerId); err != nil {
+				return err.Error()
+			}
+		default:
+			return "usage: whitelist [add <playerId> [remark]|remove <playerId>]"
+		}
+	}
+
+	list, err := sanction.Whitelist()
+	if err != nil {
+		return err.Error()
+	}
+	lines := make([]string, 0, len(list)+1)
+	lines = append(lines, fmt.Sprintf("maintenance: %v, whitelist: %d", sanction.Maintenance(), len(list)))
+	for _, w := range list {
+		lines = append(lines, fmt.Sprintf("%d %s", w.PlayerId, w.Remark))
+	}
+	return strings.Join(lines, "\r\n")
+}
@@ -0,0 +1,173 @@
+// Package admission 登录准入控制，在线人数达到上限后新的登录排队等待
+package admission
+
+import (
+	"container/list"
+	"math"
+	"time"
+
+	"gameserver/common/msg/message"
+	"gameserver/core/gate"
+)
+
+// 按最近一段时间的放行速度估算等待时间
+const rateWindow = time.Minute
+
+// Ticket 排队中的登录请求
+type Ticket struct {
+	Agent    gate.Agent
+	Priority bool   // VIP或断线重连，排在所有普通玩家之前
+	Admit    func() // 放行时调用，进入登录流程
+}
+
+// Controller 登录准入控制，优先队列和普通队列各自先进先出
+// 不加锁，只在LoginManager的Actor中调用
+type Controller struct {
+	maxOnline int
+	online    func() int
+	priority  *list.List
+	normal    *list.List
+	tickets   map[gate.Agent]*list.Element
+	admits    []time.Time // rateWindow内的放行时间
+}
+
+// NewController 创建准入控制，maxOnline为0时不限制，online返回当前在线人数
+func NewController(maxOnline int, online func() int) *Controller {
+	return &Controller{
+		maxOnline: maxOnline,
+		online:    online,
+		priority:  list.New(),
+		normal:    list.New(),
+		tickets:   make(map[gate.Agent]*list.Element),
+	}
+}
+
+// SetMaxOnline 调整在线人数上限，调大后在下一次Tick时放行
+func (c *Controller) SetMaxOnline(n int) {
+	c.maxOnline = n
+}
+
+// MaxOnline 在线人数上限
+func (c *Controller) MaxOnline() int {
+	return c.maxOnline
+}
+
+// Free 还能放行的人数
+func (c *Controller) Free() int {
+	if c.maxOnline <= 0 {
+		return math.MaxInt
+	}
+	return c.maxOnline - c.online()
+}
+
+// Len 排队人数
+func (c *Controller) Len() int {
+	return c.priority.Len() + c.normal.Len()
+}
+
+// Enter 有空位时按顺序放行，返回0表示已经放行，否则返回排队位置（从1开始）
+// 同一个连接重复进入时替换原来的请求，排队位置按新的请求计算
+func (c *Controller) Enter(t *Ticket) int {
+	c.Leave(t.Agent)
+	queue := c.normal
+	if t.Priority {
+		queue = c.priority
+	}
+	c.tickets[t.Agent] = queue.PushBack(t)
+	c.admit(c.Free())
+	return c.Position(t.Agent)
+}
+
+// Leave 连接断开时移出队列，不在队列中时返回false
+func (c *Controller) Leave(agent gate.Agent) bool {
+	elem, ok := c.tickets[agent]
+	if !ok {
+		return false
+	}
+	delete(c.tickets, agent)
+	if elem.Value.(*Ticket).Priority {
+		c.priority.Remove(elem)
+	} else {
+		c.normal.Remove(elem)
+	}
+	return true
+}
+
+// Position 排队位置，从1开始，不在队列中时返回0
+func (c *Controller) Position(agent gate.Agent) int {
+	elem, ok := c.tickets[agent]
+	if !ok {
+		return 0
+	}
+	position := 0
+	if !elem.Value.(*Ticket).Priority {
+		position = c.priority.Len()
+	}
+	for ; elem != nil; elem = elem.Prev() {
+		position++
+	}
+	return position
+}
+
+// Tick 按空位放行队首，并向其余排队的玩家推送排队位置，返回放行人数
+func (c *Controller) Tick() int {
+	if c.Len() == 0 {
+		return 0
+	}
+	admitted := c.admit(c.Free())
+
+	total := int32(c.Len())
+	wait := c.waitPerPosition()
+	position := int32(0)
+	for _, queue := range []*list.List{c.priority, c.normal} {
+		for elem := queue.Front(); elem != nil; elem = elem.Next() {
+			position++
+			elem.Value.(*Ticket).Agent.WriteMsg(&message.S2C_LoginQueue{
+				Position:   position,
+				Total:      total,
+				WaitSecond: int32(wait * float64(position)),
+			})
+		}
+	}
+	return admitted
+}
+
+// admit 放行队首的n个请求
+func (c *Controller) admit(n int) int {
+	admitted := 0
+	now := time.Now()
+	for ; admitted < n; admitted++ {
+		elem := c.priority.Front()
+		if elem == nil {
+			elem = c.normal.Front()
+		}
+		if elem == nil {
+			break
+		}
+		t := elem.Value.(*Ticket)
+		c.Leave(t.Agent)
+		c.admits = append(c.admits, now)
+		t.Admit()
+	}
+	c.trimAdmits(now)
+	return admitted
+}
+
+// trimAdmits 丢弃rateWindow之前的放行记录
+func (c *Controller) trimAdmits(now time.Time) {
+	cutoff := now.Add(-rateWindow)
+	i := 0
+	for i < len(c.admits) && c.admits[i].Before(cutoff) {
+		i++
+	}
+	c.admits = c.admits[i:]
+}
+
+// waitPerPosition 每个排队位置的预计等待秒数，最近没有放行时返回0表示无法估算
+func (c *Controller) waitPerPosition() float64 {
+	c.trimAdmits(time.Now())
+	if len(c.admits) == 0 {
+		return 0
+	}
+	return rateWindow.Seconds() / float64(len(c.admits))
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"gameserver/core/gate"
+	"gameserver/modules/login/internal/managers"
 )
 
 func init() {
@@ -16,5 +17,5 @@ func rpcNewAgent(args []interface{}) {
 
 func rpcCloseAgent(args []interface{}) {
 	a := args[0].(gate.Agent)
-	_ = a
+	managers.GetLoginManager().LeaveQueue(a)
 }
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"gameserver/common/msg"
 	"gameserver/common/msg/message"
 	"gameserver/modules/login/internal/handlers"
 	"reflect"
@@ -8,11 +9,14 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-func handleMsg(m proto.Message, h interface{}) {
-	skeleton.RegisterChanRPC(reflect.TypeOf(m), h)
+// handleMsg 注册消息处理函数，经由msg.Processor的中间件链执行
+func handleMsg(m proto.Message, h func([]interface{})) {
+	skeleton.RegisterChanRPC(reflect.TypeOf(m), msg.Processor.Wrap(m, h))
 }
 
 func InitHandler() {
 	handleMsg(&message.C2S_Login{}, handlers.C2S_LoginHandler)
 	handleMsg(&message.C2S_Heart{}, handlers.C2S_HeartHandler)
+	handleMsg(&message.C2S_BindAccount{}, handlers.C2S_BindAccountHandler)
+	handleMsg(&message.C2S_ResumeSession{}, handlers.C2S_ResumeSessionHandler)
 }
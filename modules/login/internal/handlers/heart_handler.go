@@ -4,9 +4,12 @@ import (
 	"gameserver/common/msg/message"
 	"gameserver/core/gate"
 	"gameserver/core/log"
-	"gameserver/modules/login/internal/managers"
+	"time"
 )
 
+// 超过该值的RTT样本视为客户端计时异常，丢弃
+const maxRTT = time.Minute
+
 // C2S_HeartHandler 处理C2S_Heart消息
 func C2S_HeartHandler(args []interface{}) {
 	if len(args) < 2 {
@@ -26,8 +29,18 @@ func C2S_HeartHandler(args []interface{}) {
 		return
 	}
 
-	// 更新客户端心跳
-	managers.GetConnectManager().UpdateHeartbeat(agent)
-
-	log.Debug("收到C2S_Heart消息: %v, agent: %v", msg, agent)
+	// 空闲检测由gate处理，收到任何消息都会刷新，这里只测量RTT
+	// RTT = 现在 - 上次回复的服务器时间 - 客户端收到回复后等待的时间，只用服务器时钟，不受客户端时钟偏差影响
+	now := time.Now().UnixMilli()
+	if msg.ServerTime > 0 {
+		rtt := time.Duration(now-msg.ServerTime-int64(msg.HoldMs)) * time.Millisecond
+		if rtt > 0 && rtt < maxRTT {
+			agent.ObserveRTT(rtt)
+		}
+	}
+	agent.WriteMsg(&message.S2C_Heart{
+		ClientTime: msg.ClientTime,
+		ServerTime: now,
+		RttMs:      int32(agent.RTT().Milliseconds()),
+	})
 }
@@ -26,7 +26,5 @@ func C2S_LoginHandler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到C2S_Login消息: %v, agent: %v", msg, agent)
-	managers.GetConnectManager().UpdateHeartbeat(agent)
 	managers.GetLoginManager().HandleLogin(msg, agent)
 }
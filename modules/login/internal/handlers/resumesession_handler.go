@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"gameserver/common/msg/message"
+	"gameserver/core/gate"
+	"gameserver/core/log"
+	"gameserver/modules/login/internal/managers"
+)
+
+// C2S_ResumeSessionHandler 处理C2S_ResumeSession消息
+func C2S_ResumeSessionHandler(args []interface{}) {
+	if len(args) < 2 {
+		log.Error("C2S_ResumeSessionHandler: 参数不足")
+		return
+	}
+
+	msg, ok := args[0].(*message.C2S_ResumeSession)
+	if !ok {
+		log.Error("C2S_ResumeSessionHandler: 消息类型错误")
+		return
+	}
+
+	agent, ok := args[1].(gate.Agent)
+	if !ok {
+		log.Error("C2S_ResumeSessionHandler: Agent类型错误")
+		return
+	}
+
+	managers.GetLoginManager().HandleResumeSession(msg, agent)
+}
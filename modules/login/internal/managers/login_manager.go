@@ -2,18 +2,27 @@ package managers
 
 import (
 	"context"
+	"crypto/rand"
 	"gameserver/common/base/actor"
+	"gameserver/common/models"
 	"gameserver/common/msg/message"
+	"gameserver/common/sanction"
+	"gameserver/common/session"
+	"gameserver/conf"
 	"gameserver/core/gate"
 	"gameserver/core/log"
 	"gameserver/modules/game"
-	"gameserver/modules/login/internal/processor"
+	"gameserver/modules/login/admission"
+	"gameserver/modules/login/processor"
 	"sync"
+	"time"
 )
 
 // LoginManager 使用TaskHandler实现，确保登录操作按顺序执行
 type LoginManager struct {
 	*actor.TaskHandler
+	sessions *session.Signer
+	queue    *admission.Controller // 满员时的登录排队
 }
 
 var (
@@ -21,6 +30,9 @@ var (
 	loginManagerOnce sync.Once
 )
 
+// logger login模块的日志
+var logger = log.Module("login")
+
 func GetLoginManager() *LoginManager {
 	loginManagerOnce.Do(func() {
 		loginManager = &LoginManager{}
@@ -33,49 +45,260 @@ func GetLoginManager() *LoginManager {
 func (m *LoginManager) Init() {
 	// 初始化TaskHandler
 	m.TaskHandler = actor.InitTaskHandler(actor.Login, "1", m)
+	m.sessions = newSessionSigner()
+	m.queue = admission.NewController(conf.Server.LoginQueue.MaxOnline, func() int {
+		return game.External.UserManager.OnlineCount()
+	})
 	m.TaskHandler.Start()
 }
 
+// OnTimer 放行排队的玩家并推送排队位置
+func (m *LoginManager) OnTimer() {
+	m.SendTask(func() *actor.Response {
+		if admitted := m.queue.Tick(); admitted > 0 || m.queue.Len() > 0 {
+			logger.Debug("login queue admitted: %d, waiting: %d", admitted, m.queue.Len())
+		}
+		return nil
+	})
+}
+
+func (m *LoginManager) GetInterval() int {
+	if conf.Server.LoginQueue.TickSecond > 0 {
+		return conf.Server.LoginQueue.TickSecond
+	}
+	return 2
+}
+
+// newSessionSigner 按配置创建会话令牌的Signer
+func newSessionSigner() *session.Signer {
+	secret := []byte(conf.Server.Login.SessionSecret)
+	if len(secret) == 0 {
+		// 随机密钥只在本进程有效，重启或连到其他节点后需要重新登录
+		logger.Release("Login.SessionSecret not configured, session tokens are valid on this process only")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			logger.Fatal("generate session secret failed: %v", err)
+		}
+	}
+	signer := session.NewSigner(secret,
+		time.Duration(conf.Server.Login.SessionTTLSecond)*time.Second,
+		time.Duration(conf.Server.Login.SessionRotateSecond)*time.Second)
+	if conf.Server.Login.SessionMaxLifetimeSecond > 0 {
+		signer.SetMaxLifetime(time.Duration(conf.Server.Login.SessionMaxLifetimeSecond) * time.Second)
+	}
+	return signer
+}
+
 // Stop 停止LoginManager
 func (m *LoginManager) Stop() {
 	m.TaskHandler.Stop()
 }
 
 // HandleLogin 处理登录请求 - 异步执行
+// 向登录平台验证（HTTP请求、密码哈希）耗时较长，在单独的goroutine中进行，不阻塞其他玩家的登录，
+// 验证通过后回到LoginManager中签发令牌并进入登录流程
 func (m *LoginManager) HandleLogin(msg *message.C2S_Login, agent gate.Agent) {
-	m.SendTask(func() *actor.Response {
-		m.doHandleLogin(msg, agent)
-		return nil
-	})
+	go m.verifyLogin(msg, agent)
 }
 
-// doHandleLogin 处理登录请求的同步实现
-func (m *LoginManager) doHandleLogin(msg *message.C2S_Login, agent gate.Agent) {
-	loginProcessor := getLoginProcessor(msg.LoginType)
-	if loginProcessor == nil {
-		log.Error("loginProcessor is nil")
+// verifyLogin 向登录平台验证登录请求
+func (m *LoginManager) verifyLogin(msg *message.C2S_Login, agent gate.Agent) {
+	loginProcessor, ok := processor.Get(msg.LoginType)
+	if !ok {
+		logger.Error("loginProcessor not registered, loginType: %v", msg.LoginType)
 		return
 	}
 	loginResp := loginProcessor.ReqLogin(context.Background(), msg)
-	log.Debug("loginResp %v", loginResp)
+	logger.Debug("loginResp %v", loginResp)
 	if loginResp.ErrCode != 0 {
-		log.Error("login failed %v", loginResp)
+		logger.Error("login failed %v", loginResp)
 		agent.WriteMsg(&message.S2C_Login{
 			LoginResult: -1,
 		})
 		agent.Close()
 		return
 	}
-	game.External.UserManager.UserLogin(agent, loginResp.Openid, msg.ServerId, msg.LoginType)
+	m.SendTask(func() *actor.Response {
+		m.doHandleLogin(msg, agent, loginResp.Openid)
+		return nil
+	})
+}
+
+// doHandleLogin 验证通过后签发令牌并进入登录流程
+func (m *LoginManager) doHandleLogin(msg *message.C2S_Login, agent gate.Agent, openId string) {
+	token, expire := m.sessions.Issue(msg.ServerId, msg.LoginType, openId)
+	m.enter(agent, msg.ServerId, msg.LoginType, openId, token, expire, false)
+}
+
+// enter 带着会话令牌进入登录流程，满员时排队
+func (m *LoginManager) enter(agent gate.Agent, serverId int32, loginType message.LoginType, openId string, token string, expire int64, resume bool) {
+	admit := func() {
+		game.External.UserManager.UserLogin(agent, openId, serverId, loginType, token, expire)
+	}
+	if m.queue.Len() == 0 && m.queue.Free() > 0 {
+		admit()
+		return
+	}
+
+	bypass, priority := m.classify(serverId, loginType, openId, resume)
+	if bypass {
+		admit()
+		return
+	}
+	position := m.queue.Enter(&admission.Ticket{
+		Agent:    agent,
+		Priority: priority,
+		Admit:    admit,
+	})
+	if position > 0 {
+		agent.WriteMsg(&message.S2C_LoginQueue{
+			Position: int32(position),
+			Total:    int32(m.queue.Len()),
+		})
+	}
+}
+
+// classify 满员时决定登录是否插队
+// 白名单和已在线（顶号不增加在线人数）的用户直接放行，恢复会话、刚下线和VIP用户优先
+func (m *LoginManager) classify(serverId int32, loginType message.LoginType, openId string, resume bool) (bypass, priority bool) {
+	priority = resume
+	user, err := models.FindUserByIdentity(context.Background(), serverId, loginType, openId)
+	if err != nil {
+		logger.With("openId", openId).Error("login queue find user failed: %v", err)
+		return false, priority
+	}
+	if user == nil {
+		return false, priority
+	}
+
+	if whitelisted, err := sanction.Whitelisted(user.PlayerId); err != nil {
+		logger.With("playerId", user.PlayerId).Error("login queue find whitelist failed: %v", err)
+	} else if whitelisted {
+		return true, true
+	}
+	if game.External.UserManager.IsUserOnline(user.AccountId) {
+		return true, true
+	}
+
+	queueConf := conf.Server.LoginQueue
+	if time.Now().Unix()-user.LastOfflineTime < int64(queueConf.ReconnectSecond) {
+		return false, true
+	}
+	if queueConf.VipLevel > 0 {
+		if p := game.External.UserManager.GetOfflinePlayer(user.PlayerId); p != nil && p.PlayerInfo.VipLevel >= queueConf.VipLevel {
+			return false, true
+		}
+	}
+	return false, priority
 }
 
-func getLoginProcessor(loginType message.LoginType) processor.BaseLoginProcessor {
-	switch loginType {
-	case message.LoginType_DouYin:
-		return processor.NewDouyinLoginProcessor()
-	case message.LoginType_WeChat:
-		return processor.NewWechatLoginProcessor()
-	default:
+// LeaveQueue 连接断开时移出登录排队 - 异步执行
+func (m *LoginManager) LeaveQueue(agent gate.Agent) {
+	m.SendTask(func() *actor.Response {
+		m.queue.Leave(agent)
 		return nil
+	})
+}
+
+// SetMaxOnline 调整在线人数上限 - 异步执行
+func (m *LoginManager) SetMaxOnline(n int) {
+	m.SendTask(func() *actor.Response {
+		m.queue.SetMaxOnline(n)
+		return nil
+	})
+}
+
+// QueueStats 在线人数上限和排队人数 - 异步执行
+func (m *LoginManager) QueueStats() (maxOnline, waiting int) {
+	response := m.SendTask(func() *actor.Response {
+		return &actor.Response{
+			Result: []interface{}{m.queue.MaxOnline(), m.queue.Len()},
+		}
+	})
+	if response != nil && len(response.Result) > 1 {
+		maxOnline, _ = response.Result[0].(int)
+		waiting, _ = response.Result[1].(int)
+	}
+	return
+}
+
+// HandleResumeSession 处理恢复登录请求 - 异步执行
+func (m *LoginManager) HandleResumeSession(msg *message.C2S_ResumeSession, agent gate.Agent) {
+	m.SendTask(func() *actor.Response {
+		m.doHandleResumeSession(msg, agent)
+		return nil
+	})
+}
+
+// doHandleResumeSession 在本地校验令牌后续期并直接进入登录流程，不请求登录平台
+// 距离登录平台认证超过最长有效期时不再续期；失败时不断开连接，客户端可以在同一个连接上重新登录
+func (m *LoginManager) doHandleResumeSession(msg *message.C2S_ResumeSession, agent gate.Agent) {
+	claims, err := m.sessions.Verify(msg.Token)
+	var token string
+	var expire int64
+	if err == nil {
+		token, expire, err = m.sessions.Renew(claims)
+	}
+	if err != nil {
+		logger.With("addr", agent.RemoteAddr()).Debug("resume session failed: %v", err)
+		agent.WriteMsg(&message.S2C_ResumeSession{
+			Result: message.Result_Illegal,
+		})
+		return
+	}
+	m.enter(agent, claims.ServerId, claims.LoginType, claims.OpenId, token, expire, true)
+}
+
+// HandleBindAccount 处理绑定登录身份请求 - 异步执行
+// 验证和注册不需要LoginManager的状态，绑定由UserManager保证顺序，整个流程在单独的goroutine中进行
+func (m *LoginManager) HandleBindAccount(msg *message.C2S_BindAccount, agent gate.Agent) {
+	go m.bindAccount(msg, agent)
+}
+
+// bindAccount 先向登录平台验证要绑定的身份，再绑定到当前用户
+func (m *LoginManager) bindAccount(msg *message.C2S_BindAccount, agent gate.Agent) {
+	reply := func(result message.Result) {
+		agent.WriteMsg(&message.S2C_BindAccount{
+			Result:    result,
+			LoginType: msg.LoginType,
+		})
+	}
+
+	user, ok := agent.UserData().(models.User)
+	if !ok {
+		reply(message.Result_Illegal)
+		return
+	}
+	loginProcessor, ok := processor.Get(msg.LoginType)
+	if !ok {
+		reply(message.Result_Illegal)
+		return
+	}
+
+	// 绑定账号密码时注册新的用户名，已有的用户名属于其他用户，不能绑定
+	ctx := context.Background()
+	loginResp := loginProcessor.ReqLogin(ctx, &message.C2S_Login{
+		LoginType: msg.LoginType,
+		ServerId:  user.ServerId,
+		Code:      msg.Code,
+		DeviceId:  msg.DeviceId,
+		Username:  msg.Username,
+		Password:  msg.Password,
+		Register:  true,
+	})
+	if loginResp.ErrCode != 0 {
+		logger.With("accountId", user.AccountId).Debug("bind account verify failed: %v", loginResp.ErrMsg)
+		reply(message.Result_Fail)
+		return
+	}
+
+	result := game.External.UserManager.BindIdentity(user, msg.LoginType, loginResp.Openid)
+	if result != message.Result_Success && loginResp.Registered {
+		if registrar, ok := loginProcessor.(processor.Registrar); ok {
+			if err := registrar.Unregister(ctx, user.ServerId, loginResp.Openid); err != nil {
+				logger.With("openId", loginResp.Openid).Error("bind account unregister failed: %v", err)
+			}
+		}
 	}
+	reply(result)
 }
@@ -0,0 +1,23 @@
+package models
+
+import "fmt"
+
+// Credential 用户名密码账号的凭证，密码加盐哈希后保存
+type Credential struct {
+	Id         string `bson:"_id"` // CredentialId(serverId, username)
+	ServerId   int32  `bson:"ServerId"`
+	Username   string `bson:"Username"`
+	Salt       []byte `bson:"Salt"`
+	Hash       []byte `bson:"Hash"`
+	Iterations int    `bson:"Iterations"` // 哈希迭代次数，调整后旧密码仍按记录的次数校验
+	CreateTime int64  `bson:"CreateTime"`
+}
+
+func (c Credential) GetPersistId() interface{} {
+	return c.Id
+}
+
+// CredentialId 用户名在每个服内唯一
+func CredentialId(serverId int32, username string) string {
+	return fmt.Sprintf("%d_%s", serverId, username)
+}
@@ -17,6 +17,7 @@ type LoginRequest struct {
 // - Token：用户登录成功后分配的令牌（token），用于后续鉴权和会话保持。
 // - Openid：用户在第三方平台（如抖音）下的唯一标识，用于标识当前用户身份。
 // - Unionid：用户在第三方平台下的全局唯一标识（同一用户在不同应用下的唯一ID），用于多应用间用户身份的统一。
+// - Registered：账号密码登录时本次新注册了账号，绑定失败时需要撤销注册。
 type LoginResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -27,6 +28,7 @@ type LoginResponse struct {
 	SessionKey string `protobuf:"bytes,3,opt,name=sessionKey,proto3" json:"sessionKey,omitempty"`
 	Openid     string `protobuf:"bytes,4,opt,name=openid,proto3" json:"openid,omitempty"`
 	Unionid    string `protobuf:"bytes,5,opt,name=unionid,proto3" json:"unionid,omitempty"`
+	Registered bool   `json:"registered,omitempty"` // 本次请求注册了新的账号密码
 }
 
 // 抖音登录
@@ -51,3 +53,12 @@ type Code2SessionResponseData struct {
 	UnionId    string `json:"unionid"`
 	DOpenid    string `json:"dopenid"`
 }
+
+// 微信小游戏登录
+type WeChatCode2SessionResponse struct {
+	Openid     string `json:"openid"`
+	SessionKey string `json:"session_key"`
+	UnionId    string `json:"unionid"`
+	ErrCode    int64  `json:"errcode"`
+	ErrMsg     string `json:"errmsg"`
+}
@@ -1,9 +1,12 @@
 package internal
 
 import (
+	"fmt"
 	"gameserver/common"
 	"gameserver/common/base/actor"
 	"gameserver/core/module"
+	"gameserver/modules/login/internal/managers"
+	"strconv"
 )
 
 var (
@@ -18,8 +21,24 @@ type Module struct {
 func (m *Module) OnInit() {
 	m.Skeleton = skeleton
 	InitHandler()
+	skeleton.RegisterCommand("loginqueue", "login queue stats, 'loginqueue max <n>' to change online cap, 0 for unlimited", commandLoginQueue)
 }
 
 func (m *Module) OnDestroy() {
 	actor.StopAll()
 }
+
+func commandLoginQueue(args []interface{}) interface{} {
+	if len(args) > 0 {
+		if len(args) < 2 || args[0] != "max" {
+			return "usage: loginqueue [max <n>]"
+		}
+		n, err := strconv.Atoi(args[1].(string))
+		if err != nil || n < 0 {
+			return fmt.Sprintf("invalid max online: %v", args[1])
+		}
+		managers.GetLoginManager().SetMaxOnline(n)
+	}
+	maxOnline, waiting := managers.GetLoginManager().QueueStats()
+	return fmt.Sprintf("max online: %d, waiting: %d", maxOnline, waiting)
+}
@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"gameserver/common/db/mongodb"
+	"gameserver/common/msg/message"
+	"gameserver/conf"
+	"gameserver/core/log"
+	"gameserver/modules/login/internal/models"
+	"regexp"
+	"runtime"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	passwordIterations = 100000 // PBKDF2-SHA256迭代次数
+	passwordSaltLen    = 16
+	passwordHashLen    = 32
+	passwordMinLen     = 6
+	passwordMaxLen     = 64
+)
+
+var usernameReg = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+// hashSlots 限制同时计算密码哈希的数量，大量登录请求不会占满所有CPU
+var hashSlots = make(chan struct{}, max(1, runtime.NumCPU()/2))
+
+// hashPassword PBKDF2-SHA256，在调用者的goroutine中计算，不要在Actor中调用
+func hashPassword(password string, salt []byte, iterations, keyLen int) ([]byte, error) {
+	hashSlots <- struct{}{}
+	defer func() { <-hashSlots }()
+	return pbkdf2.Key(sha256.New, password, salt, iterations, keyLen)
+}
+
+// AccountLoginProcessor 用户名密码登录，Register为true时注册新用户名
+type AccountLoginProcessor struct {
+	credentials *mongodb.Repository[models.Credential]
+}
+
+func init() {
+	Register(message.LoginType_Account, NewAccountLoginProcessor())
+}
+
+func NewAccountLoginProcessor() *AccountLoginProcessor {
+	return &AccountLoginProcessor{credentials: mongodb.NewRepository[models.Credential]()}
+}
+
+func (p *AccountLoginProcessor) ReqLogin(context context.Context, req *message.C2S_Login) *models.LoginResponse {
+	if !conf.Server.Login.Account {
+		return failed("account login disabled")
+	}
+	if !usernameReg.MatchString(req.Username) {
+		return failed("invalid username")
+	}
+	if len(req.Password) < passwordMinLen || len(req.Password) > passwordMaxLen {
+		return failed("invalid password")
+	}
+
+	if req.Register {
+		return p.register(context, req.ServerId, req.Username, req.Password)
+	}
+
+	credential, err := p.credentials.FindById(context, models.CredentialId(req.ServerId, req.Username))
+	if err != nil {
+		log.Error("find credential failed: %s, %v", req.Username, err)
+		return failed("server error")
+	}
+	// 用户名不存在和密码错误返回同样的信息，避免被用来探测用户名
+	if credential == nil || !checkPassword(credential, req.Password) {
+		return failed("wrong username or password")
+	}
+	return &models.LoginResponse{
+		ErrCode: 0,
+		ErrMsg:  "success",
+		Openid:  req.Username,
+	}
+}
+
+func (p *AccountLoginProcessor) register(ctx context.Context, serverId int32, username, password string) *models.LoginResponse {
+	salt := make([]byte, passwordSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return failed("server error")
+	}
+	hash, err := hashPassword(password, salt, passwordIterations, passwordHashLen)
+	if err != nil {
+		return failed("server error")
+	}
+	err = p.credentials.Insert(ctx, models.Credential{
+		Id:         models.CredentialId(serverId, username),
+		ServerId:   serverId,
+		Username:   username,
+		Salt:       salt,
+		Hash:       hash,
+		Iterations: passwordIterations,
+		CreateTime: time.Now().Unix(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return failed("username already exists")
+	}
+	if err != nil {
+		log.Error("register account failed: %s, %v", username, err)
+		return failed("server error")
+	}
+	return &models.LoginResponse{
+		ErrCode:    0,
+		ErrMsg:     "success",
+		Openid:     username,
+		Registered: true,
+	}
+}
+
+// Unregister 撤销注册，用于注册后绑定失败的情况
+func (p *AccountLoginProcessor) Unregister(ctx context.Context, serverId int32, username string) error {
+	_, err := p.credentials.Delete(ctx, models.CredentialId(serverId, username))
+	return err
+}
+
+func checkPassword(credential *models.Credential, password string) bool {
+	hash, err := hashPassword(password, credential.Salt, credential.Iterations, len(credential.Hash))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hash, credential.Hash) == 1
+}
@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"gameserver/common/msg/message"
+	"gameserver/modules/login/internal/models"
+)
+
+type BaseLoginProcessor interface {
+	ReqLogin(context context.Context, req *message.C2S_Login) *models.LoginResponse
+}
+
+// Registrar 登录时可以注册新身份的处理器，注册后绑定失败时撤销注册
+type Registrar interface {
+	Unregister(ctx context.Context, serverId int32, openId string) error
+}
+
+// 按登录类型注册的登录处理器
+var processors = map[message.LoginType]BaseLoginProcessor{}
+
+// Register 注册登录处理器，在init中调用，同一个登录类型只能注册一次
+func Register(loginType message.LoginType, processor BaseLoginProcessor) {
+	if _, exists := processors[loginType]; exists {
+		panic(fmt.Sprintf("login processor %v registered twice", loginType))
+	}
+	processors[loginType] = processor
+}
+
+// Get 获取登录类型对应的处理器
+func Get(loginType message.LoginType) (BaseLoginProcessor, bool) {
+	processor, ok := processors[loginType]
+	return processor, ok
+}
+
+// failed 登录失败的返回
+func failed(errMsg string) *models.LoginResponse {
+	return &models.LoginResponse{
+		ErrCode: -1,
+		ErrMsg:  errMsg,
+	}
+}
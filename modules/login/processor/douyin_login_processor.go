@@ -3,22 +3,20 @@ package processor
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"gameserver/common/msg/message"
 	"gameserver/common/utils"
 	"gameserver/conf"
 	"gameserver/modules/login/internal/models"
 )
 
-// todollw 走文件配置
-const (
-	domain           = "developer.toutiao.com"
-	sandBoxDomain    = "open-sandbox.douyin.com"
-	code2SessionPath = "/api/apps/v2/jscode2session"
-)
-
 type DouyinLoginProcessor struct {
 }
 
+func init() {
+	Register(message.LoginType_DouYin, NewDouyinLoginProcessor())
+}
+
 func NewDouyinLoginProcessor() *DouyinLoginProcessor {
 	return &DouyinLoginProcessor{}
 }
@@ -34,19 +32,11 @@ func (p *DouyinLoginProcessor) ReqLogin(context context.Context, req *message.C2
 	code2SessionResp, err := code2Session(context, code2SessionReq)
 
 	if err != nil {
-		response := &models.LoginResponse{
-			ErrCode: -1,
-			ErrMsg:  err.Error(),
-		}
-		return response
+		return failed(err.Error())
 	}
 
 	if code2SessionResp.ErrNo != 0 {
-		response := &models.LoginResponse{
-			ErrCode: -1,
-			ErrMsg:  code2SessionResp.ErrTips,
-		}
-		return response
+		return failed(code2SessionResp.ErrTips)
 	}
 
 	response := &models.LoginResponse{
@@ -61,11 +51,14 @@ func (p *DouyinLoginProcessor) ReqLogin(context context.Context, req *message.C2
 
 func code2Session(ctx context.Context, req *models.Code2SessionRequest) (*models.Code2SessionResponse, error) {
 	reqBodyByte, _ := json.Marshal(req)
-	reqDomain := domain
+	url := conf.Server.DouYinInfo.Url
 	if conf.Server.DouYinInfo.IsSandBox == 1 {
-		reqDomain = sandBoxDomain
+		url = conf.Server.DouYinInfo.SandBoxUrl
+	}
+	if url == "" {
+		return nil, fmt.Errorf("douyin jscode2session url not configured")
 	}
-	respBody, err := utils.HttpDo(ctx, code2SessionPath, utils.HttpPostMethod, string(reqBodyByte), "https", reqDomain)
+	respBody, err := utils.HttpDoUrl(ctx, utils.HttpPostMethod, url, string(reqBodyByte))
 	resp := &models.Code2SessionResponse{}
 	if err != nil {
 		return nil, err
@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"context"
+	"gameserver/common/msg/message"
+	"gameserver/conf"
+	"gameserver/modules/login/internal/models"
+)
+
+// GuestLoginProcessor 游客登录，设备id即身份，不需要第三方平台
+type GuestLoginProcessor struct {
+}
+
+func init() {
+	Register(message.LoginType_Guest, NewGuestLoginProcessor())
+}
+
+func NewGuestLoginProcessor() *GuestLoginProcessor {
+	return &GuestLoginProcessor{}
+}
+
+func (p *GuestLoginProcessor) ReqLogin(context context.Context, req *message.C2S_Login) *models.LoginResponse {
+	if !conf.Server.Login.Guest {
+		return failed("guest login disabled")
+	}
+	// 设备id由客户端生成，常见为uuid或平台的设备标识
+	if len(req.DeviceId) < 8 || len(req.DeviceId) > 128 || !isPrintable(req.DeviceId) {
+		return failed("invalid device id")
+	}
+	return &models.LoginResponse{
+		ErrCode: 0,
+		ErrMsg:  "success",
+		Openid:  req.DeviceId,
+	}
+}
+
+// isPrintable 只允许可见的ASCII字符
+func isPrintable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] <= ' ' || s[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
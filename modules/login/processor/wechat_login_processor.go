@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gameserver/common/msg/message"
+	"gameserver/common/utils"
+	"gameserver/conf"
+	"gameserver/modules/login/internal/models"
+	"net/url"
+)
+
+type WeChatLoginProcessor struct {
+}
+
+func init() {
+	Register(message.LoginType_WeChat, NewWechatLoginProcessor())
+}
+
+func NewWechatLoginProcessor() *WeChatLoginProcessor {
+	return &WeChatLoginProcessor{}
+}
+
+func (p *WeChatLoginProcessor) ReqLogin(context context.Context, req *message.C2S_Login) *models.LoginResponse {
+	if req.Code == "" {
+		return failed("code is empty")
+	}
+
+	session, err := wechatCode2Session(context, req.Code)
+	if err != nil {
+		return failed(err.Error())
+	}
+
+	// 失败时errcode不为0，例如40029 code无效、45011 请求太频繁、40226 高风险用户
+	if session.ErrCode != 0 {
+		return failed(fmt.Sprintf("%d: %s", session.ErrCode, session.ErrMsg))
+	}
+	if session.Openid == "" {
+		return failed("openid is empty")
+	}
+
+	return &models.LoginResponse{
+		ErrCode:    0,
+		ErrMsg:     "success",
+		SessionKey: session.SessionKey,
+		Openid:     session.Openid,
+		Unionid:    session.UnionId,
+	}
+}
+
+// wechatCode2Session 小游戏登录凭证校验，GET请求，参数放在query中
+func wechatCode2Session(ctx context.Context, code string) (*models.WeChatCode2SessionResponse, error) {
+	if conf.Server.WeChatInfo.Url == "" {
+		return nil, fmt.Errorf("wechat jscode2session url not configured")
+	}
+	query := url.Values{}
+	query.Set("appid", conf.Server.WeChatInfo.Appid)
+	query.Set("secret", conf.Server.WeChatInfo.Secret)
+	query.Set("js_code", code)
+	query.Set("grant_type", "authorization_code")
+
+	respBody, err := utils.HttpDoUrl(ctx, utils.HttpGetMethod, conf.Server.WeChatInfo.Url+"?"+query.Encode(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.WeChatCode2SessionResponse{}
+	err = json.Unmarshal([]byte(respBody), resp)
+	return resp, err
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"gameserver/common/msg"
 	"gameserver/common/msg/message"
 	"gameserver/modules/match/internal/handlers"
 	"reflect"
@@ -8,8 +9,9 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-func handleMsg(m proto.Message, h interface{}) {
-	skeleton.RegisterChanRPC(reflect.TypeOf(m), h)
+// handleMsg 注册消息处理函数，经由msg.Processor的中间件链执行
+func handleMsg(m proto.Message, h func([]interface{})) {
+	skeleton.RegisterChanRPC(reflect.TypeOf(m), msg.Processor.Wrap(m, h))
 }
 
 func InitHandler() {
@@ -14,7 +14,7 @@ func C2S_CancelMatchHandler(args []interface{}) {
 		return
 	}
 
-	msg, ok := args[0].(*message.C2S_CancelMatch)
+	_, ok := args[0].(*message.C2S_CancelMatch)
 	if !ok {
 		log.Error("C2S_CancelMatchHandler: 消息类型错误")
 		return
@@ -26,6 +26,5 @@ func C2S_CancelMatchHandler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到C2S_CancelMatch消息: %v, agent: %v", msg, agent)
 	managers.GetMatchManager().HandleCancelMatch(agent)
 }
@@ -26,7 +26,6 @@ func C2S_RecordGameOperateHandler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到C2S_RecordGameOperate消息: %v, agent: %v", msg, agent)
 	managers.GetRoomManager().HandleRecordOperate(msg, agent)
 
 }
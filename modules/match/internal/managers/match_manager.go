@@ -2,6 +2,7 @@ package managers
 
 import (
 	"gameserver/common/base/actor"
+	"gameserver/common/config"
 	gconf "gameserver/common/config/generated"
 	"gameserver/common/models"
 	"gameserver/common/msg/message"
@@ -26,6 +27,9 @@ var (
 	matchManagerOnce sync.Once
 )
 
+// logger match模块的日志
+var logger = log.Module("match")
+
 func GetMatchManager() *MatchManager {
 	matchManagerOnce.Do(func() {
 		matchManager = &MatchManager{}
@@ -45,6 +49,48 @@ func (m *MatchManager) Init() {
 	// 初始化TaskHandler
 	m.TaskHandler = actor.InitTaskHandler(actor.Match, "1", m)
 	m.TaskHandler.Start()
+
+	// match.json热更后重建匹配队列
+	config.Subscribe(func(old, new *config.Snapshot) {
+		if new.Changed(old, "match.json") {
+			m.RebuildQueues()
+		}
+	})
+}
+
+// RebuildQueues 根据当前配置重建匹配队列 - 异步执行
+func (m *MatchManager) RebuildQueues() {
+	m.SendTask(func() *actor.Response {
+		m.doRebuildQueues()
+		return nil
+	})
+}
+
+// doRebuildQueues 新增的模式创建队列，删除的模式取消队列中的匹配请求
+func (m *MatchManager) doRebuildQueues() {
+	configs, _ := gconf.GetAllMatchConfigs()
+	modes := make(map[int32]bool, len(configs))
+	for _, cfg := range configs {
+		matchType := int32(cfg.Id)
+		modes[matchType] = true
+		if _, ok := m.matchQueues[matchType]; !ok {
+			m.matchQueues[matchType] = match_models.NewMatchQueue()
+			logger.Release("新增匹配模式 %d: %s", matchType, cfg.Name)
+		}
+	}
+
+	for matchType, q := range m.matchQueues {
+		if modes[matchType] {
+			continue
+		}
+		for teamId := range q.TeamRequests {
+			game.External.TeamManager.SendMessage(teamId, &message.S2C_CancelMatch{
+				Result: true,
+			})
+		}
+		delete(m.matchQueues, matchType)
+		logger.Release("移除匹配模式 %d，取消 %d 个匹配请求", matchType, q.GetQueueSize())
+	}
 }
 
 // Stop 停止MatchManager
@@ -66,7 +112,7 @@ func (m *MatchManager) OnTimer() {
 
 // Matching 定时任务，每10秒执行一次匹配
 func (m *MatchManager) Matching() {
-	log.Debug("开始执行匹配任务")
+	logger.Debug("开始执行匹配任务")
 
 	// todo 队列优化，每个队列互不干扰，队列删除时跟加入队列的冲突
 	for matchType, q := range m.matchQueues {
@@ -78,7 +124,7 @@ func (m *MatchManager) Matching() {
 			continue
 		}
 		q.ProcessTeamMatchResults(groups)
-		log.Debug("匹配任务完成，处理了 %d 个匹配组，匹配类型: %d", len(groups), matchType)
+		logger.Debug("匹配任务完成，处理了 %d 个匹配组，匹配类型: %d", len(groups), matchType)
 	}
 }
 
@@ -95,13 +141,13 @@ func (m *MatchManager) doHandleMatch(agent gate.Agent, msg *message.C2S_StartMat
 	user := agent.UserData().(models.User)
 	player := game.External.UserManager.GetPlayer(user.PlayerId)
 	if player == nil {
-		log.Error("玩家不存在: %d", user.PlayerId)
+		logger.With("playerId", user.PlayerId).Error("玩家不存在")
 		return
 	}
 
 	// 检查玩家是否有队伍
 	if player.TeamId == 0 {
-		log.Error("玩家 %d 没有队伍，无法开始匹配", user.PlayerId)
+		logger.With("playerId", user.PlayerId).Error("玩家没有队伍，无法开始匹配")
 		player.SendToClient(&message.S2C_StartMatch{
 			Result: false,
 		})
@@ -110,7 +156,7 @@ func (m *MatchManager) doHandleMatch(agent gate.Agent, msg *message.C2S_StartMat
 
 	q := m.matchQueues[msg.Type]
 	if q == nil {
-		log.Error("匹配队列不合法: %d", msg.Type)
+		logger.Error("匹配队列不合法: %d", msg.Type)
 		player.SendToClient(&message.S2C_StartMatch{
 			Result: false,
 		})
@@ -119,7 +165,7 @@ func (m *MatchManager) doHandleMatch(agent gate.Agent, msg *message.C2S_StartMat
 
 	// 检查队伍是否已经在该类型匹配队列中
 	if q.IsTeamInQueue(player.TeamId) {
-		log.Debug("队伍 %d 已经在匹配队列中", player.TeamId)
+		logger.With("teamId", player.TeamId).Debug("队伍已经在匹配队列中")
 		player.SendToClient(&message.S2C_StartMatch{
 			Result: false,
 		})
@@ -129,7 +175,7 @@ func (m *MatchManager) doHandleMatch(agent gate.Agent, msg *message.C2S_StartMat
 	// 获取队伍信息
 	team := game.External.TeamManager.GetTeamByPlayerId(user.PlayerId)
 	if team == nil {
-		log.Error("无法获取玩家 %d 的队伍信息", user.PlayerId)
+		logger.With("playerId", user.PlayerId).Error("无法获取玩家的队伍信息")
 		player.SendToClient(&message.S2C_StartMatch{
 			Result: false,
 		})
@@ -150,8 +196,8 @@ func (m *MatchManager) doHandleMatch(agent gate.Agent, msg *message.C2S_StartMat
 	// 加入对应类型的匹配队列
 	q.AddTeamRequest(teamMatchReq)
 
-	log.Debug("队伍 %d 已加入匹配队列(类型:%d)，包含 %d 个玩家，当前队列大小: %d",
-		teamId, msg.Type, len(team.TeamMembers), q.GetQueueSize())
+	logger.With("teamId", teamId).Debug("队伍已加入匹配队列(类型:%d)，包含 %d 个玩家，当前队列大小: %d",
+		msg.Type, len(team.TeamMembers), q.GetQueueSize())
 
 	// 通知队伍中的所有玩家匹配已开始
 	game.External.TeamManager.SendMessage(teamId, &message.S2C_StartMatch{
@@ -172,13 +218,13 @@ func (m *MatchManager) doHandleCancelMatch(agent gate.Agent) {
 	user := agent.UserData().(models.User)
 	player := game.External.UserManager.GetPlayer(user.PlayerId)
 	if player == nil {
-		log.Error("玩家不存在: %d", user.PlayerId)
+		logger.With("playerId", user.PlayerId).Error("玩家不存在")
 		return
 	}
 
 	// 检查玩家是否有队伍
 	if player.TeamId == 0 {
-		log.Error("玩家 %d 没有队伍，无法取消匹配", user.PlayerId)
+		logger.With("playerId", user.PlayerId).Error("玩家没有队伍，无法取消匹配")
 		return
 	}
 
@@ -192,12 +238,12 @@ func (m *MatchManager) doHandleCancelMatch(agent gate.Agent) {
 	}
 
 	if removed {
-		log.Debug("队伍 %d 已从匹配队列中移除", player.TeamId)
+		logger.With("teamId", player.TeamId).Debug("队伍已从匹配队列中移除")
 		game.External.TeamManager.SendMessage(player.TeamId, &message.S2C_CancelMatch{
 			Result: true,
 		})
 	} else {
-		log.Debug("队伍 %d 不在任何匹配队列中", player.TeamId)
+		logger.With("teamId", player.TeamId).Debug("队伍不在任何匹配队列中")
 	}
 }
 
@@ -205,9 +251,9 @@ func (m *MatchManager) doHandleCancelMatch(agent gate.Agent) {
 func getTargetRoomSize(matchType int32) int {
 	cfg, ok := gconf.GetMatchConfig(strconv.Itoa(int(matchType)))
 	if ok && cfg != nil {
-		return int(cfg.Room)
+		return cfg.RoomSize
 	}
-	log.Error("获取房间数量，匹配类型 %d 不合法", matchType)
+	logger.Error("获取房间数量，匹配类型 %d 不合法", matchType)
 	return 0
 }
 
@@ -217,7 +263,7 @@ func (m *MatchManager) executeTeamMatchingForType(q *match_models.MatchQueue, ma
 	if len(teamRequests) == 0 {
 		return nil
 	}
-	log.Debug("类型 %d: 当前匹配队列中有 %d 个队伍，总共 %d 个玩家",
+	logger.Debug("类型 %d: 当前匹配队列中有 %d 个队伍，总共 %d 个玩家",
 		matchType, len(teamRequests), q.GetTotalPlayers())
 
 	targetRoomSize := getTargetRoomSize(matchType)
@@ -308,7 +354,7 @@ func RandomRobotPlayerIds(matchType int32, needRobots int, exceptPlayerId []int6
 	for i := 0; i < needRobots; i++ {
 		player := game.External.UserManager.GetRandomPlayer(exceptPlayerId)
 		if player == nil {
-			log.Error("没有找到机器人玩家，当前填充数量: %d", i)
+			logger.Error("没有找到机器人玩家，当前填充数量: %d", i)
 			continue
 		}
 		playerId := player.PlayerId
@@ -322,7 +368,7 @@ func RandomRobotPlayerIds(matchType int32, needRobots int, exceptPlayerId []int6
 			JoinTime:  time.Now(),
 		}
 		robotTeams = append(robotTeams, robotTeam)
-		log.Debug("生成机器人队伍填充，队伍ID: %d，当前填充数量: %d", robotTeam.TeamId, i)
+		logger.With("teamId", robotTeam.TeamId).Debug("生成机器人队伍填充，当前填充数量: %d", i)
 	}
 	return robotTeams
 }
@@ -346,12 +392,12 @@ func (m *MatchManager) ProcessTimeoutRequests() {
 					delete(q.PlayerToTeam, playerId)
 				}
 				delete(q.TeamRequests, teamId)
-				log.Debug("清理过期的匹配请求: 队伍 %d", teamId)
+				logger.With("teamId", teamId).Debug("清理过期的匹配请求")
 			}
 		}
 
 		if len(expiredTeams) > 0 {
-			log.Debug("清理了 %d 个过期的匹配请求", len(expiredTeams))
+			logger.Debug("清理了 %d 个过期的匹配请求", len(expiredTeams))
 		}
 	}
 }
@@ -2,8 +2,10 @@ package room
 
 import (
 	"gameserver/common/base/actor"
+	"gameserver/common/broadcast"
 	"gameserver/common/msg/message"
 	"gameserver/common/utils"
+	"gameserver/core/gate"
 	"gameserver/core/log"
 	"gameserver/modules/game"
 	"time"
@@ -25,8 +27,12 @@ type Room struct {
 	TeamIds            []int64       `bson:"team_ids"`
 	CreateTime         time.Time     `bson:"create_time"`  // 房间创建时间
 	MaxLifetime        time.Duration `bson:"max_lifetime"` // 房间最大存活时间
+	logger             *log.Logger   `bson:"-"`            // 绑定了房间id的日志
 }
 
+// logger match模块的日志
+var logger = log.Module("match")
+
 // CreateRoom 创建房间
 func CreateRoom(playerIds []int64, teamIds []int64) *Room {
 	roomId := generateRoomId()
@@ -36,14 +42,28 @@ func CreateRoom(playerIds []int64, teamIds []int64) *Room {
 		CreateTime:  time.Now(),
 		MaxLifetime: MaxRoomLifetime,
 		TeamIds:     teamIds,
+		logger:      logger.With("roomId", roomId),
 	}
 	room.TaskHandler = actor.InitTaskHandler(actor.Room, roomId, room)
 	room.Init()
-	log.Debug("房间 %d 创建成功，包含 %d 个玩家，最大存活时间: %v",
-		roomId, len(playerIds), room.MaxLifetime)
+	room.subscribeMembers()
+	room.logger.Debug("房间创建成功，包含 %d 个玩家，最大存活时间: %v", len(playerIds), room.MaxLifetime)
 	return room
 }
 
+// subscribeMembers 在线成员加入房间频道
+func (r *Room) subscribeMembers() {
+	channel := broadcast.Room(r.RoomId)
+	for _, member := range r.RoomMembers {
+		p := game.External.UserManager.GetPlayer(member)
+		if p == nil {
+			r.logger.With("playerId", member).Debug("玩家不在线")
+			continue
+		}
+		broadcast.Subscribe(p.Agent(), channel)
+	}
+}
+
 func (r *Room) Init() {
 	r.TaskHandler.Start()
 }
@@ -63,7 +83,7 @@ func (r *Room) OnTimer() {
 // CheckExpiration 检查房间是否过期，如果过期则自动停止
 func (r *Room) CheckExpiration() {
 	if r.IsExpired() {
-		log.Debug("房间 %d 已过期，开始自动停止", r.RoomId)
+		r.logger.Debug("房间已过期，开始自动停止")
 		r.StopRoom()
 	}
 }
@@ -75,18 +95,20 @@ func (r *Room) cleanup() {
 		game.External.TeamManager.LeaveRoom(teamId)
 	}
 
+	broadcast.CloseChannel(broadcast.Room(r.RoomId))
+
 	// 清空房间成员列表
 	r.RoomMembers = nil
 	r.TeamIds = nil
-	log.Debug("房间 %d 资源清理完成", r.RoomId)
+	r.logger.Debug("房间资源清理完成")
 }
 
 // StopRoom 手动停止房间
 func (r *Room) StopRoom() {
-	log.Debug("房间 %d 手动停止", r.RoomId)
+	r.logger.Debug("房间手动停止")
 
 	// 通知所有玩家房间关闭（使用日志记录，避免消息类型依赖）
-	log.Debug("房间 %d 手动关闭，通知所有玩家", r.RoomId)
+	r.logger.Debug("房间手动关闭，通知所有玩家")
 
 	// 清理资源
 	r.cleanup()
@@ -101,28 +123,16 @@ func (r *Room) IsExpired() bool {
 }
 
 func (r *Room) SendRoomMessage(msg proto.Message) {
-	for _, member := range r.RoomMembers {
-		p := game.External.UserManager.GetPlayer(member)
-		if p == nil {
-			log.Debug("玩家 %d 不在线", member)
-			continue
-		}
-		p.SendToClient(msg)
-	}
+	broadcast.Publish(broadcast.Room(r.RoomId), msg)
 }
 
 func (r *Room) SendRoomMessageExceptSelf(msg proto.Message, selfId int64) {
-	for _, member := range r.RoomMembers {
-		if member == selfId {
-			continue
-		}
-		p := game.External.UserManager.GetPlayer(member)
-		if p == nil {
-			log.Debug("玩家 %d 不在线", member)
-			continue
-		}
-		p.SendToClient(msg)
+	// 自己已断线时连接已退出频道，无需排除
+	var except []gate.Agent
+	if p := game.External.UserManager.GetPlayer(selfId); p != nil {
+		except = append(except, p.Agent())
 	}
+	broadcast.Publish(broadcast.Room(r.RoomId), msg, except...)
 }
 
 func (r *Room) PlayerOffline(playerId int64) {
@@ -6,6 +6,12 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+func init() {
+	// 房间的广播频道订阅的是本节点的连接，留在创建的节点上
+	actor.RegisterGroup(actor.Room, actor.GroupOptions{Pinned: true})
+	actor.HandleMessage(actor.Room, "PlayerOffline", handlePlayerOffline)
+}
+
 // GetInterval 调用Room的GetInterval方法
 func GetInterval(RoomId int64) int {
 	if room, ok := actor.GetActor[Room](actor.Room, RoomId); ok {
@@ -57,9 +63,20 @@ func SendRoomMessageExceptSelf(RoomId int64, msg proto.Message, selfId int64) {
 	}
 }
 
-// PlayerOffline 调用Room的PlayerOffline方法
+// PlayerOffline 通知房间玩家下线，房间不迁移，在本节点时本地投递，否则发到目录中登记的节点
 func PlayerOffline(RoomId int64, playerId int64) {
-	if room, ok := actor.GetActor[Room](actor.Room, RoomId); ok {
-		room.PlayerOffline(playerId)
+	err := actor.Post(actor.Room, RoomId, "PlayerOffline", playerId)
+	if err == actor.ErrActorNotFound {
+		// 房间已经解散，或所在节点的登记还没有到达
+		logger.With("roomId", RoomId, "playerId", playerId).Warn("room player offline: room not found")
+	} else if err != nil {
+		logger.With("roomId", RoomId, "playerId", playerId).Error("room player offline: %v", err)
+	}
+}
+
+func handlePlayerOffline(uniqueID interface{}, args []interface{}) (interface{}, error) {
+	if room, ok := actor.GetActor[Room](actor.Room, uniqueID); ok {
+		room.PlayerOffline(args[0].(int64))
 	}
+	return nil, nil
 }
@@ -5,7 +5,6 @@ import (
 	"gameserver/common/models"
 	"gameserver/common/msg/message"
 	"gameserver/core/gate"
-	"gameserver/core/log"
 	"gameserver/modules/game"
 	"gameserver/modules/match/internal/managers/room"
 	"sync"
@@ -54,12 +53,12 @@ func (r *RoomManager) doHandleRecordOperate(msg *message.C2S_RecordGameOperate,
 	playerId := agent.UserData().(models.User).PlayerId
 	team := game.External.TeamManager.GetTeamByPlayerId(playerId)
 	if team == nil {
-		log.Error("玩家 %d 没有队伍", playerId)
+		logger.With("playerId", playerId).Error("玩家没有队伍")
 		return
 	}
 	roomId := team.RoomId
 	if roomId != msg.RoomId {
-		log.Error("队伍 %d 的房间ID不匹配", team.TeamId)
+		logger.With("teamId", team.TeamId).Error("队伍的房间ID不匹配")
 		return
 	}
 	room.SendRoomMessage(roomId, &message.S2C_RecordGameOperate{
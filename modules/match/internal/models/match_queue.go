@@ -152,7 +152,7 @@ func (q *MatchQueue) ProcessTeamMatchResults(matchedGroups [][]*TeamMatchRequest
 			// 从匹配队列中移除已匹配的队伍
 			q.RemoveTeamRequests(teamIds)
 
-			log.Debug("成功匹配 %d 个队伍，包含 %d 个玩家，房间ID: %s",
+			log.Debug("成功匹配 %d 个队伍，包含 %d 个玩家，房间ID: %d",
 				len(group), len(allPlayerIds), r.RoomId)
 		}
 	}
@@ -27,8 +27,6 @@ func C2S_GetMyRankHandler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到C2S_GetMyRank消息: %v, agent: %v", msg, agent)
-
 	// 获取排行榜管理器
 	rankManager := managers.GetRankManager()
 
@@ -27,8 +27,6 @@ func C2S_GetRankListHandler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到C2S_GetRankList消息: %v, agent: %v", msg, agent)
-
 	// 获取排行榜数据
 	playerId := agent.UserData().(models.User).PlayerId
 	managers.GetRankManager().HandleGetRankList(playerId, msg)
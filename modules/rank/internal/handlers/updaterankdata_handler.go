@@ -27,8 +27,6 @@ func C2S_UpdateRankDataHandler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到C2S_UpdateRankData消息: %v, agent: %v", msg, agent)
-
 	playerId := agent.UserData().(models.User).PlayerId
 	managers.GetRankManager().HandleUpdateRankData(playerId, msg)
 }
@@ -28,6 +28,9 @@ var (
 	rankManagerOnce sync.Once
 )
 
+// logger rank模块的日志
+var logger = log.Module("rank")
+
 func GetRankManager() *RankManager {
 	rankManagerOnce.Do(func() {
 		rankManager = &RankManager{}
@@ -121,7 +124,7 @@ func (r *RankManager) doHandleUpdateRankData(playerId int64, req *message.C2S_Up
 
 	rankData.UpdateTime = time.Now()
 
-	log.Debug("排行榜数据已更新: 类型=%d, 玩家=%d, 分数=%d", rankType, playerId, req.Score)
+	logger.With("playerId", playerId).Debug("排行榜数据已更新: 类型=%d, 分数=%d", rankType, req.Score)
 }
 
 // HandleGetRankList 获取排行榜列表 - 异步执行
@@ -244,10 +247,10 @@ func (r *RankManager) sortRankData(rankData *models.RankData) {
 func (r *RankManager) loadRankDataFromDB() {
 	// 这里可以从数据库加载排行榜数据
 	// 暂时使用空数据，实际项目中应该从数据库加载
-	log.Debug("排行榜管理器初始化完成")
+	logger.Debug("排行榜管理器初始化完成")
 	data, err := mongodb.FindOneById[RankManager](r.GetPersistId())
 	if err != nil {
-		log.Error("从数据库加载排行榜数据失败: %v", err)
+		logger.Error("从数据库加载排行榜数据失败: %v", err)
 		return
 	}
 	if data == nil {
@@ -256,5 +259,5 @@ func (r *RankManager) loadRankDataFromDB() {
 		r.RankCache = data.RankCache
 	}
 	r.PersistId = 1 // 使用固定ID，因为现在使用单例模式
-	log.Debug("从数据库加载排行榜数据: %v", r)
+	logger.Debug("从数据库加载排行榜数据: %v", r)
 }
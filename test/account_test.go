@@ -0,0 +1,133 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"gameserver/common/db/mongodb"
+	"gameserver/common/models"
+	"gameserver/common/msg/message"
+	"gameserver/conf"
+	"gameserver/modules/login/processor"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestGuestAndAccountLogin 游客和账号密码登录不依赖第三方平台
+func TestGuestAndAccountLogin(t *testing.T) {
+	ctx := context.Background()
+	mongodb.UseMemory()
+	conf.Server.Login.Guest = true
+	conf.Server.Login.Account = true
+
+	t.Run("Guest", func(t *testing.T) {
+		guest, ok := processor.Get(message.LoginType_Guest)
+		assert.True(t, ok)
+		resp := guest.ReqLogin(ctx, &message.C2S_Login{DeviceId: "device-0001"})
+		assert.Equal(t, int32(0), resp.ErrCode)
+		assert.Equal(t, "device-0001", resp.Openid)
+
+		for _, deviceId := range []string{"", "short", "device 0001"} {
+			assert.Equal(t, int32(-1), guest.ReqLogin(ctx, &message.C2S_Login{DeviceId: deviceId}).ErrCode, deviceId)
+		}
+
+		conf.Server.Login.Guest = false
+		defer func() { conf.Server.Login.Guest = true }()
+		assert.Equal(t, int32(-1), guest.ReqLogin(ctx, &message.C2S_Login{DeviceId: "device-0001"}).ErrCode)
+	})
+
+	t.Run("Account", func(t *testing.T) {
+		account, _ := processor.Get(message.LoginType_Account)
+		login := func(username, password string, register bool) (string, int32) {
+			resp := account.ReqLogin(ctx, &message.C2S_Login{ServerId: 1, Username: username, Password: password, Register: register})
+			return resp.ErrMsg, resp.ErrCode
+		}
+
+		msg, _ := login("tester", "secret1", false)
+		assert.Equal(t, "wrong username or password", msg)
+		_, code := login("tester", "secret1", true)
+		assert.Equal(t, int32(0), code)
+		msg, _ = login("tester", "secret2", true)
+		assert.Equal(t, "username already exists", msg)
+
+		_, code = login("tester", "secret1", false)
+		assert.Equal(t, int32(0), code)
+		msg, _ = login("tester", "secret2", false)
+		assert.Equal(t, "wrong username or password", msg)
+
+		// 用户名按服区分
+		resp := account.ReqLogin(ctx, &message.C2S_Login{ServerId: 2, Username: "tester", Password: "secret1"})
+		assert.Equal(t, int32(-1), resp.ErrCode)
+
+		// 密码只保存加盐哈希
+		credentials := mongodb.Current().Collection("Credential")
+		n, _ := credentials.Count(ctx, bson.M{"_id": "1_tester", "Hash": bson.M{"$exists": true}, "Salt": bson.M{"$exists": true}})
+		assert.Equal(t, int64(1), n)
+		n, _ = credentials.Count(ctx, bson.M{"Hash": "secret1"})
+		assert.Equal(t, int64(0), n)
+		// 账号在第一次进入游戏时才创建用户
+		n, _ = mongodb.NewRepository[models.User]().Count(ctx, nil)
+		assert.Equal(t, int64(0), n)
+
+		msg, _ = login("a", "secret1", true)
+		assert.Equal(t, "invalid username", msg)
+		msg, _ = login("tester2", "123", true)
+		assert.Equal(t, "invalid password", msg)
+
+		// 撤销注册后用户名可以重新注册
+		assert.NoError(t, account.(processor.Registrar).Unregister(ctx, 1, "tester"))
+		_, code = login("tester", "secret3", true)
+		assert.Equal(t, int32(0), code)
+	})
+
+	t.Run("Bind", func(t *testing.T) {
+		users := mongodb.NewRepository[models.User]()
+		guest := models.User{
+			AccountId: models.AccountId(1, models.IdentityKey(message.LoginType_Guest, "device-0001")),
+			OpenId:    models.IdentityKey(message.LoginType_Guest, "device-0001"),
+			ServerId:  1,
+			PlayerId:  1001,
+			Platform:  message.LoginType_Guest,
+		}
+		assert.Equal(t, "1_guest:device-0001", guest.AccountId)
+		assert.NoError(t, users.Insert(ctx, guest))
+		// 早期的微信账号，账号id为serverId_openId
+		assert.NoError(t, users.Insert(ctx, models.User{AccountId: "1_wx-old", OpenId: "wx-old", ServerId: 1, PlayerId: 1002, Platform: message.LoginType_WeChat}))
+
+		user, err := models.FindUserByIdentity(ctx, 1, message.LoginType_Guest, "device-0001")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1001), user.PlayerId)
+		user, _ = models.FindUserByIdentity(ctx, 1, message.LoginType_WeChat, "wx-old")
+		assert.Equal(t, int64(1002), user.PlayerId)
+		user, _ = models.FindUserByIdentity(ctx, 1, message.LoginType_WeChat, "wx-new")
+		assert.Nil(t, user)
+
+		result, err := models.BindIdentity(ctx, guest, message.LoginType_WeChat, "wx-new")
+		assert.NoError(t, err)
+		assert.Equal(t, message.Result_Success, result)
+		user, _ = models.FindUserByIdentity(ctx, 1, message.LoginType_WeChat, "wx-new")
+		assert.Equal(t, int64(1001), user.PlayerId)
+		// 另一个服的同一个openid是不同的身份
+		user, _ = models.FindUserByIdentity(ctx, 2, message.LoginType_WeChat, "wx-new")
+		assert.Nil(t, user)
+
+		for _, c := range []struct {
+			loginType message.LoginType
+			openId    string
+		}{
+			{message.LoginType_WeChat, "wx-other"},   // 已经绑定过微信
+			{message.LoginType_Guest, "device-0002"}, // 本身就是游客
+			{message.LoginType_DouYin, "wx-old"},     // 抖音和微信的openid共用早期的账号id
+		} {
+			result, err := models.BindIdentity(ctx, guest, c.loginType, c.openId)
+			assert.NoError(t, err)
+			assert.Equal(t, message.Result_Duplicate, result, c.openId)
+		}
+
+		result, _ = models.BindIdentity(ctx, guest, message.LoginType_Account, "tester")
+		assert.Equal(t, message.Result_Success, result)
+		user, _ = models.FindUserByIdentity(ctx, 1, message.LoginType_Account, "tester")
+		assert.Equal(t, guest.AccountId, user.AccountId)
+	})
+}
@@ -0,0 +1,188 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gameserver/common/base/actor"
+	"gameserver/core/chanrpc"
+	"gameserver/core/cluster"
+	"gameserver/core/conf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var registerEchoOnce sync.Once
+
+// fakeActorNode 模拟另一个节点上的actor服务，记录收到的调用
+type fakeActorNode struct {
+	calls     chan []interface{}
+	importErr atomic.Value // Import返回的错误
+}
+
+func (f *fakeActorNode) start(node *cluster.Node) {
+	requests := chanrpc.NewServer(10)
+	requests.Register("Request", func(args []interface{}) []interface{} {
+		f.calls <- append([]interface{}{"Request"}, args...)
+		return []interface{}{fmt.Sprintf("remote %v", args[1]), ""}
+	})
+	requests.Register("Import", func(args []interface{}) []interface{} {
+		f.calls <- append([]interface{}{"Import"}, args...)
+		err, _ := f.importErr.Load().(string)
+		return []interface{}{err}
+	})
+	requests.Register("Drain", func(args []interface{}) {
+		f.calls <- append([]interface{}{"Drain"}, args...)
+	})
+	posts := chanrpc.NewServer(10)
+	posts.Register("Pin", func(args []interface{}) {
+		f.calls <- append([]interface{}{"Pin"}, args...)
+	})
+	posts.Register("Pins", func(args []interface{}) {})
+	posts.Register("Post", func(args []interface{}) {
+		f.calls <- append([]interface{}{"Post"}, args...)
+	})
+	for _, s := range []*chanrpc.Server{requests, posts} {
+		go func(s *chanrpc.Server) {
+			for ci := range s.ChanCall {
+				s.Exec(ci)
+			}
+		}(s)
+	}
+	node.Register("actor", requests)
+	node.Register("actor_post", posts)
+}
+
+func (f *fakeActorNode) next(t *testing.T) []interface{} {
+	select {
+	case call := <-f.calls:
+		return call
+	case <-time.After(time.Second):
+		t.Error("没有收到调用")
+		return nil
+	}
+}
+
+// TestActorRemote Actor按哈希环分配到节点，消息跨节点转发，排空时迁移到其他节点
+func TestActorRemote(t *testing.T) {
+	addrA, addrB := "127.0.0.1:39581", "127.0.0.1:39582"
+	group := actor.PlacementService(actor.Test2)
+	conf.NodeName = "node-a"
+	conf.ClusterNodes = []conf.ClusterNode{
+		{Name: "node-a", Addr: addrA, Services: []string{group}},
+		{Name: "node-b", Addr: addrB, Services: []string{group, actor.PlacementService(actor.Test1)}},
+	}
+	conf.CallTimeout = time.Second
+	defer func() {
+		conf.NodeName = ""
+		conf.ClusterNodes = nil
+	}()
+
+	actor.Init(2000)
+	registerEchoOnce.Do(func() {
+		actor.HandleMessage(actor.Test2, "Echo", func(uniqueID interface{}, args []interface{}) (interface{}, error) {
+			return fmt.Sprintf("local %v %v", uniqueID, args[0]), nil
+		})
+	})
+	exported := make(chan interface{}, 10)
+	actor.RegisterGroup(actor.Test2, actor.GroupOptions{
+		Export: func(uniqueID interface{}) ([]byte, error) {
+			exported <- uniqueID
+			return []byte(fmt.Sprintf("state %v", uniqueID)), nil
+		},
+	})
+
+	fake := &fakeActorNode{calls: make(chan []interface{}, 10)}
+	b := &cluster.Node{Name: "node-b", ListenAddr: addrB, Registry: cluster.NewRegistry(conf.ClusterNodes), ConnectInterval: 50 * time.Millisecond}
+	fake.start(b)
+	b.Start()
+	defer b.Close()
+	cluster.Init()
+	defer cluster.Destroy()
+	if !assert.True(t, waitLinked(b, "node-a", 2*time.Second)) {
+		return
+	}
+
+	// 找到分别分配到两个节点的Actor
+	var local, remote int64
+	for id := int64(1); local == 0 || remote == 0; id++ {
+		switch actor.Locate(actor.Test2, id) {
+		case "node-a":
+			local = id
+		case "node-b":
+			remote = id
+		}
+	}
+
+	// 本节点的Actor不存在时返回ErrActorNotFound
+	_, err := actor.Request(actor.Test2, local, "Echo", 1)
+	assert.Equal(t, actor.ErrActorNotFound, err)
+	a := &NewTestActor{}
+	a.TaskHandler = actor.InitTaskHandler(actor.Test2, local, a)
+	a.Init()
+	ret, err := actor.Request(actor.Test2, local, "Echo", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("local %v 1", local), ret)
+	_, err = actor.Request(actor.Test2, local, "Missing")
+	assert.Equal(t, actor.ErrNoHandler, err)
+
+	// 其他节点发来的消息在本节点的Actor中处理
+	rets, err := b.CallNode("node-a", "actor", "Request", string(actor.Test2), local, "Echo", "from b")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{fmt.Sprintf("local %v from b", local), ""}, rets)
+
+	// 其他节点上的Actor
+	ret, err = actor.Request(actor.Test2, remote, "Echo", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("remote %v", remote), ret)
+	assert.Equal(t, []interface{}{"Request", "test2", remote, "Echo", 2}, fake.next(t))
+	assert.NoError(t, actor.Post(actor.Test2, remote, "Echo", 3))
+	assert.Equal(t, []interface{}{"Post", "test2", remote, "Echo", 3}, fake.next(t))
+
+	// 目标节点导入失败时Actor留在本节点
+	fake.importErr.Store("import failed")
+	assert.Equal(t, 0, actor.Drain())
+	assert.Equal(t, local, <-exported)
+	assert.Equal(t, []interface{}{"Drain", "node-a", true}, fake.next(t))
+	assert.Equal(t, "Import", fake.next(t)[0])
+	_, ok := actor.GetHandler(fmt.Sprintf("%s_%v", actor.Test2, local))
+	assert.True(t, ok)
+
+	// 排空后通知其他节点，本节点的Actor导出状态迁移到新的节点
+	fake.importErr.Store("")
+	assert.Equal(t, 1, actor.Drain())
+	assert.Equal(t, local, <-exported)
+	assert.Equal(t, []interface{}{"Drain", "node-a", true}, fake.next(t))
+	assert.Equal(t, []interface{}{"Import", "test2", local, []byte(fmt.Sprintf("state %v", local))}, fake.next(t))
+	_, ok = actor.GetHandler(fmt.Sprintf("%s_%v", actor.Test2, local))
+	assert.False(t, ok)
+
+	// 之后发给该Actor的消息转发到新的节点
+	assert.Equal(t, "node-b", actor.Locate(actor.Test2, local))
+	ret, err = actor.Request(actor.Test2, local, "Echo", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("remote %v", local), ret)
+	assert.Equal(t, []interface{}{"Request", "test2", local, "Echo", 4}, fake.next(t))
+
+	// 不能迁移的Actor排空时留在本节点，消息仍在本地投递，Pinned的Actor启动时通知其他节点
+	assert.Equal(t, "node-b", actor.Locate(actor.Test1, local))
+	actor.RegisterGroup(actor.Test1, actor.GroupOptions{Pinned: true})
+	defer actor.RegisterGroup(actor.Test1, actor.GroupOptions{})
+	pinned := &NewTestActor{}
+	pinned.TaskHandler = actor.InitTaskHandler(actor.Test1, local, pinned)
+	pinned.Init()
+	assert.Equal(t, "node-a", actor.Locate(actor.Test1, local))
+	assert.Equal(t, []interface{}{"Pin", "test1", local, "node-a", true}, fake.next(t))
+	pinned.Stop()
+	assert.Equal(t, []interface{}{"Pin", "test1", local, "node-a", false}, fake.next(t))
+
+	// 其他节点登记的Pinned的Actor按目录投递，不按哈希环
+	other := local + 1000
+	assert.NoError(t, b.GoNode("node-a", "actor_post", "Pin", "test1", other, "node-c", true))
+	assert.Eventually(t, func() bool { return actor.Locate(actor.Test1, other) == "node-c" }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, b.GoNode("node-a", "actor_post", "Pins", "node-c"))
+	assert.Eventually(t, func() bool { return actor.Locate(actor.Test1, other) == "node-b" }, time.Second, 10*time.Millisecond)
+}
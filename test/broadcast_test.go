@@ -0,0 +1,89 @@
+package test
+
+import (
+	"bytes"
+	"gameserver/common/broadcast"
+	"gameserver/common/msg"
+	"gameserver/common/msg/message"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// broadcastAgent 记录收到的原始数据
+type broadcastAgent struct {
+	mutex sync.Mutex
+	data  [][][]byte
+	msgs  []interface{}
+}
+
+func (a *broadcastAgent) WriteMsg(msg interface{}) {
+	a.mutex.Lock()
+	a.msgs = append(a.msgs, msg)
+	a.mutex.Unlock()
+}
+
+func (a *broadcastAgent) WriteData(data ...[]byte) {
+	a.mutex.Lock()
+	a.data = append(a.data, data)
+	a.mutex.Unlock()
+}
+
+func (a *broadcastAgent) received() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return len(a.data)
+}
+
+func (a *broadcastAgent) LocalAddr() net.Addr            { return nil }
+func (a *broadcastAgent) RemoteAddr() net.Addr           { return nil }
+func (a *broadcastAgent) Close()                         {}
+func (a *broadcastAgent) Destroy()                       {}
+func (a *broadcastAgent) UserData() interface{}          { return nil }
+func (a *broadcastAgent) SetUserData(data interface{})   {}
+func (a *broadcastAgent) RTT() time.Duration             { return 0 }
+func (a *broadcastAgent) ObserveRTT(rtt time.Duration)   {}
+func (a *broadcastAgent) SetIdleTimeout(d time.Duration) {}
+
+// TestBroadcast_Channels 测试订阅、排除、多频道去重与断线清理
+func TestBroadcast_Channels(t *testing.T) {
+	s := broadcast.NewService()
+	a, b, c := &broadcastAgent{}, &broadcastAgent{}, &broadcastAgent{}
+
+	s.Subscribe(a, broadcast.World, broadcast.Team(1))
+	s.Subscribe(b, broadcast.World, broadcast.Team(1))
+	s.Subscribe(c, broadcast.World)
+	assert.Equal(t, 3, s.Members(broadcast.World))
+	assert.Equal(t, 2, s.Members(broadcast.Team(1)))
+
+	m := &message.S2C_PlayerOffline{PlayerId: 1}
+	assert.Equal(t, 1, s.Publish(broadcast.Team(1), m, a))
+	assert.Equal(t, 0, a.received())
+	assert.Equal(t, 1, b.received())
+
+	// 同时在两个频道的连接只收到一次
+	assert.Equal(t, 3, s.PublishMulti([]string{broadcast.World, broadcast.Team(1)}, m))
+	assert.Equal(t, 1, a.received())
+	assert.Equal(t, 2, b.received())
+	assert.Equal(t, 1, c.received())
+
+	// 所有连接收到的是同一份序列化结果
+	expect, err := msg.Processor.Marshal(m)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(bytes.Join(expect, nil), bytes.Join(a.data[0], nil)))
+	assert.Same(t, &a.data[0][1][0], &c.data[0][1][0])
+
+	s.UnsubscribeAll(b)
+	assert.Equal(t, 2, s.Members(broadcast.World))
+	assert.Equal(t, 1, s.Members(broadcast.Team(1)))
+
+	s.CloseChannel(broadcast.Team(1))
+	assert.Equal(t, 0, s.Members(broadcast.Team(1)))
+	assert.Equal(t, 0, s.Publish(broadcast.Team(1), m))
+
+	assert.Equal(t, 2, s.Announce("维护公告"))
+	assert.Contains(t, s.Report(), "published: 3, delivered: 6")
+}
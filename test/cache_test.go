@@ -0,0 +1,225 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gameserver/common/cache"
+	"gameserver/common/db/mongodb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type CacheDoc struct {
+	Id    int64  `bson:"_id"`
+	Name  string `bson:"name"`
+	Level int32  `bson:"level"`
+}
+
+func (d CacheDoc) GetPersistId() interface{} { return d.Id }
+
+// recordWriter 记录每次写回的内容，fail不为nil时写回失败
+type recordWriter struct {
+	mu      sync.Mutex
+	batches []map[string]int
+	fail    error
+}
+
+func (w *recordWriter) write(items map[string]int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fail != nil {
+		return w.fail
+	}
+	w.batches = append(w.batches, items)
+	return nil
+}
+
+func (w *recordWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.batches)
+}
+
+func TestCache(t *testing.T) {
+	t.Run("ReadThrough", func(t *testing.T) {
+		loads := 0
+		c := cache.New(cache.Options[string, int]{
+			Loader: func(key string) (int, bool, error) {
+				loads++
+				if key == "missing" {
+					return 0, false, nil
+				}
+				return len(key), true, nil
+			},
+		})
+		for i := 0; i < 3; i++ {
+			value, ok, err := c.Load("abc")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, 3, value)
+		}
+		_, ok, _ := c.Load("missing")
+		assert.False(t, ok)
+		_, ok, _ = c.Load("missing")
+		assert.False(t, ok)
+		assert.Equal(t, 3, loads, "不存在的数据不缓存")
+
+		stats := c.Stats()
+		assert.Equal(t, int64(2), stats["cache_hits"])
+		assert.Equal(t, int64(3), stats["cache_misses"])
+		assert.Equal(t, "40.00%", stats["cache_hit_rate"])
+	})
+
+	t.Run("WriteBehind", func(t *testing.T) {
+		w := &recordWriter{}
+		c := cache.New(cache.Options[string, int]{Writer: w.write, FlushDelay: 20 * time.Millisecond})
+		c.Store("a", 1)
+		c.Store("a", 2)
+		c.Store("b", 1)
+		assert.Equal(t, 0, w.count())
+		assert.Eventually(t, func() bool { return w.count() == 1 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, map[string]int{"a": 2, "b": 1}, w.batches[0])
+
+		// 写回后Set的数据不再写回
+		c.Set("c", 1)
+		assert.NoError(t, c.Flush())
+		assert.Equal(t, 1, w.count())
+
+		// Remove先写回脏数据
+		c.Store("a", 3)
+		assert.NoError(t, c.Remove("a"))
+		assert.Equal(t, map[string]int{"a": 3}, w.batches[1])
+		_, ok := c.Get("a")
+		assert.False(t, ok)
+
+		// 写回失败时保留脏数据
+		w.fail = errors.New("db down")
+		c.Store("b", 2)
+		assert.Error(t, c.Flush())
+		assert.Error(t, c.Remove("b"))
+		assert.Equal(t, 1, c.Stats()["dirty"])
+		w.fail = nil
+		assert.NoError(t, c.Close())
+		assert.Equal(t, map[string]int{"b": 2}, w.batches[2])
+		assert.Equal(t, int64(2), c.Stats()["flush_errors"])
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		var evicted []string
+		w := &recordWriter{}
+		c := cache.New(cache.Options[string, int]{
+			MaxSize: 2,
+			Writer:  w.write,
+			OnEvict: func(key string, _ int, reason cache.EvictReason) {
+				evicted = append(evicted, key+":"+reason.String())
+			},
+		})
+		c.Set("a", 1)
+		c.Set("b", 2)
+		c.Get("a")
+		c.Set("c", 3)
+		assert.Equal(t, []string{"b:capacity"}, evicted)
+
+		// 脏数据写回前不会被淘汰
+		c.Store("d", 4)
+		c.Store("e", 5)
+		assert.Equal(t, []string{"b:capacity", "a:capacity", "c:capacity"}, evicted)
+		assert.Equal(t, 2, c.Len())
+		c.Store("f", 6)
+		assert.Equal(t, 3, c.Len())
+		assert.NoError(t, c.Flush())
+		assert.Equal(t, 2, c.Len())
+		assert.Equal(t, "d:capacity", evicted[3])
+
+		c.Invalidate("e")
+		assert.Equal(t, "e:invalidated", evicted[4])
+		assert.NoError(t, c.Clear())
+		assert.Equal(t, "f:invalidated", evicted[5])
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("EvictWhileFlushing", func(t *testing.T) {
+		// 写回期间条目不会被淘汰，写回失败后重新标记为脏数据
+		writing, release := make(chan struct{}), make(chan struct{})
+		var once sync.Once
+		c := cache.New(cache.Options[string, int]{
+			MaxSize: 1,
+			Writer: func(items map[string]int) error {
+				once.Do(func() { close(writing) })
+				<-release
+				return errors.New("db down")
+			},
+		})
+		c.Store("a", 1)
+		done := make(chan error)
+		go func() { done <- c.Flush() }()
+		<-writing
+		c.Set("b", 2)
+		c.Set("c", 3)
+		value, ok := c.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+		close(release)
+		assert.Error(t, <-done)
+		assert.Equal(t, 1, c.Stats()["dirty"])
+		value, ok = c.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+		c.Close()
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		c := cache.New(cache.Options[string, int]{TTL: 30 * time.Millisecond})
+		c.Set("a", 1)
+		c.Set("b", 2)
+		time.Sleep(20 * time.Millisecond)
+		_, ok := c.Get("a") // 访问后重新计时
+		assert.True(t, ok)
+		time.Sleep(20 * time.Millisecond)
+		_, ok = c.Get("a")
+		assert.True(t, ok)
+		_, ok = c.Get("b")
+		assert.False(t, ok)
+		assert.Equal(t, 1, c.Len())
+	})
+
+	t.Run("Mongo", func(t *testing.T) {
+		mongodb.UseMemory()
+		repo := mongodb.NewRepository[CacheDoc]()
+		assert.NoError(t, repo.Insert(context.Background(), CacheDoc{Id: 1, Name: "tom"}))
+
+		c := cache.New(cache.Options[int64, *CacheDoc]{
+			Name:   "test_doc",
+			Loader: cache.ById[int64](repo),
+		})
+		doc, ok, err := c.Load(1)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "tom", doc.Name)
+		_, ok, err = c.Load(2)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Contains(t, cache.Report(), "test_doc: size 1")
+
+		docs := cache.New(cache.Options[int64, CacheDoc]{Writer: cache.SaveAll[int64, CacheDoc]()})
+		docs.Store(1, CacheDoc{Id: 1, Name: "tom", Level: 3})
+		docs.Store(2, CacheDoc{Id: 2, Name: "jerry"})
+		assert.NoError(t, docs.Flush())
+		n, _ := repo.Count(context.Background(), nil)
+		assert.Equal(t, int64(2), n)
+
+		// 数据库被修改后旧的缓存要失效
+		doc, _, _ = c.Load(1)
+		assert.Equal(t, int32(0), doc.Level)
+		c.Invalidate(1)
+		doc, _, _ = c.Load(1)
+		assert.Equal(t, int32(3), doc.Level)
+
+		assert.NoError(t, cache.CloseAll())
+		assert.NotContains(t, cache.Report(), "test_doc")
+	})
+}
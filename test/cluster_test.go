@@ -0,0 +1,179 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gameserver/common/msg/message"
+	"gameserver/core/chanrpc"
+	"gameserver/core/cluster"
+	"gameserver/core/conf"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+// newRankService 模拟rank模块的ChanRPC
+func newRankService(notified chan string) *chanrpc.Server {
+	s := chanrpc.NewServer(10)
+	s.Register("Add", func(args []interface{}) interface{} {
+		return args[0].(int) + args[1].(int)
+	})
+	s.Register("Split", func(args []interface{}) []interface{} {
+		return []interface{}{args[0].(string), int64(len(args[0].(string)))}
+	})
+	s.Register("Kick", func(args []interface{}) interface{} {
+		kick := args[0].(*message.S2C_Kick)
+		return &message.S2C_Kick{Reason: "re: " + kick.Reason, BanExpire: kick.BanExpire + 1}
+	})
+	s.Register("Notify", func(args []interface{}) {
+		notified <- args[0].(string)
+	})
+	s.Register("Slow", func(args []interface{}) {
+		time.Sleep(300 * time.Millisecond)
+	})
+	go func() {
+		for ci := range s.ChanCall {
+			s.Exec(ci)
+		}
+	}()
+	return s
+}
+
+func waitLinked(n *cluster.Node, name string, within time.Duration) bool {
+	deadline := time.Now().Add(within)
+	for time.Now().Before(deadline) {
+		for _, linked := range n.Links() {
+			if linked == name {
+				return true
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
+
+// TestCluster 握手、按注册表跨节点调用、超时和断线重连
+func TestCluster(t *testing.T) {
+	rankAddr := "127.0.0.1:39571"
+	nodes := []conf.ClusterNode{
+		{Name: "game-1"},
+		{Name: "rank-1", Addr: rankAddr, Services: []string{"rank"}},
+	}
+	notified := make(chan string, 1)
+	service := newRankService(notified)
+
+	startRank := func() *cluster.Node {
+		rank := &cluster.Node{
+			Name:       "rank-1",
+			ListenAddr: rankAddr,
+			Secret:     "secret",
+			Registry:   cluster.NewRegistry(nodes),
+		}
+		rank.Register("rank", service)
+		rank.Start()
+		return rank
+	}
+	rank := startRank()
+	game := &cluster.Node{
+		Name:            "game-1",
+		Secret:          "secret",
+		Registry:        cluster.NewRegistry(nodes),
+		CallTimeout:     100 * time.Millisecond,
+		ConnectInterval: 50 * time.Millisecond,
+	}
+	game.Start()
+	defer game.Close()
+
+	if !assert.True(t, waitLinked(game, "rank-1", 2*time.Second)) {
+		rank.Close()
+		return
+	}
+	assert.True(t, waitLinked(rank, "game-1", time.Second), "被连接的一方也按节点名登记")
+
+	ret, err := game.Call1("rank", "Add", 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, ret)
+
+	rets, err := game.CallN("rank", "Split", "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"hello", int64(5)}, rets)
+
+	// protobuf消息作为参数和返回值
+	ret, err = game.Call1("rank", "Kick", &message.S2C_Kick{Reason: "cheat", BanExpire: 10})
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(&message.S2C_Kick{Reason: "re: cheat", BanExpire: 11}, ret.(proto.Message)))
+
+	assert.NoError(t, game.Go("rank", "Notify", "hi"))
+	select {
+	case s := <-notified:
+		assert.Equal(t, "hi", s)
+	case <-time.After(time.Second):
+		t.Error("没有收到Go调用")
+	}
+
+	// 远端的错误原样返回，注册表中没有的服务不可用
+	_, err = game.Call1("rank", "Missing")
+	assert.ErrorContains(t, err, "not registered")
+	_, err = game.Call1("match", "Add", 1, 2)
+	assert.True(t, errors.Is(err, cluster.ErrServiceUnavailable))
+
+	// 超时
+	assert.True(t, errors.Is(game.Call0("rank", "Slow"), cluster.ErrTimeout))
+	time.Sleep(300 * time.Millisecond)
+
+	// 密钥不同的节点不能握手
+	intruder := &cluster.Node{Name: "intruder", Secret: "wrong", ConnAddrs: []string{rankAddr}, ConnectInterval: 50 * time.Millisecond}
+	intruder.Start()
+	assert.False(t, waitLinked(intruder, "rank-1", 300*time.Millisecond))
+	intruder.Close()
+
+	// 握手完成前只接受小包，超过时断开；握手后可以收发大包
+	conn, err := net.Dial("tcp", rankAddr)
+	if assert.NoError(t, err) {
+		conn.Write([]byte{0, 0, 0x10, 0})
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = io.Copy(io.Discard, conn)
+		assert.NoError(t, err, "超长的包应该被断开而不是等待读超时")
+		conn.Close()
+	}
+	rets, err = game.CallN("rank", "Split", strings.Repeat("x", 64*1024))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(64*1024), rets[1])
+
+	// rank节点重启后自动重连
+	rank.Close()
+	_, err = game.Call1("rank", "Add", 1, 2)
+	assert.True(t, errors.Is(err, cluster.ErrServiceUnavailable) || errors.Is(err, cluster.ErrNodeDown))
+	rank = startRank()
+	defer rank.Close()
+	if assert.True(t, waitLinked(game, "rank-1", 2*time.Second)) {
+		ret, err = game.Call1("rank", "Add", 2, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, ret)
+	}
+}
+
+// TestClusterRegistry 本节点提供的服务本地调用，其他服务按注册表顺序选择
+func TestClusterRegistry(t *testing.T) {
+	r := cluster.NewRegistry([]conf.ClusterNode{
+		{Name: "a", Services: []string{"rank", "match"}},
+		{Name: "b", Services: []string{"match"}},
+	})
+	assert.Equal(t, []string{"a", "b"}, r.Providers("match"))
+	assert.Equal(t, []string{"a"}, r.Providers("rank"))
+	assert.Empty(t, r.Providers("chat"))
+
+	notified := make(chan string, 1)
+	n := &cluster.Node{Name: "b", Registry: r}
+	n.Register("match", newRankService(notified))
+	ret, err := n.Call1("match", "Add", 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ret)
+	_, err = n.Call1("rank", "Add", 1, 1)
+	assert.True(t, errors.Is(err, cluster.ErrServiceUnavailable))
+}
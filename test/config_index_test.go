@@ -0,0 +1,79 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gameserver/common/config"
+	genConfig "gameserver/common/config/generated"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConfigIndex_Lookup 测试生成的默认排序、分组与联合索引
+func TestConfigIndex_Lookup(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	writeConfig("recharge.json", `[
+		{"id": "c3", "name": "68元", "amount": 6800, "currency": "CNY", "is_active": true, "sort_order": 3},
+		{"id": "c1", "name": "6元", "amount": 600, "currency": "CNY", "is_active": true, "sort_order": 1},
+		{"id": "c0", "name": "下架", "amount": 100, "currency": "CNY", "is_active": false, "sort_order": 0},
+		{"id": "c2", "name": "30元", "amount": 3000, "currency": "CNY", "is_active": true, "sort_order": 2}
+	]`)
+	writeConfig("monsters.json", `[
+		{"id": "m1", "name": "史莱姆", "type": "normal", "level": 5},
+		{"id": "m2", "name": "哥布林", "type": "normal", "level": 1},
+		{"id": "m3", "name": "骷髅", "type": "normal", "level": 5},
+		{"id": "m4", "name": "巨龙", "type": "boss", "level": 50}
+	]`)
+
+	cm := config.NewConfigManager(dir)
+	assert.NoError(t, cm.LoadAllConfigs())
+	defer config.SetGlobalManager(config.SetGlobalManager(cm))
+
+	ids := func(ids ...string) []string { return ids }
+	rechargeIds := func(list []*genConfig.Recharge) []string {
+		var result []string
+		for _, item := range list {
+			result = append(result, item.Id)
+		}
+		return result
+	}
+	monsterIds := func(list []*genConfig.Monster) []string {
+		var result []string
+		for _, item := range list {
+			result = append(result, item.Id)
+		}
+		return result
+	}
+
+	assert.Equal(t, ids("c0", "c1", "c2", "c3"), rechargeIds(genConfig.ListRechargeConfigs()))
+	assert.Equal(t, ids("c1", "c2", "c3"), rechargeIds(genConfig.ListRechargeByIsActive(true)))
+	assert.Equal(t, ids("c0"), rechargeIds(genConfig.ListRechargeByIsActive(false)))
+
+	// level相同时保持文件中的顺序
+	assert.Equal(t, ids("m2", "m1", "m3"), monsterIds(genConfig.ListMonsterByType(genConfig.MonsterTypeNormal)))
+	assert.Equal(t, ids("m1", "m3"), monsterIds(genConfig.ListMonsterByTypeAndLevel(genConfig.MonsterTypeNormal, 5)))
+	assert.Empty(t, genConfig.ListMonsterByTypeAndLevel(genConfig.MonsterTypeElite, 5))
+
+	// 返回的切片是副本，修改不影响索引
+	list := genConfig.ListMonsterByType(genConfig.MonsterTypeBoss)
+	list[0] = nil
+	assert.Equal(t, ids("m4"), monsterIds(genConfig.ListMonsterByType(genConfig.MonsterTypeBoss)))
+}
+
+// TestConfigIndex_Unique 测试唯一索引重复时加载报错
+func TestConfigIndex_Unique(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "skills.json"), []byte(`[
+		{"id": "1001", "name": "火球术", "type": "magic"},
+		{"id": "1002", "name": "火球术", "type": "magic"}
+	]`), 0644))
+
+	cm := config.NewConfigManager(dir)
+	assert.EqualError(t, cm.LoadConfig("skills.json"),
+		"配置校验失败，共1处错误:\n  skills.json 第2行(id=1002) 字段name: 唯一索引name重复，与id=1001相同")
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gameserver/common/config"
+	_ "gameserver/common/config/generated"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConfigReload_Atomic 测试整体重载、校验失败不切换、订阅通知与回滚
+func TestConfigReload_Atomic(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	writeConfig("items.json", `[{"id": "2001", "name": "生命药水", "type": "consumable"}]`)
+	writeConfig("monsters.json", `[{"id": "1001", "name": "史莱姆", "type": "normal", "drops": ["2001"]}]`)
+
+	cm := config.NewConfigManager(dir)
+	var changed [][]string
+	cm.Subscribe(func(old, new *config.Snapshot) {
+		changed = append(changed, new.ChangedFiles(old))
+	})
+
+	assert.NoError(t, cm.LoadAllConfigs())
+	v1 := cm.Current()
+	assert.Equal(t, uint64(1), v1.Version)
+	assert.Equal(t, [][]string{{"items.json", "monsters.json"}}, changed)
+
+	// items.json合法但monsters.json引用了不存在的物品，两个文件都不生效
+	writeConfig("items.json", `[{"id": "2002", "name": "魔法药水", "type": "consumable"}]`)
+	writeConfig("monsters.json", `[{"id": "1001", "name": "史莱姆", "type": "normal", "drops": ["2001"]}]`)
+	err := cm.ReloadAll()
+	assert.Contains(t, err.Error(), "monsters.json 第1行(id=1001) 字段drops[0]: 引用的items.json中不存在id 2001")
+	assert.Same(t, v1, cm.Current())
+	_, ok := cm.GetConfig("items.json", "2001")
+	assert.True(t, ok)
+	assert.Len(t, changed, 1)
+
+	// 修复后整体切换
+	writeConfig("monsters.json", `[{"id": "1001", "name": "史莱姆", "type": "normal", "drops": ["2002"]}]`)
+	assert.NoError(t, cm.ReloadAll())
+	assert.Equal(t, uint64(2), cm.Version())
+	assert.Equal(t, []string{"items.json", "monsters.json"}, changed[1])
+	_, ok = cm.GetConfig("items.json", "2001")
+	assert.False(t, ok)
+	assert.Equal(t, []uint64{1}, cm.History())
+
+	// 旧版本在切换后仍然一致可读
+	_, ok = v1.GetConfig("items.json", "2001")
+	assert.True(t, ok)
+
+	// 回滚
+	assert.NoError(t, cm.Rollback())
+	assert.Same(t, v1, cm.Current())
+	assert.Len(t, changed, 3)
+	assert.Error(t, cm.Rollback())
+}
+
+// TestConfigReload_Watch 测试文件变化后自动重载
+func TestConfigReload_Watch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "match.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`[{"id": 1, "name": "经典模式", "room_size": 2}]`), 0644))
+
+	cm := config.NewConfigManager(dir)
+	assert.NoError(t, cm.LoadAllConfigs())
+
+	reloaded := make(chan *config.Snapshot, 1)
+	cm.Subscribe(func(old, new *config.Snapshot) {
+		if new.Changed(old, "match.json") {
+			reloaded <- new
+		}
+	})
+	stop := cm.Watch(20 * time.Millisecond)
+	defer stop()
+
+	assert.NoError(t, os.WriteFile(file, []byte(`[{"id": 1, "name": "经典模式", "room_size": 2}, {"id": 4, "name": "新模式", "room_size": 6}]`), 0644))
+	select {
+	case snap := <-reloaded:
+		assert.Equal(t, uint64(2), snap.Version)
+		_, ok := snap.GetConfig("match.json", "4")
+		assert.True(t, ok)
+	case <-time.After(2 * time.Second):
+		t.Fatal("配置变化后未自动重载")
+	}
+}
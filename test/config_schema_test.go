@@ -0,0 +1,67 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gameserver/common/config"
+	_ "gameserver/common/config/generated"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 仓库中的配置必须通过schema校验
+func TestConfigSchema_RepoConfigs(t *testing.T) {
+	cm := config.NewConfigManager("../conf/config")
+	assert.NoError(t, cm.LoadAllConfigs())
+
+	_, ok := cm.GetTable("monsters.json")
+	assert.True(t, ok)
+}
+
+// TestConfigSchema_Errors 测试字段类型、必填、枚举、范围、重复id与外键的错误定位
+func TestConfigSchema_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	writeConfig("items.json", `[{"id": "2001", "name": "生命药水", "type": "consumable"}]`)
+	writeConfig("monsters.json", `[
+		{"id": "1001", "name": "史莱姆", "type": "normal", "level": 1, "hp": 50, "drops": ["2001"]},
+		{"id": "1002", "type": "mini", "level": 0, "hp": "abc", "drops": ["2001", "9999"]},
+		{"id": "1001", "name": "重复", "type": "boss"}
+	]`)
+
+	cm := config.NewConfigManager(dir)
+	assert.NoError(t, cm.LoadConfig("items.json"))
+
+	err := cm.LoadConfig("monsters.json")
+	verr, ok := err.(*config.ValidationError)
+	if !assert.True(t, ok, "expect ValidationError, got %v", err) {
+		return
+	}
+	var msgs []string
+	for _, e := range verr.Errors {
+		msgs = append(msgs, e.Error())
+	}
+	assert.Equal(t, []string{
+		"monsters.json 第3行(id=1001) 字段id: id与第1行重复",
+		"monsters.json 第2行(id=1002) 字段name: 必填字段缺失",
+		`monsters.json 第2行(id=1002) 字段type: 枚举值"mini"不合法，可选值[normal elite boss]`,
+		"monsters.json 第2行(id=1002) 字段level: 值0小于下限1",
+		"monsters.json 第2行(id=1002) 字段hp: 应为数值，实际为abc",
+		"monsters.json 第2行(id=1002) 字段drops[1]: 引用的items.json中不存在id 9999",
+	}, msgs)
+
+	// 校验失败不替换已加载的数据
+	_, ok = cm.GetTable("monsters.json")
+	assert.False(t, ok)
+
+	// 被引用的表重新加载时，反向校验其他表对它的引用
+	writeConfig("monsters.json", `[{"id": "1001", "name": "史莱姆", "type": "normal", "drops": ["2001"]}]`)
+	assert.NoError(t, cm.LoadConfig("monsters.json"))
+	writeConfig("items.json", `[{"id": "2002", "name": "魔法药水", "type": "consumable"}]`)
+	err = cm.LoadConfig("items.json")
+	assert.EqualError(t, err, "配置校验失败，共1处错误:\n  monsters.json 第1行(id=1001) 字段drops[0]: 引用的items.json中不存在id 2001")
+}
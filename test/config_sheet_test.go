@@ -0,0 +1,139 @@
+package test
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gameserver/tools/config_generator/sheet"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeXLSX 生成只包含一个工作表的最小xlsx文件
+func writeXLSX(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+}
+
+// TestConfigSheet_XLSX 测试读取xlsx：共享字符串、富文本、内联字符串、跳过的空单元格
+func TestConfigSheet_XLSX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monsters.xlsx")
+	writeXLSX(t, path, map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="怪物" sheetId="1" r:id="rId1"/><sheet name="说明" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId2" Target="worksheets/sheet2.xml"/><Relationship Id="rId1" Target="/xl/worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>id</t></si><si><t>name</t></si><si><t>drops</t></si><si><t>string</t></si><si><t>list&lt;string&gt;</t></si>
+<si><r><t>史</t></r><r><t>莱姆</t></r></si><si><t>2001|2002</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="s"><v>2</v></c></row>
+<row r="2"><c r="A2" t="s"><v>3</v></c><c r="B2" t="s"><v>3</v></c><c r="D2" t="s"><v>4</v></c></row>
+<row r="4"><c r="B4" t="inlineStr"><is><t>client</t></is></c></row>
+<row r="5"><c r="A5"><v>1001</v></c><c r="B5" t="s"><v>5</v></c><c r="D5" t="s"><v>6</v></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet/>`,
+	})
+
+	sh, err := sheet.Read(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "monsters.xlsx", sh.Name)
+	assert.Len(t, sh.Columns, 3)
+	assert.Equal(t, sheet.TargetClient, sh.Columns[1].Target)
+
+	server, err := sh.Export(sheet.TargetServer)
+	assert.NoError(t, err)
+	data, _ := json.Marshal(server)
+	assert.Equal(t, `[{"id":"1001","drops":["2001","2002"]}]`, string(data))
+
+	client, err := sh.Export(sheet.TargetClient)
+	assert.NoError(t, err)
+	data, _ = json.Marshal(client)
+	assert.Equal(t, `[{"id":"1001","name":"史莱姆","drops":["2001","2002"]}]`, string(data))
+}
+
+// TestConfigSheet_CSV 测试csv的类型转换、注释行与注释列
+func TestConfigSheet_CSV(t *testing.T) {
+	cells, err := sheet.ReadCSV(strings.NewReader("\xef\xbb\xbf"+`id,level,rate,vip,reward,#备注
+int,int,float,bool,struct,
+编号,等级,概率,VIP专属,奖励,
+server,both,,both,server,
+1,10,0.5,是,"{""gold"": 100}",测试
+#2,20,0.1,否,,已废弃
+
+3,1E3,1,0,,
+`), ',')
+	assert.NoError(t, err)
+
+	sh, err := sheet.Parse("rewards.csv", cells)
+	if !assert.NoError(t, err) {
+		return
+	}
+	rows, err := sh.Export(sheet.TargetServer)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, map[string]interface{}{
+		"id": int64(1), "level": int64(10), "rate": 0.5, "vip": true,
+		"reward": map[string]interface{}{"gold": float64(100)},
+	}, rows[0].Map())
+	assert.Equal(t, map[string]interface{}{
+		"id": int64(3), "level": int64(1000), "rate": float64(1), "vip": false,
+	}, rows[1].Map())
+
+	data, err := sheet.MarshalRows(rows)
+	assert.NoError(t, err)
+	assert.Equal(t, "[\n  {\"id\":1,\"level\":10,\"rate\":0.5,\"vip\":true,\"reward\":{\"gold\":100}},\n  {\"id\":3,\"level\":1000,\"rate\":1,\"vip\":false}\n]\n", string(data))
+}
+
+// TestConfigSheet_Errors 测试表头与单元格错误
+func TestConfigSheet_Errors(t *testing.T) {
+	_, err := sheet.Parse("bad.csv", [][]string{
+		{"id", "name", "name", "hp", "tags"},
+		{"string", "string", "string", "", "list<list<int>>"},
+		{},
+		{"both", "all"},
+	})
+	assert.EqualError(t, err, strings.Join([]string{
+		"表格解析失败，共4处错误:",
+		"  bad.csv 第4行 字段name: 导出目标all不合法，可选值[server client both]",
+		"  bad.csv 第1行 字段name: 字段名重复",
+		"  bad.csv 第2行 字段hp: 缺少字段类型",
+		"  bad.csv 第2行 字段tags: 类型list<list<int>>不支持",
+	}, "\n"))
+
+	sh, err := sheet.Parse("bad.csv", [][]string{
+		{"id", "hp", "drops"},
+		{"string", "int", "list<int>"},
+		{},
+		{},
+		{"", "10", "1"},
+		{"1002", "abc", "1|x"},
+	})
+	assert.NoError(t, err)
+	_, err = sh.Export(sheet.TargetServer)
+	assert.EqualError(t, err, strings.Join([]string{
+		"表格解析失败，共3处错误:",
+		"  bad.csv 第5行 字段id: id不能为空",
+		"  bad.csv 第6行 字段hp: 应为整数，实际为abc",
+		"  bad.csv 第6行 字段drops: 第2个元素应为整数，实际为x",
+	}, "\n"))
+}
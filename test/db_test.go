@@ -8,22 +8,28 @@ import (
 	"gameserver/common/msg/message"
 	"gameserver/core/log"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// 普通测试
+// 普通测试，需要本地的Redis和MongoDB，连接不上时跳过
 func TestDB_TestConnect(t *testing.T) {
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
 
 	// 连接本地 Redis
 	rdb := redis.NewClient(&redis.Options{
 		Addr: "localhost:6379",
 	})
 	defer rdb.Close()
+	if err := rdb.Ping().Err(); err != nil {
+		t.Skipf("redis不可用: %v", err)
+	}
 
 	err := rdb.Set("key", "value", 0).Err()
 	if err != nil {
@@ -42,6 +48,9 @@ func TestDB_TestConnect(t *testing.T) {
 	}
 
 	defer mongoClient.Disconnect(ctx)
+	if err := mongoClient.Ping(ctx, nil); err != nil {
+		t.Skipf("mongodb不可用: %v", err)
+	}
 
 	collection := mongoClient.Database("testdb").Collection("testcol")
 	doc := map[string]string{"hello": "world"}
@@ -63,26 +72,26 @@ func (u User) GetPersistId() interface{} {
 }
 
 func TestDB_TestMongo(t *testing.T) {
-	mongodb.Init("mongodb://localhost:27017", "testdb", 10, 100)
+	mongodb.UseMemory()
 	// 查询单个
 	mongodb.Save(&User{ID: "1", Name: "张三", Age: 20})
 	user, _ := mongodb.FindOneById[User]("1")
-	fmt.Println("mongo FindOne id:", user)
+	assert.Equal(t, &User{ID: "1", Name: "张三", Age: 20}, user)
 	mongodb.Save(&User{ID: "2", Name: "李四", Age: 20})
 	users, _ := mongodb.FindAll[User](bson.M{})
-	fmt.Println("mongo InsertOne id:", users)
+	assert.Len(t, users, 2)
 	// 删除
 	mongodb.DeleteByID[User]("2")
 	users, _ = mongodb.FindAll[User](bson.M{})
-	fmt.Println("mongo DeleteByID id:", users)
+	assert.Equal(t, []User{{ID: "1", Name: "张三", Age: 20}}, users)
 	mongodb.Save(&User{ID: "1", Name: "张三123", Age: 21})
 	user, _ = mongodb.FindOneById[User]("1")
-	fmt.Println("mongo UpsertByID id:", user)
+	assert.Equal(t, &User{ID: "1", Name: "张三123", Age: 21}, user)
 }
 
 // TestBulkSave 测试批量保存功能
 func TestBulkSave(t *testing.T) {
-	mongodb.Init("mongodb://localhost:27017", "testMongo", 50, 50)
+	mongodb.UseMemory()
 	// 初始化测试数据
 	users := []mongodb.PersistData{
 		&models.User{AccountId: "test1", ServerId: 2, OpenId: "open1", PlayerId: 1001, Platform: message.LoginType_DouYin},
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"encoding/binary"
+	"gameserver/common/msg"
+	"gameserver/common/msg/message"
+	"gameserver/core/gate"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestGate_Drain 测试停服排空：收到关闭信号后先下发S2C_ServerClosing，客户端断开后gate立即退出
+func TestGate_Drain(t *testing.T) {
+	addr := "127.0.0.1:39563"
+	g := &gate.Gate{
+		MaxConnNum:      10,
+		PendingWriteNum: 10,
+		MaxMsgLen:       4096,
+		TCPAddr:         addr,
+		LenMsgLen:       4,
+		Processor:       msg.Processor,
+		DrainTimeout:    5 * time.Second,
+		ClosingMsg: &message.S2C_ServerClosing{
+			Reason:           "test",
+			ReconnectDelayMs: 1000,
+		},
+	}
+
+	closeSig := make(chan bool, 1)
+	done := make(chan struct{})
+	go func() {
+		g.Run(closeSig)
+		close(done)
+	}()
+
+	var conn net.Conn
+	var err error
+	for i := 0; i < 20; i++ {
+		conn, err = net.Dial("tcp", addr)
+		if err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !assert.NoError(t, err, "连接gate失败") {
+		return
+	}
+	for i := 0; i < 20 && g.AgentNum() == 0; i++ {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	closeSig <- true
+
+	// 读取关闭通知: len + id + data
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	head := make([]byte, 4)
+	_, err = io.ReadFull(conn, head)
+	if !assert.NoError(t, err, "未收到关闭通知") {
+		return
+	}
+	body := make([]byte, binary.BigEndian.Uint32(head))
+	_, err = io.ReadFull(conn, body)
+	assert.NoError(t, err)
+	assert.Equal(t, getId(&message.S2C_ServerClosing{}), binary.BigEndian.Uint32(body))
+
+	closing := &message.S2C_ServerClosing{}
+	assert.NoError(t, proto.Unmarshal(body[4:], closing))
+	assert.Equal(t, int32(1000), closing.ReconnectDelayMs)
+
+	// 排空期间不再接受新连接
+	_, err = net.DialTimeout("tcp", addr, 500*time.Millisecond)
+	assert.Error(t, err, "排空期间不应接受新连接")
+
+	// 客户端主动断开后gate应在超时前退出
+	start := time.Now()
+	conn.Close()
+	select {
+	case <-done:
+		assert.Less(t, time.Since(start), 3*time.Second)
+	case <-time.After(5 * time.Second):
+		t.Fatal("gate未在客户端断开后退出")
+	}
+}
@@ -0,0 +1,201 @@
+package test
+
+import (
+	"encoding/binary"
+	"gameserver/common/msg"
+	"gameserver/common/msg/message"
+	"gameserver/core/chanrpc"
+	"gameserver/core/gate"
+	"gameserver/core/timer"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestTimerWheel 时间轮的到期、移动、删除和超过一圈的超时
+func TestTimerWheel(t *testing.T) {
+	w := timer.NewWheel[string](time.Second, 4)
+	w.Add("a", time.Second)
+	w.Add("b", 2*time.Second)
+	w.Add("c", 1500*time.Millisecond) // 向上取整到第2格
+	w.Add("long", time.Hour)          // 超过一圈放在最远的槽
+	w.Add("removed", time.Second)
+	assert.True(t, w.Remove("removed"))
+	assert.False(t, w.Remove("removed"))
+	assert.Equal(t, 4, w.Len())
+
+	assert.Equal(t, []string{"a"}, w.Advance())
+	expired := w.Advance()
+	sort.Strings(expired)
+	assert.Equal(t, []string{"b", "c"}, expired)
+
+	// 重新添加时移动到新的位置
+	w.Add("long", 2*time.Second)
+	assert.Empty(t, w.Advance())
+	assert.Equal(t, []string{"long"}, w.Advance())
+	assert.Equal(t, 0, w.Len())
+}
+
+// TestGate_Idle 超过空闲超时没有收到消息的连接被断开，心跳保持连接，单个连接可以调整超时
+func TestGate_Idle(t *testing.T) {
+	addr := "127.0.0.1:39564"
+	agents := make(chan gate.Agent, 10)
+	rpc := chanrpc.NewServer(10)
+	rpc.Register("NewAgent", func(args []interface{}) { agents <- args[0].(gate.Agent) })
+	rpc.Register("CloseAgent", func(args []interface{}) {})
+	go func() {
+		for ci := range rpc.ChanCall {
+			rpc.Exec(ci)
+		}
+	}()
+
+	g := &gate.Gate{
+		MaxConnNum:      10,
+		PendingWriteNum: 10,
+		MaxMsgLen:       4096,
+		TCPAddr:         addr,
+		LenMsgLen:       4,
+		Processor:       msg.Processor,
+		AgentChanRPC:    rpc,
+		IdleTimeout:     400 * time.Millisecond,
+	}
+	closeSig := make(chan bool, 1)
+	done := make(chan struct{})
+	go func() {
+		g.Run(closeSig)
+		close(done)
+	}()
+	defer func() {
+		closeSig <- true
+		<-done
+	}()
+
+	dial := func() (net.Conn, gate.Agent) {
+		var conn net.Conn
+		var err error
+		for i := 0; i < 20; i++ {
+			if conn, err = net.Dial("tcp", addr); err == nil {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		select {
+		case a := <-agents:
+			return conn, a
+		case <-time.After(time.Second):
+			t.Fatal("没有收到NewAgent")
+		}
+		return nil, nil
+	}
+	closed := func(conn net.Conn, within time.Duration) bool {
+		conn.SetReadDeadline(time.Now().Add(within))
+		_, err := conn.Read(make([]byte, 1))
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return false
+		}
+		return err != nil
+	}
+	heart := func(conn net.Conn) {
+		body, _ := proto.Marshal(&message.C2S_Heart{})
+		data := make([]byte, 8+len(body))
+		binary.BigEndian.PutUint32(data, uint32(4+len(body)))
+		binary.BigEndian.PutUint32(data[4:], getId(&message.C2S_Heart{}))
+		copy(data[8:], body)
+		conn.Write(data)
+	}
+
+	idle, _ := dial()
+	alive, _ := dial()
+	long, longAgent := dial()
+	longAgent.SetIdleTimeout(time.Hour)
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				heart(alive)
+			}
+		}
+	}()
+
+	start := time.Now()
+	assert.True(t, closed(idle, time.Second), "空闲连接应被断开")
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+	assert.False(t, closed(long, 300*time.Millisecond), "调长超时的连接不应被断开")
+	assert.False(t, closed(alive, 10*time.Millisecond), "有心跳的连接不应被断开")
+
+	// 调短超时立即按新的超时计算
+	longAgent.SetIdleTimeout(100 * time.Millisecond)
+	assert.True(t, closed(long, 500*time.Millisecond))
+
+	close(stop)
+	assert.True(t, closed(alive, time.Second), "停止心跳后应被断开")
+	alive.Close()
+}
+
+// TestGate_RTT RTT按1/8平滑
+func TestGate_RTT(t *testing.T) {
+	addr := "127.0.0.1:39565"
+	agents := make(chan gate.Agent, 1)
+	rpc := chanrpc.NewServer(10)
+	rpc.Register("NewAgent", func(args []interface{}) { agents <- args[0].(gate.Agent) })
+	rpc.Register("CloseAgent", func(args []interface{}) {})
+	go func() {
+		for ci := range rpc.ChanCall {
+			rpc.Exec(ci)
+		}
+	}()
+
+	g := &gate.Gate{
+		MaxConnNum:      10,
+		PendingWriteNum: 10,
+		MaxMsgLen:       4096,
+		TCPAddr:         addr,
+		LenMsgLen:       4,
+		Processor:       msg.Processor,
+		AgentChanRPC:    rpc,
+	}
+	closeSig := make(chan bool, 1)
+	done := make(chan struct{})
+	go func() {
+		g.Run(closeSig)
+		close(done)
+	}()
+	defer func() {
+		closeSig <- true
+		<-done
+	}()
+
+	var conn net.Conn
+	var err error
+	for i := 0; i < 20; i++ {
+		if conn, err = net.Dial("tcp", addr); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	a := <-agents
+
+	assert.Equal(t, time.Duration(0), a.RTT())
+	a.ObserveRTT(80 * time.Millisecond)
+	assert.Equal(t, 80*time.Millisecond, a.RTT())
+	a.ObserveRTT(160 * time.Millisecond)
+	assert.Equal(t, 90*time.Millisecond, a.RTT())
+	a.ObserveRTT(-time.Millisecond)
+	assert.Equal(t, 90*time.Millisecond, a.RTT())
+}
@@ -0,0 +1,279 @@
+package test
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"gameserver/common/gateway"
+	"gameserver/common/msg/message"
+	"gameserver/core/chanrpc"
+	"gameserver/core/cluster"
+	"gameserver/core/conf"
+	"gameserver/core/gate"
+	"gameserver/core/network/protobuf"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestGateway 网关转发客户端消息到后端，登录前只放行匿名消息，后端的推送和断开经网关到达客户端
+func TestGateway(t *testing.T) {
+	backendAddr, clientAddr := "127.0.0.1:39591", "127.0.0.1:39592"
+	nodes := []conf.ClusterNode{
+		{Name: "gw-1"},
+		{Name: "game-1", Addr: backendAddr, Services: []string{"login", "game", "match", "rank"}},
+	}
+
+	// 后端：登录后设置UserData，查询玩家信息时回复
+	processor := protobuf.NewProcessor()
+	processor.Register(&message.C2S_Login{})
+	processor.Register(&message.C2S_GetPlayerInfo{})
+	agents := make(chan gate.Agent, 1)
+	closed := make(chan gate.Agent, 1)
+	processor.SetHandler(&message.C2S_Login{}, func(args []interface{}) {
+		a := args[1].(gate.Agent)
+		a.SetUserData(args[0].(*message.C2S_Login).DeviceId)
+		a.WriteMsg(&message.S2C_Login{LoginResult: 1})
+	})
+	processor.SetHandler(&message.C2S_GetPlayerInfo{}, func(args []interface{}) {
+		a := args[1].(gate.Agent)
+		a.WriteMsg(&message.S2C_GetPlayerInfo{PlayerInfo: &message.PlayerInfo{PlayerName: a.UserData().(string)}})
+	})
+	agentRPC := chanrpc.NewServer(10)
+	agentRPC.Register("NewAgent", func(args []interface{}) { agents <- args[0].(gate.Agent) })
+	agentRPC.Register("CloseAgent", func(args []interface{}) { closed <- args[0].(gate.Agent) })
+	go func() {
+		for ci := range agentRPC.ChanCall {
+			agentRPC.Exec(ci)
+		}
+	}()
+	backendNode := &cluster.Node{Name: "game-1", ListenAddr: backendAddr, Registry: cluster.NewRegistry(nodes)}
+	backend := gateway.Serve(backendNode, processor, agentRPC)
+	backendNode.Start()
+	defer backendNode.Close()
+
+	// 网关
+	gwNode := &cluster.Node{Name: "gw-1", Registry: cluster.NewRegistry(nodes), ConnectInterval: 50 * time.Millisecond}
+	gw := gateway.New(gwNode, processor, false, func(id uint32) bool {
+		return id == protobuf.GetId(&message.C2S_Login{})
+	})
+	gwNode.Start()
+	defer gwNode.Close()
+	module, ok := gw.Route(protobuf.GetId(&message.C2S_GetPlayerInfo{}))
+	assert.True(t, ok)
+	assert.Equal(t, "game", module)
+	if !assert.True(t, waitLinked(gwNode, "game-1", 2*time.Second)) {
+		return
+	}
+
+	conn, stop := startGatewayClient(t, gw, processor, clientAddr)
+	if conn == nil {
+		return
+	}
+	defer stop()
+	send := func(m proto.Message) { gatewaySend(conn, m) }
+	recv := func(m proto.Message) bool { return gatewayRecv(t, conn, m) }
+
+	// 未登录时需要登录的消息在网关丢弃，不会到达后端
+	send(&message.C2S_GetPlayerInfo{})
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, backend.SessionNum())
+
+	// 登录后后端创建代理连接，回复经网关推回
+	send(&message.C2S_Login{DeviceId: "alice"})
+	var proxy gate.Agent
+	select {
+	case proxy = <-agents:
+		assert.Equal(t, conn.LocalAddr().String(), proxy.RemoteAddr().String())
+	case <-time.After(time.Second):
+		t.Fatal("后端没有收到NewAgent")
+	}
+	login := &message.S2C_Login{}
+	if assert.True(t, recv(login)) {
+		assert.Equal(t, int32(1), login.LoginResult)
+	}
+
+	send(&message.C2S_GetPlayerInfo{})
+	info := &message.S2C_GetPlayerInfo{}
+	if assert.True(t, recv(info)) {
+		assert.Equal(t, "alice", info.PlayerInfo.PlayerName)
+	}
+
+	// 后端关闭代理连接时网关断开客户端，随后关闭后端会话
+	proxy.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	select {
+	case a := <-closed:
+		assert.Equal(t, proxy, a)
+	case <-time.After(time.Second):
+		t.Error("后端没有收到CloseAgent")
+	}
+	assert.Equal(t, 0, backend.SessionNum())
+	assert.Equal(t, 0, gw.SessionNum())
+}
+
+// startGatewayClient 启动网关的gate并连接，返回客户端连接和关闭函数
+func startGatewayClient(t *testing.T, gw *gateway.Gateway, processor *protobuf.Processor, addr string) (net.Conn, func()) {
+	g := &gate.Gate{
+		MaxConnNum:      10,
+		PendingWriteNum: 10,
+		MaxMsgLen:       4096,
+		TCPAddr:         addr,
+		LenMsgLen:       4,
+		Processor:       processor,
+		AgentChanRPC:    gw.AgentChanRPC,
+		Forward:         gw.Forward,
+	}
+	closeSig := make(chan bool, 1)
+	done := make(chan struct{})
+	go func() {
+		g.Run(closeSig)
+		close(done)
+	}()
+	stopGate := func() {
+		closeSig <- true
+		<-done
+	}
+
+	var conn net.Conn
+	var err error
+	for i := 0; i < 20; i++ {
+		if conn, err = net.Dial("tcp", addr); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !assert.NoError(t, err) {
+		stopGate()
+		return nil, nil
+	}
+	return conn, func() {
+		conn.Close()
+		stopGate()
+	}
+}
+
+func gatewaySend(conn net.Conn, m proto.Message) {
+	body, _ := proto.Marshal(m)
+	data := make([]byte, 8+len(body))
+	binary.BigEndian.PutUint32(data, uint32(4+len(body)))
+	binary.BigEndian.PutUint32(data[4:], protobuf.GetId(m))
+	copy(data[8:], body)
+	conn.Write(data)
+}
+
+func gatewayRecv(t *testing.T, conn net.Conn, m proto.Message) bool {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return false
+	}
+	data := make([]byte, binary.BigEndian.Uint32(head))
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return false
+	}
+	return assert.Equal(t, protobuf.GetId(m), binary.BigEndian.Uint32(data)) &&
+		assert.NoError(t, proto.Unmarshal(data[4:], m))
+}
+
+// startAgentRPC 后端的NewAgent通知写入agents
+func startAgentRPC(agents chan gate.Agent) *chanrpc.Server {
+	s := chanrpc.NewServer(10)
+	s.Register("NewAgent", func(args []interface{}) { agents <- args[0].(gate.Agent) })
+	s.Register("CloseAgent", func(args []interface{}) {})
+	go func() {
+		for ci := range s.ChanCall {
+			s.Exec(ci)
+		}
+	}()
+	return s
+}
+
+// TestGatewayMultiBackend 模块分布在两个后端，登录身份同步到另一个后端
+func TestGatewayMultiBackend(t *testing.T) {
+	gameAddr, matchAddr, clientAddr := "127.0.0.1:39593", "127.0.0.1:39594", "127.0.0.1:39595"
+	nodes := []conf.ClusterNode{
+		{Name: "gw-2"},
+		{Name: "game-2", Addr: gameAddr, Services: []string{"login", "game"}},
+		{Name: "match-2", Addr: matchAddr, Services: []string{"match"}},
+	}
+
+	// game-2处理登录
+	gameProcessor := protobuf.NewProcessor()
+	gameProcessor.Register(&message.C2S_Login{})
+	gameAgents := make(chan gate.Agent, 1)
+	gameProcessor.SetHandler(&message.C2S_Login{}, func(args []interface{}) {
+		a := args[1].(gate.Agent)
+		a.SetUserData(args[0].(*message.C2S_Login).DeviceId)
+		a.WriteMsg(&message.S2C_Login{LoginResult: 1})
+	})
+	gameNode := &cluster.Node{Name: "game-2", ListenAddr: gameAddr, Registry: cluster.NewRegistry(nodes)}
+	gateway.Serve(gameNode, gameProcessor, startAgentRPC(gameAgents))
+	gameNode.Start()
+	defer gameNode.Close()
+
+	// match-2上的消息需要登录，回复看到的身份
+	matchProcessor := protobuf.NewProcessor()
+	matchProcessor.Register(&message.C2S_StartMatch{})
+	matchProcessor.Use(func(id uint32, next protobuf.MsgHandler) protobuf.MsgHandler {
+		return func(args []interface{}) {
+			if args[1].(gate.Agent).UserData() != nil {
+				next(args)
+			}
+		}
+	})
+	matchAgents := make(chan gate.Agent, 1)
+	matchProcessor.SetHandler(&message.C2S_StartMatch{}, func(args []interface{}) {
+		a := args[1].(gate.Agent)
+		a.WriteMsg(&message.S2C_StartMatch{Result: a.UserData() == "alice"})
+	})
+	matchNode := &cluster.Node{Name: "match-2", ListenAddr: matchAddr, Registry: cluster.NewRegistry(nodes)}
+	gateway.Serve(matchNode, matchProcessor, startAgentRPC(matchAgents))
+	matchNode.Start()
+	defer matchNode.Close()
+
+	processor := protobuf.NewProcessor()
+	processor.Register(&message.C2S_Login{})
+	processor.Register(&message.C2S_StartMatch{})
+	gwNode := &cluster.Node{Name: "gw-2", Registry: cluster.NewRegistry(nodes), ConnectInterval: 50 * time.Millisecond}
+	gw := gateway.New(gwNode, processor, false, func(id uint32) bool {
+		return id == protobuf.GetId(&message.C2S_Login{})
+	})
+	gwNode.Start()
+	defer gwNode.Close()
+	if !assert.True(t, waitLinked(gwNode, "game-2", 2*time.Second)) ||
+		!assert.True(t, waitLinked(gwNode, "match-2", 2*time.Second)) {
+		return
+	}
+
+	conn, stop := startGatewayClient(t, gw, processor, clientAddr)
+	if conn == nil {
+		return
+	}
+	defer stop()
+
+	// 在game-2登录后，match-2上新建的会话带有登录身份
+	gatewaySend(conn, &message.C2S_Login{DeviceId: "alice"})
+	if !assert.True(t, gatewayRecv(t, conn, &message.S2C_Login{})) {
+		return
+	}
+	gatewaySend(conn, &message.C2S_StartMatch{})
+	reply := &message.S2C_StartMatch{}
+	if assert.True(t, gatewayRecv(t, conn, reply)) {
+		assert.True(t, reply.Result)
+	}
+
+	// game-2上登出后match-2上的会话同步登出
+	gameProxy, matchProxy := <-gameAgents, <-matchAgents
+	gameProxy.SetUserData(nil)
+	deadline := time.Now().Add(time.Second)
+	for matchProxy.UserData() != nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, matchProxy.UserData())
+}
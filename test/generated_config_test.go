@@ -257,8 +257,8 @@ func testSkillConfig(t *testing.T, cm *config.ConfigManager, testDir string) {
 		t.Errorf("期望伤害为50，实际为%v", skill.Damage)
 	}
 
-	if skill.Mana != 30 {
-		t.Errorf("期望魔法消耗为30，实际为%v", skill.Mana)
+	if skill.ManaCost != 30 {
+		t.Errorf("期望魔法消耗为30，实际为%v", skill.ManaCost)
 	}
 
 	if skill.Cooldown != 3.0 {
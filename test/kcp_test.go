@@ -0,0 +1,164 @@
+package test
+
+import (
+	"bytes"
+	"gameserver/core/network"
+	"gameserver/core/network/kcp"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type kcpEchoAgent struct {
+	conn *network.KCPConn
+}
+
+func (a *kcpEchoAgent) Run() {
+	for {
+		data, err := a.conn.ReadMsg()
+		if err != nil {
+			return
+		}
+		a.conn.WriteMsg(data)
+	}
+}
+
+func (a *kcpEchoAgent) OnClose() {}
+
+type kcpClientAgent struct {
+	conn     *network.KCPConn
+	received chan []byte
+}
+
+func (a *kcpClientAgent) Run() {
+	for {
+		data, err := a.conn.ReadMsg()
+		if err != nil {
+			return
+		}
+		a.received <- data
+	}
+}
+
+func (a *kcpClientAgent) OnClose() {}
+
+// TestKCP_Echo 测试kcp服务端与客户端收发，包含需要分片的大消息
+func TestKCP_Echo(t *testing.T) {
+	setting := network.KCPSetting{NoDelay: true, Interval: 10, Resend: 2, NoCongestion: true}
+
+	server := &network.KCPServer{
+		Addr:            "127.0.0.1:39663",
+		MaxConnNum:      10,
+		PendingWriteNum: 100,
+		MaxMsgLen:       8192,
+		KCPSetting:      setting,
+		NewAgent: func(conn *network.KCPConn) network.Agent {
+			return &kcpEchoAgent{conn: conn}
+		},
+	}
+	server.Start()
+	defer server.Close()
+
+	clientAgent := &kcpClientAgent{received: make(chan []byte, 10)}
+	connected := make(chan struct{})
+	client := &network.KCPClient{
+		Addr:            "127.0.0.1:39663",
+		PendingWriteNum: 100,
+		MaxMsgLen:       8192,
+		KCPSetting:      setting,
+		NewAgent: func(conn *network.KCPConn) network.Agent {
+			clientAgent.conn = conn
+			close(connected)
+			return clientAgent
+		},
+	}
+	client.Start()
+	defer client.Close()
+
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("客户端未建立连接")
+	}
+
+	large := make([]byte, 5000)
+	rand.Read(large)
+	msgs := [][]byte{[]byte("hello"), large, []byte("world")}
+	for _, m := range msgs {
+		assert.NoError(t, clientAgent.conn.WriteMsg(m))
+	}
+
+	for _, m := range msgs {
+		select {
+		case data := <-clientAgent.received:
+			assert.True(t, bytes.Equal(m, data), "回显消息不一致")
+		case <-time.After(3 * time.Second):
+			t.Fatal("未收到回显消息")
+		}
+	}
+	assert.Equal(t, 1, server.ConnNum())
+}
+
+// TestKCP_Lossy 模拟30%丢包与乱序，验证消息完整且有序到达
+func TestKCP_Lossy(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var a, b *kcp.KCP
+	var toA, toB [][]byte
+	deliver := func(queue *[][]byte) func([]byte) {
+		return func(buf []byte) {
+			if r.Intn(100) < 30 {
+				return
+			}
+			pkt := append([]byte(nil), buf...)
+			// 随机插入到队列中模拟乱序
+			i := r.Intn(len(*queue) + 1)
+			*queue = append(*queue, nil)
+			copy((*queue)[i+1:], (*queue)[i:])
+			(*queue)[i] = pkt
+		}
+	}
+	a = kcp.NewKCP(1, deliver(&toB))
+	b = kcp.NewKCP(1, deliver(&toA))
+	a.NoDelay(1, 10, 2, 1)
+	b.NoDelay(1, 10, 2, 1)
+
+	const count = 200
+	for i := 0; i < count; i++ {
+		msg := bytes.Repeat([]byte{byte(i)}, 1+i*10)
+		assert.Equal(t, 0, a.Send(msg))
+	}
+
+	var received [][]byte
+	buf := make([]byte, 4096)
+	deadline := time.Now().Add(10 * time.Second)
+	for len(received) < count && time.Now().Before(deadline) {
+		a.Update()
+		b.Update()
+		for _, pkt := range toB {
+			b.Input(pkt)
+		}
+		toB = toB[:0]
+		for _, pkt := range toA {
+			a.Input(pkt)
+		}
+		toA = toA[:0]
+		for {
+			n := b.Recv(buf)
+			if n < 0 {
+				break
+			}
+			received = append(received, append([]byte(nil), buf[:n]...))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if !assert.Len(t, received, count) {
+		return
+	}
+	for i, data := range received {
+		assert.Equal(t, 1+i*10, len(data))
+		assert.Equal(t, byte(i), data[0])
+	}
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gameserver/core/log"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readLogs 目录中所有日志文件的内容，按行返回
+func readLogs(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	assert.NoError(t, err)
+	var lines []string
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		assert.NoError(t, err)
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+// TestLogFields 子Logger绑定字段，JSON格式每行一个对象
+func TestLogFields(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := log.NewWithOptions(log.Options{Level: "debug", Path: dir, Format: log.FormatJSON})
+	if !assert.NoError(t, err) {
+		return
+	}
+	player := logger.With("playerId", int64(1001))
+	player.With("msgId", 7).Release("login from %v", "127.0.0.1")
+	player.Debug("no msg id")
+	logger.Close()
+
+	lines := readLogs(t, dir)
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "release", entry["level"])
+	assert.Equal(t, "login from 127.0.0.1", entry["msg"])
+	assert.Equal(t, float64(1001), entry["playerId"])
+	assert.Equal(t, float64(7), entry["msgId"])
+	assert.True(t, strings.HasPrefix(lines[0], `{"time":`))
+
+	entry = nil
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "debug", entry["level"])
+	assert.NotContains(t, entry, "msgId")
+}
+
+// TestLogLevels 运行时修改全局级别和模块级别，子Logger跟随父Logger
+func TestLogLevels(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := log.NewWithOptions(log.Options{
+		Level:        "release",
+		Path:         dir,
+		ModuleLevels: map[string]string{"match": "debug"},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	match := logger.Module("match")
+	room := match.With("roomId", 3)
+	game := logger.Module("game")
+
+	assert.False(t, logger.DebugEnabled())
+	assert.True(t, room.DebugEnabled())
+	logger.Debug("dropped")
+	room.Debug("room debug")
+	game.Debug("dropped")
+	assert.NoError(t, logger.SetModuleLevel("match", ""))
+	room.Debug("dropped")
+	assert.NoError(t, logger.SetLevel("debug"))
+	game.Debug("game debug")
+	assert.NoError(t, logger.SetModuleLevel("game", "error"))
+	game.Warn("dropped")
+	assert.Equal(t, "debug", logger.Level())
+	assert.Equal(t, map[string]string{"game": "error"}, logger.ModuleLevels())
+	assert.Error(t, logger.SetLevel("verbose"))
+	logger.Close()
+
+	lines := readLogs(t, dir)
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, "[debug  ] room debug module=match roomId=3", lines[0])
+		assert.Equal(t, "[debug  ] game debug module=game", lines[1])
+	}
+}
+
+// TestLogRotate 超过大小时轮转，只保留MaxBackups个历史文件
+func TestLogRotate(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := log.NewWithOptions(log.Options{Level: "debug", Path: dir, MaxSize: 100, MaxBackups: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	// 每行约50字节，两行一个文件
+	for i := 0; i < 10; i++ {
+		logger.Release("%02d %s", i, strings.Repeat("x", 36))
+	}
+	logger.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+	lines := readLogs(t, dir)
+	assert.Len(t, lines, 6)
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line), 100)
+	}
+	assert.Contains(t, strings.Join(lines, "\n"), "09 ")
+}
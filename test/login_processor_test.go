@@ -0,0 +1,98 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gameserver/common/msg/message"
+	"gameserver/conf"
+	"gameserver/modules/login/processor"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoginProcessor 用本地HTTP服务代替微信和抖音的jscode2session接口
+func TestLoginProcessor(t *testing.T) {
+	_, ok := processor.Get(message.LoginType_None)
+	assert.False(t, ok)
+	assert.Panics(t, func() { processor.Register(message.LoginType_WeChat, processor.NewWechatLoginProcessor()) })
+
+	t.Run("WeChat", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method)
+			assert.Equal(t, "/sns/jscode2session", r.URL.Path)
+			query := r.URL.Query()
+			assert.Equal(t, "wx-app", query.Get("appid"))
+			assert.Equal(t, "wx-secret", query.Get("secret"))
+			assert.Equal(t, "authorization_code", query.Get("grant_type"))
+			switch query.Get("js_code") {
+			case "good":
+				w.Write([]byte(`{"openid":"o-1","session_key":"sk","unionid":"u-1"}`))
+			default:
+				w.Write([]byte(`{"errcode":40029,"errmsg":"invalid code"}`))
+			}
+		}))
+		defer server.Close()
+		conf.Server.WeChatInfo.Appid = "wx-app"
+		conf.Server.WeChatInfo.Secret = "wx-secret"
+		conf.Server.WeChatInfo.Url = server.URL + "/sns/jscode2session"
+
+		p, ok := processor.Get(message.LoginType_WeChat)
+		assert.True(t, ok)
+		resp := p.ReqLogin(context.Background(), &message.C2S_Login{LoginType: message.LoginType_WeChat, Code: "good"})
+		assert.Equal(t, int32(0), resp.ErrCode)
+		assert.Equal(t, "o-1", resp.Openid)
+		assert.Equal(t, "sk", resp.SessionKey)
+		assert.Equal(t, "u-1", resp.Unionid)
+
+		resp = p.ReqLogin(context.Background(), &message.C2S_Login{Code: "bad"})
+		assert.Equal(t, int32(-1), resp.ErrCode)
+		assert.Equal(t, "40029: invalid code", resp.ErrMsg)
+
+		resp = p.ReqLogin(context.Background(), &message.C2S_Login{})
+		assert.Equal(t, int32(-1), resp.ErrCode)
+
+		// 接口不可用
+		notFound := httptest.NewServer(http.NotFoundHandler())
+		defer notFound.Close()
+		conf.Server.WeChatInfo.Url = notFound.URL
+		resp = p.ReqLogin(context.Background(), &message.C2S_Login{Code: "good"})
+		assert.Equal(t, int32(-1), resp.ErrCode)
+	})
+
+	t.Run("DouYin", func(t *testing.T) {
+		newServer := func(openid string) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				var body map[string]string
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, "dy-app", body["appid"])
+				if body["code"] != "good" {
+					w.Write([]byte(`{"err_no":40018,"err_tips":"bad code"}`))
+					return
+				}
+				w.Write([]byte(`{"err_no":0,"data":{"openid":"` + openid + `","session_key":"sk"}}`))
+			}))
+		}
+		server, sandbox := newServer("dy-1"), newServer("sandbox-1")
+		defer server.Close()
+		defer sandbox.Close()
+		conf.Server.DouYinInfo.Appid = "dy-app"
+		conf.Server.DouYinInfo.Url = server.URL
+		conf.Server.DouYinInfo.SandBoxUrl = sandbox.URL
+
+		p, _ := processor.Get(message.LoginType_DouYin)
+		resp := p.ReqLogin(context.Background(), &message.C2S_Login{Code: "good"})
+		assert.Equal(t, "dy-1", resp.Openid)
+		resp = p.ReqLogin(context.Background(), &message.C2S_Login{Code: "bad"})
+		assert.Equal(t, "bad code", resp.ErrMsg)
+
+		conf.Server.DouYinInfo.IsSandBox = 1
+		defer func() { conf.Server.DouYinInfo.IsSandBox = 0 }()
+		resp = p.ReqLogin(context.Background(), &message.C2S_Login{Code: "good"})
+		assert.Equal(t, "sandbox-1", resp.Openid)
+	})
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"testing"
+
+	"gameserver/common/msg/message"
+	"gameserver/modules/login/admission"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoginQueue 满员排队、优先放行和断线出队
+func TestLoginQueue(t *testing.T) {
+	online := 0
+	var admitted []string
+	queue := admission.NewController(2, func() int { return online })
+	enter := func(name string, priority bool) (*recordAgent, int) {
+		agent := &recordAgent{}
+		position := queue.Enter(&admission.Ticket{
+			Agent:    agent,
+			Priority: priority,
+			Admit: func() {
+				online++
+				admitted = append(admitted, name)
+			},
+		})
+		return agent, position
+	}
+
+	// 有空位时直接放行
+	_, position := enter("a", false)
+	assert.Equal(t, 0, position)
+	_, position = enter("b", false)
+	assert.Equal(t, 0, position)
+
+	// 满员后排队，优先的排在所有普通玩家之前
+	c, position := enter("c", false)
+	assert.Equal(t, 1, position)
+	d, position := enter("d", false)
+	assert.Equal(t, 2, position)
+	_, position = enter("vip", true)
+	assert.Equal(t, 1, position)
+	assert.Equal(t, 3, queue.Position(d))
+	assert.Equal(t, 3, queue.Len())
+
+	// 没有空位时只推送排队位置，最近一分钟放行了两个，每个位置预计30秒
+	assert.Equal(t, 0, queue.Tick())
+	if assert.Len(t, d.received, 1) {
+		assert.Equal(t, &message.S2C_LoginQueue{Position: 3, Total: 3, WaitSecond: 90}, d.received[0])
+	}
+
+	// 断线的玩家出队，后面的位置前移
+	assert.True(t, queue.Leave(c))
+	assert.False(t, queue.Leave(c))
+	assert.Equal(t, 2, queue.Position(d))
+
+	// 空出两个位置，按优先顺序放行
+	online = 0
+	assert.Equal(t, 2, queue.Tick())
+	assert.Equal(t, []string{"a", "b", "vip", "d"}, admitted)
+	assert.Equal(t, 0, queue.Len())
+	assert.Len(t, d.received, 1)
+
+	// 调大上限后，排队中的玩家在Tick时放行
+	_, position = enter("e", false)
+	assert.Equal(t, 1, position)
+	queue.SetMaxOnline(0)
+	assert.Equal(t, 1, queue.Tick())
+	_, position = enter("f", false)
+	assert.Equal(t, 0, position)
+	assert.Equal(t, []string{"a", "b", "vip", "d", "e", "f"}, admitted)
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gameserver/common/db/mongodb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+type IdxEvent struct {
+	Id       string    `bson:"_id"`
+	Player   int64     `bson:"player"`
+	Created  int64     `bson:"created"`
+	Code     string    `bson:"code"`
+	ExpireAt time.Time `bson:"expire_at"`
+}
+
+func (e IdxEvent) GetPersistId() interface{} { return e.Id }
+
+func (e IdxEvent) Indexes() []mongodb.Index {
+	return []mongodb.Index{
+		{Keys: mongodb.Keys("player", "-created")},
+		{Keys: mongodb.Keys("code"), Unique: true, Partial: bson.D{{Key: "code", Value: bson.M{"$gt": ""}}}},
+		{Keys: mongodb.Keys("expire_at"), TTL: time.Hour},
+	}
+}
+
+type IdxBadField struct {
+	Id string `bson:"_id"`
+}
+
+func (e IdxBadField) GetPersistId() interface{} { return e.Id }
+
+func (e IdxBadField) Indexes() []mongodb.Index {
+	return []mongodb.Index{{Keys: mongodb.Keys("missing")}}
+}
+
+type IdxBadTTL struct {
+	Id      string `bson:"_id"`
+	Created int64  `bson:"created"`
+}
+
+func (e IdxBadTTL) GetPersistId() interface{} { return e.Id }
+
+func (e IdxBadTTL) Indexes() []mongodb.Index {
+	return []mongodb.Index{{Keys: mongodb.Keys("created"), TTL: time.Hour}}
+}
+
+func init() {
+	mongodb.RegisterIndexes[IdxEvent]()
+}
+
+// TestMongoIndex 使用mock部署检查索引差异和修改命令
+func TestMongoIndex(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ns := "testdb.IdxEvent"
+	assert.Contains(t, mongodb.IndexedCollections(), "IdxEvent")
+	assert.Panics(t, mongodb.RegisterIndexes[IdxBadField])
+	assert.Panics(t, mongodb.RegisterIndexes[IdxBadTTL])
+
+	liveIndexes := func() bson.D {
+		return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "_id", Value: int32(1)}}}, {Key: "name", Value: "_id_"}},
+			bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "player", Value: int32(1)}, {Key: "created", Value: int32(-1)}}}, {Key: "name", Value: "player_1_created_-1"}},
+			bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "code", Value: int32(1)}}}, {Key: "name", Value: "code_1"}},
+			bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "old", Value: int32(1)}}}, {Key: "name", Value: "old_1"}},
+		)
+	}
+
+	mt.Run("Diff", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+
+		mt.AddMockResponses(liveIndexes())
+		diffs, err := mongodb.DiffIndexes(context.Background(), "IdxEvent")
+		assert.NoError(mt, err)
+		assert.Len(mt, diffs, 1)
+		diff := diffs[0]
+		assert.False(mt, diff.Empty())
+		assert.Equal(mt, []string{"old_1"}, diff.Extra)
+		if assert.Len(mt, diff.Missing, 1) && assert.Len(mt, diff.Changed, 1) {
+			assert.Equal(mt, "expire_at_1", diff.Missing[0].Name)
+			assert.Equal(mt, "code_1", diff.Changed[0].Name)
+		}
+		assert.Equal(mt, "IdxEvent:\r\n  + expire_at_1 (ttl=1h0m0s)\r\n  ~ code_1 (unique, partial={\"code\":{\"$gt\":\"\"}})\r\n  - old_1",
+			diff.String())
+
+		_, err = mongodb.DiffIndexes(context.Background(), "RepoOrder")
+		assert.Error(mt, err)
+	})
+
+	mt.Run("CollectionNotExists", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 26, Name: "NamespaceNotFound", Message: "ns does not exist"}))
+		diffs, err := mongodb.DiffIndexes(context.Background(), "IdxEvent")
+		assert.NoError(mt, err)
+		assert.Len(mt, diffs[0].Missing, 3)
+		assert.Empty(mt, diffs[0].Extra)
+	})
+
+	mt.Run("Prune", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+
+		ok := mtest.CreateSuccessResponse()
+		mt.AddMockResponses(liveIndexes(), ok, ok, ok, ok)
+		_, err := mongodb.ApplyIndexes(context.Background(), true, "IdxEvent")
+		assert.NoError(mt, err)
+
+		started := mt.GetAllStartedEvents()
+		if !assert.Len(mt, started, 5) {
+			return
+		}
+		// 创建缺少的TTL索引
+		create := started[1].Command
+		assert.Equal(mt, "createIndexes", create.Index(0).Key())
+		ttl := create.Lookup("indexes").Array().Index(0).Value().Document()
+		assert.Equal(mt, "expire_at_1", ttl.Lookup("name").StringValue())
+		assert.Equal(mt, int32(3600), ttl.Lookup("expireAfterSeconds").Int32())
+		// 删除后重建定义不同的索引
+		assert.Equal(mt, "code_1", started[2].Command.Lookup("index").StringValue())
+		rebuilt := started[3].Command.Lookup("indexes").Array().Index(0).Value().Document()
+		assert.True(mt, rebuilt.Lookup("unique").Boolean())
+		assert.Equal(mt, `{"code": {"$gt": ""}}`, rebuilt.Lookup("partialFilterExpression").String())
+		// 删除没有声明的索引
+		assert.Equal(mt, "old_1", started[4].Command.Lookup("index").StringValue())
+	})
+
+	mt.Run("Ensure", func(mt *mtest.T) {
+		// 启动时只创建缺少的索引
+		mongodb.UseClient(mt.Client, "testdb", false)
+
+		mt.AddMockResponses(liveIndexes(), mtest.CreateSuccessResponse())
+		assert.NoError(mt, mongodb.EnsureIndexes(context.Background(), "IdxEvent"))
+		started := mt.GetAllStartedEvents()
+		assert.Len(mt, started, 2)
+		assert.Equal(mt, "createIndexes", started[1].Command.Index(0).Key())
+	})
+}
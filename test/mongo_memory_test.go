@@ -0,0 +1,182 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gameserver/common/db/mongodb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MemItem struct {
+	Id    string   `bson:"_id"`
+	Owner int64    `bson:"owner"`
+	Kind  string   `bson:"kind"`
+	Count int64    `bson:"count"`
+	Tags  []string `bson:"tags"`
+	Stats *struct {
+		Level int32 `bson:"level"`
+	} `bson:"stats"`
+}
+
+func (i MemItem) GetPersistId() interface{} { return i.Id }
+
+// TestMemoryBackend 内存后端支持仓储和存盘用到的查询与写入
+func TestMemoryBackend(t *testing.T) {
+	ctx := context.Background()
+	mongodb.UseMemory()
+	repo := mongodb.NewRepository[MemItem]()
+
+	for _, item := range []MemItem{
+		{Id: "a", Owner: 1, Kind: "sword", Count: 3, Tags: []string{"red"}},
+		{Id: "b", Owner: 1, Kind: "shield", Count: 1},
+		{Id: "c", Owner: 2, Kind: "sword", Count: 5, Tags: []string{"blue", "red"}},
+	} {
+		assert.NoError(t, repo.Insert(ctx, item))
+	}
+	err := repo.Insert(ctx, MemItem{Id: "a"})
+	assert.True(t, mongo.IsDuplicateKeyError(err))
+
+	ids := func(items []MemItem) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.Id)
+		}
+		return out
+	}
+
+	t.Run("Filter", func(t *testing.T) {
+		cases := []struct {
+			filter interface{}
+			want   []string
+		}{
+			{bson.M{"owner": 1}, []string{"a", "b"}},
+			{bson.M{"owner": int32(1), "kind": "sword"}, []string{"a"}},
+			{bson.M{"tags": "red"}, []string{"a", "c"}},
+			{bson.M{"count": bson.M{"$gte": 3}}, []string{"a", "c"}},
+			{bson.M{"count": bson.M{"$gt": 1, "$lt": 5}}, []string{"a"}},
+			{bson.M{"kind": bson.M{"$in": bson.A{"shield", "bow"}}}, []string{"b"}},
+			{bson.M{"kind": bson.M{"$ne": "sword"}}, []string{"b"}},
+			{bson.M{"tags": bson.M{"$exists": false}}, nil},
+			{bson.M{"$or": bson.A{bson.M{"owner": 2}, bson.M{"kind": "shield"}}}, []string{"b", "c"}},
+			{bson.M{"missing": nil}, []string{"a", "b", "c"}},
+		}
+		for _, c := range cases {
+			items, err := repo.Find(ctx, mongodb.Where(c.filter))
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, ids(items), "%v", c.filter)
+		}
+
+		_, err := repo.Find(ctx, mongodb.Where(bson.M{"kind": bson.M{"$regex": "s.*"}}))
+		assert.Error(t, err)
+	})
+
+	t.Run("SortProjectPage", func(t *testing.T) {
+		items, total, err := repo.FindPage(ctx, mongodb.Where(nil).Desc("count").Select("count").Page(1, 2))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		assert.Equal(t, []MemItem{{Id: "c", Count: 5}, {Id: "a", Count: 3}}, items)
+
+		item, err := repo.FindOne(ctx, mongodb.Where(bson.M{"owner": 1}).Asc("count"))
+		assert.NoError(t, err)
+		assert.Equal(t, "b", item.Id)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		found, err := repo.Update(ctx, "a", mongodb.NewUpdate().Inc("count", int64(2)).Set("stats.level", int32(7)).Unset("tags"))
+		assert.NoError(t, err)
+		assert.True(t, found)
+		item, _ := repo.FindById(ctx, "a")
+		assert.Equal(t, int64(5), item.Count)
+		assert.Equal(t, int32(7), item.Stats.Level)
+		assert.Nil(t, item.Tags)
+
+		matched, err := repo.UpdateMany(ctx, bson.M{"kind": "sword"}, mongodb.NewUpdate().Inc("count", int64(-1)))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), matched)
+		n, _ := repo.Count(ctx, bson.M{"count": 4})
+		assert.Equal(t, int64(2), n)
+
+		found, err = repo.Update(ctx, "missing", mongodb.NewUpdate().Set("kind", "x"))
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("SaveAndBulk", func(t *testing.T) {
+		result, err := mongodb.Save(MemItem{Id: "d", Owner: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), result.UpsertedCount)
+		result, err = mongodb.Save(MemItem{Id: "d", Owner: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), result.ModifiedCount)
+
+		bulk, err := mongodb.BulkSave([]mongodb.PersistData{MemItem{Id: "d", Owner: 4}, MemItem{Id: "e", Owner: 4}})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), bulk.ModifiedCount)
+		assert.Equal(t, int64(1), bulk.UpsertedCount)
+		n, _ := repo.Count(ctx, bson.M{"owner": 4})
+		assert.Equal(t, int64(2), n)
+
+		deleted, err := repo.Delete(ctx, "e")
+		assert.NoError(t, err)
+		assert.True(t, deleted)
+		deleted, _ = repo.Delete(ctx, "e")
+		assert.False(t, deleted)
+	})
+
+	t.Run("Versioned", func(t *testing.T) {
+		orders := mongodb.NewRepository[RepoOrder]()
+		order := &RepoOrder{Id: "o1", Amount: 100}
+		assert.NoError(t, orders.SaveVersioned(ctx, order))
+		assert.Equal(t, int64(1), order.Version)
+
+		// 另一份旧副本写入时冲突
+		stale := &RepoOrder{Id: "o1", Amount: 200}
+		assert.ErrorIs(t, orders.SaveVersioned(ctx, stale), mongodb.ErrVersionConflict)
+		assert.NoError(t, orders.UpdateVersioned(ctx, "o1", 1, mongodb.NewUpdate().Inc("amount", int64(5))))
+		assert.ErrorIs(t, orders.SaveVersioned(ctx, order), mongodb.ErrVersionConflict)
+
+		saved, _ := orders.FindById(ctx, "o1")
+		assert.Equal(t, &RepoOrder{Id: "o1", Amount: 105, Version: 2}, saved)
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		failed := errors.New("failed")
+		err := mongodb.WithTransaction(ctx, func(ctx context.Context) error {
+			assert.NoError(t, repo.Insert(ctx, MemItem{Id: "tx"}))
+			_, err := repo.Update(ctx, "b", mongodb.NewUpdate().Set("kind", "broken"))
+			assert.NoError(t, err)
+			return failed
+		})
+		assert.ErrorIs(t, err, failed)
+		item, _ := repo.FindById(ctx, "tx")
+		assert.Nil(t, item)
+		item, _ = repo.FindById(ctx, "b")
+		assert.Equal(t, "shield", item.Kind)
+
+		assert.NoError(t, mongodb.WithTransaction(ctx, func(ctx context.Context) error {
+			return repo.Insert(ctx, MemItem{Id: "tx"})
+		}))
+		item, _ = repo.FindById(ctx, "tx")
+		assert.NotNil(t, item)
+	})
+
+	t.Run("MigrateAndIndexes", func(t *testing.T) {
+		users := mongodb.Current().Collection("MigUser")
+		assert.NoError(t, users.Insert(ctx, bson.M{"_id": "u1", "name": "tom"}))
+		report, err := mongodb.Migrate(ctx, "MigUser", false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Migrated)
+		n, _ := users.Count(ctx, bson.M{mongodb.SchemaVersionField: 2, "nick": "tom", "coins": 100})
+		assert.Equal(t, int64(1), n)
+
+		assert.NoError(t, mongodb.EnsureIndexes(ctx, "IdxEvent"))
+		diffs, err := mongodb.DiffIndexes(ctx, "IdxEvent")
+		assert.NoError(t, err)
+		assert.True(t, diffs[0].Empty())
+	})
+}
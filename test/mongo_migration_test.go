@@ -0,0 +1,172 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gameserver/common/db/mongodb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+type MigProfile struct {
+	Title string `bson:"title" default:"新手"`
+	Stars int32  `bson:"stars" default:"1"`
+}
+
+type MigUser struct {
+	Id      string      `bson:"_id"`
+	Nick    string      `bson:"nick"`
+	Coins   int64       `bson:"coins" default:"100"`
+	Profile *MigProfile `bson:"profile"`
+}
+
+func (u MigUser) GetPersistId() interface{} { return u.Id }
+
+func init() {
+	// 版本1：name改名为nick
+	mongodb.RegisterMigration[MigUser](1, "name改为nick", func(doc bson.M) error {
+		if name, ok := doc["name"]; ok {
+			if _, ok := name.(string); !ok {
+				return fmt.Errorf("name不是字符串: %v", name)
+			}
+		}
+		mongodb.RenameField(doc, "name", "nick")
+		return nil
+	})
+	// 版本2：新增coins和profile，只补齐默认值
+	mongodb.RegisterMigration[MigUser](2, "补齐默认值", nil)
+}
+
+// TestMongoMigration 使用mock部署检查加载时升级、写入版本和批量迁移
+func TestMongoMigration(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ns := "testdb.MigUser"
+	assert.Equal(t, 2, mongodb.SchemaVersion("MigUser"))
+	assert.Contains(t, mongodb.MigratedCollections(), "MigUser")
+	assert.Panics(t, func() {
+		mongodb.RegisterMigration[MigUser](4, "跳过版本3", nil)
+	})
+
+	mt.Run("LazyUpgrade", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+		repo := mongodb.NewRepository[MigUser]()
+
+		// 旧文档：改名并补齐默认值，已有的值保留
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "u1"}, {Key: "name", Value: "tom"}, {Key: "profile", Value: bson.D{{Key: "stars", Value: int32(3)}}}},
+		))
+		user, err := repo.FindById(context.Background(), "u1")
+		assert.NoError(mt, err)
+		assert.Equal(mt, &MigUser{Id: "u1", Nick: "tom", Coins: 100, Profile: &MigProfile{Title: "新手", Stars: 3}}, user)
+
+		// 已是最新版本的文档原样解码
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "u2"}, {Key: "nick", Value: "amy"}, {Key: mongodb.SchemaVersionField, Value: int32(2)}},
+		))
+		users, err := repo.Find(context.Background(), mongodb.Where(nil))
+		assert.NoError(mt, err)
+		assert.Equal(mt, []MigUser{{Id: "u2", Nick: "amy"}}, users)
+
+		// 迁移失败时返回错误
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "u3"}, {Key: "name", Value: int32(1)}},
+		))
+		_, err = repo.FindById(context.Background(), "u3")
+		assert.ErrorContains(mt, err, "升级到版本1")
+	})
+
+	mt.Run("Projection", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+		repo := mongodb.NewRepository[MigUser]()
+
+		// 投影带上版本字段，最新版本的部分文档不执行升级，未返回的字段保持零值
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "u1"}, {Key: "nick", Value: "tom"}, {Key: mongodb.SchemaVersionField, Value: int32(2)}},
+		))
+		user, err := repo.FindOne(context.Background(), mongodb.Where(nil).Select("nick"))
+		assert.NoError(mt, err)
+		assert.Equal(mt, &MigUser{Id: "u1", Nick: "tom"}, user)
+		assert.Equal(mt, `{"nick": {"$numberInt":"1"},"_schema": {"$numberInt":"1"}}`,
+			mt.GetStartedEvent().Command.Lookup("projection").String())
+	})
+
+	mt.Run("StampVersion", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+		repo := mongodb.NewRepository[MigUser]()
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		assert.NoError(mt, repo.Save(context.Background(), MigUser{Id: "u1", Nick: "tom"}))
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(mt, int32(2), update.Lookup("u", mongodb.SchemaVersionField).Int32())
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		_, err := mongodb.Save(MigUser{Id: "u1"})
+		assert.NoError(mt, err)
+		update = mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(mt, int32(2), update.Lookup("u", mongodb.SchemaVersionField).Int32())
+	})
+
+	oldDocs := func() bson.D {
+		return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "u1"}, {Key: "name", Value: "tom"}},
+			bson.D{{Key: "_id", Value: "u2"}, {Key: "nick", Value: "amy"}, {Key: "coins", Value: int64(5)}, {Key: mongodb.SchemaVersionField, Value: int32(1)}},
+			bson.D{{Key: "_id", Value: "u3"}, {Key: "name", Value: int32(1)}},
+		)
+	}
+
+	mt.Run("DryRun", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+
+		mt.AddMockResponses(oldDocs())
+		report, err := mongodb.Migrate(context.Background(), "MigUser", true)
+		assert.NoError(mt, err)
+		assert.Equal(mt, 3, report.Scanned)
+		assert.Equal(mt, 2, report.Migrated)
+		assert.Equal(mt, 1, report.Failed)
+		assert.Len(mt, report.Errors, 1)
+		assert.Equal(mt, map[int]int{0: 1, 1: 1}, report.FromVersions)
+		assert.Equal(mt, map[string]int{
+			"-name": 1, "+nick": 1, "+coins": 1,
+			"+profile.title": 2, "+profile.stars": 2,
+		}, report.Changes)
+		assert.Contains(mt, report.String(), "[dry-run] MigUser -> v2: scanned 3, migrated 2, skipped 0, failed 1")
+
+		// 只查询了版本较低的文档，没有写回
+		started := mt.GetAllStartedEvents()
+		assert.Len(mt, started, 1)
+		assert.Equal(mt, `{"$or": [{"_schema": {"$lt": {"$numberInt":"2"}}},{"_schema": {"$exists": false}}]}`,
+			started[0].Command.Lookup("filter").String())
+	})
+
+	mt.Run("Run", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+
+		// u1写回成功，u2写回前已被修改
+		mt.AddMockResponses(oldDocs(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+		)
+		report, err := mongodb.Migrate(context.Background(), "MigUser", false)
+		assert.NoError(mt, err)
+		assert.Equal(mt, 1, report.Migrated)
+		assert.Equal(mt, 1, report.Skipped)
+		assert.Equal(mt, 1, report.Failed)
+
+		started := mt.GetAllStartedEvents()
+		assert.Len(mt, started, 3)
+		update := started[2].Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(mt, `{"_id": "u2","_schema": {"$numberLong":"1"}}`, update.Lookup("q").String())
+		assert.Equal(mt, "amy", update.Lookup("u", "nick").StringValue())
+		assert.Equal(mt, int64(5), update.Lookup("u", "coins").Int64())
+	})
+
+	mt.Run("NotRegistered", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+		_, err := mongodb.Migrate(context.Background(), "RepoOrder", true)
+		assert.Error(mt, err)
+	})
+}
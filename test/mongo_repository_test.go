@@ -0,0 +1,118 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"gameserver/common/db/mongodb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+type RepoOrder struct {
+	Id      string `bson:"_id"`
+	Player  int64  `bson:"player"`
+	Amount  int64  `bson:"amount"`
+	Version int64  `bson:"version"`
+}
+
+func (o RepoOrder) GetPersistId() interface{} { return o.Id }
+func (o *RepoOrder) GetVersion() int64        { return o.Version }
+func (o *RepoOrder) SetVersion(v int64)       { o.Version = v }
+
+// TestMongoRepository 使用mock部署检查仓储发出的命令
+func TestMongoRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ns := "testdb.RepoOrder"
+
+	mt.Run("FindWithOptions", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+		repo := mongodb.NewRepository[RepoOrder]()
+		assert.Equal(mt, "RepoOrder", repo.Name())
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "o2"}, {Key: "amount", Value: int64(300)}},
+			bson.D{{Key: "_id", Value: "o1"}, {Key: "amount", Value: int64(600)}},
+		))
+		orders, err := repo.Find(context.Background(), mongodb.Where(bson.M{"player": 1}).
+			Desc("amount").Asc("_id").Select("amount").Page(2, 10))
+		assert.NoError(mt, err)
+		assert.Equal(mt, []RepoOrder{{Id: "o2", Amount: 300}, {Id: "o1", Amount: 600}}, orders)
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(mt, "find", cmd.Index(0).Key())
+		assert.Equal(mt, `{"player": {"$numberInt":"1"}}`, cmd.Lookup("filter").String())
+		assert.Equal(mt, `{"amount": {"$numberInt":"-1"},"_id": {"$numberInt":"1"}}`, cmd.Lookup("sort").String())
+		assert.Equal(mt, `{"amount": {"$numberInt":"1"}}`, cmd.Lookup("projection").String())
+		assert.Equal(mt, int64(10), cmd.Lookup("skip").Int64())
+		assert.Equal(mt, int64(10), cmd.Lookup("limit").Int64())
+	})
+
+	mt.Run("FindOneNotFound", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+		repo := mongodb.NewRepository[RepoOrder]()
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+		order, err := repo.FindById(context.Background(), "missing")
+		assert.NoError(mt, err)
+		assert.Nil(mt, order)
+	})
+
+	mt.Run("PartialUpdate", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+		repo := mongodb.NewRepository[RepoOrder]()
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		found, err := repo.Update(context.Background(), "o1", mongodb.NewUpdate().Set("status", 1).Inc("amount", int64(100)).Unset("extra"))
+		assert.NoError(mt, err)
+		assert.True(mt, found)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(mt, `{"_id": "o1"}`, update.Lookup("q").String())
+		assert.Equal(mt, `{"$set": {"status": {"$numberInt":"1"}},"$inc": {"amount": {"$numberLong":"100"}},"$unset": {"extra": ""}}`,
+			update.Lookup("u").String())
+
+		_, err = repo.Update(context.Background(), "o1", mongodb.NewUpdate())
+		assert.Error(mt, err)
+	})
+
+	mt.Run("Versioned", func(mt *mtest.T) {
+		mongodb.UseClient(mt.Client, "testdb", false)
+		repo := mongodb.NewRepository[RepoOrder]()
+		order := &RepoOrder{Id: "o1", Amount: 600, Version: 3}
+
+		// 版本一致，写入后版本加一
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		assert.NoError(mt, repo.SaveVersioned(context.Background(), order))
+		assert.Equal(mt, int64(4), order.Version)
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(mt, `{"_id": "o1","version": {"$numberLong":"3"}}`, update.Lookup("q").String())
+		assert.Equal(mt, int64(4), update.Lookup("u", "version").Int64())
+
+		// 数据库中的版本已变化，返回冲突且内存版本不变
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+		assert.ErrorIs(mt, repo.SaveVersioned(context.Background(), order), mongodb.ErrVersionConflict)
+		assert.Equal(mt, int64(4), order.Version)
+		mt.ClearEvents()
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+		err := repo.UpdateVersioned(context.Background(), "o1", 4, mongodb.NewUpdate().Inc("amount", int64(1)))
+		assert.ErrorIs(mt, err, mongodb.ErrVersionConflict)
+		update = mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(mt, `{"$inc": {"amount": {"$numberLong":"1"},"version": {"$numberLong":"1"}}}`, update.Lookup("u").String())
+	})
+
+	mt.Run("NoTransaction", func(mt *mtest.T) {
+		// 不支持事务时不执行fn，返回ErrNoTransaction
+		mongodb.UseClient(mt.Client, "testdb", false)
+		called := 0
+		err := mongodb.WithTransaction(context.Background(), func(ctx context.Context) error {
+			called++
+			return nil
+		})
+		assert.ErrorIs(mt, err, mongodb.ErrNoTransaction)
+		assert.Equal(mt, 0, called)
+	})
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"errors"
+	"gameserver/common/msg/message"
+	"gameserver/common/utils"
+	"gameserver/core/network/protobuf"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestProcessor_Middleware 测试中间件顺序、按消息注册、拦截与panic捕获
+func TestProcessor_Middleware(t *testing.T) {
+	p := protobuf.NewProcessor()
+	p.Register(&message.C2S_Login{})
+	p.Register(&message.C2S_Heart{})
+
+	var trace []string
+	record := func(name string) protobuf.Middleware {
+		return func(id uint32, next protobuf.MsgHandler) protobuf.MsgHandler {
+			return func(args []interface{}) {
+				trace = append(trace, name)
+				next(args)
+			}
+		}
+	}
+	stats := protobuf.NewLatencyStats()
+	p.Use(protobuf.Recover(), protobuf.Latency(stats), record("global"))
+	p.UseFor(&message.C2S_Login{}, record("login"), protobuf.Check(func(m proto.Message) error {
+		if m.(*message.C2S_Login).Code == "" {
+			return errors.New("empty code")
+		}
+		return nil
+	}))
+
+	handled := 0
+	p.SetHandler(&message.C2S_Login{}, func(args []interface{}) {
+		handled++
+	})
+
+	// 全局中间件在外层，按注册顺序执行
+	assert.NoError(t, p.Route(&message.C2S_Login{Code: "abc"}, nil))
+	assert.Equal(t, []string{"global", "login"}, trace)
+	assert.Equal(t, 1, handled)
+
+	// 校验失败时不进入handler
+	trace = nil
+	assert.NoError(t, p.Route(&message.C2S_Login{}, nil))
+	assert.Equal(t, []string{"global", "login"}, trace)
+	assert.Equal(t, 1, handled)
+
+	// 通过chanrpc路由的handler使用Wrap包装，panic被捕获
+	trace = nil
+	heart := p.Wrap(&message.C2S_Heart{}, func(args []interface{}) {
+		panic("heart panic")
+	})
+	assert.NotPanics(t, func() { heart([]interface{}{&message.C2S_Heart{}, nil}) })
+	assert.Equal(t, []string{"global"}, trace)
+
+	report := stats.Report()
+	assert.Contains(t, report, "C2S_Login")
+	assert.Contains(t, report, "C2S_Heart")
+}
+
+// TestProcessor_LoggingMask 测试日志脱敏
+func TestProcessor_LoggingMask(t *testing.T) {
+	m := &message.C2S_Login{Code: "0a3Bx9Kk2LmNqP", ServerId: 1}
+	text := protobuf.FormatMessage(m, map[string]protobuf.Masker{"code": utils.MaskSecret})
+
+	assert.False(t, strings.Contains(text, m.Code), "code未脱敏: %v", text)
+	assert.Contains(t, text, "0a**********qP")
+	// 原消息不受影响
+	assert.Equal(t, "0a3Bx9Kk2LmNqP", m.Code)
+}
+
+// TestProcessor_MiddlewareChainCached 调用链每个消息只组装一次，注册新的中间件后重新组装
+func TestProcessor_MiddlewareChainCached(t *testing.T) {
+	p := protobuf.NewProcessor()
+	p.Register(&message.C2S_Login{})
+	p.Register(&message.C2S_Heart{})
+
+	built := 0
+	counting := func(id uint32, next protobuf.MsgHandler) protobuf.MsgHandler {
+		built++
+		return next
+	}
+	p.Use(counting)
+	handled := 0
+	p.SetHandler(&message.C2S_Login{}, func(args []interface{}) {
+		handled++
+	})
+	heart := p.Wrap(&message.C2S_Heart{}, func(args []interface{}) {
+		handled++
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, p.Route(&message.C2S_Login{}, nil))
+		heart([]interface{}{&message.C2S_Heart{}, nil})
+	}
+	assert.Equal(t, 6, handled)
+	assert.Equal(t, 2, built)
+
+	var trace []string
+	p.UseFor(&message.C2S_Login{}, func(id uint32, next protobuf.MsgHandler) protobuf.MsgHandler {
+		return func(args []interface{}) {
+			trace = append(trace, "login")
+			next(args)
+		}
+	})
+	assert.NoError(t, p.Route(&message.C2S_Login{}, nil))
+	assert.NoError(t, p.Route(&message.C2S_Login{}, nil))
+	assert.Equal(t, []string{"login", "login"}, trace)
+	assert.Equal(t, 3, built)
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gameserver/common/db/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRedis 使用miniredis代替真实的Redis
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redis.UseClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "test")
+	defer redis.Close()
+
+	t.Run("Lock", func(t *testing.T) {
+		lock, err := redis.Acquire("guild:1", time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), lock.Token())
+		_, err = redis.Acquire("guild:1", time.Second)
+		assert.ErrorIs(t, err, redis.ErrLockNotAcquired)
+		assert.True(t, mr.Exists("test:lock:guild:1"))
+
+		// 持有者停顿，锁过期后被别人拿走
+		assert.NoError(t, lock.Refresh(time.Second))
+		mr.FastForward(2 * time.Second)
+		next, err := redis.Acquire("guild:1", time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), next.Token())
+		assert.ErrorIs(t, lock.Refresh(time.Second), redis.ErrLockNotHeld)
+		assert.ErrorIs(t, lock.Release(), redis.ErrLockNotHeld)
+
+		// 新持有者写入后，旧令牌被拒绝
+		assert.NoError(t, redis.CheckFence("guild:1", next.Token()))
+		assert.ErrorIs(t, redis.CheckFence("guild:1", lock.Token()), redis.ErrStaleFence)
+		assert.NoError(t, redis.CheckFence("guild:1", next.Token()))
+
+		assert.NoError(t, next.Release())
+		_, err = redis.AcquireWait("guild:1", time.Second, 50*time.Millisecond, 10*time.Millisecond)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Leaderboard", func(t *testing.T) {
+		board := redis.NewLeaderboard("level")
+		assert.NoError(t, board.SetScore("a", 10))
+		assert.NoError(t, board.SetScore("b", 30))
+		assert.NoError(t, board.SetScore("c", 20))
+		score, err := board.AddScore("a", 25)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(35), score)
+
+		top, err := board.Top(2)
+		assert.NoError(t, err)
+		assert.Equal(t, []redis.RankEntry{{Member: "a", Score: 35, Rank: 1}, {Member: "b", Score: 30, Rank: 2}}, top)
+
+		entry, ok, err := board.Get("c")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, redis.RankEntry{Member: "c", Score: 20, Rank: 3}, entry)
+		_, ok, err = board.Get("missing")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		around, err := board.Around("b", 1)
+		assert.NoError(t, err)
+		assert.Len(t, around, 3)
+
+		assert.NoError(t, board.Remove("a"))
+		n, _ := board.Count()
+		assert.Equal(t, int64(2), n)
+	})
+
+	t.Run("Presence", func(t *testing.T) {
+		presence := redis.NewPresence("player", 10*time.Second)
+		assert.NoError(t, presence.Online(1, "game-1"))
+		assert.NoError(t, presence.Online(2, "game-2"))
+
+		// 顶号到新节点后，旧节点的下线不影响
+		assert.NoError(t, presence.Online(1, "game-2"))
+		assert.NoError(t, presence.Offline(1, "game-1"))
+		node, ok, err := presence.Lookup(1)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "game-2", node)
+
+		mr.FastForward(6 * time.Second)
+		refreshed, err := presence.Refresh(1)
+		assert.NoError(t, err)
+		assert.True(t, refreshed)
+		mr.FastForward(6 * time.Second)
+		nodes, err := presence.LookupMany([]int64{1, 2, 3})
+		assert.NoError(t, err)
+		assert.Equal(t, map[int64]string{1: "game-2"}, nodes)
+		refreshed, _ = presence.Refresh(2)
+		assert.False(t, refreshed)
+	})
+
+	t.Run("PubSub", func(t *testing.T) {
+		var mu sync.Mutex
+		var received []string
+		sub, err := redis.Subscribe(func(channel, payload string) {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, channel+":"+payload)
+			if payload == "boom" {
+				panic(payload)
+			}
+		}, "chat", "mail")
+		assert.NoError(t, err)
+
+		for _, msg := range [][2]string{{"chat", "boom"}, {"chat", "hi"}, {"mail", "new"}, {"other", "x"}} {
+			_, err := redis.Publish(msg[0], msg[1])
+			assert.NoError(t, err)
+		}
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(received) == 3
+		}, time.Second, 10*time.Millisecond)
+		assert.NoError(t, sub.Close())
+		assert.Equal(t, []string{"chat:boom", "chat:hi", "mail:new"}, received)
+	})
+
+	t.Run("RateLimit", func(t *testing.T) {
+		limiter := redis.NewRateLimiter("chat", 2, time.Minute)
+		for i := 0; i < 2; i++ {
+			ok, _, err := limiter.Allow("1")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+		}
+		ok, wait, err := limiter.Allow("1")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.InDelta(t, time.Minute, wait, float64(time.Second))
+		ok, _, _ = limiter.Allow("2")
+		assert.True(t, ok)
+
+		mr.FastForward(time.Minute)
+		ok, _, _ = limiter.Allow("1")
+		assert.True(t, ok)
+		assert.NoError(t, limiter.Reset("1"))
+	})
+
+	t.Run("NotInitialized", func(t *testing.T) {
+		redis.Close()
+		_, err := redis.Acquire("x", time.Second)
+		assert.ErrorIs(t, err, redis.ErrNotInitialized)
+	})
+}
@@ -0,0 +1,144 @@
+package test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"gameserver/common/db/mongodb"
+	"gameserver/common/models"
+	"gameserver/common/msg"
+	"gameserver/common/msg/message"
+	"gameserver/common/sanction"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordAgent 记录收到的消息
+type recordAgent struct {
+	user     interface{}
+	received []interface{}
+}
+
+func (a *recordAgent) WriteMsg(msg interface{})       { a.received = append(a.received, msg) }
+func (a *recordAgent) WriteData(data ...[]byte)       {}
+func (a *recordAgent) LocalAddr() net.Addr            { return nil }
+func (a *recordAgent) RemoteAddr() net.Addr           { return nil }
+func (a *recordAgent) Close()                         {}
+func (a *recordAgent) Destroy()                       {}
+func (a *recordAgent) UserData() interface{}          { return a.user }
+func (a *recordAgent) SetUserData(data interface{})   { a.user = data }
+func (a *recordAgent) RTT() time.Duration             { return 0 }
+func (a *recordAgent) ObserveRTT(rtt time.Duration)   {}
+func (a *recordAgent) SetIdleTimeout(d time.Duration) {}
+
+// TestSanction 封号、禁言和维护白名单
+func TestSanction(t *testing.T) {
+	mongodb.UseMemory()
+
+	t.Run("Ban", func(t *testing.T) {
+		ban, err := sanction.Find(1001, models.SanctionBan)
+		assert.NoError(t, err)
+		assert.Nil(t, ban)
+
+		_, err = sanction.Ban(1001, "cheating", "gm", time.Hour)
+		assert.NoError(t, err)
+		ban, err = sanction.Find(1001, models.SanctionBan)
+		assert.NoError(t, err)
+		assert.Equal(t, "cheating", ban.Reason)
+		assert.Equal(t, "gm", ban.Operator)
+		assert.InDelta(t, time.Now().Add(time.Hour).Unix(), ban.ExpireTime, 1)
+		// 封号不影响禁言
+		assert.Nil(t, sanction.Muted(1001))
+
+		// 重复封号覆盖原来的记录，0为永久
+		_, err = sanction.Ban(1001, "cheating again", "gm", 0)
+		assert.NoError(t, err)
+		ban, _ = sanction.Find(1001, models.SanctionBan)
+		assert.Equal(t, int64(0), ban.ExpireTime)
+		records, err := sanction.List(1001)
+		assert.NoError(t, err)
+		assert.Len(t, records, 1)
+
+		lifted, err := sanction.Lift(1001, models.SanctionBan)
+		assert.NoError(t, err)
+		assert.True(t, lifted)
+		lifted, _ = sanction.Lift(1001, models.SanctionBan)
+		assert.False(t, lifted)
+
+		// 过期的记录不再生效
+		assert.NoError(t, mongodb.NewRepository[models.Sanction]().Save(context.Background(), models.Sanction{
+			Id:         models.SanctionId(models.SanctionBan, 1002),
+			PlayerId:   1002,
+			Type:       models.SanctionBan,
+			ExpireTime: time.Now().Add(-time.Second).Unix(),
+		}))
+		ban, err = sanction.Find(1002, models.SanctionBan)
+		assert.NoError(t, err)
+		assert.Nil(t, ban)
+	})
+
+	t.Run("Mute", func(t *testing.T) {
+		handled := 0
+		handler := msg.Processor.Wrap(&message.C2S_ModifyName{}, func(args []interface{}) {
+			handled++
+		})
+		agent := &recordAgent{user: models.User{AccountId: "1_mute", PlayerId: 2001}}
+		send := func() { handler([]interface{}{&message.C2S_ModifyName{Name: "name"}, agent}) }
+
+		send()
+		assert.Equal(t, 1, handled)
+
+		_, err := sanction.Mute(2001, "spam", "gm", time.Hour)
+		assert.NoError(t, err)
+		send()
+		assert.Equal(t, 1, handled)
+		if assert.Len(t, agent.received, 1) {
+			muted := agent.received[0].(*message.S2C_Muted)
+			assert.Equal(t, "spam", muted.Reason)
+			assert.Greater(t, muted.Expire, time.Now().Unix())
+		}
+
+		// 不受禁言限制的消息
+		info := msg.Processor.Wrap(&message.C2S_GetPlayerInfo{}, func(args []interface{}) {
+			handled++
+		})
+		info([]interface{}{&message.C2S_GetPlayerInfo{}, agent})
+		assert.Equal(t, 2, handled)
+
+		_, err = sanction.Lift(2001, models.SanctionMute)
+		assert.NoError(t, err)
+		send()
+		assert.Equal(t, 3, handled)
+	})
+
+	t.Run("Maintenance", func(t *testing.T) {
+		allowed, err := sanction.AllowLogin(3001)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+
+		sanction.SetMaintenance(true)
+		defer sanction.SetMaintenance(false)
+		allowed, _ = sanction.AllowLogin(3001)
+		assert.False(t, allowed)
+
+		assert.NoError(t, sanction.AddWhitelist(3001, "gm", "tester"))
+		allowed, err = sanction.AllowLogin(3001)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		// 新玩家维护期间不能注册
+		allowed, _ = sanction.AllowLogin(0)
+		assert.False(t, allowed)
+
+		list, err := sanction.Whitelist()
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Whitelist{{PlayerId: 3001, Operator: "gm", Remark: "tester", CreateTime: list[0].CreateTime}}, list)
+
+		removed, err := sanction.RemoveWhitelist(3001)
+		assert.NoError(t, err)
+		assert.True(t, removed)
+		allowed, _ = sanction.AllowLogin(3001)
+		assert.False(t, allowed)
+	})
+}
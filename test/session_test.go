@@ -0,0 +1,120 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gameserver/common/msg/message"
+	"gameserver/common/session"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSessionToken 会话令牌的签发、过期和密钥轮换
+func TestSessionToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	signer := session.NewSigner([]byte("secret"), time.Hour, 24*time.Hour)
+	signer.SetClock(clock)
+
+	token, expire := signer.Issue(1, message.LoginType_WeChat, "o-1")
+	assert.Equal(t, now.Add(time.Hour).Unix(), expire)
+	claims, err := signer.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), claims.ServerId)
+	assert.Equal(t, message.LoginType_WeChat, claims.LoginType)
+	assert.Equal(t, "o-1", claims.OpenId)
+
+	t.Run("Invalid", func(t *testing.T) {
+		payload, sig, _ := strings.Cut(token, ".")
+		for _, bad := range []string{"", "abc", payload, payload + ".", "x" + token, payload + "." + sig[1:]} {
+			_, err := signer.Verify(bad)
+			assert.ErrorIs(t, err, session.ErrInvalidToken, bad)
+		}
+
+		// 其他密钥签发的令牌
+		other := session.NewSigner([]byte("other"), time.Hour, 24*time.Hour)
+		other.SetClock(clock)
+		forged, _ := other.Issue(1, message.LoginType_WeChat, "o-1")
+		_, err := signer.Verify(forged)
+		assert.ErrorIs(t, err, session.ErrInvalidToken)
+
+		// 相同密钥的其他节点可以校验
+		peer := session.NewSigner([]byte("secret"), time.Hour, 24*time.Hour)
+		peer.SetClock(clock)
+		_, err = peer.Verify(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		defer func(start time.Time) { now = start }(now)
+		now = now.Add(59 * time.Minute)
+		_, err := signer.Verify(token)
+		assert.NoError(t, err)
+		now = now.Add(time.Minute)
+		_, err = signer.Verify(token)
+		assert.ErrorIs(t, err, session.ErrTokenExpired)
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		defer func(start time.Time) { now = start }(now)
+		// 轮换周期不小于有效期
+		short := session.NewSigner([]byte("secret"), time.Hour, time.Minute)
+		short.SetClock(clock)
+		token, _ := short.Issue(1, message.LoginType_Guest, "device-0001")
+		now = now.Add(30 * time.Minute)
+		_, err := short.Verify(token)
+		assert.NoError(t, err)
+
+		// 周期末签发的令牌，进入下一个周期后仍然可以用上一个周期的密钥校验
+		hourly := session.NewSigner([]byte("secret"), time.Hour, time.Hour)
+		hourly.SetClock(clock)
+		now = now.Truncate(time.Hour).Add(time.Hour - 10*time.Second)
+		token, _ = hourly.Issue(1, message.LoginType_Guest, "device-0001")
+		now = now.Add(30 * time.Minute)
+		_, err = hourly.Verify(token)
+		assert.NoError(t, err)
+		now = now.Add(30*time.Minute + 5*time.Second)
+		_, err = hourly.Verify(token)
+		assert.ErrorIs(t, err, session.ErrTokenExpired)
+		// 再往后上一个周期的密钥被轮换掉
+		now = now.Add(time.Minute)
+		_, err = hourly.Verify(token)
+		assert.ErrorIs(t, err, session.ErrInvalidToken)
+	})
+
+	t.Run("Renew", func(t *testing.T) {
+		defer func(start time.Time) { now = start }(now)
+		renewing := session.NewSigner([]byte("secret"), time.Hour, 24*time.Hour)
+		renewing.SetClock(clock)
+		renewing.SetMaxLifetime(2 * time.Hour)
+		auth := now
+		token, _ := renewing.Issue(1, message.LoginType_Guest, "device-0001")
+
+		// 续期不改变认证时间，过期时间不超过认证时间加最长有效期
+		now = now.Add(50 * time.Minute)
+		claims, err := renewing.Verify(token)
+		assert.NoError(t, err)
+		token, expire, err := renewing.Renew(claims)
+		assert.NoError(t, err)
+		assert.Equal(t, now.Add(time.Hour).Unix(), expire)
+		now = now.Add(50 * time.Minute)
+		claims, err = renewing.Verify(token)
+		assert.NoError(t, err)
+		assert.Equal(t, auth.Unix(), claims.AuthAt)
+		token, expire, err = renewing.Renew(claims)
+		assert.NoError(t, err)
+		assert.Equal(t, auth.Add(2*time.Hour).Unix(), expire)
+
+		// 到达上限后令牌过期，也不能再续期
+		now = auth.Add(2*time.Hour - time.Second)
+		claims, err = renewing.Verify(token)
+		assert.NoError(t, err)
+		now = auth.Add(2 * time.Hour)
+		_, _, err = renewing.Renew(claims)
+		assert.ErrorIs(t, err, session.ErrRenewLimit)
+		_, err = renewing.Verify(token)
+		assert.ErrorIs(t, err, session.ErrTokenExpired)
+	})
+}
@@ -1,7 +1,7 @@
 @echo off
 echo 正在生成配置代码...
 cd /d "%~dp0"
-go run main.go
+go run .
 echo.
 echo 按任意键退出...
 pause >nul
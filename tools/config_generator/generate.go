@@ -0,0 +1,617 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// GoField 生成的结构体字段
+type GoField struct {
+	Name     string
+	Type     string
+	JSONName string
+	Comment  string
+	Decode   string // 解码表达式
+}
+
+// GoStruct 生成的结构体，包括表结构体和嵌套结构
+type GoStruct struct {
+	Name    string
+	Comment string
+	Fields  []GoField
+	AsList  bool // 被list<struct>引用，需要生成列表解码函数
+}
+
+// GoConst 枚举常量
+type GoConst struct {
+	Name  string
+	Value string
+}
+
+// GoEnum 生成的枚举类型
+type GoEnum struct {
+	Name    string
+	Comment string
+	Consts  []GoConst
+}
+
+// GoOrder 默认排序中的一个字段
+type GoOrder struct {
+	Field   string // Go字段名
+	Desc    string // 说明，如 "sort_order升序"
+	Compare string // 字段不相等时a排在b前面的条件
+}
+
+// GoKeyField 索引中的一个字段
+type GoKeyField struct {
+	Name     string // Go字段名
+	Type     string // Go类型
+	JSONName string
+	Param    string // 查询函数的参数名
+}
+
+// GoIndex 生成的索引
+type GoIndex struct {
+	Name    string // 函数名后缀，如 Type、TypeAndLevel
+	Unique  bool
+	Fields  []GoKeyField
+	KeyType string // 单字段时为字段类型，联合索引为生成的key结构体
+	Desc    string // 说明，如 "type"、"type+level"
+}
+
+// MapName 表结构体中索引的字段名
+func (idx *GoIndex) MapName() string {
+	return "by" + idx.Name
+}
+
+// Composite 是否是联合索引
+func (idx *GoIndex) Composite() bool {
+	return len(idx.Fields) > 1
+}
+
+// Params 查询函数的参数列表
+func (idx *GoIndex) Params() string {
+	params := make([]string, len(idx.Fields))
+	for i, f := range idx.Fields {
+		params[i] = f.Param + " " + f.Type
+	}
+	return strings.Join(params, ", ")
+}
+
+// ItemKey 由配置项得到索引key的表达式
+func (idx *GoIndex) ItemKey() string {
+	return idx.keyExpr(func(f GoKeyField) string { return "item." + f.Name })
+}
+
+// ArgKey 由查询参数得到索引key的表达式
+func (idx *GoIndex) ArgKey() string {
+	return idx.keyExpr(func(f GoKeyField) string { return f.Param })
+}
+
+func (idx *GoIndex) keyExpr(value func(GoKeyField) string) string {
+	if !idx.Composite() {
+		return value(idx.Fields[0])
+	}
+	values := make([]string, len(idx.Fields))
+	for i, f := range idx.Fields {
+		values[i] = value(f)
+	}
+	return idx.KeyType + "{" + strings.Join(values, ", ") + "}"
+}
+
+// ConfigInfo 单个配置表的生成信息
+type ConfigInfo struct {
+	FileName   string
+	StructName string
+	Comment    string
+	Structs    []*GoStruct // 第一个为表结构体
+	Enums      []*GoEnum
+	HasName    bool
+	Orders     []GoOrder
+	Indexes    []*GoIndex
+}
+
+// UniqueIndexes 唯一索引
+func (info *ConfigInfo) UniqueIndexes() []*GoIndex {
+	var result []*GoIndex
+	for _, idx := range info.Indexes {
+		if idx.Unique {
+			result = append(result, idx)
+		}
+	}
+	return result
+}
+
+// GroupIndexes 分组索引
+func (info *ConfigInfo) GroupIndexes() []*GoIndex {
+	var result []*GoIndex
+	for _, idx := range info.Indexes {
+		if !idx.Unique {
+			result = append(result, idx)
+		}
+	}
+	return result
+}
+
+// OrderDesc 默认排序的说明
+func (info *ConfigInfo) OrderDesc() string {
+	if len(info.Orders) == 0 {
+		return "文件中的顺序"
+	}
+	descs := make([]string, len(info.Orders))
+	for i, o := range info.Orders {
+		descs[i] = o.Desc
+	}
+	return strings.Join(descs, "，")
+}
+
+// newConfigInfo 根据schema构造生成信息
+func newConfigInfo(fileName string, schema *TableSchema) *ConfigInfo {
+	info := &ConfigInfo{
+		FileName:   fileName,
+		StructName: schema.Struct,
+		Comment:    schema.Comment,
+	}
+	if info.Comment == "" {
+		info.Comment = fileName + "配置结构体"
+	}
+	table := info.addStruct(schema.Struct, info.Comment, schema.Fields)
+	for _, f := range schema.Fields {
+		if f.Name == "name" && f.Type == "string" {
+			info.HasName = true
+		}
+	}
+
+	fields := make(map[string]GoField, len(table.Fields))
+	for _, f := range table.Fields {
+		fields[f.JSONName] = f
+	}
+	for _, name := range schema.Order {
+		name, desc := orderField(name)
+		info.Orders = append(info.Orders, newGoOrder(fields[name], schema.field(name).Type, desc))
+	}
+	for _, index := range schema.Indexes {
+		idx := &GoIndex{Unique: index.Unique}
+		var names []string
+		for _, name := range index.Fields {
+			f := fields[name]
+			idx.Fields = append(idx.Fields, GoKeyField{Name: f.Name, Type: f.Type, JSONName: name, Param: paramName(f.Name)})
+			names = append(names, f.Name)
+		}
+		idx.Name = strings.Join(names, "And")
+		idx.Desc = strings.Join(index.Fields, "+")
+		idx.KeyType = idx.Fields[0].Type
+		if idx.Composite() {
+			idx.KeyType = info.VarName() + idx.Name + "Key"
+		}
+		info.Indexes = append(info.Indexes, idx)
+	}
+	return info
+}
+
+func newGoOrder(f GoField, typ string, desc bool) GoOrder {
+	order := GoOrder{Field: f.Name, Desc: f.JSONName + "升序"}
+	a, b := "a."+f.Name, "b."+f.Name
+	if desc {
+		order.Desc = f.JSONName + "降序"
+		a, b = b, a
+	}
+	if typ == "bool" {
+		// false排在true前面
+		order.Compare = "!" + a
+	} else {
+		order.Compare = a + " < " + b
+	}
+	return order
+}
+
+// paramName 查询函数的参数名，避开关键字和生成代码中的局部变量
+func paramName(goName string) string {
+	r := []rune(goName)
+	r[0] = unicode.ToLower(r[0])
+	name := string(r)
+	switch {
+	case name == "type":
+		return "typ"
+	case token.IsKeyword(name), name == "table", name == "item", name == "exists", name == "config":
+		return name + "Value"
+	}
+	return name
+}
+
+func (info *ConfigInfo) addStruct(name, comment string, fields []*FieldSchema) *GoStruct {
+	s := &GoStruct{Name: name, Comment: comment}
+	info.Structs = append(info.Structs, s)
+	for _, f := range fields {
+		goName := f.Go
+		if goName == "" {
+			goName = toCamelCase(f.Name)
+		}
+		field := GoField{
+			Name:     goName,
+			JSONName: f.Name,
+			Comment:  f.Comment,
+		}
+		if field.Comment == "" {
+			field.Comment = f.Name
+		}
+
+		rule := ruleLiteral(f)
+		typeName := name + goName
+		switch elem := f.elemType(); {
+		case f.Type == "enum":
+			info.addEnum(typeName, field.Comment, f.Values)
+			field.Type = typeName
+			field.Decode = fmt.Sprintf("%s(r.String(%q, %s))", typeName, f.Name, rule)
+		case f.Type == "struct":
+			info.addStruct(typeName, field.Comment, f.Fields)
+			field.Type = "*" + typeName
+			field.Decode = fmt.Sprintf("decode%s(r.Struct(%q, %s))", typeName, f.Name, rule)
+		case elem == "struct":
+			info.addStruct(typeName, field.Comment, f.Fields).AsList = true
+			field.Type = "[]*" + typeName
+			field.Decode = fmt.Sprintf("decode%sList(r.Structs(%q, %s))", typeName, f.Name, rule)
+		case elem != "":
+			field.Type = "[]" + goScalarType(elem)
+			field.Decode = fmt.Sprintf("r.%s(%q, %s)", listGetter(elem), f.Name, rule)
+		default:
+			field.Type = goScalarType(f.Type)
+			field.Decode = fmt.Sprintf("r.%s(%q, %s)", scalarGetter(f.Type), f.Name, rule)
+		}
+		s.Fields = append(s.Fields, field)
+	}
+	return s
+}
+
+func (info *ConfigInfo) addEnum(name, comment string, values []string) {
+	enum := &GoEnum{Name: name, Comment: comment}
+	for _, v := range values {
+		enum.Consts = append(enum.Consts, GoConst{Name: name + toCamelCase(v), Value: v})
+	}
+	info.Enums = append(info.Enums, enum)
+}
+
+func goScalarType(typ string) string {
+	switch typ {
+	case "float":
+		return "float64"
+	}
+	return typ
+}
+
+func scalarGetter(typ string) string {
+	switch typ {
+	case "int":
+		return "Int"
+	case "int64":
+		return "Int64"
+	case "float":
+		return "Float"
+	case "bool":
+		return "Bool"
+	}
+	return "String"
+}
+
+func listGetter(typ string) string {
+	switch typ {
+	case "int":
+		return "Ints"
+	case "int64":
+		return "Int64s"
+	case "float":
+		return "Floats"
+	}
+	return "Strings"
+}
+
+// ruleLiteral 生成config.Rule字面量
+func ruleLiteral(f *FieldSchema) string {
+	var parts []string
+	if f.Required {
+		parts = append(parts, "Required: true")
+	}
+	if f.Min != nil {
+		parts = append(parts, "Min: config.Limit("+strconv.FormatFloat(*f.Min, 'g', -1, 64)+")")
+	}
+	if f.Max != nil {
+		parts = append(parts, "Max: config.Limit("+strconv.FormatFloat(*f.Max, 'g', -1, 64)+")")
+	}
+	if len(f.Values) > 0 {
+		values := make([]string, len(f.Values))
+		for i, v := range f.Values {
+			values[i] = strconv.Quote(v)
+		}
+		parts = append(parts, "Values: []string{"+strings.Join(values, ", ")+"}")
+	}
+	if f.Ref != "" {
+		parts = append(parts, "Ref: "+strconv.Quote(f.Ref))
+	}
+	return "config.Rule{" + strings.Join(parts, ", ") + "}"
+}
+
+// 生成Go代码的模板
+const goTemplate = `// Code generated by config_generator. DO NOT EDIT.
+
+package config
+
+import (
+	"fmt"
+	"gameserver/common/config"
+{{- if .Orders}}
+	"sort"
+{{- end}}
+)
+{{range $enum := .Enums}}
+// {{.Name}} {{.Comment}}
+type {{.Name}} string
+
+const (
+{{- range .Consts}}
+	{{.Name}} {{$enum.Name}} = {{printf "%q" .Value}}
+{{- end}}
+)
+{{end}}
+{{- range .Structs}}
+// {{.Name}} {{.Comment}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`json:\"{{.JSONName}}\"`" + ` // {{.Comment}}
+{{- end}}
+}
+
+// decode{{.Name}} 解码{{.Name}}，字段错误记录在行上
+func decode{{.Name}}(r *config.Row) *{{.Name}} {
+	if r == nil {
+		return nil
+	}
+	return &{{.Name}}{
+{{- range .Fields}}
+		{{.Name}}: {{.Decode}},
+{{- end}}
+	}
+}
+{{if .AsList}}
+func decode{{.Name}}List(rows []*config.Row) []*{{.Name}} {
+	if rows == nil {
+		return nil
+	}
+	result := make([]*{{.Name}}, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, decode{{.Name}}(r))
+	}
+	return result
+}
+{{end}}
+{{- end}}
+{{- $info := .}}
+{{- range .Indexes}}{{if .Composite}}
+// {{.KeyType}} {{.Desc}}联合索引的key
+type {{.KeyType}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}}
+{{- end}}
+}
+{{end}}{{end}}
+// {{.VarName}}Table {{.FileName}}解码后的数据
+type {{.VarName}}Table struct {
+	rows map[string]*{{.StructName}}
+	list []*{{.StructName}} // {{.OrderDesc}}
+{{- range .UniqueIndexes}}
+	{{.MapName}} map[{{.KeyType}}]*{{$info.StructName}}
+{{- end}}
+{{- range .GroupIndexes}}
+	{{.MapName}} map[{{.KeyType}}][]*{{$info.StructName}}
+{{- end}}
+}
+{{if .Orders}}
+// less{{.StructName}} 默认排序：{{.OrderDesc}}
+func less{{.StructName}}(a, b *{{.StructName}}) bool {
+{{- range .Orders}}
+	if a.{{.Field}} != b.{{.Field}} {
+		return {{.Compare}}
+	}
+{{- end}}
+	return false
+}
+{{end}}
+// load{{.StructName}}Table 加载{{.FileName}}时解码、校验并建立索引，之后的查询不再转换
+func load{{.StructName}}Table(t *config.TableReader) interface{} {
+	table := &{{.VarName}}Table{
+		rows: make(map[string]*{{.StructName}}),
+{{- range .UniqueIndexes}}
+		{{.MapName}}: make(map[{{.KeyType}}]*{{$info.StructName}}),
+{{- end}}
+{{- range .GroupIndexes}}
+		{{.MapName}}: make(map[{{.KeyType}}][]*{{$info.StructName}}),
+{{- end}}
+	}
+	for _, r := range t.Rows() {
+		item := decode{{.StructName}}(r)
+{{- range .UniqueIndexes}}
+		if prev, exists := table.{{.MapName}}[{{.ItemKey}}]; exists {
+			r.Errorf("{{(index .Fields 0).JSONName}}", "唯一索引{{.Desc}}重复，与id=%s相同", prev.Id)
+		} else {
+			table.{{.MapName}}[{{.ItemKey}}] = item
+		}
+{{- end}}
+		table.rows[r.Id()] = item
+		table.list = append(table.list, item)
+	}
+{{- if .Orders}}
+	sort.SliceStable(table.list, func(i, j int) bool {
+		return less{{.StructName}}(table.list[i], table.list[j])
+	})
+{{- end}}
+{{- range .GroupIndexes}}
+	for _, item := range table.list {
+		key := {{.ItemKey}}
+		table.{{.MapName}}[key] = append(table.{{.MapName}}[key], item)
+	}
+{{- end}}
+	return table
+}
+
+func get{{.StructName}}Table() *{{.VarName}}Table {
+	if table, ok := config.GetTable("{{.FileName}}"); ok {
+		return table.(*{{.VarName}}Table)
+	}
+	return nil
+}
+
+// Get{{.StructName}}Config 获取{{.FileName}}配置
+func Get{{.StructName}}Config(id string) (*{{.StructName}}, bool) {
+	table := get{{.StructName}}Table()
+	if table == nil {
+		return nil, false
+	}
+	item, exists := table.rows[id]
+	return item, exists
+}
+
+// GetAll{{.StructName}}Configs 获取所有{{.FileName}}配置
+func GetAll{{.StructName}}Configs() (map[string]*{{.StructName}}, bool) {
+	table := get{{.StructName}}Table()
+	if table == nil {
+		return nil, false
+	}
+	result := make(map[string]*{{.StructName}}, len(table.rows))
+	for id, item := range table.rows {
+		result[id] = item
+	}
+	return result, true
+}
+
+// List{{.StructName}}Configs 获取所有{{.FileName}}配置，按{{.OrderDesc}}
+func List{{.StructName}}Configs() []*{{.StructName}} {
+	table := get{{.StructName}}Table()
+	if table == nil {
+		return nil
+	}
+	return append([]*{{.StructName}}(nil), table.list...)
+}
+{{range .UniqueIndexes}}
+// Get{{$info.StructName}}By{{.Name}} 按{{.Desc}}获取{{$info.FileName}}配置
+func Get{{$info.StructName}}By{{.Name}}({{.Params}}) (*{{$info.StructName}}, bool) {
+	table := get{{$info.StructName}}Table()
+	if table == nil {
+		return nil, false
+	}
+	item, exists := table.{{.MapName}}[{{.ArgKey}}]
+	return item, exists
+}
+{{end}}
+{{- range .GroupIndexes}}
+// List{{$info.StructName}}By{{.Name}} 获取{{.Desc}}相同的{{$info.FileName}}配置，按{{$info.OrderDesc}}
+func List{{$info.StructName}}By{{.Name}}({{.Params}}) []*{{$info.StructName}} {
+	table := get{{$info.StructName}}Table()
+	if table == nil {
+		return nil
+	}
+	return append([]*{{$info.StructName}}(nil), table.{{.MapName}}[{{.ArgKey}}]...)
+}
+{{end}}
+{{- if .HasName}}
+// Get{{.StructName}}Name 获取{{.FileName}}名称
+func Get{{.StructName}}Name(id string) (string, bool) {
+	if item, exists := Get{{.StructName}}Config(id); exists {
+		return item.Name, true
+	}
+	return "", false
+}
+{{end}}
+// Reload{{.StructName}}Config 重新加载{{.FileName}}配置
+func Reload{{.StructName}}Config() error {
+	return config.ReloadConfig("{{.FileName}}")
+}
+
+// Validate{{.StructName}}Config 验证{{.FileName}}配置
+func Validate{{.StructName}}Config(id string) error {
+	if _, exists := Get{{.StructName}}Config(id); !exists {
+		return fmt.Errorf("配置不存在: %s", id)
+	}
+	return nil
+}
+`
+
+// VarName 表结构体的小写名称
+func (info *ConfigInfo) VarName() string {
+	r := []rune(info.StructName)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// generateGoFile 生成Go文件
+func generateGoFile(configInfo *ConfigInfo, outputDir string) error {
+	tmpl, err := template.New("config").Parse(goTemplate)
+	if err != nil {
+		return err
+	}
+
+	outputFile := filepath.Join(outputDir, strings.ToLower(configInfo.StructName)+".go")
+	return executeTemplate(tmpl, configInfo, outputFile)
+}
+
+// generateRegisterFile 生成注册文件
+func generateRegisterFile(configInfos []*ConfigInfo, outputDir string) error {
+	// 注册文件模板
+	const registerTemplate = `// Code generated by config_generator. DO NOT EDIT.
+
+package config
+
+import (
+	"gameserver/common/config"
+)
+
+// init 注册所有表的解码函数，加载配置文件时解码并校验
+func init() {
+{{- range .}}
+	config.RegisterTable("{{.FileName}}", load{{.StructName}}Table)
+{{- end}}
+}
+`
+
+	tmpl, err := template.New("register").Parse(registerTemplate)
+	if err != nil {
+		return err
+	}
+	return executeTemplate(tmpl, configInfos, filepath.Join(outputDir, "register.go"))
+}
+
+// executeTemplate 执行模板并格式化输出
+func executeTemplate(tmpl *template.Template, data interface{}, outputFile string) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("格式化代码失败: %v", err)
+	}
+	return os.WriteFile(outputFile, src, 0644)
+}
+
+// toCamelCase 转换为驼峰命名，如 mana_cost -> ManaCost
+func toCamelCase(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, part := range parts {
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		parts[i] = string(r)
+	}
+	name := strings.Join(parts, "")
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "V" + name
+	}
+	return name
+}
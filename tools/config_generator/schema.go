@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FieldSchema 字段定义
+//
+// type 取值:
+//   - int / int64 / float / string / bool
+//   - enum: 字符串枚举，values 给出可选值
+//   - struct: 嵌套结构，fields 给出子字段
+//   - list<int> / list<int64> / list<float> / list<string> / list<struct>
+type FieldSchema struct {
+	Name     string         `json:"name"`               // 配置中的字段名
+	Go       string         `json:"go,omitempty"`       // Go字段名，默认由name转驼峰
+	Type     string         `json:"type"`               // 字段类型
+	Comment  string         `json:"comment,omitempty"`  // 注释
+	Required bool           `json:"required,omitempty"` // 必填，列表要求非空
+	Min      *float64       `json:"min,omitempty"`      // 数值下限（含），列表时作用于元素
+	Max      *float64       `json:"max,omitempty"`      // 数值上限（含）
+	Values   []string       `json:"values,omitempty"`   // 枚举可选值
+	Ref      string         `json:"ref,omitempty"`      // 外键，引用的配置文件，如 items.json
+	Fields   []*FieldSchema `json:"fields,omitempty"`   // struct/list<struct>的子字段
+}
+
+// IndexSchema 索引定义，加载时建好，查询不再扫描
+//   - unique为true时生成Get<Struct>By<Fields>，值重复时加载报错
+//   - 否则按字段分组，生成List<Struct>By<Fields>，组内按表的默认排序
+type IndexSchema struct {
+	Fields []string `json:"fields"`           // 索引字段，多个字段组成联合索引
+	Unique bool     `json:"unique,omitempty"` // 唯一索引
+}
+
+// TableSchema 配置表定义，对应schema目录下与配置同名的json文件
+type TableSchema struct {
+	Struct  string         `json:"struct"`            // 结构体名
+	Comment string         `json:"comment,omitempty"` // 注释
+	Fields  []*FieldSchema `json:"fields"`
+	Order   []string       `json:"order,omitempty"`   // 默认排序字段，-前缀表示降序，相同时保持文件中的顺序
+	Indexes []*IndexSchema `json:"indexes,omitempty"` // 索引与分组
+}
+
+// 可以作为排序与索引的字段类型
+var keyTypes = map[string]bool{
+	"int":    true,
+	"int64":  true,
+	"string": true,
+	"bool":   true,
+	"enum":   true,
+}
+
+// field 顶层字段
+func (s *TableSchema) field(name string) *FieldSchema {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// orderField 解析排序字段，返回字段名与是否降序
+func orderField(name string) (string, bool) {
+	if strings.HasPrefix(name, "-") {
+		return name[1:], true
+	}
+	return name, false
+}
+
+var scalarTypes = map[string]bool{
+	"int":    true,
+	"int64":  true,
+	"float":  true,
+	"string": true,
+	"bool":   true,
+}
+
+// elemType list<T>的元素类型，非列表返回空
+func (f *FieldSchema) elemType() string {
+	if strings.HasPrefix(f.Type, "list<") && strings.HasSuffix(f.Type, ">") {
+		return f.Type[len("list<") : len(f.Type)-1]
+	}
+	return ""
+}
+
+// loadSchema 读取配置表对应的schema，文件不存在时返回nil
+func loadSchema(schemaDir, fileName string) (*TableSchema, error) {
+	path := filepath.Join(schemaDir, fileName)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schema TableSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("解析schema失败 %s: %v", path, err)
+	}
+	return &schema, nil
+}
+
+// checkSchema 检查schema本身是否合法，tables为所有配置文件名，用于检查外键目标
+func checkSchema(fileName string, schema *TableSchema, tables map[string]bool) []error {
+	var errs []error
+	if schema.Struct == "" {
+		errs = append(errs, fmt.Errorf("%s: 缺少struct", fileName))
+	}
+	hasId := false
+	for _, f := range schema.Fields {
+		if f.Name == "id" {
+			hasId = true
+			if f.Type != "string" && f.Type != "int" && f.Type != "int64" {
+				errs = append(errs, fmt.Errorf("%s: id字段只能是string/int/int64", fileName))
+			}
+		}
+	}
+	if !hasId {
+		errs = append(errs, fmt.Errorf("%s: 缺少id字段", fileName))
+	}
+	errs = append(errs, checkFields(fileName, "", schema.Fields, tables)...)
+	return append(errs, checkIndexes(fileName, schema)...)
+}
+
+// checkIndexes 检查排序与索引字段，只能使用整数、字符串、布尔与枚举类型的顶层字段
+func checkIndexes(fileName string, schema *TableSchema) []error {
+	var errs []error
+	checkKey := func(usage, name string) {
+		f := schema.field(name)
+		switch {
+		case f == nil:
+			errs = append(errs, fmt.Errorf("%s: %s字段%s不存在", fileName, usage, name))
+		case !keyTypes[f.Type]:
+			errs = append(errs, fmt.Errorf("%s: %s字段%s的类型%s不支持，只能是int/int64/string/bool/enum", fileName, usage, name, f.Type))
+		}
+	}
+
+	for _, name := range schema.Order {
+		name, _ = orderField(name)
+		checkKey("排序", name)
+	}
+
+	seen := make(map[string]bool)
+	for _, index := range schema.Indexes {
+		if len(index.Fields) == 0 {
+			errs = append(errs, fmt.Errorf("%s: 存在没有字段的索引", fileName))
+			continue
+		}
+		key := strings.Join(index.Fields, ",")
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("%s: 索引[%s]重复", fileName, key))
+		}
+		seen[key] = true
+		for _, name := range index.Fields {
+			checkKey("索引", name)
+		}
+	}
+	return errs
+}
+
+func checkFields(fileName, prefix string, fields []*FieldSchema, tables map[string]bool) []error {
+	var errs []error
+	names := make(map[string]bool)
+	for _, f := range fields {
+		path := prefix + f.Name
+		if f.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: 存在未命名的字段", fileName))
+			continue
+		}
+		if names[f.Name] {
+			errs = append(errs, fmt.Errorf("%s: 字段%s重复", fileName, path))
+		}
+		names[f.Name] = true
+
+		typ := f.Type
+		if elem := f.elemType(); elem != "" {
+			typ = elem
+		}
+		switch {
+		case scalarTypes[typ]:
+		case typ == "enum" && f.elemType() == "":
+			if len(f.Values) == 0 {
+				errs = append(errs, fmt.Errorf("%s: 枚举字段%s缺少values", fileName, path))
+			}
+		case typ == "struct":
+			if len(f.Fields) == 0 {
+				errs = append(errs, fmt.Errorf("%s: 结构字段%s缺少fields", fileName, path))
+			}
+			errs = append(errs, checkFields(fileName, path+".", f.Fields, tables)...)
+		default:
+			errs = append(errs, fmt.Errorf("%s: 字段%s的类型%s不支持", fileName, path, f.Type))
+		}
+
+		if (f.Min != nil || f.Max != nil) && typ != "int" && typ != "int64" && typ != "float" {
+			errs = append(errs, fmt.Errorf("%s: 字段%s不是数值，不能设置min/max", fileName, path))
+		}
+		if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+			errs = append(errs, fmt.Errorf("%s: 字段%s的min大于max", fileName, path))
+		}
+		if f.Ref != "" && !tables[f.Ref] {
+			errs = append(errs, fmt.Errorf("%s: 字段%s引用的配置%s不存在", fileName, path, f.Ref))
+		}
+	}
+	return errs
+}
+
+// inferSchema 根据现有数据推断schema草稿，供新表第一次生成时使用
+func inferSchema(structName string, rows []map[string]interface{}) *TableSchema {
+	types := make(map[string]string)
+	for _, row := range rows {
+		for key, value := range row {
+			types[key] = mergeType(types[key], inferType(value))
+		}
+	}
+
+	var names []string
+	for key := range types {
+		names = append(names, key)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		// id、name排在最前面，其余按字母顺序
+		pi, pj := fieldPriority(names[i]), fieldPriority(names[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return names[i] < names[j]
+	})
+
+	schema := &TableSchema{Struct: structName}
+	for _, name := range names {
+		schema.Fields = append(schema.Fields, &FieldSchema{
+			Name:     name,
+			Type:     types[name],
+			Comment:  name,
+			Required: name == "id",
+		})
+	}
+	return schema
+}
+
+func fieldPriority(name string) int {
+	switch name {
+	case "id":
+		return 0
+	case "name":
+		return 1
+	}
+	return 2
+}
+
+func inferType(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64:
+		if v == math.Trunc(v) {
+			return "int"
+		}
+		return "float"
+	case []interface{}:
+		elem := ""
+		for _, item := range v {
+			elem = mergeType(elem, inferType(item))
+		}
+		if elem == "" || strings.HasPrefix(elem, "list<") {
+			elem = "string"
+		}
+		return "list<" + elem + ">"
+	}
+	return "string"
+}
+
+// mergeType 同一字段在不同行中推断出的类型合并，int与float合并为float
+func mergeType(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case (a == "int" && b == "float") || (a == "float" && b == "int"):
+		return "float"
+	case a == "list<int>" && b == "list<float>", a == "list<float>" && b == "list<int>":
+		return "list<float>"
+	}
+	return a
+}
+
+// writeSchema 写出schema草稿
+func writeSchema(schemaDir, fileName string, schema *TableSchema) error {
+	if err := os.MkdirAll(schemaDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(schemaDir, fileName), append(data, '\n'), 0644)
+}
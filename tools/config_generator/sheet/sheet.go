@@ -0,0 +1,371 @@
+// Package sheet 读取策划填写的xlsx/csv配置表，转换为服务器与客户端使用的JSON
+//
+// 表格格式（每个文件只读取第一个工作表）:
+//
+//	第1行 字段名，空或以#开头的列不导出
+//	第2行 字段类型，同schema：int/int64/float/string/bool/enum/struct/list<T>
+//	第3行 字段注释
+//	第4行 导出目标：server/client/both，留空为both
+//	第5行起为数据，第一列以#开头的行为注释行，整行为空的行被忽略
+//
+// 列表类型的单元格用|分隔元素，struct与list<struct>的单元格填写JSON。
+package sheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 表头行数
+const headerRows = 4
+
+// 列表单元格的元素分隔符
+const listSeparator = "|"
+
+// Target 导出目标
+type Target string
+
+const (
+	TargetBoth   Target = "both"
+	TargetServer Target = "server"
+	TargetClient Target = "client"
+)
+
+// Column 表头定义的一列
+type Column struct {
+	Name    string
+	Type    string
+	Comment string
+	Target  Target
+	index   int // 在表格中的列号，从0开始
+}
+
+// ExportTo 该列是否导出到target
+func (c *Column) ExportTo(target Target) bool {
+	return c.Target == TargetBoth || c.Target == target
+}
+
+// Sheet 一张配置表
+type Sheet struct {
+	Name    string // 文件名，如 skills.xlsx
+	Columns []*Column
+	records []record
+}
+
+type record struct {
+	line  int // 表格中的行号，从1开始
+	cells []string
+}
+
+// Field 导出行中的一个字段
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// Row 导出的一行，字段顺序与表格列顺序一致
+type Row []Field
+
+// MarshalJSON 按列顺序输出JSON对象，便于与表格对照
+func (r Row) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.Name)
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Map 转换为map，与ConfigManager解析JSON得到的结构相同
+func (r Row) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(r))
+	for _, f := range r {
+		m[f.Name] = f.Value
+	}
+	return m
+}
+
+// Error 表格中某个单元格的错误
+type Error struct {
+	File  string
+	Line  int
+	Field string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s 第%d行: %s", e.File, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s 第%d行 字段%s: %s", e.File, e.Line, e.Field, e.Msg)
+}
+
+// Errors 一张表中的所有错误
+type Errors []*Error
+
+func (e Errors) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("表格解析失败，共%d处错误:", len(e)))
+	for _, err := range e {
+		lines = append(lines, "  "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// IsSheet 是否是支持的表格文件，忽略Excel打开时生成的~$临时文件
+func IsSheet(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, "~$") {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".xlsx", ".csv", ".tsv":
+		return true
+	}
+	return false
+}
+
+// Read 按扩展名读取xlsx/csv/tsv表格并解析表头
+func Read(path string) (*Sheet, error) {
+	var cells [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx":
+		cells, err = ReadXLSX(path)
+	case ".csv", ".tsv":
+		var f *os.File
+		if f, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		comma := ','
+		if strings.EqualFold(filepath.Ext(path), ".tsv") {
+			comma = '\t'
+		}
+		cells, err = ReadCSV(f, comma)
+	default:
+		return nil, fmt.Errorf("不支持的表格格式: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取表格失败 %s: %v", path, err)
+	}
+	return Parse(filepath.Base(path), cells)
+}
+
+// ReadCSV 读取UTF-8编码的csv，兼容Excel导出时带的BOM
+func ReadCSV(r io.Reader, comma rune) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.ReadAll()
+}
+
+// Parse 解析表头，name为文件名，用于错误信息
+func Parse(name string, cells [][]string) (*Sheet, error) {
+	if len(cells) < headerRows {
+		return nil, fmt.Errorf("%s: 表头不完整，需要字段名、类型、注释、导出目标共%d行", name, headerRows)
+	}
+
+	sheet := &Sheet{Name: name}
+	var errs Errors
+	names := make(map[string]bool)
+	for i, colName := range cells[0] {
+		colName = strings.TrimSpace(colName)
+		if colName == "" || strings.HasPrefix(colName, "#") {
+			continue
+		}
+		col := &Column{
+			Name:    colName,
+			Type:    strings.TrimSpace(cellAt(cells[1], i)),
+			Comment: strings.TrimSpace(cellAt(cells[2], i)),
+			Target:  Target(strings.ToLower(strings.TrimSpace(cellAt(cells[3], i)))),
+			index:   i,
+		}
+		if col.Target == "" {
+			col.Target = TargetBoth
+		}
+		switch {
+		case names[colName]:
+			errs = append(errs, &Error{File: name, Line: 1, Field: colName, Msg: "字段名重复"})
+		case col.Type == "":
+			errs = append(errs, &Error{File: name, Line: 2, Field: colName, Msg: "缺少字段类型"})
+		case !validType(col.Type):
+			errs = append(errs, &Error{File: name, Line: 2, Field: colName, Msg: fmt.Sprintf("类型%s不支持", col.Type)})
+		case col.Target != TargetBoth && col.Target != TargetServer && col.Target != TargetClient:
+			errs = append(errs, &Error{File: name, Line: 4, Field: colName, Msg: fmt.Sprintf("导出目标%s不合法，可选值[server client both]", col.Target)})
+		}
+		names[colName] = true
+		sheet.Columns = append(sheet.Columns, col)
+	}
+	if !names["id"] {
+		errs = append(errs, &Error{File: name, Line: 1, Msg: "缺少id列"})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	for i := headerRows; i < len(cells); i++ {
+		cells := cells[i]
+		if isBlank(cells) || strings.HasPrefix(strings.TrimSpace(cellAt(cells, 0)), "#") {
+			continue
+		}
+		sheet.records = append(sheet.records, record{line: i + 1, cells: cells})
+	}
+	return sheet, nil
+}
+
+// Export 导出target需要的列，空单元格不输出该字段，由加载时的校验决定是否必填
+func (s *Sheet) Export(target Target) ([]Row, error) {
+	var errs Errors
+	rows := make([]Row, 0, len(s.records))
+	for _, rec := range s.records {
+		row := Row{}
+		for _, col := range s.Columns {
+			if !col.ExportTo(target) {
+				continue
+			}
+			text := strings.TrimSpace(cellAt(rec.cells, col.index))
+			if text == "" {
+				if col.Name == "id" {
+					errs = append(errs, &Error{File: s.Name, Line: rec.line, Field: col.Name, Msg: "id不能为空"})
+				}
+				continue
+			}
+			value, err := convert(col.Type, text)
+			if err != nil {
+				errs = append(errs, &Error{File: s.Name, Line: rec.line, Field: col.Name, Msg: err.Error()})
+				continue
+			}
+			row = append(row, Field{Name: col.Name, Value: value})
+		}
+		rows = append(rows, row)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return rows, nil
+}
+
+// MarshalRows 输出带缩进的JSON数组，一行一个对象
+func MarshalRows(rows []Row) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for i, row := range rows {
+		data, err := row.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("  ")
+		buf.Write(data)
+		if i < len(rows)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("]\n")
+	return buf.Bytes(), nil
+}
+
+func validType(typ string) bool {
+	if strings.HasPrefix(typ, "list<") && strings.HasSuffix(typ, ">") {
+		typ = typ[len("list<") : len(typ)-1]
+		return typ != "enum" && validType(typ) && !strings.HasPrefix(typ, "list<")
+	}
+	switch typ {
+	case "int", "int64", "float", "string", "bool", "enum", "struct":
+		return true
+	}
+	return false
+}
+
+// convert 按列类型转换单元格
+func convert(typ, text string) (interface{}, error) {
+	switch typ {
+	case "int", "int64":
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			// Excel中的整数可能以1.0E3等形式保存
+			f, ferr := strconv.ParseFloat(text, 64)
+			if ferr != nil || f != float64(int64(f)) {
+				return nil, fmt.Errorf("应为整数，实际为%s", text)
+			}
+			n = int64(f)
+		}
+		return n, nil
+	case "float":
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("应为数值，实际为%s", text)
+		}
+		return f, nil
+	case "bool":
+		switch strings.ToLower(text) {
+		case "1", "true", "是":
+			return true, nil
+		case "0", "false", "否":
+			return false, nil
+		}
+		return nil, fmt.Errorf("应为布尔值，实际为%s", text)
+	case "string", "enum":
+		return text, nil
+	case "struct", "list<struct>":
+		var v interface{}
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			return nil, fmt.Errorf("应为JSON: %v", err)
+		}
+		return v, nil
+	}
+
+	elem := typ[len("list<") : len(typ)-1]
+	parts := strings.Split(text, listSeparator)
+	list := make([]interface{}, 0, len(parts))
+	for i, part := range parts {
+		v, err := convert(elem, strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("第%d个元素%v", i+1, err)
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func cellAt(cells []string, i int) string {
+	if i < len(cells) {
+		return cells[i]
+	}
+	return ""
+}
+
+func isBlank(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
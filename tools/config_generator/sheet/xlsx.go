@@ -0,0 +1,188 @@
+package sheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// xlsx是zip压缩的一组XML文件，这里只解析读取单元格文本所需的部分：
+//   xl/workbook.xml            工作表列表
+//   xl/_rels/workbook.xml.rels 工作表对应的文件
+//   xl/sharedStrings.xml       共享字符串
+//   xl/worksheets/sheetN.xml   单元格
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		Id   string `xml:"id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText 字符串，富文本时由多个r/t片段组成
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var sb strings.Builder
+	for _, r := range t.Runs {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string    `xml:"r,attr"`
+			T  string    `xml:"t,attr"`
+			V  string    `xml:"v"`
+			Is *xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX 读取xlsx第一个工作表的所有单元格文本，返回值按行列排列，空单元格为""
+func ReadXLSX(filename string) ([][]string, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	decode := func(name string, v interface{}) error {
+		f, ok := files[name]
+		if !ok {
+			return fmt.Errorf("缺少%s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return xml.NewDecoder(rc).Decode(v)
+	}
+
+	var workbook xlsxWorkbook
+	if err := decode("xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, fmt.Errorf("没有工作表")
+	}
+	var rels xlsxRelationships
+	if err := decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetFile := ""
+	for _, rel := range rels.Relationships {
+		if rel.Id == workbook.Sheets[0].Id {
+			// Target可能是相对xl/的路径，也可能是/xl/开头的绝对路径
+			if strings.HasPrefix(rel.Target, "/") {
+				sheetFile = strings.TrimPrefix(rel.Target, "/")
+			} else {
+				sheetFile = path.Join("xl", rel.Target)
+			}
+		}
+	}
+	if sheetFile == "" {
+		return nil, fmt.Errorf("找不到工作表%s", workbook.Sheets[0].Name)
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decode("xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	var worksheet xlsxWorksheet
+	if err := decode(sheetFile, &worksheet); err != nil {
+		return nil, err
+	}
+
+	var cells [][]string
+	for i, row := range worksheet.Rows {
+		rowIndex := i
+		if row.R > 0 {
+			rowIndex = row.R - 1
+		}
+		for len(cells) <= rowIndex {
+			cells = append(cells, nil)
+		}
+		line := cells[rowIndex]
+		for j, c := range row.Cells {
+			colIndex := j
+			if c.R != "" {
+				if colIndex, err = columnIndex(c.R); err != nil {
+					return nil, err
+				}
+			}
+			for len(line) <= colIndex {
+				line = append(line, "")
+			}
+
+			switch c.T {
+			case "s":
+				idx, err := strconv.Atoi(c.V)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, fmt.Errorf("单元格%s的共享字符串索引%s无效", c.R, c.V)
+				}
+				line[colIndex] = shared.Items[idx].String()
+			case "inlineStr":
+				if c.Is != nil {
+					line[colIndex] = c.Is.String()
+				}
+			case "b":
+				line[colIndex] = map[string]string{"1": "true", "0": "false"}[c.V]
+			default:
+				line[colIndex] = c.V
+			}
+		}
+		cells[rowIndex] = line
+	}
+	return cells, nil
+}
+
+// columnIndex 由单元格引用（如AB12）得到从0开始的列号
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("单元格引用%s无效", ref)
+	}
+	return col - 1, nil
+}
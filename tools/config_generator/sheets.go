@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gameserver/tools/config_generator/sheet"
+)
+
+// convertSheets 把表格目录下的xlsx/csv转换为服务器JSON（以及可选的客户端JSON）
+// 返回JSON文件名到表格的映射，供后续检查schema使用
+func convertSheets(cfg *GeneratorConfig) (map[string]*sheet.Sheet, []error) {
+	sheets := make(map[string]*sheet.Sheet)
+	entries, err := ioutil.ReadDir(cfg.SheetDir)
+	if os.IsNotExist(err) {
+		return sheets, nil
+	}
+	if err != nil {
+		return nil, []error{fmt.Errorf("扫描表格目录失败: %v", err)}
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && sheet.IsSheet(entry.Name()) {
+			paths = append(paths, filepath.Join(cfg.SheetDir, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+	fmt.Printf("发现 %d 个表格文件\n", len(paths))
+
+	var errs []error
+	for _, path := range paths {
+		base := filepath.Base(path)
+		fileName := strings.TrimSuffix(base, filepath.Ext(base)) + ".json"
+		if other, ok := sheets[fileName]; ok {
+			errs = append(errs, fmt.Errorf("%s与%s都导出%s", other.Name, base, fileName))
+			continue
+		}
+
+		sh, err := sheet.Read(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sheets[fileName] = sh
+
+		if err := exportSheet(sh, sheet.TargetServer, filepath.Join(cfg.ConfigDir, fileName)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if cfg.ClientDir != "" {
+			if err := exportSheet(sh, sheet.TargetClient, filepath.Join(cfg.ClientDir, fileName)); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		fmt.Printf("表格转换: %s -> %s\n", base, fileName)
+	}
+	return sheets, errs
+}
+
+// exportSheet 导出表格到JSON文件，内容没有变化时不重写，避免触发服务器的配置热更新
+func exportSheet(sh *sheet.Sheet, target sheet.Target, path string) error {
+	rows, err := sh.Export(target)
+	if err != nil {
+		return err
+	}
+	data, err := sheet.MarshalRows(rows)
+	if err != nil {
+		return err
+	}
+	if old, err := ioutil.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// sheetSchema 根据表头生成schema草稿，只包含导出到服务器的列
+func sheetSchema(structName string, sh *sheet.Sheet) *TableSchema {
+	schema := &TableSchema{Struct: structName}
+	for _, col := range sh.Columns {
+		if !col.ExportTo(sheet.TargetServer) {
+			continue
+		}
+		comment := col.Comment
+		if comment == "" {
+			comment = col.Name
+		}
+		schema.Fields = append(schema.Fields, &FieldSchema{
+			Name:     col.Name,
+			Type:     col.Type,
+			Comment:  comment,
+			Required: col.Name == "id",
+		})
+	}
+	return schema
+}
+
+// checkSheetSchema 检查表头与schema是否一致：导出到服务器的列必须在schema中且类型相同，必填字段必须有对应的列
+func checkSheetSchema(fileName string, sh *sheet.Sheet, schema *TableSchema) []error {
+	var errs []error
+	fields := make(map[string]*FieldSchema, len(schema.Fields))
+	for _, f := range schema.Fields {
+		fields[f.Name] = f
+	}
+	columns := make(map[string]bool)
+	for _, col := range sh.Columns {
+		if !col.ExportTo(sheet.TargetServer) {
+			continue
+		}
+		columns[col.Name] = true
+		f, ok := fields[col.Name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: 表格列%s在schema中不存在", sh.Name, col.Name))
+		} else if f.Type != col.Type {
+			errs = append(errs, fmt.Errorf("%s: 表格列%s的类型%s与schema中的%s不一致", sh.Name, col.Name, col.Type, f.Type))
+		}
+	}
+	for _, f := range schema.Fields {
+		if f.Required && !columns[f.Name] {
+			errs = append(errs, fmt.Errorf("%s: 缺少%s的必填字段%s，或该列未导出到server", sh.Name, fileName, f.Name))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// IdRange proto文件头部声明的消息id区间，例如 "// C2S 300--399"，闭区间
+type IdRange struct {
+	Min  int
+	Max  int
+	File string
+}
+
+func (r IdRange) contains(id int) bool {
+	return id >= r.Min && id <= r.Max
+}
+
+func (r IdRange) String() string {
+	return fmt.Sprintf("%d--%d", r.Min, r.Max)
+}
+
+// FileIdRanges 单个proto文件的上下行id区间
+type FileIdRanges struct {
+	C2S *IdRange
+	S2C *IdRange
+}
+
+// 匹配区间声明的正则表达式
+var idRangeRegex = regexp.MustCompile(`^//\s*(C2S|S2C)\s+(\d+)\s*-+\s*(\d+)`)
+
+// parseIdRanges 解析proto文件中的id区间声明
+func parseIdRanges(protoFile string) (FileIdRanges, error) {
+	var ranges FileIdRanges
+
+	file, err := os.Open(protoFile)
+	if err != nil {
+		return ranges, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		matches := idRangeRegex.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if len(matches) < 4 {
+			continue
+		}
+		min, _ := strconv.Atoi(matches[2])
+		max, _ := strconv.Atoi(matches[3])
+		r := &IdRange{Min: min, Max: max, File: protoFile}
+		if min > max {
+			return ranges, fmt.Errorf("%s: 区间 %s 非法", protoFile, r)
+		}
+		if matches[1] == "C2S" {
+			ranges.C2S = r
+		} else {
+			ranges.S2C = r
+		}
+	}
+	return ranges, scanner.Err()
+}
+
+// CheckMessageIds 校验消息id: 重复、超出所属文件区间、区间重叠、C2S/S2C消息缺少id
+// 返回所有错误，便于一次性修复
+func (g *HandlerGenerator) CheckMessageIds(messages []MessageInfo) []error {
+	var errs []error
+
+	// 各文件的区间
+	fileRanges := make(map[string]FileIdRanges)
+	var allRanges []*IdRange
+	for _, msg := range messages {
+		if _, ok := fileRanges[msg.File]; ok {
+			continue
+		}
+		ranges, err := parseIdRanges(msg.File)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fileRanges[msg.File] = ranges
+		for _, r := range []*IdRange{ranges.C2S, ranges.S2C} {
+			if r != nil {
+				allRanges = append(allRanges, r)
+			}
+		}
+	}
+
+	// 区间不能重叠
+	sort.Slice(allRanges, func(i, j int) bool { return allRanges[i].Min < allRanges[j].Min })
+	for i := 1; i < len(allRanges); i++ {
+		prev, cur := allRanges[i-1], allRanges[i]
+		if cur.Min <= prev.Max {
+			errs = append(errs, fmt.Errorf("id区间重叠: %s %s 与 %s %s", prev.File, prev, cur.File, cur))
+		}
+	}
+
+	usedIds := make(map[int]MessageInfo)
+	for _, msg := range messages {
+		isC2S := strings.HasPrefix(msg.Name, "C2S_")
+		isS2C := strings.HasPrefix(msg.Name, "S2C_")
+
+		if msg.ID == "" {
+			if isC2S || isS2C {
+				errs = append(errs, fmt.Errorf("%s:%d: 消息 %s 未设置message_id", msg.File, msg.Line, msg.Name))
+			}
+			continue
+		}
+		if !isC2S && !isS2C {
+			errs = append(errs, fmt.Errorf("%s:%d: 只有C2S_/S2C_消息可以设置message_id, %s", msg.File, msg.Line, msg.Name))
+			continue
+		}
+
+		id, err := strconv.Atoi(msg.ID)
+		if err != nil || id <= 0 {
+			errs = append(errs, fmt.Errorf("%s:%d: 消息 %s 的message_id %s 非法", msg.File, msg.Line, msg.Name, msg.ID))
+			continue
+		}
+
+		if prev, ok := usedIds[id]; ok {
+			errs = append(errs, fmt.Errorf("%s:%d: 消息 %s 的message_id %d 与 %s:%d %s 重复",
+				msg.File, msg.Line, msg.Name, id, prev.File, prev.Line, prev.Name))
+		} else {
+			usedIds[id] = msg
+		}
+
+		ranges := fileRanges[msg.File]
+		r, dir := ranges.C2S, "C2S"
+		if isS2C {
+			r, dir = ranges.S2C, "S2C"
+		}
+		if r == nil {
+			errs = append(errs, fmt.Errorf("%s: 未声明%s的id区间，请在文件头部添加注释如 \"// %s 100--199\"", msg.File, dir, dir))
+			continue
+		}
+		if !r.contains(id) {
+			errs = append(errs, fmt.Errorf("%s:%d: 消息 %s 的message_id %d 超出%s区间 %s",
+				msg.File, msg.Line, msg.Name, id, dir, r))
+		}
+	}
+
+	return errs
+}
+
+// generateIdTable 生成common/msg/msg_id.go，Processor从中注册消息
+func (g *HandlerGenerator) generateIdTable(messages []MessageInfo) error {
+	var idMessages []MessageInfo
+	for _, msg := range messages {
+		if msg.ID != "" {
+			idMessages = append(idMessages, msg)
+		}
+	}
+	sort.Slice(idMessages, func(i, j int) bool {
+		a, _ := strconv.Atoi(idMessages[i].ID)
+		b, _ := strconv.Atoi(idMessages[j].ID)
+		return a < b
+	})
+
+	tmpl := template.Must(template.New("msg_id").Parse(`// Code generated by handler_generator. DO NOT EDIT.
+
+package msg
+
+import (
+	"gameserver/common/msg/message"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// 消息id，与proto中的message_id一致
+const (
+{{- range .}}
+	ID_{{.Name}} uint32 = {{.ID}}
+{{- end}}
+)
+
+// c2sMessages 客户端上行消息，启动时注册到Processor
+var c2sMessages = []proto.Message{
+{{- range .}}{{if eq (slice .Name 0 4) "C2S_"}}
+	&message.{{.Name}}{},
+{{- end}}{{end}}
+}
+`))
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, idMessages); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("格式化代码失败: %v", err)
+	}
+
+	idFile := filepath.Join(g.ModulesDir, "..", "common", "msg", "msg_id.go")
+	if err := os.WriteFile(idFile, src, 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+
+	fmt.Printf("已生成消息id表: %s\n", idFile)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 仓库中的proto必须通过校验
+func TestCheck_RepoProtos(t *testing.T) {
+	g := NewHandlerGenerator("../../common/msg/pb", "", "../../modules")
+	if _, err := g.Check(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheck_Errors(t *testing.T) {
+	dir := t.TempDir()
+	proto := `syntax = "proto3";
+import "message_id.proto";
+
+// C2S 300--399
+// S2C 400--499
+
+message C2S_A {
+    option (message_id) = 301;
+}
+
+message S2C_A {
+    option (message_id) = 401;
+}
+
+message S2C_B {
+    option (message_id) = 401;
+}
+
+message S2C_C {
+    option (message_id) = 501;
+}
+
+message C2S_D {
+}
+
+message Item {
+    option (message_id) = 302;
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "match.proto"), []byte(proto), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewHandlerGenerator(dir, "", "")
+	messages, err := g.parseProtoFile(filepath.Join(dir, "match.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := g.CheckMessageIds(messages)
+	expects := []string{"S2C_B 的message_id 401", "S2C_C 的message_id 501 超出S2C区间", "C2S_D 未设置message_id", "只有C2S_/S2C_消息可以设置message_id, Item"}
+	if len(errs) != len(expects) {
+		t.Fatalf("expect %d errors, got %v", len(expects), errs)
+	}
+	for i, expect := range expects {
+		if !strings.Contains(errs[i].Error(), expect) {
+			t.Errorf("error %d: expect %q, got %q", i, expect, errs[i])
+		}
+	}
+}
@@ -21,6 +21,8 @@ type MessageInfo struct {
 	Name   string
 	ID     string
 	Module string
+	File   string // 所在proto文件
+	Line   int    // message定义所在行
 }
 
 func NewHandlerGenerator(protoDir, outputDir, modulesDir string) *HandlerGenerator {
@@ -32,38 +34,19 @@ func NewHandlerGenerator(protoDir, outputDir, modulesDir string) *HandlerGenerat
 }
 
 func (g *HandlerGenerator) Generate() error {
-	// 先执行protoc命令生成Go文件
-	if err := g.runProtoc(); err != nil {
-		return fmt.Errorf("执行protoc失败: %v", err)
+	// 先校验消息id，避免生成有问题的代码
+	allMessages, err := g.Check()
+	if err != nil {
+		return err
 	}
 
-	var allMessages []MessageInfo
-	var existingHandlers []string
-
-	// 递归扫描所有proto文件
-	err := filepath.Walk(g.ProtoDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// 只处理.proto文件
-		if !info.IsDir() && strings.HasSuffix(path, ".proto") {
-			messages, err := g.parseProtoFile(path)
-			if err != nil {
-				return fmt.Errorf("解析proto文件 %s 失败: %v", path, err)
-			}
-			allMessages = append(allMessages, messages...)
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return fmt.Errorf("扫描proto文件失败: %v", err)
+	// 执行protoc命令生成Go文件
+	if err := g.runProtoc(); err != nil {
+		return fmt.Errorf("执行protoc失败: %v", err)
 	}
 
 	// 获取现有的handler文件列表
-	existingHandlers = g.getExistingHandlers()
+	existingHandlers := g.getExistingHandlers()
 
 	// 过滤出C2S开头的消息
 	var c2sMessages []MessageInfo
@@ -78,6 +61,11 @@ func (g *HandlerGenerator) Generate() error {
 		return fmt.Errorf("清理已删除的handler失败: %v", err)
 	}
 
+	// 生成消息id表，Processor从中注册消息
+	if err := g.generateIdTable(allMessages); err != nil {
+		return fmt.Errorf("生成消息id表失败: %v", err)
+	}
+
 	if len(c2sMessages) == 0 {
 		fmt.Println("未找到C2S开头的消息")
 		return nil
@@ -93,6 +81,42 @@ func (g *HandlerGenerator) Generate() error {
 	return nil
 }
 
+// Check 扫描所有proto文件并校验消息id
+func (g *HandlerGenerator) Check() ([]MessageInfo, error) {
+	var allMessages []MessageInfo
+
+	// 递归扫描所有proto文件
+	err := filepath.Walk(g.ProtoDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// 只处理.proto文件
+		if !info.IsDir() && strings.HasSuffix(path, ".proto") {
+			messages, err := g.parseProtoFile(path)
+			if err != nil {
+				return fmt.Errorf("解析proto文件 %s 失败: %v", path, err)
+			}
+			allMessages = append(allMessages, messages...)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("扫描proto文件失败: %v", err)
+	}
+
+	if errs := g.CheckMessageIds(allMessages); len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Printf("  %v\n", e)
+		}
+		return nil, fmt.Errorf("消息id校验失败，共 %d 个错误", len(errs))
+	}
+
+	return allMessages, nil
+}
+
 func (g *HandlerGenerator) parseProtoFile(protoFile string) ([]MessageInfo, error) {
 	file, err := os.Open(protoFile)
 	if err != nil {
@@ -110,17 +134,21 @@ func (g *HandlerGenerator) parseProtoFile(protoFile string) ([]MessageInfo, erro
 
 	var currentMessage string
 	var currentID string
+	var currentLine int
+	lineNum := 0
 
 	// 根据proto文件路径判断模块
 	module := g.detectModuleFromPath(protoFile)
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
+		lineNum++
 
 		// 检查是否是message定义
 		if matches := messageRegex.FindStringSubmatch(line); len(matches) > 1 {
 			currentMessage = matches[1]
 			currentID = ""
+			currentLine = lineNum
 		}
 
 		// 检查是否是message_id
@@ -134,6 +162,8 @@ func (g *HandlerGenerator) parseProtoFile(protoFile string) ([]MessageInfo, erro
 				Name:   currentMessage,
 				ID:     currentID,
 				Module: module,
+				File:   protoFile,
+				Line:   currentLine,
 			})
 			currentMessage = ""
 			currentID = ""
@@ -228,8 +258,9 @@ func {{.Name}}Handler(args []interface{}) {
 		return
 	}
 
-	log.Debug("收到{{.Name}}消息: %v, agent: %v", msg, agent)
+	// 消息日志、耗时统计、参数校验由Processor中间件统一处理
 	// TODO: 实现具体的业务逻辑
+	_, _ = msg, agent
 }
 `))
 
@@ -456,11 +487,6 @@ func (g *HandlerGenerator) updateRegistrationFiles(msg MessageInfo) error {
 		return fmt.Errorf("更新路由失败: %v", err)
 	}
 
-	// 3. 更新common/msg/msg.go文件
-	if err := g.updateMsgProcessor(msg); err != nil {
-		return fmt.Errorf("更新消息处理器失败: %v", err)
-	}
-
 	return nil
 }
 
@@ -572,59 +598,6 @@ func (g *HandlerGenerator) updateRouter(msg MessageInfo) error {
 	return nil
 }
 
-// 更新common/msg/msg.go文件
-func (g *HandlerGenerator) updateMsgProcessor(msg MessageInfo) error {
-	msgFile := filepath.Join(g.ModulesDir, "..", "common", "msg", "msg.go")
-
-	// 检查文件是否存在
-	if _, err := os.Stat(msgFile); err != nil {
-		fmt.Printf("消息处理器文件不存在，跳过更新: %s\n", msgFile)
-		return nil
-	}
-
-	// 读取文件内容
-	content, err := os.ReadFile(msgFile)
-	if err != nil {
-		return fmt.Errorf("读取文件失败: %v", err)
-	}
-
-	// 检查是否已经注册了该消息
-	processorLine := fmt.Sprintf(`	Processor.Register(&message.%s{})`, msg.Name)
-	if strings.Contains(string(content), processorLine) {
-		fmt.Printf("消息 %s 已在消息处理器中注册，跳过\n", msg.Name)
-		return nil
-	}
-
-	// 在init函数中添加注册
-	lines := strings.Split(string(content), "\n")
-	var newLines []string
-	inInit := false
-	added := false
-
-	for _, line := range lines {
-		newLines = append(newLines, line)
-
-		// 检查是否在init函数中
-		if strings.Contains(line, "func init() {") {
-			inInit = true
-		}
-
-		// 在init函数的最后添加注册
-		if inInit && strings.Contains(line, "}") && !added {
-			newLines = append(newLines, fmt.Sprintf("	Processor.Register(&message.%s{})", msg.Name))
-			added = true
-		}
-	}
-
-	// 写回文件
-	if err := os.WriteFile(msgFile, []byte(strings.Join(newLines, "\n")), 0644); err != nil {
-		return fmt.Errorf("写入文件失败: %v", err)
-	}
-
-	fmt.Printf("已更新消息处理器文件: %s\n", msgFile)
-	return nil
-}
-
 // 获取现有的handler文件列表
 func (g *HandlerGenerator) getExistingHandlers() []string {
 	var handlers []string
@@ -726,11 +699,6 @@ func (g *HandlerGenerator) removeFromRegistrationFiles(msg MessageInfo) error {
 		return fmt.Errorf("从路由中移除失败: %v", err)
 	}
 
-	// 3. 从消息处理器文件中移除
-	if err := g.removeFromMsgProcessor(msg); err != nil {
-		return fmt.Errorf("从消息处理器中移除失败: %v", err)
-	}
-
 	return nil
 }
 
@@ -807,40 +775,3 @@ func (g *HandlerGenerator) removeFromRouter(msg MessageInfo) error {
 	fmt.Printf("已从路由文件中移除: %s\n", msg.Name)
 	return nil
 }
-
-// 从消息处理器文件中移除消息
-func (g *HandlerGenerator) removeFromMsgProcessor(msg MessageInfo) error {
-	msgFile := filepath.Join(g.ModulesDir, "..", "common", "msg", "msg.go")
-
-	// 检查文件是否存在
-	if _, err := os.Stat(msgFile); err != nil {
-		return nil // 文件不存在，无需处理
-	}
-
-	// 读取文件内容
-	content, err := os.ReadFile(msgFile)
-	if err != nil {
-		return fmt.Errorf("读取文件失败: %v", err)
-	}
-
-	// 要移除的行
-	lineToRemove := fmt.Sprintf("	Processor.Register(&message.%s{})", msg.Name)
-
-	// 移除包含该消息的行
-	lines := strings.Split(string(content), "\n")
-	var newLines []string
-
-	for _, line := range lines {
-		if !strings.Contains(line, lineToRemove) {
-			newLines = append(newLines, line)
-		}
-	}
-
-	// 写回文件
-	if err := os.WriteFile(msgFile, []byte(strings.Join(newLines, "\n")), 0644); err != nil {
-		return fmt.Errorf("写入文件失败: %v", err)
-	}
-
-	fmt.Printf("已从消息处理器文件中移除: %s\n", msg.Name)
-	return nil
-}
@@ -10,10 +10,12 @@ func main() {
 	var protoDir string
 	var outputDir string
 	var modulesDir string
+	var checkOnly bool
 
 	flag.StringVar(&protoDir, "proto", "", "proto文件目录")
 	flag.StringVar(&outputDir, "output", "", "输出目录")
 	flag.StringVar(&modulesDir, "modules", "", "modules目录")
+	flag.BoolVar(&checkOnly, "check", false, "只校验消息id，不生成文件")
 	flag.Parse()
 
 	if protoDir == "" {
@@ -34,6 +36,15 @@ func main() {
 	fmt.Printf("Modules目录: %s\n", modulesDir)
 
 	generator := NewHandlerGenerator(protoDir, outputDir, modulesDir)
+	if checkOnly {
+		if _, err := generator.Check(); err != nil {
+			fmt.Printf("校验失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("消息id校验通过")
+		return
+	}
+
 	if err := generator.Generate(); err != nil {
 		fmt.Printf("生成失败: %v\n", err)
 		os.Exit(1)